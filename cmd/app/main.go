@@ -15,17 +15,20 @@ import (
 	"otus-highload-arh-homework/internal/social/repository/postgres"
 	cachewarmer "otus-highload-arh-homework/internal/social/transport/cache"
 	"otus-highload-arh-homework/internal/social/transport/clients/dialog/grpc"
+	"otus-highload-arh-homework/internal/social/transport/presence"
 	"otus-highload-arh-homework/internal/social/transport/server"
 	authInternal "otus-highload-arh-homework/internal/social/transport/service"
+	"otus-highload-arh-homework/internal/social/transport/websocket"
 	authUC "otus-highload-arh-homework/internal/social/usecase/auth"
 	postUC "otus-highload-arh-homework/internal/social/usecase/post"
 	userUC "otus-highload-arh-homework/internal/social/usecase/user"
 	"otus-highload-arh-homework/pkg/auth"
-	"otus-highload-arh-homework/pkg/clients/kafka"
 	"otus-highload-arh-homework/pkg/clients/pg"
 	"otus-highload-arh-homework/pkg/clients/redis"
 
 	"github.com/pressly/goose/v3"
+	grpcgo "google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 )
 
 // @title Social Network API
@@ -70,19 +73,18 @@ func main() {
 		}
 	}()
 
-	// Инициализация Kafka Producer
-	feedProducer := kafka.NewProducer(
-		[]string{cfg.Kafka.Address},
-		cfg.Kafka.FeedUpdatesTopic,
-	)
-	defer func(feedProducer *kafka.Producer) {
-		err := feedProducer.Close()
+	// С DIALOG_TLS_CA_FILE сервис диалогов проверяется по CA, а app
+	// предъявляет ему свой сертификат
+	var dialogOpts []grpcgo.DialOption
+	if cfg.Dialog.TLS.CAFile != "" {
+		tlsConfig, err := auth.ClientTLSConfig(cfg.Dialog.TLS.ClientCertFile, cfg.Dialog.TLS.ClientKeyFile, cfg.Dialog.TLS.CAFile, cfg.Dialog.TLS.ServerName)
 		if err != nil {
-			log.Printf("Failed to close Kafka producer: %v", err)
+			log.Fatalf("Failed to load dialog TLS: %v", err)
 		}
-	}(feedProducer)
+		dialogOpts = append(dialogOpts, grpcgo.WithTransportCredentials(credentials.NewTLS(tlsConfig)))
+	}
 
-	dialogClient, err := grpc.New(cfg.Dialog.ClientAddr, cfg.Dialog.Timeout)
+	dialogClient, err := grpc.New(cfg.Dialog.ClientAddr, cfg.Dialog.Timeout, dialogOpts...)
 	if err != nil {
 		log.Fatalf("Failed to initialize Dialog gRPC client: %v", err)
 	}
@@ -100,30 +102,60 @@ func main() {
 	// 3. Репозитории
 	userRepo := postgres.NewUserRepository(pgPool)
 	postRepo := postgres.NewPostRepository(pgPool)
+	outboxRepo := postgres.NewOutboxRepository(pgPool)
+	txManager := postgres.NewTxManager(pgPool)
 
 	// 4. Инициализация очереди и CacheWarmer
-	redisQueue := cachewarmer.NewRedisQueue(redisClient)
-	cacheWarmer := cachewarmer.New(redisQueue, redisClient)
+	cacheStorage, err := cachewarmer.NewStorage(ctx, &cfg.Cache, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to initialize cache storage: %v", err)
+	}
+
+	taskQueue, err := cachewarmer.NewTaskQueue(&cfg.Cache, redisClient, &cfg.Kafka)
+	if err != nil {
+		log.Fatalf("Failed to initialize cache task queue: %v", err)
+	}
+	defer func() {
+		if err := taskQueue.Close(); err != nil {
+			log.Printf("Failed to close cache task queue: %v", err)
+		}
+	}()
+
+	cacheWarmer := cachewarmer.New(taskQueue, cacheStorage, cfg.Cache.WarmDedupWindow)
 
 	// 5. Бизнес слои
 	authUseCase := authUC.NewAuth(userRepo, hasher, cacheWarmer)
 	userUseCase := userUC.New(userRepo)
-	friendUseCase := userUC.NewFriendUseCase(userRepo)
-	postUseCase := postUC.NewPostUseCase(postRepo)
+	friendUseCase := userUC.NewFriendUseCase(userRepo, txManager, outboxRepo)
+	postUseCase := postUC.NewPostUseCase(postRepo, txManager, outboxRepo)
+
+	// Присутствие: активность в API продлевает online, а изменения статуса
+	// доставляются друзьям через websocket-узлы feed-updater
+	presenceStore := presence.NewStore(redisClient, cfg.Presence.TTL)
+	wsRegistry := websocket.NewRegistry(redisClient, cfg.WS.NodeTTL)
+	wsHistory := websocket.NewHistory(redisClient, cfg.WS.HistorySize, cfg.WS.HistoryTTL)
+	wsRouter := websocket.NewRouter(redisClient, wsRegistry, wsHistory)
+	presenceTracker := presence.NewTracker(presenceStore, userRepo, wsRouter, wsRegistry)
 
 	// 6. Сервисы транспортного уровня
 	jwtService := authInternal.NewJWTGenerator(cfg.Auth.JwtSecretKey, cfg.Auth.JwtDuration)
 	authService := authInternal.NewAuthService(authUseCase, jwtService)
-	userService := authInternal.NewUserService(userUseCase, friendUseCase, dialogClient)
-	postService := authInternal.NewPostService(postUseCase, friendUseCase, cacheWarmer, feedProducer)
+	userService := authInternal.NewUserService(userUseCase, friendUseCase, presenceStore, dialogClient)
+	postService := authInternal.NewPostService(postUseCase, friendUseCase, cacheWarmer, authInternal.FeedCacheConfig{
+		SoftTTL: cfg.Cache.FeedSoftTTL,
+		HardTTL: cfg.Cache.TTL,
+	})
 
 	// 7. Запуск воркеров для обработки задач прогрева кэша
-	go func() {
-		log.Println("Starting StartCacheWorkers...", cfg.Cache.NumWorkers)
-		cachewarmer.StartCacheWorkers(ctx, redisClient, cfg.Cache.NumWorkers, postService)
-	}()
+	log.Println("Starting StartCacheWorkers...", cfg.Cache.NumWorkers)
+	cacheWorkers := cachewarmer.StartCacheWorkers(ctx, taskQueue, &cfg.Cache, postService, cacheWarmer)
 
-	srv := server.New(authService, userService, postService, jwtService)
+	// Ручки /api/v1/dialog обслуживает сервис диалогов, замена - /api/v2/dialog
+	srv := server.New(authService, userService, postService, jwtService, presenceTracker, server.Deprecation{
+		DeprecatedAt: cfg.Dialog.V1DeprecatedAt,
+		Sunset:       cfg.Dialog.V1Sunset,
+		Successor:    "/api/v2/dialog",
+	})
 
 	// Запуск сервера
 	go func() {
@@ -146,6 +178,13 @@ func main() {
 	} else {
 		log.Println("Server stopped gracefully")
 	}
+
+	// Дожидаемся завершения задач прогрева, взятых в работу
+	if err := cacheWorkers.Wait(shutdownCtx); err != nil {
+		log.Printf("Cache workers shutdown error: %v", err)
+	} else {
+		log.Println("Cache workers stopped gracefully")
+	}
 }
 
 func runMigrations(dbURL string) error {
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	cachewarmer "otus-highload-arh-homework/internal/social/transport/cache"
+	"otus-highload-arh-homework/pkg/clients/redis"
+
+	"github.com/caarlos0/env/v9"
+	"github.com/joho/godotenv"
+)
+
+// Административная утилита для DLQ задач прогрева кэша в Redis (CACHE_QUEUE_TYPE=redis):
+//
+//	cache-dlq -list -count 20    - показать задачи в DLQ
+//	cache-dlq -requeue -count 20 - вернуть задачи в основной стрим
+func main() {
+	list := flag.Bool("list", false, "list dead-lettered warm tasks")
+	requeue := flag.Bool("requeue", false, "move dead-lettered warm tasks back to the task stream")
+	count := flag.Int64("count", 100, "max number of tasks to process")
+	flag.Parse()
+
+	if *list == *requeue {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_ = godotenv.Load()
+
+	var cfg redis.Config
+	if err := env.Parse(&cfg); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	client, err := redis.New(ctx, &cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis: %v", err)
+	}
+	defer func() {
+		if err := redis.Close(client); err != nil {
+			log.Printf("Failed to close Redis connection: %v", err)
+		}
+	}()
+
+	if *list {
+		letters, err := cachewarmer.ListDeadLetters(ctx, client, *count)
+		if err != nil {
+			log.Fatalf("Failed to list DLQ: %v", err)
+		}
+
+		for _, letter := range letters {
+			log.Printf("%s user_id=%v attempt=%v failed_at=%v error=%v",
+				letter.ID, letter.Values["user_id"], letter.Values["attempt"],
+				letter.Values["failed_at"], letter.Values["error"])
+		}
+		log.Printf("Total: %d", len(letters))
+
+		return
+	}
+
+	requeued, err := cachewarmer.RequeueDeadLetters(ctx, client, *count)
+	if err != nil {
+		log.Printf("Requeue stopped: %v", err)
+	}
+	log.Printf("Requeued %d tasks", requeued)
+}
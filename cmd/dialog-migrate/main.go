@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"otus-highload-arh-homework/internal/social/config"
+	"otus-highload-arh-homework/internal/social/repository/postgres"
+	"otus-highload-arh-homework/pkg/clients/pg"
+
+	"github.com/caarlos0/env/v9"
+	"github.com/joho/godotenv"
+)
+
+// Перенос диалогов из общей базы (PG_*) в выделенную (DIALOG_PG_*):
+//
+//	dialog-migrate -copy [-reset]    - перенести dialogs, messages и dialog_inbox
+//	                                   пачками с места прошлой остановки
+//	dialog-migrate -verify [-repair] - сверить число строк и контрольные суммы,
+//	                                   с -repair перенести несовпавшие диапазоны
+//
+// Порядок переезда: DIALOG_DB_MODE=dual, -copy, -verify -repair до полного
+// совпадения, затем DIALOG_DB_MODE=dedicated
+func main() {
+	copyRows := flag.Bool("copy", false, "copy dialog tables into the dedicated database")
+	reset := flag.Bool("reset", false, "start copying from the beginning instead of the saved position")
+	verify := flag.Bool("verify", false, "compare row counts and checksums of both databases")
+	repair := flag.Bool("repair", false, "recopy key ranges that failed verification")
+	batch := flag.Int("batch", 1000, "rows per batch")
+	flag.Parse()
+
+	if *copyRows == *verify {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_ = godotenv.Load()
+
+	var sourceConfig pg.Config
+	if err := env.Parse(&sourceConfig); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+	targetConfig, err := config.LoadDialogPG()
+	if err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	source, err := pg.New(ctx, &sourceConfig)
+	if err != nil {
+		log.Fatalf("Failed to init source PG: %v", err)
+	}
+	defer source.Close()
+
+	target, err := pg.New(ctx, targetConfig)
+	if err != nil {
+		log.Fatalf("Failed to init dialog PG: %v", err)
+	}
+	defer target.Close()
+
+	migrator := postgres.NewDialogMigrator(source, target, *batch)
+
+	if *copyRows {
+		if err := migrator.Copy(ctx, *reset); err != nil {
+			log.Fatalf("Copy stopped: %v", err)
+		}
+		log.Println("Copy finished, run -verify to compare the databases")
+
+		return
+	}
+
+	results, err := migrator.Verify(ctx, *repair)
+	if err != nil {
+		log.Fatalf("Verify stopped: %v", err)
+	}
+
+	unresolved, repaired := 0, 0
+	for _, res := range results {
+		log.Printf("%s: source=%d target=%d mismatched ranges=%d repaired=%d first=%s",
+			res.Table, res.SourceRows, res.TargetRows, res.Mismatches, res.Repaired, res.FirstMismatch)
+		unresolved += res.Mismatches - res.Repaired
+		repaired += res.Repaired
+	}
+
+	switch {
+	case unresolved > 0:
+		log.Printf("Databases differ in %d ranges, rerun with -repair", unresolved)
+		os.Exit(1)
+	case repaired > 0:
+		log.Printf("Repaired %d ranges, rerun -verify to confirm", repaired)
+	default:
+		log.Println("Databases match")
+	}
+}
@@ -2,18 +2,33 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"syscall"
 
 	"otus-highload-arh-homework/internal/social/config"
+	"otus-highload-arh-homework/internal/social/repository"
 	postgres2 "otus-highload-arh-homework/internal/social/repository/postgres"
+	redisRepo "otus-highload-arh-homework/internal/social/repository/redis"
 	grpcServer "otus-highload-arh-homework/internal/social/transport/server/dialog/grpc"
+	authInternal "otus-highload-arh-homework/internal/social/transport/service"
 	userUC "otus-highload-arh-homework/internal/social/usecase/user"
+	"otus-highload-arh-homework/pkg/auth"
 	"otus-highload-arh-homework/pkg/clients/pg"
+	"otus-highload-arh-homework/pkg/clients/redis"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/pressly/goose/v3"
+	goredis "github.com/redis/go-redis/v9"
 )
 
+// reconcilerLockKey - ключ advisory-блокировки лидера сверки счетчиков
+const reconcilerLockKey = 7_300_002
+
 func main() {
 	log.Println("Starting application...")
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -28,15 +43,85 @@ func main() {
 	}
 	defer pgPool.Close()
 
+	redisClient, err := redis.New(ctx, &cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis: %v", err)
+	}
+	defer func() {
+		if err := redis.Close(redisClient); err != nil {
+			log.Printf("Failed to close Redis connection: %v", err)
+		}
+	}()
+
+	// 2. Выделенная база диалогов, в режиме shared не используется
+	var dialogPool *pgxpool.Pool
+	if cfg.Dialog.DBMode == "dual" || cfg.Dialog.DBMode == "dedicated" {
+		dialogPGConfig, err := config.LoadDialogPG()
+		if err != nil {
+			log.Fatalf("Failed to load dialog PG config: %v", err)
+		}
+
+		dialogPool, err = pg.New(ctx, dialogPGConfig)
+		if err != nil {
+			log.Fatalf("Failed to init dialog PG: %v", err)
+		}
+		defer dialogPool.Close()
+
+		if err := runMigrations(dialogPGConfig.URL, cfg.Dialog.MigrationsDir); err != nil {
+			log.Fatalf("Failed to run dialog migrations: %v", err)
+		}
+	}
+
 	// 3. Репозитории
-	userRepo := postgres2.NewUserRepository(pgPool)
-	userUseCase := userUC.New(userRepo)
+	storage, err := newDialogStorage(ctx, cfg, pgPool, dialogPool, redisClient)
+	if err != nil {
+		log.Fatalf("Failed to init dialog storage: %v", err)
+	}
+	unreadCounters := redisRepo.NewUnreadCounters(redisClient, cfg.Dialog.UnreadTTL)
+	// Группы пока остаются в общей базе
+	txManager := postgres2.NewTxManager(pgPool)
+	outboxRepo := postgres2.NewOutboxRepository(pgPool)
+
+	// Сигналы о новых сообщениях для потоков StreamMessages со всех экземпляров
+	notifier := redisRepo.NewMessageNotifier(redisClient)
+	if err := notifier.Start(ctx); err != nil {
+		log.Fatalf("Failed to start message notifier: %v", err)
+	}
+
+	dialogUseCase := userUC.NewDialogUseCase(storage.repo, storage.txManager, storage.outbox, unreadCounters, notifier, cfg.Dialog.EditWindow)
+	groupUseCase := userUC.NewGroupUseCase(postgres2.NewGroupRepository(pgPool), txManager, outboxRepo)
+
+	// Пользователь вызова берется из токена, выданного app, или из x-user-id
+	// доверенного клиента с сертификатом
+	grpcAuth := grpcServer.Auth{
+		Tokens:         authInternal.NewJWTGenerator(cfg.Auth.JwtSecretKey, cfg.Auth.JwtDuration),
+		TrustedClients: cfg.Dialog.TLS.TrustedClients,
+	}
+	if cfg.Dialog.TLS.CAFile != "" {
+		grpcAuth.TLS, err = auth.ServerTLSConfig(cfg.Dialog.TLS.CertFile, cfg.Dialog.TLS.KeyFile, cfg.Dialog.TLS.CAFile)
+		if err != nil {
+			log.Fatalf("Failed to load dialog TLS: %v", err)
+		}
+	}
 
-	srv, err := grpcServer.New(userUseCase, cfg.Dialog.Address)
+	srv, err := grpcServer.New(dialogUseCase, groupUseCase, cfg.Dialog.Address, grpcServer.Keepalive{
+		Time:    cfg.Dialog.KeepaliveTime,
+		Timeout: cfg.Dialog.KeepaliveTimeout,
+	}, grpcAuth)
 	if err != nil {
 		log.Fatalf("Failed to create gRPC server: %v", err)
 	}
 
+	// Сверка счетчиков непрочитанных после сбоев их обновления при отправке
+	reconciler := userUC.NewUnreadReconciler(
+		storage.repo,
+		unreadCounters,
+		postgres2.NewAdvisoryLock(pgPool, reconcilerLockKey),
+		cfg.Dialog.ReconcileInterval,
+		cfg.Dialog.ReconcileBatchSize,
+	)
+	go reconciler.Run(ctx)
+
 	go func() {
 		log.Printf("gRPC server listening on port %s", cfg.Dialog.Address)
 		if err := srv.Run(); err != nil {
@@ -51,3 +136,71 @@ func main() {
 	srv.Stop()
 	log.Println("Server stopped gracefully")
 }
+
+// dialogStorage - хранилище диалогов и база, в транзакциях которой usecase
+// диалогов пишет события outbox
+type dialogStorage struct {
+	repo      repository.DialogRepository
+	txManager *postgres2.TxManager
+	outbox    repository.OutboxRepository
+}
+
+// newDialogStorage выбирает базу по DIALOG_DB_MODE и хранилище сообщений по
+// DIALOG_STORAGE. В режиме dual записи переносятся в выделенную базу
+func newDialogStorage(ctx context.Context, cfg *config.Config, sharedPool, dialogPool *pgxpool.Pool, redisClient *goredis.Client) (*dialogStorage, error) {
+	pool := sharedPool
+	switch cfg.Dialog.DBMode {
+	case "", "shared", "dual":
+	case "dedicated":
+		pool = dialogPool
+		// Строки, перенесенные в режиме dual, не двигали последовательности
+		if err := postgres2.AlignDialogSequences(ctx, dialogPool); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown dialog db mode %q", cfg.Dialog.DBMode)
+	}
+
+	storage := &dialogStorage{
+		txManager: postgres2.NewTxManager(pool),
+		outbox:    postgres2.NewOutboxRepository(pool),
+	}
+
+	switch cfg.Dialog.Storage {
+	case "", "postgres":
+		storage.repo = postgres2.NewDialogRepository(pool)
+		if cfg.Dialog.DBMode == "dual" {
+			storage.repo = postgres2.NewDualWriteDialogRepository(storage.repo, postgres2.NewDialogMirror(sharedPool, dialogPool))
+		}
+	case "redis":
+		repo, err := redisRepo.NewDialogRepository(ctx, redisClient)
+		if err != nil {
+			return nil, err
+		}
+		storage.repo = repo
+	default:
+		return nil, fmt.Errorf("unknown dialog storage %q", cfg.Dialog.Storage)
+	}
+
+	return storage, nil
+}
+
+// runMigrations применяет миграции выделенной базы диалогов. Они лежат
+// отдельно от миграций общей базы, которые применяет cmd/app
+func runMigrations(dbURL, dir string) (err error) {
+	db, err := sql.Open("postgres", dbURL)
+	if err != nil {
+		return err
+	}
+	defer func(db *sql.DB) {
+		err = errors.Join(err, db.Close())
+	}(db)
+
+	goose.SetBaseFS(os.DirFS(dir))
+
+	if err := goose.SetDialect("postgres"); err != nil {
+		return err
+	}
+
+	return goose.Up(db, ".")
+}
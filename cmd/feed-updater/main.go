@@ -2,42 +2,48 @@ package main
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	nethttp "net/http"
 	"os"
 	"os/signal"
+	"strconv"
+	"sync"
 	"syscall"
 
+	"otus-highload-arh-homework/docs"
 	"otus-highload-arh-homework/internal/social/config"
 	"otus-highload-arh-homework/internal/social/handler/http"
 	"otus-highload-arh-homework/internal/social/repository/postgres"
-	"otus-highload-arh-homework/internal/social/transport/dto"
+	"otus-highload-arh-homework/internal/social/transport/consumer"
+	"otus-highload-arh-homework/internal/social/transport/presence"
 	"otus-highload-arh-homework/internal/social/transport/service"
 	"otus-highload-arh-homework/internal/social/transport/websocket"
 	userUC "otus-highload-arh-homework/internal/social/usecase/user"
 	"otus-highload-arh-homework/pkg/clients/pg"
+	"otus-highload-arh-homework/pkg/clients/redis"
+	"otus-highload-arh-homework/pkg/events"
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
 
 	"github.com/gin-gonic/gin"
-	"github.com/segmentio/kafka-go"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
+const consumerGroup = "feed-updaters"
+
+var feedEventTypes = map[eventsv1.PostAction]string{
+	eventsv1.PostAction_POST_ACTION_CREATED: websocket.EventFeedPostCreated,
+	eventsv1.PostAction_POST_ACTION_UPDATED: websocket.EventFeedPostUpdated,
+	eventsv1.PostAction_POST_ACTION_DELETED: websocket.EventFeedPostDeleted,
+}
+
 func main() {
 	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer cancel()
 
 	cfg := config.Load()
 
-	// Инициализация Kafka Consumer
-	r := kafka.NewReader(kafka.ReaderConfig{
-		Brokers:  []string{cfg.Kafka.Address},
-		GroupID:  "feed-updaters",
-		Topic:    cfg.Kafka.FeedUpdatesTopic,
-		MinBytes: 10e3, // 10KB
-		MaxBytes: 10e6, // 10MB
-	})
-	defer r.Close()
-
 	pgPool, err := pg.New(ctx, &cfg.PG)
 	if err != nil {
 		log.Fatalf("Failed to init PG: %v", err)
@@ -46,76 +52,217 @@ func main() {
 
 	jwtService := service.NewJWTGenerator(cfg.Auth.JwtSecretKey, cfg.Auth.JwtDuration)
 	userRepo := postgres.NewUserRepository(pgPool)
-	friendUseCase := userUC.NewFriendUseCase(userRepo)
+	friendUseCase := userUC.NewFriendUseCase(userRepo, postgres.NewTxManager(pgPool), postgres.NewOutboxRepository(pgPool))
+
+	redisClient, err := redis.New(ctx, &cfg.Redis)
+	if err != nil {
+		log.Fatalf("Failed to initialize Redis: %v", err)
+	}
+	defer func() {
+		if err := redis.Close(redisClient); err != nil {
+			log.Printf("Failed to close Redis connection: %v", err)
+		}
+	}()
 
-	// Инициализация WebSocket сервера
+	// Инициализация WebSocket сервера. Узел регистрирует соединения в Redis,
+	// а Router доставляет уведомления на узел, где подключен пользователь
 	wsServer := websocket.NewServer()
+	history := websocket.NewHistory(redisClient, cfg.WS.HistorySize, cfg.WS.HistoryTTL)
+	wsServer.SetHistory(history)
+	registry := websocket.NewRegistry(redisClient, cfg.WS.NodeTTL)
+	wsNode := websocket.NewNode(redisClient, wsServer, registry, cfg.WS.NodeID)
+	if err := wsNode.Start(ctx); err != nil {
+		log.Fatalf("Failed to start WebSocket node: %v", err)
+	}
+	wsRouter := websocket.NewRouter(redisClient, registry, history)
+	wsServer.SetRouter(wsRouter)
+	presenceTracker := presence.NewTracker(presence.NewStore(redisClient, cfg.Presence.TTL), userRepo, wsRouter, registry)
+	wsServer.SetPresence(presenceTracker)
+
 	router := gin.Default()
+	router.GET("/metrics", gin.WrapH(promhttp.Handler()))
 
 	// Добавляем аутентификацию (используем тот же middleware, что и в API)
-	router.GET("/ws/post/feed/posted", http.AuthMiddleware(jwtService), func(c *gin.Context) {
+	router.GET("/ws/post/feed/posted", http.AuthMiddleware(jwtService, presenceTracker), func(c *gin.Context) {
 		userID := c.GetInt("userID") // Получаем из middleware
 		wsServer.HandleConnection(c.Writer, c.Request, userID)
 	})
+	// Схема протокола для генерации клиентов
+	router.GET("/ws/schema", func(c *gin.Context) {
+		c.Data(nethttp.StatusOK, "application/schema+json", docs.WebsocketSchema)
+	})
+	// Тот же поток событий для клиентов без websocket
+	router.GET("/sse/post/feed/posted", http.AuthMiddleware(jwtService, presenceTracker), func(c *gin.Context) {
+		wsServer.HandleSSE(c.Writer, c.Request, c.GetInt("userID"))
+	})
 
 	// Запускаем HTTP-сервер для WebSocket
 	go func() {
-		log.Printf("WebSocket server starting on %s", cfg.WS.Port)
+		log.Printf("WebSocket node %s starting on %s", wsNode.ID(), cfg.WS.Port)
 		if err := router.Run(cfg.WS.Port); err != nil {
 			log.Fatalf("WebSocket server failed: %v", err)
 		}
 	}()
 
+	// Смещения фиксируются после обработки; упавшие события проходят
+	// через топики повтора и попадают в DLQ. Обработанные события
+	// запоминаются отдельно для каждого топика
+	feedConsumer := consumer.NewKafkaConsumer(
+		[]string{cfg.Kafka.Address},
+		cfg.Kafka.FeedUpdatesTopic,
+		cfg.Kafka.FeedUpdatesDLQTopic,
+		consumerGroup,
+		&cfg.FeedUpdater,
+		consumer.NewRedisDeduplicator(redisClient, consumerGroup+":"+cfg.Kafka.FeedUpdatesTopic, cfg.FeedUpdater.DedupTTL),
+		func(ctx context.Context, env *eventsv1.Envelope) error {
+			return processFeedUpdate(ctx, env, wsRouter, friendUseCase)
+		},
+	)
+	// Сообщения диалогов доставляются получателю тем же websocket-сервером
+	dialogConsumer := consumer.NewKafkaConsumer(
+		[]string{cfg.Kafka.Address},
+		cfg.Kafka.DialogEventsTopic,
+		cfg.Kafka.DialogEventsDLQTopic,
+		consumerGroup,
+		&cfg.FeedUpdater,
+		consumer.NewRedisDeduplicator(redisClient, consumerGroup+":"+cfg.Kafka.DialogEventsTopic, cfg.FeedUpdater.DedupTTL),
+		func(ctx context.Context, env *eventsv1.Envelope) error {
+			return processDialogEvent(ctx, env, wsRouter)
+		},
+	)
+	defer func() {
+		if err := errors.Join(feedConsumer.Close(), dialogConsumer.Close()); err != nil {
+			log.Printf("Failed to close consumers: %v", err)
+		}
+	}()
+
 	log.Println("Starting feed updater with WebSocket notifications...")
 
-	for {
-		select {
-		case <-ctx.Done():
-			log.Println("Shutting down feed updater...")
-			return
-		default:
-			msg, err := r.ReadMessage(ctx)
-			if err != nil {
-				log.Printf("failed to read message: %v", err)
-				continue
-			}
-
-			var event dto.FeedUpdateEvent
-			if err := json.Unmarshal(msg.Value, &event); err != nil {
-				log.Printf("failed to unmarshal event: %v", err)
-				continue
-			}
-
-			// Обработка события
-			if err := processFeedUpdate(ctx, event, wsServer, friendUseCase); err != nil {
-				log.Printf("failed to process feed update: %v", err)
-			}
-		}
-	}
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		dialogConsumer.Run(ctx)
+	}()
+
+	feedConsumer.Run(ctx)
+	wg.Wait()
+
+	log.Println("Shutting down feed updater...")
 }
 
-func processFeedUpdate(ctx context.Context, event dto.FeedUpdateEvent, wsServer *websocket.Server, friendUseCase *userUC.FriendUseCase) error {
-	friendIDs, err := friendUseCase.GetFriendsIDs(ctx, event.AuthorID)
+func processFeedUpdate(ctx context.Context, env *eventsv1.Envelope, wsRouter *websocket.Router, friendUseCase *userUC.FriendUseCase) error {
+	payload, err := events.Payload(env)
+	if err != nil {
+		return err
+	}
+
+	event, ok := payload.(*eventsv1.PostEvent)
+	if !ok {
+		return fmt.Errorf("unexpected %s event in feed updates", env.GetType())
+	}
+
+	friendIDs, err := friendUseCase.GetFriendsIDs(ctx, int(event.GetAuthorId()))
 	if err != nil {
 		return fmt.Errorf("failed to get friends: %w", err)
 	}
 
+	eventType, ok := feedEventTypes[event.GetAction()]
+	if !ok {
+		return fmt.Errorf("unexpected post action %s", event.GetAction())
+	}
+
+	message := websocket.FeedPostPayload{
+		PostID:       event.GetPostId(),
+		PostText:     event.GetText(),
+		AuthorUserID: event.GetAuthorId(),
+		CreatedAt:    env.GetOccurredAt().GetSeconds(),
+	}
+
 	// Отправляем уведомление каждому другу через WebSocket
 	for _, friendID := range friendIDs {
-		message := map[string]interface{}{
-			"postId":         event.PostID,
-			"postText":       event.Text,
-			"author_user_id": event.AuthorID,
-			"created_at":     event.Timestamp,
-		}
-
-		if err := wsServer.BroadcastToUser(friendID, message); err != nil {
+		if err := wsRouter.SendToUser(ctx, friendID, eventType, message); err != nil {
+			// Ошибка отправки одному другу не повод повторять событие для всех
 			log.Printf("failed to send WebSocket notification to user %d: %v", friendID, err)
-			// Можно добавить retry логику или dead letter queue
 		} else {
-			log.Printf("successfully sent notification to user %d about post %s", friendID, event.PostID)
+			log.Printf("successfully sent notification to user %d about post %s", friendID, event.GetPostId())
 		}
 	}
 
 	return nil
 }
+
+// sendToParticipants доставляет событие обоим участникам диалога, чтобы
+// изменение увидели и другие устройства отправителя
+func sendToParticipants(ctx context.Context, wsRouter *websocket.Router, senderID, receiverID int64, eventType string, payload interface{}) error {
+	if err := wsRouter.SendToUser(ctx, int(receiverID), eventType, payload); err != nil {
+		return err
+	}
+
+	return wsRouter.SendToUser(ctx, int(senderID), eventType, payload)
+}
+
+// sendToGroup рассылает событие участникам группы, как и лента - без повтора:
+// повтор всего события продублировал бы его тем, кому доставка удалась
+func sendToGroup(ctx context.Context, wsRouter *websocket.Router, event *eventsv1.GroupMessageEvent, payload websocket.GroupMessagePayload) {
+	for _, memberID := range event.GetMemberIds() {
+		if err := wsRouter.SendToUser(ctx, int(memberID), websocket.EventDialogGroupMessageCreated, payload); err != nil {
+			log.Printf("failed to send group message %d to user %d: %v", event.GetMessageId(), memberID, err)
+		}
+	}
+}
+
+func processDialogEvent(ctx context.Context, env *eventsv1.Envelope, wsRouter *websocket.Router) error {
+	payload, err := events.Payload(env)
+	if err != nil {
+		return err
+	}
+
+	// Получателю событие нужно доставить, поэтому ошибка ведет к повтору
+	switch event := payload.(type) {
+	case *eventsv1.DialogMessageEvent:
+		return wsRouter.SendToUser(ctx, int(event.GetReceiverId()), websocket.EventDialogMessageCreated, websocket.DialogMessagePayload{
+			MessageID:  strconv.FormatInt(event.GetMessageId(), 10),
+			SenderID:   event.GetSenderId(),
+			ReceiverID: event.GetReceiverId(),
+			Text:       event.GetText(),
+			SentAt:     env.GetOccurredAt().GetSeconds(),
+		})
+	case *eventsv1.DialogReadEvent:
+		return wsRouter.SendToUser(ctx, int(event.GetPeerId()), websocket.EventDialogMessageRead, websocket.MessageReadPayload{
+			ReaderID:      event.GetReaderId(),
+			UpToMessageID: strconv.FormatInt(event.GetUpToMessageId(), 10),
+		})
+	case *eventsv1.UnreadChangedEvent:
+		return wsRouter.SendToUser(ctx, int(event.GetUserId()), websocket.EventDialogUnreadChanged, websocket.UnreadPayload{
+			PeerID:      event.GetPeerId(),
+			UnreadCount: event.GetUnreadCount(),
+			TotalUnread: event.GetTotalUnread(),
+		})
+	case *eventsv1.DialogMessageEditedEvent:
+		return sendToParticipants(ctx, wsRouter, event.GetSenderId(), event.GetReceiverId(), websocket.EventDialogMessageEdited, websocket.MessageEditedPayload{
+			MessageID:  strconv.FormatInt(event.GetMessageId(), 10),
+			SenderID:   event.GetSenderId(),
+			ReceiverID: event.GetReceiverId(),
+			Text:       event.GetText(),
+			EditedAt:   event.GetEditedAt(),
+		})
+	case *eventsv1.DialogMessageDeletedEvent:
+		return sendToParticipants(ctx, wsRouter, event.GetSenderId(), event.GetReceiverId(), websocket.EventDialogMessageDeleted, websocket.MessageDeletedPayload{
+			MessageID:  strconv.FormatInt(event.GetMessageId(), 10),
+			SenderID:   event.GetSenderId(),
+			ReceiverID: event.GetReceiverId(),
+		})
+	case *eventsv1.GroupMessageEvent:
+		sendToGroup(ctx, wsRouter, event, websocket.GroupMessagePayload{
+			MessageID: strconv.FormatInt(event.GetMessageId(), 10),
+			GroupID:   event.GetGroupId(),
+			SenderID:  event.GetSenderId(),
+			Text:      event.GetText(),
+			SentAt:    env.GetOccurredAt().GetSeconds(),
+		})
+		return nil
+	default:
+		return fmt.Errorf("unexpected %s event in dialog events", env.GetType())
+	}
+}
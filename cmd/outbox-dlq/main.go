@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/config"
+	"otus-highload-arh-homework/internal/social/repository/postgres"
+	"otus-highload-arh-homework/pkg/clients/pg"
+
+	"github.com/caarlos0/env/v9"
+	"github.com/joho/godotenv"
+)
+
+// Административная утилита для событий outbox, помеченных failed: relay
+// не смог их закодировать за OUTBOX_MAX_ATTEMPTS попыток.
+//
+//	outbox-dlq -list -count 20 - показать события
+//	outbox-dlq -requeue        - вернуть все события в публикацию
+//
+// С -dialog утилита работает с outbox выделенной базы диалогов (DIALOG_PG_*)
+func main() {
+	list := flag.Bool("list", false, "list failed outbox events")
+	requeue := flag.Bool("requeue", false, "return failed outbox events to the relay")
+	count := flag.Int("count", 100, "max number of events to list")
+	dialog := flag.Bool("dialog", false, "use the dedicated dialog database")
+	flag.Parse()
+
+	if *list == *requeue {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	_ = godotenv.Load()
+
+	pgConfig := &pg.Config{}
+	if *dialog {
+		var err error
+		if pgConfig, err = config.LoadDialogPG(); err != nil {
+			log.Fatalf("failed to load config: %v", err)
+		}
+	} else if err := env.Parse(pgConfig); err != nil {
+		log.Fatalf("failed to load config: %v", err)
+	}
+
+	pool, err := pg.New(ctx, pgConfig)
+	if err != nil {
+		log.Fatalf("Failed to init PG: %v", err)
+	}
+	defer pool.Close()
+
+	repo := postgres.NewOutboxRepository(pool)
+
+	if *list {
+		events, err := repo.ListFailed(ctx, *count)
+		if err != nil {
+			log.Fatalf("Failed to list failed events: %v", err)
+		}
+
+		for _, event := range events {
+			log.Printf("%d %s:%d %s created_at=%s attempts=%d error=%s",
+				event.ID, event.AggregateType, event.AggregateID, event.EventType,
+				event.CreatedAt.Format(time.RFC3339), event.Attempts, event.LastError)
+		}
+		log.Printf("Total: %d", len(events))
+
+		return
+	}
+
+	requeued, err := repo.RequeueFailed(ctx)
+	if err != nil {
+		log.Fatalf("Requeue failed: %v", err)
+	}
+	log.Printf("Requeued %d events", requeued)
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/config"
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository/postgres"
+	"otus-highload-arh-homework/internal/social/transport/outbox"
+	"otus-highload-arh-homework/pkg/clients/kafka"
+	"otus-highload-arh-homework/pkg/clients/pg"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// relayLockKey - ключ advisory-блокировки лидера relay
+const relayLockKey = 7_300_001
+
+func main() {
+	log.Println("Starting outbox relay...")
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	cfg := config.Load()
+
+	pgPool, err := pg.New(ctx, &cfg.PG)
+	if err != nil {
+		log.Fatalf("Failed to init PG: %v", err)
+	}
+	defer pgPool.Close()
+
+	// Топик для каждого типа агрегата
+	feedProducer := kafka.NewProducer([]string{cfg.Kafka.Address}, cfg.Kafka.FeedUpdatesTopic)
+	friendshipProducer := kafka.NewProducer([]string{cfg.Kafka.Address}, cfg.Kafka.FriendshipTopic)
+	dialogProducer := kafka.NewProducer([]string{cfg.Kafka.Address}, cfg.Kafka.DialogEventsTopic)
+	defer func() {
+		if err := errors.Join(feedProducer.Close(), friendshipProducer.Close(), dialogProducer.Close()); err != nil {
+			log.Printf("Failed to close Kafka producers: %v", err)
+		}
+	}()
+
+	relay := outbox.NewRelay(
+		postgres.NewOutboxRepository(pgPool),
+		postgres.NewAdvisoryLock(pgPool, relayLockKey),
+		map[string]outbox.Publisher{
+			entity.AggregatePost:       feedProducer,
+			entity.AggregateFriendship: friendshipProducer,
+			entity.AggregateDialog:     dialogProducer,
+			// Сообщения групп доставляет тот же консьюмер событий диалогов
+			entity.AggregateGroup: dialogProducer,
+		},
+		&cfg.Outbox,
+	)
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	metricsSrv := &http.Server{Addr: cfg.Outbox.MetricsPort, Handler: mux}
+
+	go func() {
+		log.Printf("Metrics server starting on %s", cfg.Outbox.MetricsPort)
+		if err := metricsSrv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Metrics server error: %v", err)
+		}
+	}()
+
+	if err := relay.Run(ctx); err != nil {
+		log.Printf("Outbox relay stopped: %v", err)
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+
+	if err := metricsSrv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Metrics server shutdown error: %v", err)
+	}
+
+	log.Println("Outbox relay stopped gracefully")
+}
@@ -0,0 +1,8 @@
+package docs
+
+import _ "embed"
+
+// WebsocketSchema - JSON-схема websocket-протокола уведомлений
+//
+//go:embed websocket-protocol.schema.json
+var WebsocketSchema []byte
@@ -1,10 +1,14 @@
 package config
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	cachewarmer "otus-highload-arh-homework/internal/social/transport/cache"
+	"otus-highload-arh-homework/internal/social/transport/consumer"
+	"otus-highload-arh-homework/internal/social/transport/outbox"
+	"otus-highload-arh-homework/internal/social/transport/presence"
 	"otus-highload-arh-homework/pkg/clients/kafka"
 	"otus-highload-arh-homework/pkg/clients/pg"
 	"otus-highload-arh-homework/pkg/clients/redis"
@@ -22,10 +26,50 @@ type Config struct {
 	PG     pg.Config
 	Cache  cachewarmer.Config
 	Kafka  kafka.Config
-	Dialog struct {
+	Outbox outbox.Config
+
+	Presence presence.Config
+
+	FeedUpdater consumer.Config
+	Dialog      struct {
 		Address    string        `env:"DIALOG_SERVICE_ADDRESS" env-default:":50051"`
 		ClientAddr string        `env:"DIALOG_CLIENT_ADDRESS" env-default:"dialog:50051"`
 		Timeout    time.Duration `env:"DIALOG_SERVICE_TIMEOUT" env-default:"5s"`
+		// Счетчики непрочитанных в Redis без обращений пересобираются из Postgres
+		UnreadTTL time.Duration `env:"DIALOG_UNREAD_TTL" env-default:"24h"`
+		// Сколько после отправки сообщение можно редактировать
+		EditWindow time.Duration `env:"DIALOG_EDIT_WINDOW" env-default:"15m"`
+		// Сверка счетчиков непрочитанных с messages.read_at
+		ReconcileInterval  time.Duration `env:"DIALOG_UNREAD_RECONCILE_INTERVAL" env-default:"10m"`
+		ReconcileBatchSize int           `env:"DIALOG_UNREAD_RECONCILE_BATCH_SIZE" env-default:"500"`
+		// Пинг простаивающих соединений gRPC, держащих потоки сообщений
+		KeepaliveTime    time.Duration `env:"DIALOG_KEEPALIVE_TIME" env-default:"30s"`
+		KeepaliveTimeout time.Duration `env:"DIALOG_KEEPALIVE_TIMEOUT" env-default:"10s"`
+		// Хранилище сообщений: postgres или redis (функции Redis, без кластера)
+		Storage string `env:"DIALOG_STORAGE" env-default:"postgres"`
+		// Где хранятся диалоги: shared - общая база, dual - общая с
+		// переносом записей в выделенную, dedicated - выделенная база
+		DBMode string `env:"DIALOG_DB_MODE" env-default:"shared"`
+		// Миграции выделенной базы, применяются сервисом диалогов
+		MigrationsDir string `env:"DIALOG_MIGRATIONS_DIR" env-default:"migrations/dialog"`
+		// Вывод /api/v1/dialog: даты в RFC 3339 для заголовков Deprecation и Sunset
+		V1DeprecatedAt time.Time `env:"DIALOG_V1_DEPRECATED_AT"`
+		V1Sunset       time.Time `env:"DIALOG_V1_SUNSET"`
+		// Проверка вызывающих сервис диалогов. Без DIALOG_TLS_CA_FILE
+		// соединение без TLS и принимаются только токены пользователей
+		TLS struct {
+			CAFile string `env:"DIALOG_TLS_CA_FILE"`
+			// Сертификат сервиса диалогов
+			CertFile string `env:"DIALOG_TLS_CERT_FILE"`
+			KeyFile  string `env:"DIALOG_TLS_KEY_FILE"`
+			// Сертификат клиента, которым app подтверждает себя сервису
+			ClientCertFile string `env:"DIALOG_TLS_CLIENT_CERT_FILE"`
+			ClientKeyFile  string `env:"DIALOG_TLS_CLIENT_KEY_FILE"`
+			ServerName     string `env:"DIALOG_TLS_SERVER_NAME" env-default:"dialog"`
+			// Имена в сертификатах клиентов, которым разрешено передавать
+			// пользователя в x-user-id без его токена
+			TrustedClients []string `env:"DIALOG_TLS_TRUSTED_CLIENTS"`
+		}
 	}
 }
 
@@ -39,6 +83,12 @@ type HTTP struct {
 
 type WS struct {
 	Port string `env:"WS_PORT" env-default:"8081"`
+	// NodeID - идентификатор узла в реестре соединений, по умолчанию hostname-pid
+	NodeID  string        `env:"WS_NODE_ID"`
+	NodeTTL time.Duration `env:"WS_NODE_TTL" env-default:"30s"`
+	// История уведомлений пользователя для догрузки по last_event_id
+	HistorySize int           `env:"WS_HISTORY_SIZE" env-default:"100"`
+	HistoryTTL  time.Duration `env:"WS_HISTORY_TTL" env-default:"10m"`
 }
 
 type App struct {
@@ -63,3 +113,16 @@ func Load() *Config {
 
 	return &cfg
 }
+
+// LoadDialogPG читает выделенную базу сервиса диалогов из DIALOG_PG_*.
+// Нужна только в режимах dual и dedicated, поэтому не входит в Config
+func LoadDialogPG() (*pg.Config, error) {
+	_ = godotenv.Load()
+
+	cfg := pg.Config{}
+	if err := env.ParseWithOptions(&cfg, env.Options{Prefix: "DIALOG_"}); err != nil {
+		return nil, fmt.Errorf("failed to load dialog pg config: %w", err)
+	}
+
+	return &cfg, nil
+}
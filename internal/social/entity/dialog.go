@@ -2,6 +2,19 @@ package entity
 
 import "time"
 
+// MessagePreviewLength - сколько символов последнего сообщения хранится в списке диалогов
+const MessagePreviewLength = 100
+
+// MessagePreview обрезает текст сообщения для списка диалогов
+func MessagePreview(content string) string {
+	runes := []rune(content)
+	if len(runes) <= MessagePreviewLength {
+		return content
+	}
+
+	return string(runes[:MessagePreviewLength])
+}
+
 // DialogMessage представляет сообщение в диалоге между пользователями
 type DialogMessage struct {
 	ID         string    `json:"id" db:"id"`
@@ -10,6 +23,65 @@ type DialogMessage struct {
 	Text       string    `json:"text" db:"text"`
 	SentAt     time.Time `json:"sent_at" db:"sent_at"`
 	IsRead     bool      `json:"is_read" db:"is_read"`
+	// EditedAt - время последнего редактирования, nil - не редактировалось
+	EditedAt *time.Time `json:"edited_at,omitempty" db:"edited_at"`
+	// Deleted - удалено для всех, текст стерт
+	Deleted bool `json:"deleted" db:"deleted"`
+}
+
+// SentMessage - результат отправки сообщения. Duplicate - сообщение с тем
+// же client_message_id уже было сохранено, возвращены его id и время
+type SentMessage struct {
+	ID        int64
+	SentAt    time.Time
+	Duplicate bool
+}
+
+// MessagesQuery - параметры страницы истории диалога. Before и After -
+// курсоры по message_id, указывается не больше одного. Без курсоров
+// запрашиваются последние сообщения
+type MessagesQuery struct {
+	Before int64
+	After  int64
+	Limit  int
+}
+
+// MessagesPage - страница истории диалога. Сообщения идут по убыванию
+// message_id, а при курсоре After - по возрастанию. NextCursor продолжает
+// выборку в том же направлении и пуст на последней странице
+type MessagesPage struct {
+	Messages   []*DialogMessage
+	NextCursor string
+}
+
+// DialogPreview - строка списка диалогов пользователя: собеседник,
+// последнее сообщение (Text - первые символы) и число непрочитанных
+type DialogPreview struct {
+	PeerID      int64
+	LastMessage DialogMessage
+	UnreadCount int
+}
+
+// DialogsPage - страница списка диалогов от недавних к давним. NextCursor
+// - id последнего сообщения в последнем диалоге страницы, пуст на последней странице
+type DialogsPage struct {
+	Dialogs    []*DialogPreview
+	NextCursor string
+}
+
+// UnreadCount - результат отметки прочтения: сколько сообщений отмечено и
+// сколько осталось непрочитанных в диалоге с PeerID и всего
+type UnreadCount struct {
+	PeerID int64
+	Marked int
+	Unread int
+	Total  int
+}
+
+// UnreadCounters - непрочитанные сообщения пользователя по собеседникам и всего
+type UnreadCounters struct {
+	Dialogs map[int64]int
+	Total   int
 }
 
 // Dialog представляет диалог между двумя пользователями
@@ -0,0 +1,56 @@
+package entity
+
+import "time"
+
+// GroupRole - роль участника группы
+type GroupRole string
+
+const (
+	// GroupRoleAdmin добавляет и удаляет участников и меняет их роли
+	GroupRoleAdmin  GroupRole = "admin"
+	GroupRoleMember GroupRole = "member"
+)
+
+// Valid проверяет, что роль известна
+func (r GroupRole) Valid() bool {
+	return r == GroupRoleAdmin || r == GroupRoleMember
+}
+
+// Group - групповой чат. Role и MemberCount - с точки зрения запросившего участника
+type Group struct {
+	ID          int64
+	Title       string
+	CreatedBy   int64
+	CreatedAt   time.Time
+	Role        GroupRole
+	MemberCount int
+}
+
+// GroupMember - участник группы
+type GroupMember struct {
+	UserID   int64
+	Role     GroupRole
+	JoinedAt time.Time
+}
+
+// GroupMessage - сообщение группового чата
+type GroupMessage struct {
+	ID       int64
+	GroupID  int64
+	SenderID int64
+	Text     string
+	SentAt   time.Time
+}
+
+// GroupMessagesPage - страница истории группы, порядок и курсоры как у MessagesPage
+type GroupMessagesPage struct {
+	Messages   []*GroupMessage
+	NextCursor string
+}
+
+// GroupsPage - страница групп пользователя от новых к старым. NextCursor -
+// group_id последней группы страницы, пуст на последней странице
+type GroupsPage struct {
+	Groups     []*Group
+	NextCursor string
+}
@@ -0,0 +1,139 @@
+package entity
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+const (
+	AggregatePost       = "post"
+	AggregateFriendship = "friendship"
+	AggregateDialog     = "dialog"
+	AggregateGroup      = "group"
+)
+
+const (
+	EventPostCreated    = "post.created"
+	EventPostUpdated    = "post.updated"
+	EventPostDeleted    = "post.deleted"
+	EventFriendAdded    = "friendship.added"
+	EventFriendRemoved  = "friendship.removed"
+	EventMessageCreated = "message.created"
+	EventMessageRead    = "message.read"
+	EventUnreadChanged  = "unread.changed"
+	EventMessageEdited  = "message.edited"
+	EventMessageDeleted = "message.deleted"
+
+	EventGroupMessageCreated = "group.message.created"
+)
+
+const (
+	FeedActionCreate = "create"
+	FeedActionUpdate = "update"
+	FeedActionDelete = "delete"
+
+	FriendshipActionAdd    = "add"
+	FriendshipActionRemove = "remove"
+)
+
+// OutboxEvent - событие, записанное в outbox в одной транзакции с изменением
+// агрегата. Relay публикует события агрегата в порядке ID
+type OutboxEvent struct {
+	ID            int64     `json:"id"`
+	AggregateType string    `json:"aggregate_type"`
+	AggregateID   int64     `json:"aggregate_id"`
+	EventType     string    `json:"event_type"`
+	Payload       []byte    `json:"payload"`
+	CreatedAt     time.Time `json:"created_at"`
+	Attempts      int       `json:"attempts"`
+	LastError     string    `json:"last_error,omitempty"`
+	RequestID     string    `json:"request_id"`
+	TraceParent   string    `json:"traceparent"`
+}
+
+// FeedUpdateEvent - изменение поста для обновления лент друзей автора
+type FeedUpdateEvent struct {
+	PostID    string `json:"post_id"`
+	AuthorID  int    `json:"author_id"`
+	Action    string `json:"action"` // "create", "update", "delete"
+	Text      string `json:"text,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// FriendshipEvent - добавление или удаление друга
+type FriendshipEvent struct {
+	UserID    int    `json:"user_id"`
+	FriendID  int    `json:"friend_id"`
+	Action    string `json:"action"` // "add", "remove"
+	Timestamp int64  `json:"timestamp"`
+}
+
+// DialogMessageEvent - новое сообщение в диалоге
+type DialogMessageEvent struct {
+	MessageID  int64  `json:"message_id"`
+	SenderID   int64  `json:"sender_id"`
+	ReceiverID int64  `json:"receiver_id"`
+	Text       string `json:"text"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// DialogReadEvent - ReaderID прочитал сообщения PeerID до UpToMessageID
+type DialogReadEvent struct {
+	ReaderID      int64 `json:"reader_id"`
+	PeerID        int64 `json:"peer_id"`
+	UpToMessageID int64 `json:"up_to_message_id"`
+	Timestamp     int64 `json:"timestamp"`
+}
+
+// UnreadChangedEvent - новое число непрочитанных сообщений пользователя
+type UnreadChangedEvent struct {
+	UserID      int64 `json:"user_id"`
+	PeerID      int64 `json:"peer_id"`
+	UnreadCount int   `json:"unread_count"`
+	TotalUnread int   `json:"total_unread"`
+	Timestamp   int64 `json:"timestamp"`
+}
+
+// DialogMessageEditedEvent - сообщение отредактировано отправителем
+type DialogMessageEditedEvent struct {
+	MessageID  int64  `json:"message_id"`
+	SenderID   int64  `json:"sender_id"`
+	ReceiverID int64  `json:"receiver_id"`
+	Text       string `json:"text"`
+	EditedAt   int64  `json:"edited_at"`
+}
+
+// DialogMessageDeletedEvent - сообщение удалено для всех
+type DialogMessageDeletedEvent struct {
+	MessageID  int64 `json:"message_id"`
+	SenderID   int64 `json:"sender_id"`
+	ReceiverID int64 `json:"receiver_id"`
+	Timestamp  int64 `json:"timestamp"`
+}
+
+// GroupMessageEvent - новое сообщение группы. MemberIDs - участники на момент
+// отправки, им событие доставляется в реальном времени
+type GroupMessageEvent struct {
+	MessageID int64   `json:"message_id"`
+	GroupID   int64   `json:"group_id"`
+	SenderID  int64   `json:"sender_id"`
+	Text      string  `json:"text"`
+	MemberIDs []int64 `json:"member_ids"`
+	Timestamp int64   `json:"timestamp"`
+}
+
+// NewOutboxEvent сериализует payload в JSON и создает событие для outbox
+func NewOutboxEvent(aggregateType string, aggregateID int64, eventType string, payload any) (*OutboxEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return &OutboxEvent{
+		AggregateType: aggregateType,
+		AggregateID:   aggregateID,
+		EventType:     eventType,
+		Payload:       data,
+	}, nil
+}
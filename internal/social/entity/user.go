@@ -13,6 +13,40 @@ const (
 	GenderOther  Gender = "other"
 )
 
+// Visibility - кому виден статус online и время последнего визита
+type Visibility string
+
+const (
+	VisibilityEveryone Visibility = "everyone"
+	VisibilityFriends  Visibility = "friends"
+	VisibilityNobody   Visibility = "nobody"
+)
+
+func (v Visibility) IsValid() bool {
+	switch v {
+	case VisibilityEveryone, VisibilityFriends, VisibilityNobody:
+		return true
+	default:
+		return false
+	}
+}
+
+// VisibleTo сообщает, видит ли присутствие пользователь viewerID
+func (v Visibility) VisibleTo(ownerID, viewerID int, isFriend bool) bool {
+	if ownerID == viewerID {
+		return true
+	}
+
+	switch v {
+	case VisibilityEveryone:
+		return true
+	case VisibilityFriends:
+		return isFriend
+	default:
+		return false
+	}
+}
+
 type User struct {
 	ID        int
 	FirstName string
@@ -23,6 +57,8 @@ type User struct {
 	Interests []string
 	City      string
 
+	LastSeenVisibility Visibility
+
 	CreatedAt time.Time
 	UpdatedAt time.Time
 
@@ -0,0 +1,422 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/transport/dto"
+	"otus-highload-arh-homework/internal/social/transport/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/metadata"
+)
+
+// CreateGroupV2 godoc
+// @Summary Создать групповой чат (v2)
+// @Description Создатель становится администратором группы
+// @Tags groups-v2
+// @Accept json
+// @Produce json
+// @Param input body dto.CreateGroupRequest true "Название и участники"
+// @Security ApiKeyAuth
+// @Success 201 {object} dto.GroupV2
+// @Header 201 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups [post]
+func (h *UserHandler) CreateGroupV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.CreateGroupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	group, err := h.userService.CreateGroupV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		req,
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to create group")
+		return
+	}
+
+	c.JSON(http.StatusCreated, group)
+}
+
+// ListGroupsV2 godoc
+// @Summary Список групп (v2)
+// @Description Группы пользователя от новых к старым
+// @Tags groups-v2
+// @Produce json
+// @Param cursor query string false "Курсор из X-Next-Cursor предыдущей страницы"
+// @Param limit query int false "Размер страницы, по умолчанию 50, не больше 100"
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.GroupV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups [get]
+func (h *UserHandler) ListGroupsV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var page dto.DialogsPageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid pagination parameters",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	groups, nextCursor, err := h.userService.ListGroupsV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		page,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaginationParams) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid pagination parameters",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
+			Error:     "Failed to list groups",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	setNextCursor(c, nextCursor)
+	c.JSON(http.StatusOK, groups)
+}
+
+// ListGroupMembersV2 godoc
+// @Summary Участники группы (v2)
+// @Tags groups-v2
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.GroupMemberV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups/{group_id}/members [get]
+func (h *UserHandler) ListGroupMembersV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	members, err := h.userService.ListGroupMembersV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to list group members")
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// AddGroupMembersV2 godoc
+// @Summary Добавить участников (v2)
+// @Description Доступно администраторам группы. Уже состоящие в группе пропускаются
+// @Tags groups-v2
+// @Accept json
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Param input body dto.AddGroupMembersRequest true "Добавляемые участники"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.AddGroupMembersResponseV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 403 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups/{group_id}/members [post]
+func (h *UserHandler) AddGroupMembersV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.AddGroupMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	result, err := h.userService.AddGroupMembersV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+		req.MemberIDs,
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to add group members")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RemoveGroupMemberV2 godoc
+// @Summary Удалить участника (v2)
+// @Description Доступно администраторам группы
+// @Tags groups-v2
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Param user_id path string true "ID участника"
+// @Security ApiKeyAuth
+// @Success 204
+// @Header 204 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 403 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Failure 409 {object} dto.ErrorResponseV2 "В группе не останется администратора"
+// @Router /api/v2/groups/{group_id}/members/{user_id} [delete]
+func (h *UserHandler) RemoveGroupMemberV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	err := h.userService.RemoveGroupMemberV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+		c.Param("user_id"),
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to remove group member")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SetGroupMemberRoleV2 godoc
+// @Summary Изменить роль участника (v2)
+// @Description Доступно администраторам группы. Последнего администратора понизить нельзя
+// @Tags groups-v2
+// @Accept json
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Param user_id path string true "ID участника"
+// @Param input body dto.SetGroupRoleRequest true "Новая роль"
+// @Security ApiKeyAuth
+// @Success 204
+// @Header 204 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 403 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Failure 409 {object} dto.ErrorResponseV2 "В группе не останется администратора"
+// @Router /api/v2/groups/{group_id}/members/{user_id} [patch]
+func (h *UserHandler) SetGroupMemberRoleV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.SetGroupRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	err := h.userService.SetGroupMemberRoleV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+		c.Param("user_id"),
+		req.Role,
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to set group member role")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LeaveGroupV2 godoc
+// @Summary Выйти из группы (v2)
+// @Description Если выходит последний администратор, администратором становится самый давний участник
+// @Tags groups-v2
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Security ApiKeyAuth
+// @Success 204
+// @Header 204 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups/{group_id}/leave [post]
+func (h *UserHandler) LeaveGroupV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	err := h.userService.LeaveGroupV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to leave group")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// SendGroupMessageV2 godoc
+// @Summary Отправить сообщение в группу (v2)
+// @Description С client_message_id отправку можно безопасно повторить: повтор вернет уже отправленное сообщение с duplicate=true
+// @Tags groups-v2
+// @Accept json
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Param input body dto.SendMessageRequest true "Текст сообщения"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.SuccessResponseV2{data=dto.SentMessageV2}
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups/{group_id}/send [post]
+func (h *UserHandler) SendGroupMessageV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	sent, err := h.userService.SendGroupMessageV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+		req.Text,
+		req.ClientMessageID,
+	)
+	if err != nil {
+		respondGroupError(c, err, "Failed to send message")
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.SuccessResponseV2{
+		Status:    "success",
+		Message:   "Message sent",
+		Data:      sent,
+		RequestID: requestID,
+		Timestamp: time.Now().UTC(),
+	})
+}
+
+// GetGroupMessagesV2 godoc
+// @Summary История группы (v2)
+// @Description По умолчанию последняя страница от новых сообщений к старым
+// @Tags groups-v2
+// @Produce json
+// @Param group_id path string true "ID группы"
+// @Param before query string false "Сообщения старше этого message_id"
+// @Param after query string false "Сообщения новее этого message_id, по возрастанию"
+// @Param limit query int false "Размер страницы, по умолчанию 50, не больше 100"
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.GroupMessageV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/groups/{group_id}/messages [get]
+func (h *UserHandler) GetGroupMessagesV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var page dto.MessagesPageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid pagination parameters",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	messages, nextCursor, err := h.userService.GetGroupMessagesV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("group_id"),
+		page,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaginationParams) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid pagination parameters",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
+		respondGroupError(c, err, "Failed to get group messages")
+		return
+	}
+
+	setNextCursor(c, nextCursor)
+	c.JSON(http.StatusOK, messages)
+}
+
+// respondGroupError отвечает на ошибку операции с группой. Группа, в которой
+// пользователь не состоит, для него не существует - 404
+func respondGroupError(c *gin.Context, err error, internalMsg string) {
+	resp := dto.ErrorResponseV2{
+		Details:   err.Error(),
+		RequestID: c.GetString("x-request-id"),
+		Timestamp: time.Now().UTC(),
+	}
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		code, resp.Error = http.StatusBadRequest, "Invalid request"
+	case errors.Is(err, service.ErrGroupNotFound):
+		code, resp.Error = http.StatusNotFound, "Group not found"
+	case errors.Is(err, service.ErrNotGroupAdmin):
+		code, resp.Error = http.StatusForbidden, "Only group admins can manage members"
+	case errors.Is(err, service.ErrLastGroupAdmin):
+		code, resp.Error = http.StatusConflict, "Group must keep at least one admin"
+	default:
+		logrus.Error(err)
+		resp.Error, resp.Details = internalMsg, ""
+	}
+
+	c.JSON(code, resp)
+}
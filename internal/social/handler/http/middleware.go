@@ -5,11 +5,21 @@ import (
 	"net/http"
 
 	"otus-highload-arh-homework/internal/social/transport/service"
+	"otus-highload-arh-homework/pkg/auth"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AuthMiddleware(jwtService *service.JWTGenerator) gin.HandlerFunc {
+// ActivityTracker отмечает активность аутентифицированного пользователя.
+// Active не должен блокировать запрос
+type ActivityTracker interface {
+	Active(userID int)
+}
+
+// AuthMiddleware проверяет токен. Если передан activity, запрос
+// аутентифицированного пользователя продлевает его присутствие, но не
+// переводит в offline: статус без соединений истекает по TTL
+func AuthMiddleware(jwtService *service.JWTGenerator, activity ActivityTracker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -25,6 +35,16 @@ func AuthMiddleware(jwtService *service.JWTGenerator) gin.HandlerFunc {
 
 		// Сохраняем userID в контекст Gin
 		c.Set("userID", userID)
+		// и вместе с токеном в контекст запроса для вызовов сервиса диалогов
+		c.Request = c.Request.WithContext(auth.WithIdentity(c.Request.Context(), auth.Identity{
+			UserID: userID,
+			Token:  token,
+		}))
+
+		if activity != nil {
+			activity.Active(userID)
+		}
+
 		c.Next()
 	}
 }
@@ -90,6 +90,75 @@ func (h *UserHandler) SearchUsers(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+// GetPresence godoc
+// @Summary Присутствие пользователей
+// @Description Статус online и время последнего визита для списка пользователей с учетом их настроек приватности
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param ids query string true "ID пользователей через запятую (не более 100)"
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.PresenceResponse
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /user/presence [get]
+func (h *UserHandler) GetPresence(c *gin.Context) {
+	currentUserID := c.Value("userID").(int)
+
+	statuses, err := h.userService.GetPresence(c.Request.Context(), currentUserID, c.Query("ids"))
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPresenceQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+
+		log.Println(fmt.Errorf("GetPresence: %w", err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error", "details": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// SetPrivacy godoc
+// @Summary Настройки приватности
+// @Description Кому показывать статус online и время последнего визита: everyone, friends или nobody
+// @Tags user
+// @Accept json
+// @Produce json
+// @Param request body dto.PrivacyRequest true "Настройки приватности"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.PrivacyRequest
+// @Failure 400 {object} dto.ErrorResponse
+// @Failure 404 {object} dto.ErrorResponse
+// @Failure 500 {object} dto.ErrorResponse
+// @Router /user/privacy [put]
+func (h *UserHandler) SetPrivacy(c *gin.Context) {
+	currentUserID := c.Value("userID").(int)
+
+	var req dto.PrivacyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	err := h.userService.SetLastSeenVisibility(c.Request.Context(), currentUserID, req.LastSeenVisibility)
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidVisibility):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "last_seen_visibility must be one of everyone, friends, nobody"})
+		case errors.Is(err, service.ErrUserNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		default:
+			log.Println(fmt.Errorf("SetPrivacy: %w", err))
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error", "details": err.Error()})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, req)
+}
+
 // SetFriend godoc
 // @Summary Добавить в друзья
 // @Description Добавить пользователя в друзья
@@ -176,13 +245,17 @@ func (h *UserHandler) DeleteFriend(c *gin.Context) {
 
 // SendDialogMessage godoc
 // @Summary Отправить сообщение пользователю
+// @Description Устарела, замена - /api/v2/dialog/{user_id}/send
 // @Tags user
+// @Deprecated
 // @Accept json
 // @Produce json
 // @Param user_id path string true "ID пользователя-получателя"
 // @Param input body dto.SendMessageRequest true "Текст сообщения"
 // @Security ApiKeyAuth
 // @Success 200 {object} dto.SuccessResponse
+// @Header 200 {string} Deprecation "Дата устаревания, @unix-время"
+// @Header 200 {string} Sunset "Дата удаления"
 // @Router /dialog/{user_id}/send [post]
 func (h *UserHandler) SendDialogMessage(c *gin.Context) {
 	receiverIDStr := c.Param("user_id")
@@ -218,6 +291,7 @@ func (h *UserHandler) SendDialogMessage(c *gin.Context) {
 		int64(senderID),
 		receiverID,
 		req.Text,
+		req.ClientMessageID,
 	)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
@@ -242,11 +316,20 @@ func (h *UserHandler) SendDialogMessage(c *gin.Context) {
 
 // GetDialogMessages godoc
 // @Summary Получить диалог с пользователем
+// @Description По умолчанию последняя страница от новых сообщений к старым. Устарела, замена - /api/v2/dialog/{user_id}/list
 // @Tags user
+// @Deprecated
 // @Produce json
 // @Param user_id path string true "ID пользователя"
+// @Param before query string false "Сообщения старше этого message_id"
+// @Param after query string false "Сообщения новее этого message_id, по возрастанию"
+// @Param limit query int false "Размер страницы, по умолчанию 50, не больше 100"
 // @Security ApiKeyAuth
 // @Success 200 {array} dto.DialogMessage
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Header 200 {string} Deprecation "Дата устаревания, @unix-время"
+// @Header 200 {string} Sunset "Дата удаления"
+// @Failure 400 {object} dto.ErrorResponse
 // @Router /dialog/:user_id/list [get]
 func (h *UserHandler) GetDialogMessages(c *gin.Context) {
 	otherUserIDStr := c.Param("user_id")
@@ -260,12 +343,22 @@ func (h *UserHandler) GetDialogMessages(c *gin.Context) {
 		return
 	}
 
+	var page dto.MessagesPageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+			Error:   "Invalid pagination parameters",
+			Details: err.Error(),
+		})
+		return
+	}
+
 	currentUserID := c.Value("userID").(int)
 
-	messages, err := h.userService.GetDialogMessages(
+	messages, nextCursor, err := h.userService.GetDialogMessages(
 		c.Request.Context(),
 		int64(currentUserID),
 		otherUserID,
+		page,
 	)
 	if err != nil {
 		switch {
@@ -273,8 +366,11 @@ func (h *UserHandler) GetDialogMessages(c *gin.Context) {
 			c.JSON(http.StatusNotFound, dto.ErrorResponse{
 				Error: "User not found",
 			})
-		case errors.Is(err, repository.ErrNoMessagesFound):
-			c.JSON(http.StatusOK, []dto.DialogMessage{}) // Пустой массив вместо ошибки
+		case errors.Is(err, service.ErrInvalidPaginationParams):
+			c.JSON(http.StatusBadRequest, dto.ErrorResponse{
+				Error:   "Invalid pagination parameters",
+				Details: err.Error(),
+			})
 		default:
 			c.JSON(http.StatusInternalServerError, dto.ErrorResponse{
 				Error:   "Failed to get dialog messages",
@@ -285,19 +381,22 @@ func (h *UserHandler) GetDialogMessages(c *gin.Context) {
 		return
 	}
 
+	setNextCursor(c, nextCursor)
 	c.JSON(http.StatusOK, messages)
 }
 
 // SendDialogMessageV2 godoc
 // @Summary Отправить сообщение (v2)
+// @Description С client_message_id отправку можно безопасно повторить: повтор вернет уже отправленное сообщение с duplicate=true
 // @Tags dialog-v2
 // @Accept json
 // @Produce json
 // @Param user_id path string true "ID получателя"
 // @Param input body dto.SendMessageRequest true "Текст сообщения"
 // @Security ApiKeyAuth
-// @Success 200 {object} dto.SuccessResponseV2
+// @Success 200 {object} dto.SuccessResponseV2{data=dto.SentMessageV2}
 // @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
 // @Router /api/v2/dialog/{user_id}/send [post]
 func (h *UserHandler) SendDialogMessageV2(c *gin.Context) {
 	requestID := c.GetString("x-request-id")
@@ -315,13 +414,23 @@ func (h *UserHandler) SendDialogMessageV2(c *gin.Context) {
 	}
 
 	// Вызов сервиса
-	err := h.userService.SendDialogMessageV2(
+	sent, err := h.userService.SendDialogMessageV2(
 		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
 		senderID,
 		receiverIDStr,
 		req.Text,
+		req.ClientMessageID,
 	)
 	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid request",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
 		logrus.Error(err)
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
 			Error:     "Failed to send message",
@@ -334,6 +443,7 @@ func (h *UserHandler) SendDialogMessageV2(c *gin.Context) {
 	c.JSON(http.StatusOK, dto.SuccessResponseV2{
 		Status:    "success",
 		Message:   "Message sent",
+		Data:      sent,
 		RequestID: requestID,
 		Timestamp: time.Now().UTC(),
 	})
@@ -343,23 +453,50 @@ func (h *UserHandler) SendDialogMessageV2(c *gin.Context) {
 // @Summary Получить диалог (v2)
 // @Tags dialog-v2
 // @Produce json
+// @Description По умолчанию последняя страница от новых сообщений к старым
 // @Param user_id path string true "ID собеседника"
+// @Param before query string false "Сообщения старше этого message_id"
+// @Param after query string false "Сообщения новее этого message_id, по возрастанию"
+// @Param limit query int false "Размер страницы, по умолчанию 50, не больше 100"
 // @Security ApiKeyAuth
 // @Success 200 {array} dto.DialogMessageV2
 // @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Failure 400 {object} dto.ErrorResponseV2
 // @Router /api/v2/dialog/{user_id}/list [get]
 func (h *UserHandler) GetDialogMessagesV2(c *gin.Context) {
 	requestID := c.GetString("x-request-id")
 	otherUserIDStr := c.Param("user_id")
 	currentUserID := c.MustGet("userID").(int)
 
+	var page dto.MessagesPageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid pagination parameters",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
 	// Вызов сервиса
-	messages, err := h.userService.GetDialogMessagesV2(
+	messages, nextCursor, err := h.userService.GetDialogMessagesV2(
 		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
 		currentUserID,
 		otherUserIDStr,
+		page,
 	)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaginationParams) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid pagination parameters",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
 			Error:     "Failed to get messages",
 			RequestID: requestID,
@@ -368,5 +505,272 @@ func (h *UserHandler) GetDialogMessagesV2(c *gin.Context) {
 		return
 	}
 
+	setNextCursor(c, nextCursor)
 	c.JSON(http.StatusOK, messages)
 }
+
+// ListDialogsV2 godoc
+// @Summary Список диалогов (v2)
+// @Description Диалоги пользователя от недавних к давним с последним сообщением и числом непрочитанных
+// @Tags dialog-v2
+// @Produce json
+// @Param cursor query string false "Курсор из X-Next-Cursor предыдущей страницы"
+// @Param limit query int false "Размер страницы, по умолчанию 50, не больше 100"
+// @Security ApiKeyAuth
+// @Success 200 {array} dto.DialogPreviewV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Header 200 {string} X-Next-Cursor "Курсор следующей страницы"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Router /api/v2/dialog/list [get]
+func (h *UserHandler) ListDialogsV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var page dto.DialogsPageQuery
+	if err := c.ShouldBindQuery(&page); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid pagination parameters",
+			Details:   err.Error(),
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	dialogs, nextCursor, err := h.userService.ListDialogsV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		page,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidPaginationParams) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid pagination parameters",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
+			Error:     "Failed to list dialogs",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	setNextCursor(c, nextCursor)
+	c.JSON(http.StatusOK, dialogs)
+}
+
+// MarkReadV2 godoc
+// @Summary Отметить диалог прочитанным (v2)
+// @Description Отмечает прочитанными сообщения собеседника до up_to_message_id включительно
+// @Tags dialog-v2
+// @Accept json
+// @Produce json
+// @Param user_id path string true "ID собеседника"
+// @Param input body dto.MarkReadRequest true "Последнее прочитанное сообщение"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.MarkReadResponseV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Router /api/v2/dialog/{user_id}/read [post]
+func (h *UserHandler) MarkReadV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	peerIDStr := c.Param("user_id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.MarkReadRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	result, err := h.userService.MarkReadV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		peerIDStr,
+		req.UpToMessageID,
+	)
+	if err != nil {
+		if errors.Is(err, service.ErrValidation) {
+			c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+				Error:     "Invalid request",
+				Details:   err.Error(),
+				RequestID: requestID,
+				Timestamp: time.Now().UTC(),
+			})
+			return
+		}
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
+			Error:     "Failed to mark messages read",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// GetUnreadCountersV2 godoc
+// @Summary Непрочитанные сообщения (v2)
+// @Description Общее число непрочитанных сообщений и счетчики по собеседникам
+// @Tags dialog-v2
+// @Produce json
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.UnreadCountersV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Router /api/v2/dialog/unread [get]
+func (h *UserHandler) GetUnreadCountersV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	counters, err := h.userService.GetUnreadCountersV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+	)
+	if err != nil {
+		logrus.Error(err)
+		c.JSON(http.StatusInternalServerError, dto.ErrorResponseV2{
+			Error:     "Failed to get unread counters",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, counters)
+}
+
+// EditMessageV2 godoc
+// @Summary Редактировать сообщение (v2)
+// @Description Меняет текст своего сообщения. Редактировать можно в течение окна редактирования после отправки
+// @Tags dialog-v2
+// @Accept json
+// @Produce json
+// @Param user_id path string true "ID собеседника"
+// @Param message_id path string true "ID сообщения"
+// @Param input body dto.EditMessageRequest true "Новый текст сообщения"
+// @Security ApiKeyAuth
+// @Success 200 {object} dto.DialogMessageV2
+// @Header 200 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 403 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Failure 409 {object} dto.ErrorResponseV2 "Окно редактирования истекло"
+// @Router /api/v2/dialog/{user_id}/messages/{message_id} [patch]
+func (h *UserHandler) EditMessageV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	var req dto.EditMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request body",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	msg, err := h.userService.EditMessageV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("user_id"),
+		c.Param("message_id"),
+		req.Text,
+	)
+	if err != nil {
+		respondMessageError(c, err, "Failed to edit message")
+		return
+	}
+
+	c.JSON(http.StatusOK, msg)
+}
+
+// DeleteMessageV2 godoc
+// @Summary Удалить сообщение (v2)
+// @Description Удаляет сообщение у текущего пользователя. С for=everyone отправитель удаляет сообщение у обоих участников
+// @Tags dialog-v2
+// @Produce json
+// @Param user_id path string true "ID собеседника"
+// @Param message_id path string true "ID сообщения"
+// @Param for query string false "everyone - удалить для всех"
+// @Security ApiKeyAuth
+// @Success 204
+// @Header 204 {string} x-request-id "Идентификатор запроса"
+// @Failure 400 {object} dto.ErrorResponseV2
+// @Failure 403 {object} dto.ErrorResponseV2
+// @Failure 404 {object} dto.ErrorResponseV2
+// @Router /api/v2/dialog/{user_id}/messages/{message_id} [delete]
+func (h *UserHandler) DeleteMessageV2(c *gin.Context) {
+	requestID := c.GetString("x-request-id")
+	currentUserID := c.MustGet("userID").(int)
+
+	scope := c.Query("for")
+	if scope != "" && scope != "everyone" && scope != "me" {
+		c.JSON(http.StatusBadRequest, dto.ErrorResponseV2{
+			Error:     "Invalid request",
+			Details:   "for must be me or everyone",
+			RequestID: requestID,
+			Timestamp: time.Now().UTC(),
+		})
+		return
+	}
+
+	err := h.userService.DeleteMessageV2(
+		metadata.NewOutgoingContext(c.Request.Context(), metadata.Pairs("x-request-id", requestID)),
+		currentUserID,
+		c.Param("user_id"),
+		c.Param("message_id"),
+		scope == "everyone",
+	)
+	if err != nil {
+		respondMessageError(c, err, "Failed to delete message")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// respondMessageError отвечает на ошибку изменения сообщения
+func respondMessageError(c *gin.Context, err error, internalMsg string) {
+	resp := dto.ErrorResponseV2{
+		Details:   err.Error(),
+		RequestID: c.GetString("x-request-id"),
+		Timestamp: time.Now().UTC(),
+	}
+
+	code := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, service.ErrValidation):
+		code, resp.Error = http.StatusBadRequest, "Invalid request"
+	case errors.Is(err, service.ErrMessageNotFound):
+		code, resp.Error = http.StatusNotFound, "Message not found"
+	case errors.Is(err, service.ErrNotMessageSender):
+		code, resp.Error = http.StatusForbidden, "Only the sender can change the message"
+	case errors.Is(err, service.ErrEditWindowExpired):
+		code, resp.Error = http.StatusConflict, "Message edit window has expired"
+	default:
+		logrus.Error(err)
+		resp.Error, resp.Details = internalMsg, ""
+	}
+
+	c.JSON(code, resp)
+}
+
+// setNextCursor отдает курсор следующей страницы, тело ответа остается массивом
+func setNextCursor(c *gin.Context, cursor string) {
+	if cursor != "" {
+		c.Header("X-Next-Cursor", cursor)
+	}
+}
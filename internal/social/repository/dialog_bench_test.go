@@ -0,0 +1,167 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"testing"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+	"otus-highload-arh-homework/internal/social/repository/postgres"
+	redisRepo "otus-highload-arh-homework/internal/social/repository/redis"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/pressly/goose/v3"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Сравнение хранилищ диалогов на одинаковой нагрузке:
+//
+//	go test -run '^$' -bench DialogRepository ./internal/social/repository/
+//
+// Каждое хранилище поднимается в своем контейнере, Postgres - образ Citus
+// из docker/compose.yml с примененными миграциями
+
+// benchDialogs - число диалогов, по которым распределяется нагрузка
+const benchDialogs = 100
+
+func BenchmarkDialogRepository(b *testing.B) {
+	ctx := context.Background()
+	skipWithoutDocker(b, ctx)
+
+	backends := []struct {
+		name  string
+		setup func(b *testing.B) repository.DialogRepository
+	}{
+		{"postgres", func(b *testing.B) repository.DialogRepository {
+			return postgres.NewDialogRepository(setupPostgres(b, ctx))
+		}},
+		{"redis", func(b *testing.B) repository.DialogRepository {
+			repo, err := redisRepo.NewDialogRepository(ctx, setupRedis(b, ctx))
+			require.NoError(b, err)
+			return repo
+		}},
+	}
+
+	for _, backend := range backends {
+		b.Run(backend.name, func(b *testing.B) {
+			repo := backend.setup(b)
+
+			// История, которую читают и отмечают прочитанной
+			var lastID int64
+			for i := 0; i < benchDialogs*10; i++ {
+				sent, err := repo.StoreDialogMessage(ctx, int64(i%benchDialogs)+1, benchDialogs+1, "warmup message", "")
+				require.NoError(b, err)
+				lastID = sent.ID
+			}
+
+			b.Run("Store", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					sender := int64(i%benchDialogs) + 1
+					if _, err := repo.StoreDialogMessage(ctx, sender, benchDialogs+1, "benchmark message", ""); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			b.Run("Page", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					peer := int64(i%benchDialogs) + 1
+					if _, err := repo.GetDialogMessages(ctx, benchDialogs+1, peer, entity.MessagesQuery{Limit: 50}); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			b.Run("MarkRead", func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					peer := int64(i%benchDialogs) + 1
+					if _, err := repo.MarkDialogRead(ctx, benchDialogs+1, peer, lastID); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		})
+	}
+}
+
+func setupPostgres(b *testing.B, ctx context.Context) *pgxpool.Pool {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "citusdata/citus:13.0.3",
+			ExposedPorts: []string{"5432/tcp"},
+			Env: map[string]string{
+				"POSTGRES_USER":     "postgres",
+				"POSTGRES_PASSWORD": "postgres",
+				"POSTGRES_DB":       "social",
+			},
+			WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2),
+		},
+		Started: true,
+	})
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(b, err)
+	url := fmt.Sprintf("postgres://postgres:postgres@%s/social?sslmode=disable", endpoint)
+
+	db, err := sql.Open("pgx", url)
+	require.NoError(b, err)
+	defer db.Close()
+
+	goose.SetBaseFS(os.DirFS("../../../migrations"))
+	require.NoError(b, goose.SetDialect("postgres"))
+	require.NoError(b, goose.Up(db, "."))
+
+	pool, err := pgxpool.New(ctx, url)
+	require.NoError(b, err)
+	b.Cleanup(pool.Close)
+
+	return pool
+}
+
+func setupRedis(b *testing.B, ctx context.Context) *redis.Client {
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	require.NoError(b, err)
+	b.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(b, err)
+
+	client := redis.NewClient(&redis.Options{Addr: endpoint})
+	b.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+// skipWithoutDocker - аналог testcontainers.SkipIfProviderIsNotHealthy для бенчмарков
+func skipWithoutDocker(b *testing.B, ctx context.Context) {
+	b.Helper()
+	defer func() {
+		if r := recover(); r != nil {
+			b.Skipf("Docker is not running: %v", r)
+		}
+	}()
+
+	provider, err := testcontainers.ProviderDocker.GetProvider()
+	if err == nil {
+		err = provider.Health(ctx)
+	}
+	if err != nil {
+		b.Skipf("Docker is not running: %v", err)
+	}
+}
@@ -5,9 +5,14 @@ import "errors"
 var (
 	ErrUserNotFound      = errors.New("user not found")
 	ErrUserAlreadyExists = errors.New("user already exists")
-	ErrNoMessagesFound   = errors.New("no messages found")
+	ErrMessageNotFound   = errors.New("message not found")
 )
 
 var (
 	ErrPostNotFound = errors.New("post not found")
 )
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupMemberNotFound = errors.New("group member not found")
+)
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"otus-highload-arh-homework/internal/social/entity"
 )
@@ -11,12 +12,80 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id int) (*entity.User, error)
 	GetByEmail(ctx context.Context, email string) (*entity.User, error)
 	Search(ctx context.Context, firstName, lastName string) ([]*entity.User, error)
+	GetLastSeenVisibility(ctx context.Context, ids []int) (map[int]entity.Visibility, error)
+	SetLastSeenVisibility(ctx context.Context, userID int, visibility entity.Visibility) error
 	AddFriend(ctx context.Context, userID, friendID int) error
 	RemoveFriend(ctx context.Context, userID, friendID int) error
 	CheckFriendship(ctx context.Context, userID, friendID int) (bool, error)
 	GetFriendsIDs(ctx context.Context, userID int) ([]int, error)
-	StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content string) (int64, error)
-	GetDialogMessages(ctx context.Context, senderID, recipientID int64) ([]*entity.DialogMessage, error)
+}
+
+// DialogRepository - хранилище диалогов: сообщения, списки диалогов
+// пользователей и счетчики непрочитанных в них
+type DialogRepository interface {
+	StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content, clientMessageID string) (*entity.SentMessage, error)
+	GetDialogMessages(ctx context.Context, senderID, recipientID int64, query entity.MessagesQuery) ([]*entity.DialogMessage, error)
+	ListDialogs(ctx context.Context, userID, before int64, limit int) ([]*entity.DialogPreview, error)
+	GetUserMessagesAfter(ctx context.Context, userID, after int64, limit int) ([]*entity.DialogMessage, error)
+	GetLastUserMessageID(ctx context.Context, userID int64) (int64, error)
+	MarkDialogRead(ctx context.Context, userID, peerID, upToMessageID int64) (int, error)
+	GetUnreadCount(ctx context.Context, userID, peerID int64) (unread, total int, err error)
+	GetUnreadCounters(ctx context.Context, userID int64) (map[int64]int, error)
+	DeleteDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error
+	ListInboxUsers(ctx context.Context, afterUserID int64, limit int) ([]int64, error)
+	RecountUnread(ctx context.Context, userID int64) (map[int64]int, error)
+	GetDialogMessage(ctx context.Context, messageID, userID, peerID int64) (*entity.DialogMessage, error)
+	EditDialogMessage(ctx context.Context, messageID, senderID int64, text string, editedAt time.Time) error
+	TombstoneDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error
+	HideDialogMessage(ctx context.Context, messageID, userID, peerID int64) error
+}
+
+// NonTransactional помечает хранилище, записи которого не участвуют в
+// транзакциях TxManager и не откатываются вместе с ними. Хранилище без
+// этого интерфейса считается транзакционным
+type NonTransactional interface {
+	NonTransactional()
+}
+
+// UnreadCounterRepository - быстрые счетчики непрочитанных сообщений.
+// Источник истины - DialogRepository, счетчики пересобираются из него
+type UnreadCounterRepository interface {
+	// Get возвращает счетчики по собеседникам и общий; found=false, если счетчиков нет
+	Get(ctx context.Context, userID int64) (counters map[int64]int, total int, found bool, err error)
+	// Set заменяет все счетчики пользователя
+	Set(ctx context.Context, userID int64, counters map[int64]int) error
+	// Increment меняет счетчик диалога, если счетчики пользователя загружены
+	Increment(ctx context.Context, userID, peerID int64, delta int) error
+	// SetDialog выставляет счетчик диалога и общий, если счетчики пользователя загружены
+	SetDialog(ctx context.Context, userID, peerID int64, unread, total int) error
+}
+
+// MessageNotifier сообщает подписчикам о новых сообщениях пользователя.
+// Уведомление - только сигнал перечитать сообщения из хранилища, поэтому
+// несколько уведомлений подряд могут слиться в одно
+type MessageNotifier interface {
+	Notify(ctx context.Context, userIDs ...int64) error
+	// Subscribe возвращает канал сигналов для userID и функцию отписки
+	Subscribe(userID int64) (<-chan struct{}, func())
+}
+
+// GroupRepository - групповые чаты. Группа, участники и сообщения лежат
+// на шарде группы, список групп пользователя - на шарде пользователя
+type GroupRepository interface {
+	CreateGroup(ctx context.Context, title string, createdBy int64) (*entity.Group, error)
+	// GetGroup возвращает группу с ролью userID; ErrGroupNotFound, если userID не участник
+	GetGroup(ctx context.Context, groupID, userID int64) (*entity.Group, error)
+	ListUserGroups(ctx context.Context, userID, before int64, limit int) ([]*entity.Group, error)
+	// AddMembers добавляет участников, уже состоящих в группе пропускает.
+	// Возвращает действительно добавленных
+	AddMembers(ctx context.Context, groupID int64, userIDs []int64, role entity.GroupRole) ([]int64, error)
+	RemoveMember(ctx context.Context, groupID, userID int64) error
+	SetMemberRole(ctx context.Context, groupID, userID int64, role entity.GroupRole) error
+	ListMembers(ctx context.Context, groupID int64) ([]*entity.GroupMember, error)
+	// StoreGroupMessage сохраняет сообщение; повтор clientMessageID возвращает
+	// уже сохраненное с Duplicate=true
+	StoreGroupMessage(ctx context.Context, groupID, senderID int64, content, clientMessageID string) (*entity.SentMessage, error)
+	GetGroupMessages(ctx context.Context, groupID int64, query entity.MessagesQuery) ([]*entity.GroupMessage, error)
 }
 
 // PostRepository определяет контракт для работы с хранилищем постов
@@ -27,3 +96,18 @@ type PostRepository interface {
 	Get(ctx context.Context, postID string) (*entity.Post, error)
 	GetFeed(ctx context.Context, userID, offset, limit int) ([]*entity.Post, error)
 }
+
+// TxManager выполняет fn в транзакции, переданной репозиториям через ctx
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// OutboxRepository хранит события для публикации в брокер
+type OutboxRepository interface {
+	Add(ctx context.Context, event *entity.OutboxEvent) error
+	FetchPending(ctx context.Context, limit int) ([]*entity.OutboxEvent, error)
+	MarkSent(ctx context.Context, ids []int64) error
+	MarkFailed(ctx context.Context, id int64, cause string, maxAttempts int) (failed bool, err error)
+	Stats(ctx context.Context) (pending int64, oldest time.Time, err error)
+	DeleteSent(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,677 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DialogRepository хранит диалоги в Citus: messages распределена по
+// отправителю, dialog_inbox - по владельцу списка диалогов
+type DialogRepository struct {
+	pool *pgxpool.Pool
+}
+
+func NewDialogRepository(pool *pgxpool.Pool) repository.DialogRepository {
+	return &DialogRepository{pool: pool}
+}
+
+func (r *DialogRepository) db(ctx context.Context) querier {
+	return conn(ctx, r.pool)
+}
+
+// GetOrCreateDialog получает или создает диалог между пользователями
+func (r *DialogRepository) getOrCreateDialog(ctx context.Context, user1ID, user2ID int64) (int64, error) {
+	if user1ID > user2ID {
+		user1ID, user2ID = user2ID, user1ID
+	}
+
+	// Сначала попробуем найти существующий диалог
+	var dialogID int64
+	err := r.db(ctx).QueryRow(ctx,
+		"SELECT dialog_id FROM dialogs WHERE user1_id = $1 AND user2_id = $2",
+		user1ID, user2ID).Scan(&dialogID)
+
+	if err == nil {
+		return dialogID, nil // Диалог найден
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return 0, fmt.Errorf("failed to find dialog: %w", err)
+	}
+
+	// Диалога нет - создаем новый
+	err = r.db(ctx).QueryRow(ctx,
+		"INSERT INTO dialogs (user1_id, user2_id) VALUES ($1, $2) RETURNING dialog_id",
+		user1ID, user2ID).Scan(&dialogID)
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to create dialog: %w", err)
+	}
+
+	return dialogID, nil
+}
+
+// StoreDialogMessage сохраняет сообщение в диалоге. Повтор с тем же
+// непустым clientMessageID отправителя не создает новое сообщение, а
+// возвращает уже сохраненное с Duplicate=true
+func (r *DialogRepository) StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content, clientMessageID string) (*entity.SentMessage, error) {
+	if clientMessageID != "" {
+		sent, err := r.findSentMessage(ctx, senderID, clientMessageID)
+		if err != nil || sent != nil {
+			return sent, err
+		}
+	}
+
+	dialogID, err := r.getOrCreateDialog(ctx, senderID, recipientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get or create dialog: %w", err)
+	}
+
+	// Конкурентный повтор упирается в уникальный индекс и не вставляет строку
+	const query = `
+		INSERT INTO messages (dialog_id, sender_id, recipient_id, content, client_message_id)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''))
+		ON CONFLICT (sender_id, client_message_id) WHERE client_message_id IS NOT NULL DO NOTHING
+		RETURNING message_id, created_at
+	`
+
+	sent := &entity.SentMessage{}
+	err = r.db(ctx).QueryRow(ctx, query, dialogID, senderID, recipientID, content, clientMessageID).Scan(&sent.ID, &sent.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.findSentMessage(ctx, senderID, clientMessageID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store message: %w", err)
+	}
+
+	if err := r.updateInbox(ctx, dialogID, sent.ID, senderID, recipientID, content, sent.SentAt); err != nil {
+		return nil, err
+	}
+
+	return sent, nil
+}
+
+// findSentMessage ищет сообщение отправителя по client_message_id, nil - не найдено
+func (r *DialogRepository) findSentMessage(ctx context.Context, senderID int64, clientMessageID string) (*entity.SentMessage, error) {
+	const query = `
+		SELECT message_id, created_at
+		FROM messages
+		WHERE sender_id = $1 AND client_message_id = $2
+	`
+
+	sent := &entity.SentMessage{Duplicate: true}
+	err := r.db(ctx).QueryRow(ctx, query, senderID, clientMessageID).Scan(&sent.ID, &sent.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find message by client id: %w", err)
+	}
+
+	return sent, nil
+}
+
+// updateInbox обновляет последнее сообщение в списках диалогов обоих
+// участников, у получателя растет число непрочитанных
+func (r *DialogRepository) updateInbox(ctx context.Context, dialogID, messageID, senderID, recipientID int64, content string, createdAt time.Time) error {
+	const query = `
+		INSERT INTO dialog_inbox (user_id, peer_id, dialog_id, last_message_id, last_sender_id,
+		                          last_message_text, last_message_at, unread_count)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id, peer_id) DO UPDATE SET
+			last_message_id = GREATEST(dialog_inbox.last_message_id, EXCLUDED.last_message_id),
+			last_sender_id = CASE WHEN EXCLUDED.last_message_id > dialog_inbox.last_message_id
+				THEN EXCLUDED.last_sender_id ELSE dialog_inbox.last_sender_id END,
+			last_message_text = CASE WHEN EXCLUDED.last_message_id > dialog_inbox.last_message_id
+				THEN EXCLUDED.last_message_text ELSE dialog_inbox.last_message_text END,
+			last_message_at = CASE WHEN EXCLUDED.last_message_id > dialog_inbox.last_message_id
+				THEN EXCLUDED.last_message_at ELSE dialog_inbox.last_message_at END,
+			unread_count = dialog_inbox.unread_count + EXCLUDED.unread_count
+	`
+
+	content = entity.MessagePreview(content)
+
+	// Строки участников лежат на разных шардах, поэтому два отдельных запроса
+	if _, err := r.db(ctx).Exec(ctx, query, senderID, recipientID, dialogID, messageID, senderID, content, createdAt, 0); err != nil {
+		return fmt.Errorf("failed to update sender inbox: %w", err)
+	}
+	if _, err := r.db(ctx).Exec(ctx, query, recipientID, senderID, dialogID, messageID, senderID, content, createdAt, 1); err != nil {
+		return fmt.Errorf("failed to update recipient inbox: %w", err)
+	}
+
+	return nil
+}
+
+// GetDialogMessages возвращает до query.Limit сообщений между двумя пользователями,
+// кроме удаленных senderID только у себя, по курсорам message_id: по умолчанию и с Before - от новых к старым, с After - от старых к новым
+func (r *DialogRepository) GetDialogMessages(ctx context.Context, senderID, recipientID int64, page entity.MessagesQuery) ([]*entity.DialogMessage, error) {
+	order := "DESC"
+	if page.After > 0 {
+		order = "ASC"
+	}
+
+	query := `
+        SELECT 
+            message_id::text,
+            sender_id::text,
+            recipient_id::text,
+            content as text,
+            created_at as sent_at,
+            read_at IS NOT NULL as is_read,
+            edited_at,
+            deleted_at IS NOT NULL as deleted
+        FROM messages
+        WHERE ((sender_id = $1 AND recipient_id = $2)
+           OR (sender_id = $2 AND recipient_id = $1))
+          AND NOT ($1 = ANY(hidden_for))
+          AND ($3::bigint = 0 OR message_id < $3)
+          AND ($4::bigint = 0 OR message_id > $4)
+        ORDER BY message_id ` + order + `
+        LIMIT $5
+    `
+
+	rows, err := r.db(ctx).Query(ctx, query, senderID, recipientID, page.Before, page.After, page.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dialog messages: %w", err)
+	}
+
+	return scanDialogMessages(rows)
+}
+
+// GetUserMessagesAfter возвращает сообщения пользователя во всех диалогах,
+// входящие и исходящие, с message_id больше after по возрастанию
+func (r *DialogRepository) GetUserMessagesAfter(ctx context.Context, userID, after int64, limit int) ([]*entity.DialogMessage, error) {
+	const query = `
+        SELECT
+            message_id::text,
+            sender_id::text,
+            recipient_id::text,
+            content as text,
+            created_at as sent_at,
+            read_at IS NOT NULL as is_read,
+            edited_at,
+            deleted_at IS NOT NULL as deleted
+        FROM messages
+        WHERE (sender_id = $1 OR recipient_id = $1)
+          AND message_id > $2
+          AND NOT ($1 = ANY(hidden_for))
+        ORDER BY message_id
+        LIMIT $3
+    `
+
+	rows, err := r.db(ctx).Query(ctx, query, userID, after, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user messages: %w", err)
+	}
+
+	return scanDialogMessages(rows)
+}
+
+// GetLastUserMessageID возвращает последний message_id в диалогах
+// пользователя, 0 - диалогов нет. Читается из инбокса с одного шарда
+func (r *DialogRepository) GetLastUserMessageID(ctx context.Context, userID int64) (int64, error) {
+	const query = `SELECT COALESCE(MAX(last_message_id), 0) FROM dialog_inbox WHERE user_id = $1`
+
+	var lastID int64
+	if err := r.db(ctx).QueryRow(ctx, query, userID).Scan(&lastID); err != nil {
+		return 0, fmt.Errorf("failed to get last user message: %w", err)
+	}
+
+	return lastID, nil
+}
+
+func scanDialogMessages(rows pgx.Rows) ([]*entity.DialogMessage, error) {
+	defer rows.Close()
+
+	var messages []*entity.DialogMessage
+	for rows.Next() {
+		var msg entity.DialogMessage
+		err := rows.Scan(
+			&msg.ID,
+			&msg.SenderID,
+			&msg.ReceiverID,
+			&msg.Text,
+			&msg.SentAt,
+			&msg.IsRead,
+			&msg.EditedAt,
+			&msg.Deleted,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ListDialogs возвращает до limit диалогов пользователя от недавних к давним.
+// before - курсор по id последнего сообщения диалога, 0 - с начала
+func (r *DialogRepository) ListDialogs(ctx context.Context, userID, before int64, limit int) ([]*entity.DialogPreview, error) {
+	const query = `
+		SELECT peer_id, last_message_id, last_sender_id, last_message_text, last_message_at, unread_count
+		FROM dialog_inbox
+		WHERE user_id = $1
+		  AND ($2::bigint = 0 OR last_message_id < $2)
+		ORDER BY last_message_id DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dialogs: %w", err)
+	}
+	defer rows.Close()
+
+	var dialogs []*entity.DialogPreview
+	for rows.Next() {
+		var (
+			dialog    entity.DialogPreview
+			messageID int64
+			senderID  int64
+		)
+		err := rows.Scan(
+			&dialog.PeerID,
+			&messageID,
+			&senderID,
+			&dialog.LastMessage.Text,
+			&dialog.LastMessage.SentAt,
+			&dialog.UnreadCount,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan dialog: %w", err)
+		}
+
+		dialog.LastMessage.ID = strconv.FormatInt(messageID, 10)
+		dialog.LastMessage.SenderID = strconv.FormatInt(senderID, 10)
+		receiverID := dialog.PeerID
+		if senderID == dialog.PeerID {
+			receiverID = userID
+		}
+		dialog.LastMessage.ReceiverID = strconv.FormatInt(receiverID, 10)
+		dialogs = append(dialogs, &dialog)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return dialogs, nil
+}
+
+// MarkDialogRead отмечает прочитанными сообщения peerID пользователю userID
+// до upToMessageID включительно и уменьшает счетчик непрочитанных в инбоксе.
+// Возвращает число отмеченных сообщений
+func (r *DialogRepository) MarkDialogRead(ctx context.Context, userID, peerID, upToMessageID int64) (int, error) {
+	const markQuery = `
+		UPDATE messages SET read_at = NOW()
+		WHERE sender_id = $1 AND recipient_id = $2
+		  AND message_id <= $3 AND read_at IS NULL
+	`
+
+	tag, err := r.db(ctx).Exec(ctx, markQuery, peerID, userID, upToMessageID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+
+	marked := int(tag.RowsAffected())
+	if marked == 0 {
+		return 0, nil
+	}
+
+	// messages и dialog_inbox читателя лежат на разных шардах, поэтому
+	// счетчик уменьшается на число отмеченных, а не пересчитывается
+	const inboxQuery = `
+		UPDATE dialog_inbox SET unread_count = GREATEST(unread_count - $3, 0)
+		WHERE user_id = $1 AND peer_id = $2
+	`
+
+	if _, err := r.db(ctx).Exec(ctx, inboxQuery, userID, peerID, marked); err != nil {
+		return 0, fmt.Errorf("failed to update inbox unread count: %w", err)
+	}
+
+	return marked, nil
+}
+
+// GetUnreadCount возвращает число непрочитанных в диалоге с peerID и всего
+func (r *DialogRepository) GetUnreadCount(ctx context.Context, userID, peerID int64) (int, int, error) {
+	const query = `
+		SELECT
+			COALESCE(SUM(unread_count) FILTER (WHERE peer_id = $2), 0),
+			COALESCE(SUM(unread_count), 0)
+		FROM dialog_inbox
+		WHERE user_id = $1
+	`
+
+	var unread, total int
+	if err := r.db(ctx).QueryRow(ctx, query, userID, peerID).Scan(&unread, &total); err != nil {
+		return 0, 0, fmt.Errorf("failed to get unread count: %w", err)
+	}
+
+	return unread, total, nil
+}
+
+// GetUnreadCounters возвращает ненулевые счетчики непрочитанных по собеседникам
+func (r *DialogRepository) GetUnreadCounters(ctx context.Context, userID int64) (map[int64]int, error) {
+	const query = `
+		SELECT peer_id, unread_count
+		FROM dialog_inbox
+		WHERE user_id = $1 AND unread_count > 0
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread counters: %w", err)
+	}
+	defer rows.Close()
+
+	counters := make(map[int64]int)
+	for rows.Next() {
+		var (
+			peerID int64
+			unread int
+		)
+		if err := rows.Scan(&peerID, &unread); err != nil {
+			return nil, fmt.Errorf("failed to scan unread counter: %w", err)
+		}
+		counters[peerID] = unread
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return counters, nil
+}
+
+// DeleteDialogMessage удаляет сообщение и возвращает списки диалогов обоих
+// участников к последнему оставшемуся сообщению. Используется для отката
+// отправки, поэтому должен выполняться в транзакции
+func (r *DialogRepository) DeleteDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	var unread bool
+	err := r.db(ctx).QueryRow(ctx,
+		"DELETE FROM messages WHERE message_id = $1 AND sender_id = $2 RETURNING read_at IS NULL",
+		messageID, senderID).Scan(&unread)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil // Уже удалено
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	if unread {
+		_, err := r.db(ctx).Exec(ctx, `
+			UPDATE dialog_inbox SET unread_count = GREATEST(unread_count - 1, 0)
+			WHERE user_id = $1 AND peer_id = $2
+		`, recipientID, senderID)
+		if err != nil {
+			return fmt.Errorf("failed to update inbox unread count: %w", err)
+		}
+	}
+
+	return r.refreshInboxLastMessage(ctx, senderID, recipientID, messageID)
+}
+
+// refreshInboxLastMessage заменяет в списках диалогов удаленное сообщение
+// предыдущим, а если сообщений не осталось - убирает диалог из списков
+func (r *DialogRepository) refreshInboxLastMessage(ctx context.Context, user1ID, user2ID, removedMessageID int64) error {
+	var (
+		messageID int64
+		senderID  int64
+		content   string
+		createdAt time.Time
+	)
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT message_id, sender_id, content, created_at
+		FROM messages
+		WHERE (sender_id = $1 AND recipient_id = $2)
+		   OR (sender_id = $2 AND recipient_id = $1)
+		ORDER BY message_id DESC
+		LIMIT 1
+	`, user1ID, user2ID).Scan(&messageID, &senderID, &content, &createdAt)
+
+	for _, pair := range [][2]int64{{user1ID, user2ID}, {user2ID, user1ID}} {
+		var execErr error
+		switch {
+		case errors.Is(err, pgx.ErrNoRows):
+			_, execErr = r.db(ctx).Exec(ctx,
+				"DELETE FROM dialog_inbox WHERE user_id = $1 AND peer_id = $2",
+				pair[0], pair[1])
+		case err != nil:
+			return fmt.Errorf("failed to find last message: %w", err)
+		default:
+			_, execErr = r.db(ctx).Exec(ctx, `
+				UPDATE dialog_inbox
+				SET last_message_id = $3, last_sender_id = $4, last_message_text = $5, last_message_at = $6
+				WHERE user_id = $1 AND peer_id = $2 AND last_message_id = $7
+			`, pair[0], pair[1], messageID, senderID, entity.MessagePreview(content), createdAt, removedMessageID)
+		}
+		if execErr != nil {
+			return fmt.Errorf("failed to refresh inbox: %w", execErr)
+		}
+	}
+
+	return nil
+}
+
+// ListInboxUsers возвращает до limit владельцев списков диалогов с id больше afterUserID
+func (r *DialogRepository) ListInboxUsers(ctx context.Context, afterUserID int64, limit int) ([]int64, error) {
+	rows, err := r.db(ctx).Query(ctx, `
+		SELECT DISTINCT user_id
+		FROM dialog_inbox
+		WHERE user_id > $1
+		ORDER BY user_id
+		LIMIT $2
+	`, afterUserID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbox users: %w", err)
+	}
+	defer rows.Close()
+
+	var users []int64
+	for rows.Next() {
+		var userID int64
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan inbox user: %w", err)
+		}
+		users = append(users, userID)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return users, nil
+}
+
+// RecountUnread пересчитывает счетчики непрочитанных пользователя в
+// dialog_inbox по messages.read_at и возвращает ненулевые счетчики.
+// messages распределена по отправителю, поэтому подсчет идет по собеседникам
+func (r *DialogRepository) RecountUnread(ctx context.Context, userID int64) (map[int64]int, error) {
+	rows, err := r.db(ctx).Query(ctx, "SELECT peer_id, unread_count FROM dialog_inbox WHERE user_id = $1", userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbox: %w", err)
+	}
+
+	stored := make(map[int64]int)
+	for rows.Next() {
+		var peerID int64
+		var unread int
+		if err := rows.Scan(&peerID, &unread); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan inbox: %w", err)
+		}
+		stored[peerID] = unread
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	counters := make(map[int64]int, len(stored))
+	for peerID, unread := range stored {
+		var actual int
+		err := r.db(ctx).QueryRow(ctx, `
+			SELECT count(*) FROM messages
+			WHERE sender_id = $1 AND recipient_id = $2 AND read_at IS NULL
+		`, peerID, userID).Scan(&actual)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count unread messages: %w", err)
+		}
+
+		if actual != unread {
+			_, err := r.db(ctx).Exec(ctx,
+				"UPDATE dialog_inbox SET unread_count = $3 WHERE user_id = $1 AND peer_id = $2",
+				userID, peerID, actual)
+			if err != nil {
+				return nil, fmt.Errorf("failed to fix inbox unread count: %w", err)
+			}
+		}
+		if actual > 0 {
+			counters[peerID] = actual
+		}
+	}
+
+	return counters, nil
+}
+
+// GetDialogMessage возвращает сообщение диалога userID и peerID
+func (r *DialogRepository) GetDialogMessage(ctx context.Context, messageID, userID, peerID int64) (*entity.DialogMessage, error) {
+	const query = `
+		SELECT
+			message_id::text,
+			sender_id::text,
+			recipient_id::text,
+			content,
+			created_at,
+			read_at IS NOT NULL,
+			edited_at,
+			deleted_at IS NOT NULL
+		FROM messages
+		WHERE message_id = $1
+		  AND ((sender_id = $2 AND recipient_id = $3)
+		   OR (sender_id = $3 AND recipient_id = $2))
+		  AND NOT ($2 = ANY(hidden_for))
+	`
+
+	var msg entity.DialogMessage
+	err := r.db(ctx).QueryRow(ctx, query, messageID, userID, peerID).Scan(
+		&msg.ID,
+		&msg.SenderID,
+		&msg.ReceiverID,
+		&msg.Text,
+		&msg.SentAt,
+		&msg.IsRead,
+		&msg.EditedAt,
+		&msg.Deleted,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, repository.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	return &msg, nil
+}
+
+// EditDialogMessage меняет текст сообщения и превью в списках диалогов
+func (r *DialogRepository) EditDialogMessage(ctx context.Context, messageID, senderID int64, text string, editedAt time.Time) error {
+	var recipientID int64
+	err := r.db(ctx).QueryRow(ctx, `
+		UPDATE messages SET content = $3, edited_at = $4
+		WHERE message_id = $1 AND sender_id = $2 AND deleted_at IS NULL
+		RETURNING recipient_id
+	`, messageID, senderID, text, editedAt).Scan(&recipientID)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to edit message: %w", err)
+	}
+
+	return r.updateInboxPreview(ctx, messageID, senderID, recipientID, entity.MessagePreview(text))
+}
+
+// TombstoneDialogMessage удаляет сообщение для всех: текст стирается, а
+// непрочитанное сообщение перестает учитываться в счетчике получателя
+func (r *DialogRepository) TombstoneDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	var wasUnread bool
+	err := r.db(ctx).QueryRow(ctx, `
+		SELECT read_at IS NULL FROM messages
+		WHERE message_id = $1 AND sender_id = $2 AND deleted_at IS NULL
+		FOR UPDATE
+	`, messageID, senderID).Scan(&wasUnread)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return repository.ErrMessageNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find message: %w", err)
+	}
+
+	_, err = r.db(ctx).Exec(ctx, `
+		UPDATE messages SET content = '', deleted_at = NOW(), read_at = COALESCE(read_at, NOW())
+		WHERE message_id = $1 AND sender_id = $2
+	`, messageID, senderID)
+	if err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	if wasUnread {
+		_, err := r.db(ctx).Exec(ctx, `
+			UPDATE dialog_inbox SET unread_count = GREATEST(unread_count - 1, 0)
+			WHERE user_id = $1 AND peer_id = $2
+		`, recipientID, senderID)
+		if err != nil {
+			return fmt.Errorf("failed to update inbox unread count: %w", err)
+		}
+	}
+
+	return r.updateInboxPreview(ctx, messageID, senderID, recipientID, "")
+}
+
+// HideDialogMessage удаляет сообщение только у userID
+func (r *DialogRepository) HideDialogMessage(ctx context.Context, messageID, userID, peerID int64) error {
+	tag, err := r.db(ctx).Exec(ctx, `
+		UPDATE messages SET hidden_for = array_append(hidden_for, $2)
+		WHERE message_id = $1
+		  AND ((sender_id = $2 AND recipient_id = $3)
+		   OR (sender_id = $3 AND recipient_id = $2))
+		  AND NOT ($2 = ANY(hidden_for))
+	`, messageID, userID, peerID)
+	if err != nil {
+		return fmt.Errorf("failed to hide message: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// updateInboxPreview меняет превью в списках диалогов, где сообщение последнее
+func (r *DialogRepository) updateInboxPreview(ctx context.Context, messageID, senderID, recipientID int64, preview string) error {
+	for _, pair := range [][2]int64{{senderID, recipientID}, {recipientID, senderID}} {
+		_, err := r.db(ctx).Exec(ctx, `
+			UPDATE dialog_inbox SET last_message_text = $3
+			WHERE user_id = $1 AND peer_id = $2 AND last_message_id = $4
+		`, pair[0], pair[1], preview, messageID)
+		if err != nil {
+			return fmt.Errorf("failed to update inbox preview: %w", err)
+		}
+	}
+
+	return nil
+}
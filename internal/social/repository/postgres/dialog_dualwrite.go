@@ -0,0 +1,207 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dialogMirror переносит строки, затронутые записью, в выделенную базу
+type dialogMirror interface {
+	// SyncMessage переносит сообщение и строки диалога между userID и
+	// peerID. Отправитель - один из них; peerID 0 - собеседник читается
+	// из общей базы
+	SyncMessage(ctx context.Context, messageID, userID, peerID int64) error
+	// SyncRead переносит сообщения peerID пользователю userID до
+	// upToMessageID, непрочитанные в выделенной базе
+	SyncRead(ctx context.Context, userID, peerID, upToMessageID int64) error
+	// SyncInbox переносит список диалогов пользователя
+	SyncInbox(ctx context.Context, userID int64) error
+}
+
+// DialogMirror - dialogMirror из общей базы source в выделенную target
+type DialogMirror struct {
+	sync dialogSync
+}
+
+func NewDialogMirror(source, target *pgxpool.Pool) *DialogMirror {
+	return &DialogMirror{sync: dialogSync{source: source, target: target}}
+}
+
+func (m *DialogMirror) SyncMessage(ctx context.Context, messageID, userID, peerID int64) error {
+	if peerID == 0 {
+		err := conn(ctx, m.sync.source).QueryRow(ctx,
+			"SELECT recipient_id FROM messages WHERE message_id = $1 AND sender_id = $2",
+			messageID, userID).Scan(&peerID)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("failed to get message recipient: %w", err)
+		}
+	}
+
+	_, err := m.sync.sync(ctx, messagesTable, "message_id = $1 AND sender_id IN ($2, $3)",
+		[]any{messageID, userID, peerID}, nil)
+	if err != nil {
+		return err
+	}
+	if peerID == 0 {
+		return nil
+	}
+
+	return m.syncDialog(ctx, userID, peerID)
+}
+
+func (m *DialogMirror) SyncRead(ctx context.Context, userID, peerID, upToMessageID int64) error {
+	rows, err := m.sync.target.Query(ctx, `
+		SELECT message_id FROM messages
+		WHERE sender_id = $1 AND recipient_id = $2 AND message_id <= $3 AND read_at IS NULL
+	`, peerID, userID, upToMessageID)
+	if err != nil {
+		return fmt.Errorf("failed to query unread messages: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int64])
+	if err != nil {
+		return fmt.Errorf("failed to scan unread messages: %w", err)
+	}
+
+	if len(ids) > 0 {
+		_, err = m.sync.sync(ctx, messagesTable, "sender_id = $1 AND message_id = ANY($2)",
+			[]any{peerID, ids}, nil)
+		if err != nil {
+			return err
+		}
+	}
+
+	return m.syncDialog(ctx, userID, peerID)
+}
+
+func (m *DialogMirror) SyncInbox(ctx context.Context, userID int64) error {
+	_, err := m.sync.sync(ctx, inboxTable, "user_id = $1", []any{userID}, nil)
+	return err
+}
+
+// syncDialog переносит диалог пары и строки списков диалогов обоих участников
+func (m *DialogMirror) syncDialog(ctx context.Context, userID, peerID int64) error {
+	user1ID, user2ID := min(userID, peerID), max(userID, peerID)
+	_, err := m.sync.sync(ctx, dialogsTable, "user1_id = $1 AND user2_id = $2",
+		[]any{user1ID, user2ID}, nil)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.sync.sync(ctx, inboxTable,
+		"(user_id = $1 AND peer_id = $2) OR (user_id = $2 AND peer_id = $1)",
+		[]any{userID, peerID}, nil)
+	return err
+}
+
+// DualWriteDialogRepository - хранилище на время переезда диалогов в
+// выделенную базу. Чтение и запись идут в основное хранилище, после
+// фиксации каждой записи затронутые строки переносятся зеркалом: запись,
+// откаченная вместе с транзакцией, в выделенную базу не попадает. Ошибки
+// переноса только логируются: основное хранилище остается источником истины, а
+// расхождения находит и исправляет dialog-migrate -verify -repair
+type DualWriteDialogRepository struct {
+	repository.DialogRepository
+	mirror dialogMirror
+}
+
+func NewDualWriteDialogRepository(primary repository.DialogRepository, mirror dialogMirror) repository.DialogRepository {
+	return &DualWriteDialogRepository{DialogRepository: primary, mirror: mirror}
+}
+
+func (r *DualWriteDialogRepository) StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content, clientMessageID string) (*entity.SentMessage, error) {
+	sent, err := r.DialogRepository.StoreDialogMessage(ctx, senderID, recipientID, content, clientMessageID)
+	if err != nil || sent.Duplicate {
+		return sent, err
+	}
+
+	r.mirrored(ctx, "store message", func(ctx context.Context) error {
+		return r.mirror.SyncMessage(ctx, sent.ID, senderID, recipientID)
+	})
+	return sent, nil
+}
+
+func (r *DualWriteDialogRepository) MarkDialogRead(ctx context.Context, userID, peerID, upToMessageID int64) (int, error) {
+	marked, err := r.DialogRepository.MarkDialogRead(ctx, userID, peerID, upToMessageID)
+	if err != nil || marked == 0 {
+		return marked, err
+	}
+
+	r.mirrored(ctx, "mark read", func(ctx context.Context) error {
+		return r.mirror.SyncRead(ctx, userID, peerID, upToMessageID)
+	})
+	return marked, nil
+}
+
+func (r *DualWriteDialogRepository) DeleteDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	if err := r.DialogRepository.DeleteDialogMessage(ctx, messageID, senderID, recipientID); err != nil {
+		return err
+	}
+
+	r.mirrored(ctx, "delete message", func(ctx context.Context) error {
+		return r.mirror.SyncMessage(ctx, messageID, senderID, recipientID)
+	})
+	return nil
+}
+
+func (r *DualWriteDialogRepository) RecountUnread(ctx context.Context, userID int64) (map[int64]int, error) {
+	counters, err := r.DialogRepository.RecountUnread(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.mirrored(ctx, "recount unread", func(ctx context.Context) error {
+		return r.mirror.SyncInbox(ctx, userID)
+	})
+	return counters, nil
+}
+
+func (r *DualWriteDialogRepository) EditDialogMessage(ctx context.Context, messageID, senderID int64, text string, editedAt time.Time) error {
+	if err := r.DialogRepository.EditDialogMessage(ctx, messageID, senderID, text, editedAt); err != nil {
+		return err
+	}
+
+	r.mirrored(ctx, "edit message", func(ctx context.Context) error {
+		return r.mirror.SyncMessage(ctx, messageID, senderID, 0)
+	})
+	return nil
+}
+
+func (r *DualWriteDialogRepository) TombstoneDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	if err := r.DialogRepository.TombstoneDialogMessage(ctx, messageID, senderID, recipientID); err != nil {
+		return err
+	}
+
+	r.mirrored(ctx, "delete message for everyone", func(ctx context.Context) error {
+		return r.mirror.SyncMessage(ctx, messageID, senderID, recipientID)
+	})
+	return nil
+}
+
+func (r *DualWriteDialogRepository) HideDialogMessage(ctx context.Context, messageID, userID, peerID int64) error {
+	if err := r.DialogRepository.HideDialogMessage(ctx, messageID, userID, peerID); err != nil {
+		return err
+	}
+
+	r.mirrored(ctx, "hide message", func(ctx context.Context) error {
+		return r.mirror.SyncMessage(ctx, messageID, userID, peerID)
+	})
+	return nil
+}
+
+// mirrored переносит строки после фиксации транзакции из ctx
+func (r *DualWriteDialogRepository) mirrored(ctx context.Context, op string, sync func(ctx context.Context) error) {
+	afterCommit(ctx, func(ctx context.Context) {
+		if err := sync(ctx); err != nil {
+			log.Printf("Dialog mirror: failed to %s: %v", op, err)
+		}
+	})
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakePrimary struct {
+	repository.DialogRepository
+	sent   *entity.SentMessage
+	marked int
+	err    error
+}
+
+func (f *fakePrimary) StoreDialogMessage(context.Context, int64, int64, string, string) (*entity.SentMessage, error) {
+	return f.sent, f.err
+}
+
+func (f *fakePrimary) MarkDialogRead(context.Context, int64, int64, int64) (int, error) {
+	return f.marked, f.err
+}
+
+func (f *fakePrimary) EditDialogMessage(context.Context, int64, int64, string, time.Time) error {
+	return f.err
+}
+
+func (f *fakePrimary) HideDialogMessage(context.Context, int64, int64, int64) error {
+	return f.err
+}
+
+type mirrorCall struct {
+	op   string
+	args []int64
+}
+
+type fakeMirror struct {
+	calls []mirrorCall
+	err   error
+}
+
+func (f *fakeMirror) SyncMessage(_ context.Context, messageID, userID, peerID int64) error {
+	f.calls = append(f.calls, mirrorCall{"message", []int64{messageID, userID, peerID}})
+	return f.err
+}
+
+func (f *fakeMirror) SyncRead(_ context.Context, userID, peerID, upToMessageID int64) error {
+	f.calls = append(f.calls, mirrorCall{"read", []int64{userID, peerID, upToMessageID}})
+	return f.err
+}
+
+func (f *fakeMirror) SyncInbox(_ context.Context, userID int64) error {
+	f.calls = append(f.calls, mirrorCall{"inbox", []int64{userID}})
+	return f.err
+}
+
+func TestDualWriteDialogRepository_Store(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("new message is mirrored", func(t *testing.T) {
+		mirror := &fakeMirror{}
+		repo := NewDualWriteDialogRepository(&fakePrimary{sent: &entity.SentMessage{ID: 7}}, mirror)
+
+		sent, err := repo.StoreDialogMessage(ctx, 1, 2, "hi", "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), sent.ID)
+		assert.Equal(t, []mirrorCall{{"message", []int64{7, 1, 2}}}, mirror.calls)
+	})
+
+	t.Run("duplicate is not mirrored", func(t *testing.T) {
+		mirror := &fakeMirror{}
+		repo := NewDualWriteDialogRepository(&fakePrimary{sent: &entity.SentMessage{ID: 7, Duplicate: true}}, mirror)
+
+		_, err := repo.StoreDialogMessage(ctx, 1, 2, "hi", "c-1")
+		require.NoError(t, err)
+		assert.Empty(t, mirror.calls)
+	})
+
+	t.Run("primary error skips mirror", func(t *testing.T) {
+		mirror := &fakeMirror{}
+		repo := NewDualWriteDialogRepository(&fakePrimary{err: errors.New("db down")}, mirror)
+
+		_, err := repo.StoreDialogMessage(ctx, 1, 2, "hi", "")
+		require.Error(t, err)
+		assert.Empty(t, mirror.calls)
+	})
+
+	t.Run("mirror waits for commit", func(t *testing.T) {
+		mirror := &fakeMirror{}
+		repo := NewDualWriteDialogRepository(&fakePrimary{sent: &entity.SentMessage{ID: 7}}, mirror)
+		state := &txState{}
+
+		_, err := repo.StoreDialogMessage(context.WithValue(ctx, txKey{}, state), 1, 2, "hi", "")
+		require.NoError(t, err)
+		assert.Empty(t, mirror.calls)
+
+		// Откаченная транзакция не вызывает отложенные функции, фиксация - вызывает
+		for _, hook := range state.afterCommit {
+			hook(ctx)
+		}
+		assert.Equal(t, []mirrorCall{{"message", []int64{7, 1, 2}}}, mirror.calls)
+	})
+
+	t.Run("mirror error does not fail write", func(t *testing.T) {
+		mirror := &fakeMirror{err: errors.New("dialog db down")}
+		repo := NewDualWriteDialogRepository(&fakePrimary{sent: &entity.SentMessage{ID: 7}}, mirror)
+
+		sent, err := repo.StoreDialogMessage(ctx, 1, 2, "hi", "")
+		require.NoError(t, err)
+		assert.Equal(t, int64(7), sent.ID)
+	})
+}
+
+func TestDualWriteDialogRepository_Changes(t *testing.T) {
+	ctx := context.Background()
+	mirror := &fakeMirror{}
+	primary := &fakePrimary{}
+	repo := NewDualWriteDialogRepository(primary, mirror)
+
+	// Нечего отмечать - нечего переносить
+	_, err := repo.MarkDialogRead(ctx, 2, 1, 5)
+	require.NoError(t, err)
+	assert.Empty(t, mirror.calls)
+
+	primary.marked = 3
+	_, err = repo.MarkDialogRead(ctx, 2, 1, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []mirrorCall{{"read", []int64{2, 1, 5}}}, mirror.calls)
+
+	// Собеседник редактируемого сообщения читается зеркалом
+	mirror.calls = nil
+	require.NoError(t, repo.EditDialogMessage(ctx, 5, 1, "edited", time.Now()))
+	assert.Equal(t, []mirrorCall{{"message", []int64{5, 1, 0}}}, mirror.calls)
+
+	mirror.calls = nil
+	primary.err = repository.ErrMessageNotFound
+	assert.ErrorIs(t, repo.HideDialogMessage(ctx, 5, 2, 1), repository.ErrMessageNotFound)
+	assert.Empty(t, mirror.calls)
+}
@@ -0,0 +1,225 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DialogMigrator переносит dialogs, messages и dialog_inbox из общей базы
+// в выделенную базу сервиса диалогов пачками по ключу таблицы
+type DialogMigrator struct {
+	sync      dialogSync
+	batchSize int
+}
+
+// dialogMigrateTables - таблицы в порядке переноса
+var dialogMigrateTables = []dialogTable{dialogsTable, messagesTable, inboxTable}
+
+// maxDialogKey - граница последнего диапазона, за ней строк нет
+var maxDialogKey = dialogKey{math.MaxInt64, math.MaxInt64}
+
+func NewDialogMigrator(source, target *pgxpool.Pool, batchSize int) *DialogMigrator {
+	if batchSize <= 0 {
+		batchSize = 1000
+	}
+
+	return &DialogMigrator{sync: dialogSync{source: source, target: target}, batchSize: batchSize}
+}
+
+// Copy переносит строки с места прошлой остановки: позиция каждой таблицы
+// сохраняется в dialog_migrate_progress вместе с пачкой. reset начинает
+// перенос заново. После переноса последовательности выделенной базы
+// сдвигаются за перенесенные ключи
+func (m *DialogMigrator) Copy(ctx context.Context, reset bool) error {
+	for _, t := range dialogMigrateTables {
+		if err := m.copyTable(ctx, t, reset); err != nil {
+			return err
+		}
+	}
+
+	return AlignDialogSequences(ctx, m.sync.target)
+}
+
+func (m *DialogMigrator) copyTable(ctx context.Context, t dialogTable, reset bool) error {
+	if reset {
+		if _, err := m.sync.target.Exec(ctx, "DELETE FROM dialog_migrate_progress WHERE table_name = $1", t.name); err != nil {
+			return fmt.Errorf("failed to reset %s progress: %w", t.name, err)
+		}
+	}
+
+	from, copied, err := m.progress(ctx, t)
+	if err != nil {
+		return err
+	}
+
+	for {
+		to, last, err := m.batchEnd(ctx, t, from)
+		if err != nil {
+			return err
+		}
+
+		where, args := rangeWhere(t, from, to)
+		res, err := m.sync.sync(ctx, t, where, args, func(tx pgx.Tx, res syncResult) error {
+			if res.rows == 0 {
+				return nil
+			}
+			return saveProgress(ctx, tx, t, res.maxKey, copied+int64(res.rows))
+		})
+		if err != nil {
+			return err
+		}
+
+		if res.rows > 0 {
+			from = res.maxKey
+			copied += int64(res.rows)
+		}
+		log.Printf("Dialog migrate: %s copied %d rows, position %d:%d", t.name, copied, from[0], from[1])
+
+		if last {
+			return nil
+		}
+	}
+}
+
+// progress возвращает позицию переноса таблицы и число перенесенных строк
+func (m *DialogMigrator) progress(ctx context.Context, t dialogTable) (dialogKey, int64, error) {
+	var (
+		key    dialogKey
+		copied int64
+	)
+	err := m.sync.target.QueryRow(ctx,
+		"SELECT last_key1, last_key2, rows_copied FROM dialog_migrate_progress WHERE table_name = $1",
+		t.name).Scan(&key[0], &key[1], &copied)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return dialogKey{math.MinInt64, math.MinInt64}, 0, nil
+	}
+	if err != nil {
+		return dialogKey{}, 0, fmt.Errorf("failed to get %s progress: %w", t.name, err)
+	}
+
+	return key, copied, nil
+}
+
+func saveProgress(ctx context.Context, tx pgx.Tx, t dialogTable, key dialogKey, copied int64) error {
+	const query = `
+		INSERT INTO dialog_migrate_progress (table_name, last_key1, last_key2, rows_copied, updated_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		ON CONFLICT (table_name) DO UPDATE SET
+			last_key1 = EXCLUDED.last_key1,
+			last_key2 = EXCLUDED.last_key2,
+			rows_copied = EXCLUDED.rows_copied,
+			updated_at = EXCLUDED.updated_at
+	`
+
+	if _, err := tx.Exec(ctx, query, t.name, key[0], key[1], copied); err != nil {
+		return fmt.Errorf("failed to save %s progress: %w", t.name, err)
+	}
+
+	return nil
+}
+
+// batchEnd возвращает ключ последней строки пачки после from в общей базе.
+// last - пачка последняя, ее диапазон открыт до maxDialogKey
+func (m *DialogMigrator) batchEnd(ctx context.Context, t dialogTable, from dialogKey) (dialogKey, bool, error) {
+	query := fmt.Sprintf(`
+		SELECT %[1]s, %[2]s FROM %[3]s
+		WHERE (%[1]s, %[2]s) > ($1, $2)
+		ORDER BY %[1]s, %[2]s
+		OFFSET $3 LIMIT 1
+	`, t.key[0], t.key[1], t.name)
+
+	var to dialogKey
+	err := m.sync.source.QueryRow(ctx, query, from[0], from[1], m.batchSize-1).Scan(&to[0], &to[1])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return maxDialogKey, true, nil
+	}
+	if err != nil {
+		return dialogKey{}, false, fmt.Errorf("failed to find %s batch end: %w", t.name, err)
+	}
+
+	return to, to == maxDialogKey, nil
+}
+
+// rangeWhere - условие на ключи в диапазоне (from, to]
+func rangeWhere(t dialogTable, from, to dialogKey) (string, []any) {
+	where := fmt.Sprintf("(%[1]s, %[2]s) > ($1, $2) AND (%[1]s, %[2]s) <= ($3, $4)", t.key[0], t.key[1])
+	return where, []any{from[0], from[1], to[0], to[1]}
+}
+
+// VerifyResult - итог сверки таблицы: число строк в базах и диапазоны
+// ключей, где не совпали число строк или контрольная сумма
+type VerifyResult struct {
+	Table         string
+	SourceRows    int64
+	TargetRows    int64
+	Mismatches    int
+	Repaired      int
+	FirstMismatch string
+}
+
+// Verify сверяет таблицы диапазонами по batchSize строк общей базы: число
+// строк и сумму хэшей содержимого. repair переносит несовпавшие диапазоны
+// заново, удаляя в выделенной базе строки, которых нет в общей
+func (m *DialogMigrator) Verify(ctx context.Context, repair bool) ([]VerifyResult, error) {
+	results := make([]VerifyResult, 0, len(dialogMigrateTables))
+	for _, t := range dialogMigrateTables {
+		res, err := m.verifyTable(ctx, t, repair)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, res)
+	}
+
+	return results, nil
+}
+
+func (m *DialogMigrator) verifyTable(ctx context.Context, t dialogTable, repair bool) (VerifyResult, error) {
+	res := VerifyResult{Table: t.name}
+	from := dialogKey{math.MinInt64, math.MinInt64}
+
+	for {
+		to, last, err := m.batchEnd(ctx, t, from)
+		if err != nil {
+			return res, err
+		}
+
+		where, args := rangeWhere(t, from, to)
+		sourceRows, sourceSum, err := checksum(ctx, m.sync.source, t, where, args)
+		if err != nil {
+			return res, err
+		}
+		targetRows, targetSum, err := checksum(ctx, m.sync.target, t, where, args)
+		if err != nil {
+			return res, err
+		}
+		res.SourceRows += sourceRows
+		res.TargetRows += targetRows
+
+		if sourceRows != targetRows || sourceSum != targetSum {
+			res.Mismatches++
+			if res.FirstMismatch == "" {
+				res.FirstMismatch = fmt.Sprintf("(%d:%d, %d:%d]", from[0], from[1], to[0], to[1])
+			}
+			log.Printf("Dialog migrate: %s mismatch in (%d:%d, %d:%d]: %d rows vs %d",
+				t.name, from[0], from[1], to[0], to[1], sourceRows, targetRows)
+
+			if repair {
+				if _, err := m.sync.sync(ctx, t, where, args, nil); err != nil {
+					return res, err
+				}
+				res.Repaired++
+			}
+		}
+
+		if last {
+			return res, nil
+		}
+		from = to
+	}
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// dialogKey - ключ строки таблицы диалогов. У всех переносимых таблиц он
+// из двух bigint, поэтому обход и сравнение устроены одинаково
+type dialogKey [2]int64
+
+func (k dialogKey) less(other dialogKey) bool {
+	return k[0] < other[0] || (k[0] == other[0] && k[1] < other[1])
+}
+
+// dialogTable - таблица, переносимая из общей базы в выделенную
+type dialogTable struct {
+	name string
+	key  [2]string
+	// columns - колонки вне ключа, обновляемые у существующих строк
+	columns []string
+}
+
+var (
+	dialogsTable = dialogTable{
+		name:    "dialogs",
+		key:     [2]string{"dialog_id", "user1_id"},
+		columns: []string{"user2_id", "created_at"},
+	}
+	messagesTable = dialogTable{
+		name: "messages",
+		key:  [2]string{"message_id", "sender_id"},
+		columns: []string{"dialog_id", "recipient_id", "content", "created_at", "read_at",
+			"edited_at", "deleted_at", "hidden_for", "client_message_id"},
+	}
+	inboxTable = dialogTable{
+		name: "dialog_inbox",
+		key:  [2]string{"user_id", "peer_id"},
+		columns: []string{"dialog_id", "last_message_id", "last_sender_id", "last_message_text",
+			"last_message_at", "unread_count"},
+	}
+)
+
+// keyText - ключ строки одной строкой для сравнения со списком ключей
+func (t dialogTable) keyText() string {
+	return fmt.Sprintf("%s::text || ':' || %s::text", t.key[0], t.key[1])
+}
+
+// dialogSync приводит строки таблиц диалогов в target к строкам source.
+// Строки передаются как jsonb, поэтому типы колонок переносятся без
+// преобразований на стороне Go
+type dialogSync struct {
+	source *pgxpool.Pool
+	target *pgxpool.Pool
+}
+
+// syncResult - сколько строк source перенесено и наибольший их ключ
+type syncResult struct {
+	rows   int
+	maxKey dialogKey
+}
+
+// sync переносит строки, подходящие под where, из source в target:
+// недостающие вставляются, отличающиеся обновляются, отсутствующие в
+// source удаляются. where с параметрами args выполняется в обеих базах.
+// Чтение source идет в транзакции из ctx, если она есть. afterWrite
+// выполняется в транзакции записи target
+func (s *dialogSync) sync(ctx context.Context, t dialogTable, where string, args []any, afterWrite func(tx pgx.Tx, res syncResult) error) (syncResult, error) {
+	query := fmt.Sprintf(`
+		SELECT %s, %s, to_jsonb(t)::text
+		FROM (SELECT * FROM %s WHERE %s) t
+	`, t.key[0], t.key[1], t.name, where)
+
+	rows, err := conn(ctx, s.source).Query(ctx, query, args...)
+	if err != nil {
+		return syncResult{}, fmt.Errorf("failed to read %s: %w", t.name, err)
+	}
+	defer rows.Close()
+
+	var (
+		res     syncResult
+		records []string
+		// Пустой список, а не NULL: с NULL условие удаления не выполняется
+		keys = []string{}
+	)
+	for rows.Next() {
+		var (
+			key    dialogKey
+			record string
+		)
+		if err := rows.Scan(&key[0], &key[1], &record); err != nil {
+			return syncResult{}, fmt.Errorf("failed to scan %s: %w", t.name, err)
+		}
+		if res.rows == 0 || res.maxKey.less(key) {
+			res.maxKey = key
+		}
+		res.rows++
+		keys = append(keys, fmt.Sprintf("%d:%d", key[0], key[1]))
+		records = append(records, record)
+	}
+	if err := rows.Err(); err != nil {
+		return syncResult{}, fmt.Errorf("rows error: %w", err)
+	}
+
+	err = pgx.BeginFunc(ctx, s.target, func(tx pgx.Tx) error {
+		deleteQuery := fmt.Sprintf(`DELETE FROM %s WHERE (%s) AND %s <> ALL($%d::text[])`,
+			t.name, where, t.keyText(), len(args)+1)
+		deleteArgs := append(append([]any{}, args...), keys)
+		if _, err := tx.Exec(ctx, deleteQuery, deleteArgs...); err != nil {
+			return fmt.Errorf("failed to delete stale %s: %w", t.name, err)
+		}
+
+		if len(records) > 0 {
+			if _, err := tx.Exec(ctx, t.upsertQuery(), "["+strings.Join(records, ",")+"]"); err != nil {
+				return fmt.Errorf("failed to write %s: %w", t.name, err)
+			}
+		}
+
+		if afterWrite != nil {
+			return afterWrite(tx, res)
+		}
+		return nil
+	})
+	if err != nil {
+		return syncResult{}, err
+	}
+
+	return res, nil
+}
+
+func (t dialogTable) upsertQuery() string {
+	set := make([]string, len(t.columns))
+	for i, column := range t.columns {
+		set[i] = fmt.Sprintf("%s = EXCLUDED.%s", column, column)
+	}
+
+	return fmt.Sprintf(`
+		INSERT INTO %[1]s
+		SELECT * FROM jsonb_populate_recordset(NULL::%[1]s, $1::jsonb)
+		ON CONFLICT (%[2]s, %[3]s) DO UPDATE SET %[4]s
+	`, t.name, t.key[0], t.key[1], strings.Join(set, ", "))
+}
+
+// checksum считает число строк под where и сумму хэшей их содержимого.
+// Сумма не зависит от порядка строк и считается на шардах
+func checksum(ctx context.Context, pool *pgxpool.Pool, t dialogTable, where string, args []any) (count int64, sum string, err error) {
+	query := fmt.Sprintf(`
+		SELECT count(*), COALESCE(sum(('x' || left(md5(to_jsonb(t)::text), 15))::bit(60)::bigint), 0)::text
+		FROM (SELECT * FROM %s WHERE %s) t
+	`, t.name, where)
+
+	// Время в jsonb выводится в часовом поясе сессии, он должен совпадать в обеих базах
+	err = pgx.BeginFunc(ctx, pool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "SET LOCAL citus.propagate_set_commands = 'local'"); err != nil {
+			return fmt.Errorf("failed to propagate session settings: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "SET LOCAL TimeZone = 'UTC'"); err != nil {
+			return fmt.Errorf("failed to set time zone: %w", err)
+		}
+		return tx.QueryRow(ctx, query, args...).Scan(&count, &sum)
+	})
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to checksum %s: %w", t.name, err)
+	}
+
+	return count, sum, nil
+}
+
+// AlignDialogSequences сдвигает последовательности dialog_id и message_id
+// за наибольший ключ таблицы. Строки, перенесенные из общей базы, пишутся с
+// готовыми ключами и последовательности не двигают
+func AlignDialogSequences(ctx context.Context, pool *pgxpool.Pool) error {
+	for _, t := range []dialogTable{dialogsTable, messagesTable} {
+		query := fmt.Sprintf(`
+			SELECT setval(pg_get_serial_sequence('%[1]s', '%[2]s'),
+				GREATEST((SELECT COALESCE(max(%[2]s), 1) FROM %[1]s),
+				         (SELECT last_value FROM %[1]s_%[2]s_seq)))
+		`, t.name, t.key[0])
+		if _, err := pool.Exec(ctx, query); err != nil {
+			return fmt.Errorf("failed to align %s sequence: %w", t.name, err)
+		}
+	}
+
+	return nil
+}
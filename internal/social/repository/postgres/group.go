@@ -0,0 +1,329 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type GroupRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewGroupRepository создает репозиторий групповых чатов
+func NewGroupRepository(pool *pgxpool.Pool) *GroupRepository {
+	return &GroupRepository{pool: pool}
+}
+
+func (r *GroupRepository) db(ctx context.Context) querier {
+	return conn(ctx, r.pool)
+}
+
+// CreateGroup создает группу без участников
+func (r *GroupRepository) CreateGroup(ctx context.Context, title string, createdBy int64) (*entity.Group, error) {
+	const query = `
+		INSERT INTO group_chats (title, created_by)
+		VALUES ($1, $2)
+		RETURNING group_id, created_at
+	`
+
+	group := &entity.Group{Title: title, CreatedBy: createdBy}
+	if err := r.db(ctx).QueryRow(ctx, query, title, createdBy).Scan(&group.ID, &group.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to create group: %w", err)
+	}
+
+	return group, nil
+}
+
+// GetGroup возвращает группу с ролью участника userID
+func (r *GroupRepository) GetGroup(ctx context.Context, groupID, userID int64) (*entity.Group, error) {
+	groups, err := r.getGroups(ctx, []int64{groupID}, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(groups) == 0 {
+		return nil, repository.ErrGroupNotFound
+	}
+
+	return groups[0], nil
+}
+
+// ListUserGroups возвращает до limit групп пользователя с group_id меньше
+// before (0 - без курсора) от новых к старым
+func (r *GroupRepository) ListUserGroups(ctx context.Context, userID, before int64, limit int) ([]*entity.Group, error) {
+	const query = `
+		SELECT group_id
+		FROM user_groups
+		WHERE user_id = $1 AND ($2 = 0 OR group_id < $2)
+		ORDER BY group_id DESC
+		LIMIT $3
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, userID, before, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list user groups: %w", err)
+	}
+	groupIDs, err := scanIDs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to scan user groups: %w", err)
+	}
+	if len(groupIDs) == 0 {
+		return nil, nil
+	}
+
+	return r.getGroups(ctx, groupIDs, userID)
+}
+
+// getGroups читает группы с шардов групп и роль в них userID. Группы, где
+// userID не участник, пропускаются. Результат - по убыванию group_id
+func (r *GroupRepository) getGroups(ctx context.Context, groupIDs []int64, userID int64) ([]*entity.Group, error) {
+	// Все таблицы колоцированы по group_id, поэтому соединения выполняются внутри шардов
+	const query = `
+		SELECT g.group_id, g.title, g.created_by, g.created_at, m.role, c.member_count
+		FROM group_chats g
+		JOIN group_members m ON m.group_id = g.group_id AND m.user_id = $2
+		JOIN (
+			SELECT group_id, count(*) AS member_count
+			FROM group_members
+			WHERE group_id = ANY($1)
+			GROUP BY group_id
+		) c ON c.group_id = g.group_id
+		WHERE g.group_id = ANY($1)
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, groupIDs, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	groups := make([]*entity.Group, 0, len(groupIDs))
+	for rows.Next() {
+		var group entity.Group
+		if err := rows.Scan(&group.ID, &group.Title, &group.CreatedBy, &group.CreatedAt, &group.Role, &group.MemberCount); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		groups = append(groups, &group)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	sort.Slice(groups, func(i, j int) bool { return groups[i].ID > groups[j].ID })
+
+	return groups, nil
+}
+
+// AddMembers добавляет участников с ролью role и возвращает добавленных.
+// Несуществующий пользователь - ErrUserNotFound
+func (r *GroupRepository) AddMembers(ctx context.Context, groupID int64, userIDs []int64, role entity.GroupRole) ([]int64, error) {
+	const addMembers = `
+		INSERT INTO group_members (group_id, user_id, role)
+		SELECT $1, user_id, $3 FROM unnest($2::bigint[]) AS user_id
+		ON CONFLICT (group_id, user_id) DO NOTHING
+		RETURNING user_id
+	`
+	const addUserGroups = `
+		INSERT INTO user_groups (user_id, group_id)
+		SELECT user_id, $2 FROM unnest($1::bigint[]) AS user_id
+		ON CONFLICT (user_id, group_id) DO NOTHING
+	`
+
+	rows, err := r.db(ctx).Query(ctx, addMembers, groupID, userIDs, role)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add group members: %w", err)
+	}
+	added, err := scanIDs(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add group members: %w", err)
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	// Строки user_groups лежат на шардах пользователей
+	if _, err := r.db(ctx).Exec(ctx, addUserGroups, added, groupID); err != nil {
+		if isForeignKeyViolation(err) {
+			return nil, repository.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to add user groups: %w", err)
+	}
+
+	return added, nil
+}
+
+// RemoveMember удаляет участника из группы
+func (r *GroupRepository) RemoveMember(ctx context.Context, groupID, userID int64) error {
+	const removeMember = `DELETE FROM group_members WHERE group_id = $1 AND user_id = $2`
+	const removeUserGroup = `DELETE FROM user_groups WHERE user_id = $1 AND group_id = $2`
+
+	tag, err := r.db(ctx).Exec(ctx, removeMember, groupID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to remove group member: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrGroupMemberNotFound
+	}
+
+	if _, err := r.db(ctx).Exec(ctx, removeUserGroup, userID, groupID); err != nil {
+		return fmt.Errorf("failed to remove user group: %w", err)
+	}
+
+	return nil
+}
+
+// SetMemberRole меняет роль участника
+func (r *GroupRepository) SetMemberRole(ctx context.Context, groupID, userID int64, role entity.GroupRole) error {
+	const query = `UPDATE group_members SET role = $3 WHERE group_id = $1 AND user_id = $2`
+
+	tag, err := r.db(ctx).Exec(ctx, query, groupID, userID, role)
+	if err != nil {
+		return fmt.Errorf("failed to set group member role: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return repository.ErrGroupMemberNotFound
+	}
+
+	return nil
+}
+
+// ListMembers возвращает участников группы в порядке вступления
+func (r *GroupRepository) ListMembers(ctx context.Context, groupID int64) ([]*entity.GroupMember, error) {
+	const query = `
+		SELECT user_id, role, joined_at
+		FROM group_members
+		WHERE group_id = $1
+		ORDER BY joined_at, user_id
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list group members: %w", err)
+	}
+	defer rows.Close()
+
+	var members []*entity.GroupMember
+	for rows.Next() {
+		var member entity.GroupMember
+		if err := rows.Scan(&member.UserID, &member.Role, &member.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group member: %w", err)
+		}
+		members = append(members, &member)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return members, nil
+}
+
+// StoreGroupMessage сохраняет сообщение группы. Повтор с тем же непустым
+// clientMessageID отправителя возвращает уже сохраненное с Duplicate=true
+func (r *GroupRepository) StoreGroupMessage(ctx context.Context, groupID, senderID int64, content, clientMessageID string) (*entity.SentMessage, error) {
+	if clientMessageID != "" {
+		sent, err := r.findSentGroupMessage(ctx, groupID, senderID, clientMessageID)
+		if err != nil || sent != nil {
+			return sent, err
+		}
+	}
+
+	const query = `
+		INSERT INTO group_messages (group_id, sender_id, content, client_message_id)
+		VALUES ($1, $2, $3, NULLIF($4, ''))
+		ON CONFLICT (group_id, sender_id, client_message_id) WHERE client_message_id IS NOT NULL DO NOTHING
+		RETURNING message_id, created_at
+	`
+
+	sent := &entity.SentMessage{}
+	err := r.db(ctx).QueryRow(ctx, query, groupID, senderID, content, clientMessageID).Scan(&sent.ID, &sent.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return r.findSentGroupMessage(ctx, groupID, senderID, clientMessageID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to store group message: %w", err)
+	}
+
+	return sent, nil
+}
+
+// findSentGroupMessage ищет сообщение по client_message_id, nil - не найдено
+func (r *GroupRepository) findSentGroupMessage(ctx context.Context, groupID, senderID int64, clientMessageID string) (*entity.SentMessage, error) {
+	const query = `
+		SELECT message_id, created_at
+		FROM group_messages
+		WHERE group_id = $1 AND sender_id = $2 AND client_message_id = $3
+	`
+
+	sent := &entity.SentMessage{Duplicate: true}
+	err := r.db(ctx).QueryRow(ctx, query, groupID, senderID, clientMessageID).Scan(&sent.ID, &sent.SentAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find group message by client id: %w", err)
+	}
+
+	return sent, nil
+}
+
+// GetGroupMessages возвращает до query.Limit сообщений группы по курсорам
+// message_id: по умолчанию и с Before - от новых к старым, с After - от старых к новым
+func (r *GroupRepository) GetGroupMessages(ctx context.Context, groupID int64, page entity.MessagesQuery) ([]*entity.GroupMessage, error) {
+	order := "DESC"
+	if page.After > 0 {
+		order = "ASC"
+	}
+
+	query := `
+		SELECT message_id, group_id, sender_id, content, created_at
+		FROM group_messages
+		WHERE group_id = $1
+		  AND ($2 = 0 OR message_id < $2)
+		  AND ($3 = 0 OR message_id > $3)
+		ORDER BY message_id ` + order + `
+		LIMIT $4
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, groupID, page.Before, page.After, page.Limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get group messages: %w", err)
+	}
+	defer rows.Close()
+
+	var messages []*entity.GroupMessage
+	for rows.Next() {
+		var msg entity.GroupMessage
+		if err := rows.Scan(&msg.ID, &msg.GroupID, &msg.SenderID, &msg.Text, &msg.SentAt); err != nil {
+			return nil, fmt.Errorf("failed to scan group message: %w", err)
+		}
+		messages = append(messages, &msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return messages, nil
+}
+
+// scanIDs читает строки из одной колонки BIGINT и закрывает rows
+func scanIDs(rows pgx.Rows) ([]int64, error) {
+	defer rows.Close()
+
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, rows.Err()
+}
@@ -0,0 +1,69 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AdvisoryLock - сессионная advisory-блокировка Postgres для выбора лидера
+// среди нескольких экземпляров процесса. Блокировка удерживается выделенным
+// соединением и снимается при его закрытии, в том числе при падении процесса
+type AdvisoryLock struct {
+	pool *pgxpool.Pool
+	key  int64
+	conn *pgxpool.Conn
+}
+
+func NewAdvisoryLock(pool *pgxpool.Pool, key int64) *AdvisoryLock {
+	return &AdvisoryLock{pool: pool, key: key}
+}
+
+// TryAcquire пытается захватить блокировку без ожидания. Повторный вызов
+// у владельца проверяет, что соединение с блокировкой живо
+func (l *AdvisoryLock) TryAcquire(ctx context.Context) (bool, error) {
+	if l.conn != nil {
+		if err := l.conn.Ping(ctx); err == nil {
+			return true, nil
+		}
+		// Соединение потеряно вместе с блокировкой
+		l.conn.Release()
+		l.conn = nil
+	}
+
+	conn, err := l.pool.Acquire(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire connection: %w", err)
+	}
+
+	var acquired bool
+	if err := conn.QueryRow(ctx, "SELECT pg_try_advisory_lock($1)", l.key).Scan(&acquired); err != nil {
+		conn.Release()
+		return false, fmt.Errorf("failed to try advisory lock: %w", err)
+	}
+
+	if !acquired {
+		conn.Release()
+		return false, nil
+	}
+
+	l.conn = conn
+	return true, nil
+}
+
+func (l *AdvisoryLock) Release(ctx context.Context) error {
+	if l.conn == nil {
+		return nil
+	}
+	defer func() {
+		l.conn.Release()
+		l.conn = nil
+	}()
+
+	if _, err := l.conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", l.key); err != nil {
+		return fmt.Errorf("failed to release advisory lock: %w", err)
+	}
+
+	return nil
+}
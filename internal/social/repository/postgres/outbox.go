@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/pkg/tracing"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type OutboxRepository struct {
+	pool *pgxpool.Pool
+}
+
+// NewOutboxRepository создает новый экземпляр OutboxRepository
+func NewOutboxRepository(pool *pgxpool.Pool) *OutboxRepository {
+	return &OutboxRepository{pool: pool}
+}
+
+func (r *OutboxRepository) db(ctx context.Context) querier {
+	return conn(ctx, r.pool)
+}
+
+// Add записывает событие. Вызывается внутри транзакции изменения агрегата.
+// Если контекст запроса не задан в событии, он берётся из ctx
+func (r *OutboxRepository) Add(ctx context.Context, event *entity.OutboxEvent) error {
+	const query = `
+		INSERT INTO outbox (aggregate_type, aggregate_id, event_type, payload, request_id, traceparent)
+		VALUES ($1, $2, $3, $4, NULLIF($5, ''), NULLIF($6, ''))
+		RETURNING id, created_at
+	`
+
+	trace := tracing.FromContext(ctx)
+	if event.RequestID == "" {
+		event.RequestID = trace.RequestID
+	}
+	if event.TraceParent == "" {
+		event.TraceParent = trace.TraceParent
+	}
+
+	err := r.db(ctx).QueryRow(ctx, query,
+		event.AggregateType,
+		event.AggregateID,
+		event.EventType,
+		event.Payload,
+		event.RequestID,
+		event.TraceParent,
+	).Scan(&event.ID, &event.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+
+	return nil
+}
+
+// FetchPending возвращает неопубликованные события в порядке записи
+func (r *OutboxRepository) FetchPending(ctx context.Context, limit int) ([]*entity.OutboxEvent, error) {
+	const query = `
+		SELECT id, aggregate_type, aggregate_id, event_type, payload, created_at, attempts,
+			COALESCE(request_id, ''), COALESCE(traceparent, '')
+		FROM outbox
+		WHERE sent_at IS NULL AND failed_at IS NULL
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query outbox: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.OutboxEvent
+	for rows.Next() {
+		var event entity.OutboxEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.EventType,
+			&event.Payload,
+			&event.CreatedAt,
+			&event.Attempts,
+			&event.RequestID,
+			&event.TraceParent,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return events, nil
+}
+
+func (r *OutboxRepository) MarkSent(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	const query = `
+		UPDATE outbox
+		SET sent_at = NOW()
+		WHERE id = ANY($1)
+	`
+
+	if _, err := r.db(ctx).Exec(ctx, query, ids); err != nil {
+		return fmt.Errorf("failed to mark outbox events sent: %w", err)
+	}
+
+	return nil
+}
+
+// MarkFailed учитывает неудачную попытку публикации. После maxAttempts
+// попыток событие помечается failed_at и больше не возвращается
+// FetchPending; результат сообщает, произошло ли это
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id int64, cause string, maxAttempts int) (bool, error) {
+	const query = `
+		UPDATE outbox
+		SET attempts = attempts + 1,
+			last_error = $2,
+			failed_at = CASE WHEN attempts + 1 >= $3 THEN NOW() END
+		WHERE id = $1
+		RETURNING failed_at IS NOT NULL
+	`
+
+	var failed bool
+	if err := r.db(ctx).QueryRow(ctx, query, id, cause, maxAttempts).Scan(&failed); err != nil {
+		return false, fmt.Errorf("failed to mark outbox event failed: %w", err)
+	}
+
+	return failed, nil
+}
+
+// ListFailed возвращает события, помеченные failed, в порядке записи
+func (r *OutboxRepository) ListFailed(ctx context.Context, limit int) ([]*entity.OutboxEvent, error) {
+	const query = `
+		SELECT id, aggregate_type, aggregate_id, event_type, created_at, attempts, COALESCE(last_error, '')
+		FROM outbox
+		WHERE failed_at IS NOT NULL
+		ORDER BY id
+		LIMIT $1
+	`
+
+	rows, err := r.db(ctx).Query(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query failed outbox events: %w", err)
+	}
+	defer rows.Close()
+
+	var events []*entity.OutboxEvent
+	for rows.Next() {
+		var event entity.OutboxEvent
+		err := rows.Scan(
+			&event.ID,
+			&event.AggregateType,
+			&event.AggregateID,
+			&event.EventType,
+			&event.CreatedAt,
+			&event.Attempts,
+			&event.LastError,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan outbox event: %w", err)
+		}
+		events = append(events, &event)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return events, nil
+}
+
+// RequeueFailed возвращает события, помеченные failed, в публикацию со
+// сброшенным счетчиком попыток. Более поздние события их агрегатов уже
+// опубликованы, так что повторное событие придет после них
+func (r *OutboxRepository) RequeueFailed(ctx context.Context) (int64, error) {
+	const query = `
+		UPDATE outbox
+		SET failed_at = NULL, attempts = 0
+		WHERE failed_at IS NOT NULL
+	`
+
+	res, err := r.db(ctx).Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to requeue failed outbox events: %w", err)
+	}
+
+	return res.RowsAffected(), nil
+}
+
+// Stats возвращает число неопубликованных событий и время записи самого старого
+func (r *OutboxRepository) Stats(ctx context.Context) (int64, time.Time, error) {
+	const query = `
+		SELECT COUNT(*), COALESCE(MIN(created_at), NOW())
+		FROM outbox
+		WHERE sent_at IS NULL AND failed_at IS NULL
+	`
+
+	var (
+		pending int64
+		oldest  time.Time
+	)
+	if err := r.db(ctx).QueryRow(ctx, query).Scan(&pending, &oldest); err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to get outbox stats: %w", err)
+	}
+
+	return pending, oldest, nil
+}
+
+// DeleteSent удаляет опубликованные события старше before
+func (r *OutboxRepository) DeleteSent(ctx context.Context, before time.Time) (int64, error) {
+	const query = `
+		DELETE FROM outbox
+		WHERE sent_at IS NOT NULL AND sent_at < $1
+	`
+
+	res, err := r.db(ctx).Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete sent outbox events: %w", err)
+	}
+
+	return res.RowsAffected(), nil
+}
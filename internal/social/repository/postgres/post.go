@@ -26,6 +26,10 @@ func NewPostRepository(pool *pgxpool.Pool) *PostRepository {
 	return &PostRepository{pool: pool}
 }
 
+func (r *PostRepository) db(ctx context.Context) querier {
+	return conn(ctx, r.pool)
+}
+
 func (r *PostRepository) Create(ctx context.Context, post *entity.Post) (string, error) {
 	const query = `
 		INSERT INTO posts (
@@ -37,7 +41,7 @@ func (r *PostRepository) Create(ctx context.Context, post *entity.Post) (string,
 	dao := dao.FromPostEntity(*post)
 
 	var postID string
-	err := r.pool.QueryRow(ctx, query,
+	err := r.db(ctx).QueryRow(ctx, query,
 		dao.AuthorID,
 		dao.Text,
 		dao.CreatedAt,
@@ -64,7 +68,7 @@ func (r *PostRepository) Update(ctx context.Context, post *entity.Post) error {
 
 	dao := dao.FromPostEntity(*post)
 
-	res, err := r.pool.Exec(ctx, query,
+	res, err := r.db(ctx).Exec(ctx, query,
 		dao.Text,
 		dao.UpdatedAt,
 		dao.ID,
@@ -87,7 +91,7 @@ func (r *PostRepository) Delete(ctx context.Context, postID string) error {
 		WHERE id = $1
 	`
 
-	res, err := r.pool.Exec(ctx, query, postID)
+	res, err := r.db(ctx).Exec(ctx, query, postID)
 	if err != nil {
 		return fmt.Errorf("failed to delete post: %w", err)
 	}
@@ -108,7 +112,7 @@ func (r *PostRepository) Get(ctx context.Context, postID string) (*entity.Post,
 	`
 
 	var dao dao.Post
-	err := r.pool.QueryRow(ctx, query, postID).Scan(
+	err := r.db(ctx).QueryRow(ctx, query, postID).Scan(
 		&dao.ID,
 		&dao.AuthorID,
 		&dao.Text,
@@ -138,7 +142,7 @@ func (r *PostRepository) GetFeed(ctx context.Context, userID, offset, limit int)
 		LIMIT $2 OFFSET $3
 	`
 
-	rows, err := r.pool.Query(ctx, query, userID, limit, offset)
+	rows, err := r.db(ctx).Query(ctx, query, userID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query feed: %w", err)
 	}
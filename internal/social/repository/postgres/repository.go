@@ -9,7 +9,6 @@ import (
 	"otus-highload-arh-homework/internal/social/repository"
 	"otus-highload-arh-homework/internal/social/repository/dao"
 
-	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/lib/pq"
@@ -26,6 +25,10 @@ func NewUserRepository(pool *pgxpool.Pool) repository.UserRepository {
 	return &UserRepository{pool: pool}
 }
 
+func (r *UserRepository) db(ctx context.Context) querier {
+	return conn(ctx, r.pool)
+}
+
 func (r *UserRepository) Create(ctx context.Context, user *entity.User, passwordHash string) error {
 	const query = `
         INSERT INTO users (
@@ -36,7 +39,7 @@ func (r *UserRepository) Create(ctx context.Context, user *entity.User, password
 
 	dao := dao.FromEntity(*user)
 
-	err := r.pool.QueryRow(ctx, query,
+	err := r.db(ctx).QueryRow(ctx, query,
 		dao.FirstName,
 		dao.LastName,
 		dao.Email,
@@ -79,7 +82,8 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*entity.User, err
             gender, 
             interests, 
             city, 
-            created_at
+            created_at,
+            last_seen_visibility
         FROM users 
         WHERE id = $1
     `
@@ -87,7 +91,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*entity.User, err
 	var user entity.User
 	var interests []sql.NullString
 
-	err := r.pool.QueryRow(ctx, query, id).Scan(
+	err := r.db(ctx).QueryRow(ctx, query, id).Scan(
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
@@ -97,6 +101,7 @@ func (r *UserRepository) GetByID(ctx context.Context, id int) (*entity.User, err
 		&interests,
 		&user.City,
 		&user.CreatedAt,
+		&user.LastSeenVisibility,
 	)
 
 	if err != nil {
@@ -136,7 +141,7 @@ func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entity.
 	var user entity.User
 	var interests []sql.NullString
 
-	err := r.pool.QueryRow(ctx, query, email).Scan(
+	err := r.db(ctx).QueryRow(ctx, query, email).Scan(
 		&user.ID,
 		&user.FirstName,
 		&user.LastName,
@@ -185,7 +190,7 @@ func (r *UserRepository) Search(ctx context.Context, firstName, lastName string)
         LIMIT 100
     `
 
-	rows, err := r.pool.Query(ctx, query, firstName, lastName)
+	rows, err := r.db(ctx).Query(ctx, query, firstName, lastName)
 	if err != nil {
 		return nil, fmt.Errorf("failed to search users: %w", err)
 	}
@@ -232,6 +237,51 @@ func (r *UserRepository) Search(ctx context.Context, firstName, lastName string)
 	return users, nil
 }
 
+// GetLastSeenVisibility возвращает настройки видимости присутствия пользователей.
+// Несуществующие пользователи в результат не попадают
+func (r *UserRepository) GetLastSeenVisibility(ctx context.Context, ids []int) (map[int]entity.Visibility, error) {
+	const query = `SELECT id, last_seen_visibility FROM users WHERE id = ANY($1)`
+
+	rows, err := r.db(ctx).Query(ctx, query, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get last seen visibility: %w", err)
+	}
+	defer rows.Close()
+
+	result := make(map[int]entity.Visibility, len(ids))
+	for rows.Next() {
+		var (
+			id         int
+			visibility entity.Visibility
+		)
+		if err := rows.Scan(&id, &visibility); err != nil {
+			return nil, fmt.Errorf("failed to scan last seen visibility: %w", err)
+		}
+		result[id] = visibility
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("rows error: %w", err)
+	}
+
+	return result, nil
+}
+
+// SetLastSeenVisibility меняет настройку видимости присутствия
+func (r *UserRepository) SetLastSeenVisibility(ctx context.Context, userID int, visibility entity.Visibility) error {
+	const query = `UPDATE users SET last_seen_visibility = $2 WHERE id = $1`
+
+	tag, err := r.db(ctx).Exec(ctx, query, userID, visibility)
+	if err != nil {
+		return fmt.Errorf("failed to set last seen visibility: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("user with id %d not found: %w", userID, repository.ErrUserNotFound)
+	}
+
+	return nil
+}
+
 // AddFriend добавляет друга для пользователя
 func (r *UserRepository) AddFriend(ctx context.Context, userID, friendID int) error {
 	const query = `
@@ -240,7 +290,7 @@ func (r *UserRepository) AddFriend(ctx context.Context, userID, friendID int) er
 		ON CONFLICT (user_id, friend_id) DO NOTHING
 	`
 
-	_, err := r.pool.Exec(ctx, query, userID, friendID)
+	_, err := r.db(ctx).Exec(ctx, query, userID, friendID)
 	if err != nil {
 		var pgErr *pgconn.PgError
 		if errors.As(err, &pgErr) {
@@ -265,7 +315,7 @@ func (r *UserRepository) RemoveFriend(ctx context.Context, userID, friendID int)
 		OR (user_id = $2 AND friend_id = $1)
 	`
 
-	result, err := r.pool.Exec(ctx, query, userID, friendID)
+	result, err := r.db(ctx).Exec(ctx, query, userID, friendID)
 	if err != nil {
 		return fmt.Errorf("failed to remove friend: %w", err)
 	}
@@ -288,7 +338,7 @@ func (r *UserRepository) CheckFriendship(ctx context.Context, userID, friendID i
 	`
 
 	var exists bool
-	err := r.pool.QueryRow(ctx, query, userID, friendID).Scan(&exists)
+	err := r.db(ctx).QueryRow(ctx, query, userID, friendID).Scan(&exists)
 	if err != nil {
 		return false, fmt.Errorf("failed to check friendship: %w", err)
 	}
@@ -302,7 +352,7 @@ func (r *UserRepository) GetFriendsIDs(ctx context.Context, userID int) ([]int,
         SELECT friend_id FROM friends WHERE user_id = $1
     `
 
-	rows, err := r.pool.Query(ctx, query, userID)
+	rows, err := r.db(ctx).Query(ctx, query, userID)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query friends IDs: %w", err)
 	}
@@ -323,107 +373,3 @@ func (r *UserRepository) GetFriendsIDs(ctx context.Context, userID int) ([]int,
 
 	return friendsIDs, nil
 }
-
-// GetOrCreateDialog получает или создает диалог между пользователями
-func (r *UserRepository) getOrCreateDialog(ctx context.Context, user1ID, user2ID int64) (int64, error) {
-	if user1ID > user2ID {
-		user1ID, user2ID = user2ID, user1ID
-	}
-
-	// Сначала попробуем найти существующий диалог
-	var dialogID int64
-	err := r.pool.QueryRow(ctx,
-		"SELECT dialog_id FROM dialogs WHERE user1_id = $1 AND user2_id = $2",
-		user1ID, user2ID).Scan(&dialogID)
-
-	if err == nil {
-		return dialogID, nil // Диалог найден
-	}
-	if !errors.Is(err, pgx.ErrNoRows) {
-		return 0, fmt.Errorf("failed to find dialog: %w", err)
-	}
-
-	// Диалога нет - создаем новый
-	err = r.pool.QueryRow(ctx,
-		"INSERT INTO dialogs (user1_id, user2_id) VALUES ($1, $2) RETURNING dialog_id",
-		user1ID, user2ID).Scan(&dialogID)
-
-	if err != nil {
-		return 0, fmt.Errorf("failed to create dialog: %w", err)
-	}
-
-	return dialogID, nil
-}
-
-// StoreDialogMessage сохраняет сообщение в диалоге
-func (r *UserRepository) StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content string) (int64, error) {
-
-	dialogID, err := r.getOrCreateDialog(ctx, senderID, recipientID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to get or create dialog: %w", err)
-	}
-
-	const query = `
-		INSERT INTO messages (dialog_id, sender_id, recipient_id, content)
-		VALUES ($1, $2, $3, $4)
-		RETURNING message_id
-	`
-
-	var messageID int64
-	err = r.pool.QueryRow(ctx, query, dialogID, senderID, recipientID, content).Scan(&messageID)
-	if err != nil {
-		return 0, fmt.Errorf("failed to store message: %w", err)
-	}
-
-	return messageID, nil
-}
-
-// GetDialogMessages возвращает все сообщения между двумя пользователями, отсортированные по времени
-func (r *UserRepository) GetDialogMessages(ctx context.Context, senderID, recipientID int64) ([]*entity.DialogMessage, error) {
-	const query = `
-        SELECT 
-            message_id::text,
-            sender_id::text,
-            recipient_id::text,
-            content as text,
-            created_at as sent_at,
-            read_at IS NOT NULL as is_read
-        FROM messages
-        WHERE (sender_id = $1 AND recipient_id = $2)
-           OR (sender_id = $2 AND recipient_id = $1)
-        ORDER BY created_at ASC
-    `
-
-	rows, err := r.pool.Query(ctx, query, senderID, recipientID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query dialog messages: %w", err)
-	}
-	defer rows.Close()
-
-	var messages []*entity.DialogMessage
-	for rows.Next() {
-		var msg entity.DialogMessage
-		err := rows.Scan(
-			&msg.ID,
-			&msg.SenderID,
-			&msg.ReceiverID,
-			&msg.Text,
-			&msg.SentAt,
-			&msg.IsRead,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("failed to scan message: %w", err)
-		}
-		messages = append(messages, &msg)
-	}
-
-	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("rows error: %w", err)
-	}
-
-	if len(messages) == 0 {
-		return nil, repository.ErrNoMessagesFound
-	}
-
-	return messages, nil
-}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// querier - общий набор методов пула и транзакции
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+type txKey struct{}
+
+// txState - открытая транзакция и функции, отложенные до её фиксации
+type txState struct {
+	tx          pgx.Tx
+	afterCommit []func(ctx context.Context)
+}
+
+// TxManager выполняет функцию в транзакции. Репозитории, получившие ctx
+// внутри WithinTx, выполняют запросы в этой же транзакции
+type TxManager struct {
+	pool *pgxpool.Pool
+}
+
+func NewTxManager(pool *pgxpool.Pool) *TxManager {
+	return &TxManager{pool: pool}
+}
+
+// WithinTx открывает транзакцию и фиксирует её, если fn не вернула ошибку.
+// Вложенный вызов переиспользует уже открытую транзакцию
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if _, ok := ctx.Value(txKey{}).(*txState); ok {
+		return fn(ctx)
+	}
+
+	tx, err := m.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(context.WithoutCancel(ctx)); rbErr != nil && !errors.Is(rbErr, pgx.ErrTxClosed) {
+				err = errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rbErr))
+			}
+		}
+	}()
+
+	state := &txState{tx: tx}
+	if err = fn(context.WithValue(ctx, txKey{}, state)); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	for _, hook := range state.afterCommit {
+		hook(ctx)
+	}
+
+	return nil
+}
+
+// afterCommit откладывает fn до фиксации транзакции из ctx; при откате fn
+// не вызывается. Без транзакции fn выполняется сразу
+func afterCommit(ctx context.Context, fn func(ctx context.Context)) {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		state.afterCommit = append(state.afterCommit, fn)
+		return
+	}
+
+	fn(ctx)
+}
+
+// conn возвращает транзакцию из ctx или пул
+func conn(ctx context.Context, pool *pgxpool.Pool) querier {
+	if state, ok := ctx.Value(txKey{}).(*txState); ok {
+		return state.tx
+	}
+
+	return pool
+}
@@ -0,0 +1,378 @@
+package redis
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// dialogLibrary - библиотека функций Redis: запись, чтение страниц и
+// отметка прочтения выполняются на сервере атомарно
+//
+//go:embed dialog.lua
+var dialogLibrary string
+
+// DialogRepository хранит диалоги в Redis. Изменения выполняются функциями
+// библиотеки dialog и атомарны по отдельности, но не участвуют в транзакциях
+// Postgres: при ошибке записи outbox отправка удаляет сохраненное сообщение.
+// Ключи строятся внутри функций, поэтому нужен Redis без кластера
+type DialogRepository struct {
+	client *redis.Client
+}
+
+// NewDialogRepository загружает библиотеку функций, заменяя прежнюю версию
+func NewDialogRepository(ctx context.Context, client *redis.Client) (*DialogRepository, error) {
+	if err := client.FunctionLoadReplace(ctx, dialogLibrary).Err(); err != nil {
+		return nil, fmt.Errorf("failed to load dialog functions: %w", err)
+	}
+
+	return &DialogRepository{client: client}, nil
+}
+
+// NonTransactional - записи Redis не откатываются транзакцией Postgres
+func (*DialogRepository) NonTransactional() {}
+
+func (r *DialogRepository) StoreDialogMessage(ctx context.Context, senderID, recipientID int64, content, clientMessageID string) (*entity.SentMessage, error) {
+	values, err := r.client.FCall(ctx, "dialog_store", nil,
+		senderID, recipientID, content, entity.MessagePreview(content), clientMessageID, time.Now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to store message: %w", err)
+	}
+
+	return &entity.SentMessage{
+		ID:        values[0],
+		SentAt:    time.UnixMilli(values[1]),
+		Duplicate: values[2] == 1,
+	}, nil
+}
+
+func (r *DialogRepository) GetDialogMessages(ctx context.Context, senderID, recipientID int64, page entity.MessagesQuery) ([]*entity.DialogMessage, error) {
+	reply, err := r.client.FCallRO(ctx, "dialog_page", nil,
+		senderID, recipientID, page.Before, page.After, page.Limit,
+	).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dialog messages: %w", err)
+	}
+
+	return parseMessages(reply)
+}
+
+func (r *DialogRepository) GetUserMessagesAfter(ctx context.Context, userID, after int64, limit int) ([]*entity.DialogMessage, error) {
+	reply, err := r.client.FCallRO(ctx, "dialog_user_page", nil, userID, after, limit).Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user messages: %w", err)
+	}
+
+	return parseMessages(reply)
+}
+
+func (r *DialogRepository) GetLastUserMessageID(ctx context.Context, userID int64) (int64, error) {
+	ids, err := r.client.ZRevRange(ctx, userMessagesKey(userID), 0, 0).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to get last user message: %w", err)
+	}
+	if len(ids) == 0 {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(ids[0], 10, 64)
+}
+
+func (r *DialogRepository) ListDialogs(ctx context.Context, userID, before int64, limit int) ([]*entity.DialogPreview, error) {
+	max := "+inf"
+	if before > 0 {
+		max = "(" + strconv.FormatInt(before, 10)
+	}
+
+	peers, err := r.client.ZRangeArgs(ctx, redis.ZRangeArgs{
+		Key:     inboxKey(userID),
+		Start:   max,
+		Stop:    "-inf",
+		ByScore: true,
+		Rev:     true,
+		Count:   int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dialogs: %w", err)
+	}
+
+	lastCmds := make([]*redis.SliceCmd, len(peers))
+	unreadCmds := make([]*redis.IntCmd, len(peers))
+	_, err = r.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, peer := range peers {
+			lastCmds[i] = pipe.HMGet(ctx, peerKey(userID, peer), "last_id", "last_sender", "last_text", "last_at")
+			unreadCmds[i] = pipe.ZCard(ctx, unreadIDsKey(userID, peer))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query dialogs: %w", err)
+	}
+
+	dialogs := make([]*entity.DialogPreview, 0, len(peers))
+	for i, peer := range peers {
+		var last struct {
+			ID     string `redis:"last_id"`
+			Sender string `redis:"last_sender"`
+			Text   string `redis:"last_text"`
+			At     int64  `redis:"last_at"`
+		}
+		if err := lastCmds[i].Scan(&last); err != nil {
+			return nil, fmt.Errorf("failed to scan dialog: %w", err)
+		}
+
+		peerID, err := strconv.ParseInt(peer, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid dialog peer %q: %w", peer, err)
+		}
+
+		receiverID := peer
+		if last.Sender == peer {
+			receiverID = strconv.FormatInt(userID, 10)
+		}
+
+		dialogs = append(dialogs, &entity.DialogPreview{
+			PeerID: peerID,
+			LastMessage: entity.DialogMessage{
+				ID:         last.ID,
+				SenderID:   last.Sender,
+				ReceiverID: receiverID,
+				Text:       last.Text,
+				SentAt:     time.UnixMilli(last.At),
+			},
+			UnreadCount: int(unreadCmds[i].Val()),
+		})
+	}
+
+	return dialogs, nil
+}
+
+func (r *DialogRepository) MarkDialogRead(ctx context.Context, userID, peerID, upToMessageID int64) (int, error) {
+	marked, err := r.client.FCall(ctx, "dialog_mark_read", nil, userID, peerID, upToMessageID).Int()
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark messages read: %w", err)
+	}
+
+	return marked, nil
+}
+
+func (r *DialogRepository) GetUnreadCount(ctx context.Context, userID, peerID int64) (int, int, error) {
+	counters, err := r.GetUnreadCounters(ctx, userID)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	var total int
+	for _, count := range counters {
+		total += count
+	}
+
+	return counters[peerID], total, nil
+}
+
+func (r *DialogRepository) GetUnreadCounters(ctx context.Context, userID int64) (map[int64]int, error) {
+	values, err := r.client.FCallRO(ctx, "dialog_unread", nil, userID).Int64Slice()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query unread counters: %w", err)
+	}
+
+	counters := make(map[int64]int, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		counters[values[i]] = int(values[i+1])
+	}
+
+	return counters, nil
+}
+
+func (r *DialogRepository) DeleteDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	// Уже удаленное сообщение не ошибка: откат отправки может повторяться
+	if err := r.client.FCall(ctx, "dialog_delete", nil, messageID, senderID, recipientID).Err(); err != nil {
+		return fmt.Errorf("failed to delete message: %w", err)
+	}
+
+	return nil
+}
+
+func (r *DialogRepository) ListInboxUsers(ctx context.Context, afterUserID int64, limit int) ([]int64, error) {
+	users, err := r.client.ZRangeArgs(ctx, redis.ZRangeArgs{
+		Key:     inboxUsersKey,
+		Start:   "(" + strconv.FormatInt(afterUserID, 10),
+		Stop:    "+inf",
+		ByScore: true,
+		Count:   int64(limit),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to query inbox users: %w", err)
+	}
+
+	ids := make([]int64, 0, len(users))
+	for _, user := range users {
+		id, err := strconv.ParseInt(user, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid inbox user %q: %w", user, err)
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
+
+// RecountUnread возвращает счетчики непрочитанных. Здесь они не хранятся,
+// а считаются по множествам непрочитанных сообщений, поэтому не расходятся
+func (r *DialogRepository) RecountUnread(ctx context.Context, userID int64) (map[int64]int, error) {
+	return r.GetUnreadCounters(ctx, userID)
+}
+
+func (r *DialogRepository) GetDialogMessage(ctx context.Context, messageID, userID, peerID int64) (*entity.DialogMessage, error) {
+	key := messageKey(messageID)
+	var stored storedMessage
+	if err := r.client.HMGet(ctx, key, storedMessageFields...).Scan(&stored); err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+	hidden, err := r.client.HExists(ctx, key, hiddenField(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get message: %w", err)
+	}
+
+	user, peer := strconv.FormatInt(userID, 10), strconv.FormatInt(peerID, 10)
+	inDialog := (stored.Sender == user && stored.Recipient == peer) || (stored.Sender == peer && stored.Recipient == user)
+	if !inDialog || hidden {
+		return nil, repository.ErrMessageNotFound
+	}
+
+	msg := &entity.DialogMessage{
+		ID:         strconv.FormatInt(messageID, 10),
+		SenderID:   stored.Sender,
+		ReceiverID: stored.Recipient,
+		Text:       stored.Text,
+		SentAt:     time.UnixMilli(stored.SentAt),
+		IsRead:     stored.Read,
+		Deleted:    stored.Deleted,
+	}
+	if stored.EditedAt > 0 {
+		editedAt := time.UnixMilli(stored.EditedAt)
+		msg.EditedAt = &editedAt
+	}
+
+	return msg, nil
+}
+
+func (r *DialogRepository) EditDialogMessage(ctx context.Context, messageID, senderID int64, text string, editedAt time.Time) error {
+	return r.changeMessage(ctx, "dialog_edit", "edit",
+		messageID, senderID, text, entity.MessagePreview(text), editedAt.UnixMilli())
+}
+
+func (r *DialogRepository) TombstoneDialogMessage(ctx context.Context, messageID, senderID, recipientID int64) error {
+	return r.changeMessage(ctx, "dialog_tombstone", "delete", messageID, senderID, recipientID)
+}
+
+func (r *DialogRepository) HideDialogMessage(ctx context.Context, messageID, userID, peerID int64) error {
+	return r.changeMessage(ctx, "dialog_hide", "hide", messageID, userID, peerID)
+}
+
+// changeMessage вызывает функцию изменения сообщения; 0 - сообщение не найдено
+func (r *DialogRepository) changeMessage(ctx context.Context, function, action string, args ...interface{}) error {
+	changed, err := r.client.FCall(ctx, function, nil, args...).Int()
+	if err != nil {
+		return fmt.Errorf("failed to %s message: %w", action, err)
+	}
+	if changed == 0 {
+		return repository.ErrMessageNotFound
+	}
+
+	return nil
+}
+
+// storedMessage - поля хэша dialog:msg:<id>
+type storedMessage struct {
+	Sender    string `redis:"sender"`
+	Recipient string `redis:"recipient"`
+	Text      string `redis:"text"`
+	SentAt    int64  `redis:"sent_at"`
+	Read      bool   `redis:"read"`
+	EditedAt  int64  `redis:"edited_at"`
+	Deleted   bool   `redis:"deleted"`
+}
+
+var storedMessageFields = []string{"sender", "recipient", "text", "sent_at", "read", "edited_at", "deleted"}
+
+// parseMessages разбирает ответ функций чтения: сообщения - массивы
+// {id, sender, recipient, text, sent_at, read, edited_at, deleted}
+func parseMessages(reply []interface{}) ([]*entity.DialogMessage, error) {
+	messages := make([]*entity.DialogMessage, 0, len(reply))
+	for _, item := range reply {
+		fields, ok := item.([]interface{})
+		if !ok || len(fields) != 8 {
+			return nil, errors.New("malformed dialog message reply")
+		}
+
+		values := make([]string, len(fields))
+		for i, field := range fields {
+			values[i], _ = field.(string)
+		}
+
+		sentAt, err := strconv.ParseInt(values[4], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid sent_at of message %s: %w", values[0], err)
+		}
+
+		msg := &entity.DialogMessage{
+			ID:         values[0],
+			SenderID:   values[1],
+			ReceiverID: values[2],
+			Text:       values[3],
+			SentAt:     time.UnixMilli(sentAt),
+			IsRead:     values[5] == "1",
+			Deleted:    values[7] == "1",
+		}
+		if values[6] != "" {
+			editedAt, err := strconv.ParseInt(values[6], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid edited_at of message %s: %w", values[0], err)
+			}
+			t := time.UnixMilli(editedAt)
+			msg.EditedAt = &t
+		}
+		messages = append(messages, msg)
+	}
+
+	return messages, nil
+}
+
+const (
+	dialogKeyPrefix = "dialog:"
+	inboxUsersKey   = dialogKeyPrefix + "inbox_users"
+)
+
+func messageKey(messageID int64) string {
+	return dialogKeyPrefix + "msg:" + strconv.FormatInt(messageID, 10)
+}
+
+func userMessagesKey(userID int64) string {
+	return dialogKeyPrefix + "user:" + strconv.FormatInt(userID, 10)
+}
+
+func inboxKey(userID int64) string {
+	return dialogKeyPrefix + "inbox:" + strconv.FormatInt(userID, 10)
+}
+
+func peerKey(userID int64, peer string) string {
+	return dialogKeyPrefix + "peer:" + strconv.FormatInt(userID, 10) + ":" + peer
+}
+
+func unreadIDsKey(userID int64, peer string) string {
+	return dialogKeyPrefix + "unreadids:" + strconv.FormatInt(userID, 10) + ":" + peer
+}
+
+func hiddenField(userID int64) string {
+	return "hidden:" + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,288 @@
+#!lua name=dialog
+
+-- Хранилище диалогов. Ключи строятся внутри функций, поэтому библиотека
+-- рассчитана на Redis без кластера.
+--
+-- dialog:seq                    счетчик message_id
+-- dialog:msg:<id>               HASH сообщения; hidden:<user> - скрыто у user
+-- dialog:msgs:<min>:<max>       ZSET id сообщений пары пользователей
+-- dialog:user:<user>            ZSET id всех сообщений пользователя
+-- dialog:client:<sender>        HASH client_message_id -> id
+-- dialog:inbox:<user>           ZSET собеседников по id последнего сообщения
+-- dialog:peer:<user>:<peer>     HASH последнего сообщения диалога в списке user
+-- dialog:unreadids:<user>:<peer> ZSET непрочитанных user сообщений от peer
+-- dialog:inbox_users            ZSET владельцев списков диалогов
+
+local P = 'dialog:'
+
+local MESSAGE_FIELDS = {'sender', 'recipient', 'text', 'sent_at', 'read', 'edited_at', 'deleted'}
+
+local function pair_key(a, b)
+  a, b = tonumber(a), tonumber(b)
+  if a > b then
+    a, b = b, a
+  end
+  return P .. 'msgs:' .. a .. ':' .. b
+end
+
+local function peer_key(user, peer)
+  return P .. 'peer:' .. user .. ':' .. peer
+end
+
+local function unread_key(user, peer)
+  return P .. 'unreadids:' .. user .. ':' .. peer
+end
+
+-- read_message возвращает поля сообщения для user или nil, если
+-- сообщения нет или user его скрыл
+local function read_message(id, user)
+  local key = P .. 'msg:' .. id
+  local values = redis.call('HMGET', key, 'hidden:' .. user, unpack(MESSAGE_FIELDS))
+  if values[1] or not values[2] then
+    return nil
+  end
+  return {tostring(id), values[2], values[3], values[4], values[5], values[6], values[7] or '', values[8]}
+end
+
+local function set_last(user, peer, id, sender, preview, at)
+  redis.call('HSET', peer_key(user, peer), 'last_id', id, 'last_sender', sender, 'last_text', preview, 'last_at', at)
+  redis.call('ZADD', P .. 'inbox:' .. user, id, peer)
+  redis.call('ZADD', P .. 'inbox_users', user, user)
+end
+
+-- set_preview меняет превью в списках диалогов, где сообщение последнее
+local function set_preview(id, sender, recipient, preview)
+  for _, pair in ipairs({{sender, recipient}, {recipient, sender}}) do
+    local key = peer_key(pair[1], pair[2])
+    if redis.call('HGET', key, 'last_id') == tostring(id) then
+      redis.call('HSET', key, 'last_text', preview)
+    end
+  end
+end
+
+-- store(sender, recipient, text, preview, client_message_id, now_ms)
+-- -> {id, sent_at_ms, duplicate}
+local function store(_, args)
+  local sender, recipient, text, preview, client_id, now = args[1], args[2], args[3], args[4], args[5], args[6]
+
+  local client_key = P .. 'client:' .. sender
+  if client_id ~= '' then
+    local existing = redis.call('HGET', client_key, client_id)
+    if existing then
+      return {tonumber(existing), tonumber(redis.call('HGET', P .. 'msg:' .. existing, 'sent_at')), 1}
+    end
+  end
+
+  local id = redis.call('INCR', P .. 'seq')
+  redis.call('HSET', P .. 'msg:' .. id,
+    'sender', sender, 'recipient', recipient, 'text', text, 'preview', preview,
+    'sent_at', now, 'read', 0, 'deleted', 0)
+  if client_id ~= '' then
+    redis.call('HSET', P .. 'msg:' .. id, 'client_id', client_id)
+    redis.call('HSET', client_key, client_id, id)
+  end
+
+  redis.call('ZADD', pair_key(sender, recipient), id, id)
+  redis.call('ZADD', P .. 'user:' .. sender, id, id)
+  redis.call('ZADD', P .. 'user:' .. recipient, id, id)
+  redis.call('ZADD', unread_key(recipient, sender), id, id)
+  set_last(sender, recipient, id, sender, preview, now)
+  set_last(recipient, sender, id, sender, preview, now)
+
+  return {id, tonumber(now), 0}
+end
+
+-- page(user, peer, before, after, limit) - страница истории диалога без
+-- скрытых у user сообщений: с after по возрастанию, иначе по убыванию
+local function page(_, args)
+  local user, peer = args[1], args[2]
+  local before, after, limit = tonumber(args[3]), tonumber(args[4]), tonumber(args[5])
+  local key = pair_key(user, peer)
+
+  local result = {}
+  local offset = 0
+  while #result < limit do
+    local ids
+    if after > 0 then
+      ids = redis.call('ZRANGE', key, '(' .. after, '+inf', 'BYSCORE', 'LIMIT', offset, limit)
+    else
+      local max = '+inf'
+      if before > 0 then
+        max = '(' .. before
+      end
+      ids = redis.call('ZRANGE', key, max, '-inf', 'BYSCORE', 'REV', 'LIMIT', offset, limit)
+    end
+
+    for _, id in ipairs(ids) do
+      local msg = read_message(id, user)
+      if msg then
+        result[#result + 1] = msg
+        if #result == limit then
+          break
+        end
+      end
+    end
+
+    if #ids < limit then
+      break
+    end
+    offset = offset + #ids
+  end
+
+  return result
+end
+
+-- user_page(user, after, limit) - сообщения пользователя во всех диалогах
+-- после after по возрастанию
+local function user_page(_, args)
+  local user, after, limit = args[1], tonumber(args[2]), tonumber(args[3])
+  local key = P .. 'user:' .. user
+
+  local result = {}
+  local offset = 0
+  while #result < limit do
+    local ids = redis.call('ZRANGE', key, '(' .. after, '+inf', 'BYSCORE', 'LIMIT', offset, limit)
+    for _, id in ipairs(ids) do
+      local msg = read_message(id, user)
+      if msg then
+        result[#result + 1] = msg
+        if #result == limit then
+          break
+        end
+      end
+    end
+
+    if #ids < limit then
+      break
+    end
+    offset = offset + #ids
+  end
+
+  return result
+end
+
+-- mark_read(user, peer, up_to) -> число отмеченных сообщений
+local function mark_read(_, args)
+  local key = unread_key(args[1], args[2])
+  local ids = redis.call('ZRANGE', key, '-inf', args[3], 'BYSCORE')
+  for _, id in ipairs(ids) do
+    redis.call('HSET', P .. 'msg:' .. id, 'read', 1)
+  end
+  if #ids > 0 then
+    redis.call('ZREMRANGEBYSCORE', key, '-inf', args[3])
+  end
+
+  return #ids
+end
+
+-- unread(user) -> {peer, count, ...} по ненулевым счетчикам
+local function unread(_, args)
+  local user = args[1]
+  local result = {}
+  for _, peer in ipairs(redis.call('ZRANGE', P .. 'inbox:' .. user, 0, -1)) do
+    local count = redis.call('ZCARD', unread_key(user, peer))
+    if count > 0 then
+      result[#result + 1] = peer
+      result[#result + 1] = count
+    end
+  end
+
+  return result
+end
+
+-- delete(id, sender, recipient) удаляет сообщение бесследно (откат отправки)
+-- и возвращает списки диалогов к последнему оставшемуся сообщению
+local function delete(_, args)
+  local id, sender, recipient = args[1], args[2], args[3]
+  local key = P .. 'msg:' .. id
+  if redis.call('HGET', key, 'sender') ~= sender then
+    return 0
+  end
+
+  local client_id = redis.call('HGET', key, 'client_id')
+  if client_id then
+    redis.call('HDEL', P .. 'client:' .. sender, client_id)
+  end
+  redis.call('DEL', key)
+
+  local pair = pair_key(sender, recipient)
+  redis.call('ZREM', pair, id)
+  redis.call('ZREM', P .. 'user:' .. sender, id)
+  redis.call('ZREM', P .. 'user:' .. recipient, id)
+  redis.call('ZREM', unread_key(recipient, sender), id)
+
+  local last = redis.call('ZRANGE', pair, '+inf', '-inf', 'BYSCORE', 'REV', 'LIMIT', 0, 1)
+  for _, users in ipairs({{sender, recipient}, {recipient, sender}}) do
+    local user, peer = users[1], users[2]
+    if #last == 0 then
+      redis.call('DEL', peer_key(user, peer))
+      redis.call('ZREM', P .. 'inbox:' .. user, peer)
+      if redis.call('ZCARD', P .. 'inbox:' .. user) == 0 then
+        redis.call('ZREM', P .. 'inbox_users', user)
+      end
+    elseif redis.call('HGET', peer_key(user, peer), 'last_id') == id then
+      local msg = redis.call('HMGET', P .. 'msg:' .. last[1], 'sender', 'preview', 'sent_at')
+      set_last(user, peer, last[1], msg[1], msg[2], msg[3])
+    end
+  end
+
+  return 1
+end
+
+-- edit(id, sender, text, preview, edited_at_ms) -> 0, если сообщения нет
+-- или оно удалено для всех
+local function edit(_, args)
+  local id, sender = args[1], args[2]
+  local key = P .. 'msg:' .. id
+  local msg = redis.call('HMGET', key, 'sender', 'recipient', 'deleted')
+  if msg[1] ~= sender or msg[3] ~= '0' then
+    return 0
+  end
+
+  redis.call('HSET', key, 'text', args[3], 'preview', args[4], 'edited_at', args[5])
+  set_preview(id, sender, msg[2], args[4])
+
+  return 1
+end
+
+-- tombstone(id, sender, recipient) стирает текст и снимает сообщение
+-- из непрочитанных получателя
+local function tombstone(_, args)
+  local id, sender, recipient = args[1], args[2], args[3]
+  local key = P .. 'msg:' .. id
+  local msg = redis.call('HMGET', key, 'sender', 'deleted')
+  if msg[1] ~= sender or msg[2] ~= '0' then
+    return 0
+  end
+
+  redis.call('HSET', key, 'text', '', 'preview', '', 'deleted', 1, 'read', 1)
+  redis.call('ZREM', unread_key(recipient, sender), id)
+  set_preview(id, sender, recipient, '')
+
+  return 1
+end
+
+-- hide(id, user, peer) скрывает сообщение диалога только у user
+local function hide(_, args)
+  local id, user, peer = args[1], args[2], args[3]
+  local key = P .. 'msg:' .. id
+  local msg = redis.call('HMGET', key, 'sender', 'recipient', 'hidden:' .. user)
+  local in_dialog = (msg[1] == user and msg[2] == peer) or (msg[1] == peer and msg[2] == user)
+  if not in_dialog or msg[3] then
+    return 0
+  end
+
+  redis.call('HSET', key, 'hidden:' .. user, 1)
+
+  return 1
+end
+
+redis.register_function('dialog_store', store)
+redis.register_function{function_name = 'dialog_page', callback = page, flags = {'no-writes'}}
+redis.register_function{function_name = 'dialog_user_page', callback = user_page, flags = {'no-writes'}}
+redis.register_function('dialog_mark_read', mark_read)
+redis.register_function{function_name = 'dialog_unread', callback = unread, flags = {'no-writes'}}
+redis.register_function('dialog_delete', delete)
+redis.register_function('dialog_edit', edit)
+redis.register_function('dialog_tombstone', tombstone)
+redis.register_function('dialog_hide', hide)
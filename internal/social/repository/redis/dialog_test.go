@@ -0,0 +1,191 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDialogRepository_StoreAndPage(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewDialogRepository(ctx, setupRedis(t, ctx))
+	require.NoError(t, err)
+
+	first, err := repo.StoreDialogMessage(ctx, 1, 2, "hello", "c-1")
+	require.NoError(t, err)
+	assert.False(t, first.Duplicate)
+
+	// Повтор с тем же client_message_id возвращает исходное сообщение
+	again, err := repo.StoreDialogMessage(ctx, 1, 2, "hello", "c-1")
+	require.NoError(t, err)
+	assert.True(t, again.Duplicate)
+	assert.Equal(t, first.ID, again.ID)
+	assert.Equal(t, first.SentAt.UnixMilli(), again.SentAt.UnixMilli())
+
+	second, err := repo.StoreDialogMessage(ctx, 2, 1, "hi", "")
+	require.NoError(t, err)
+	_, err = repo.StoreDialogMessage(ctx, 1, 3, "other dialog", "")
+	require.NoError(t, err)
+
+	latest, err := repo.GetDialogMessages(ctx, 2, 1, entity.MessagesQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, "hi", latest[0].Text)
+	assert.Equal(t, "hello", latest[1].Text)
+	assert.Equal(t, "1", latest[1].SenderID)
+	assert.Equal(t, "2", latest[1].ReceiverID)
+
+	after, err := repo.GetDialogMessages(ctx, 1, 2, entity.MessagesQuery{After: first.ID, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, after, 1)
+	assert.Equal(t, "hi", after[0].Text)
+
+	stream, err := repo.GetUserMessagesAfter(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Len(t, stream, 3)
+
+	last, err := repo.GetLastUserMessageID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, second.ID, last)
+
+	dialogs, err := repo.ListDialogs(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, dialogs, 2)
+	assert.Equal(t, int64(3), dialogs[0].PeerID)
+	assert.Equal(t, int64(2), dialogs[1].PeerID)
+	assert.Equal(t, "hi", dialogs[1].LastMessage.Text)
+	assert.Equal(t, "1", dialogs[1].LastMessage.ReceiverID)
+	assert.Equal(t, 1, dialogs[1].UnreadCount)
+
+	users, err := repo.ListInboxUsers(ctx, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{2, 3}, users)
+}
+
+func TestDialogRepository_MarkRead(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewDialogRepository(ctx, setupRedis(t, ctx))
+	require.NoError(t, err)
+
+	var ids []int64
+	for _, text := range []string{"a", "b", "c"} {
+		sent, err := repo.StoreDialogMessage(ctx, 1, 2, text, "")
+		require.NoError(t, err)
+		ids = append(ids, sent.ID)
+	}
+	_, err = repo.StoreDialogMessage(ctx, 3, 2, "d", "")
+	require.NoError(t, err)
+
+	unread, total, err := repo.GetUnreadCount(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, unread)
+	assert.Equal(t, 4, total)
+
+	marked, err := repo.MarkDialogRead(ctx, 2, 1, ids[1])
+	require.NoError(t, err)
+	assert.Equal(t, 2, marked)
+
+	// Повторная отметка ничего не меняет
+	marked, err = repo.MarkDialogRead(ctx, 2, 1, ids[1])
+	require.NoError(t, err)
+	assert.Zero(t, marked)
+
+	counters, err := repo.RecountUnread(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 1, 3: 1}, counters)
+
+	msg, err := repo.GetDialogMessage(ctx, ids[0], 2, 1)
+	require.NoError(t, err)
+	assert.True(t, msg.IsRead)
+}
+
+func TestDialogRepository_EditAndDelete(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewDialogRepository(ctx, setupRedis(t, ctx))
+	require.NoError(t, err)
+
+	first, err := repo.StoreDialogMessage(ctx, 1, 2, "first", "")
+	require.NoError(t, err)
+	second, err := repo.StoreDialogMessage(ctx, 1, 2, "second", "c-2")
+	require.NoError(t, err)
+
+	editedAt := time.Now()
+	require.NoError(t, repo.EditDialogMessage(ctx, second.ID, 1, "edited", editedAt))
+	assert.ErrorIs(t, repo.EditDialogMessage(ctx, second.ID, 2, "foreign", editedAt), repository.ErrMessageNotFound)
+
+	msg, err := repo.GetDialogMessage(ctx, second.ID, 1, 2)
+	require.NoError(t, err)
+	assert.Equal(t, "edited", msg.Text)
+	require.NotNil(t, msg.EditedAt)
+	assert.Equal(t, editedAt.UnixMilli(), msg.EditedAt.UnixMilli())
+
+	dialogs, err := repo.ListDialogs(ctx, 2, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, dialogs, 1)
+	assert.Equal(t, "edited", dialogs[0].LastMessage.Text)
+
+	// Удаление для всех стирает текст и снимает непрочитанное
+	require.NoError(t, repo.TombstoneDialogMessage(ctx, first.ID, 1, 2))
+	assert.ErrorIs(t, repo.TombstoneDialogMessage(ctx, first.ID, 1, 2), repository.ErrMessageNotFound)
+	assert.ErrorIs(t, repo.EditDialogMessage(ctx, first.ID, 1, "late", editedAt), repository.ErrMessageNotFound)
+
+	msg, err = repo.GetDialogMessage(ctx, first.ID, 2, 1)
+	require.NoError(t, err)
+	assert.True(t, msg.Deleted)
+	assert.Empty(t, msg.Text)
+
+	counters, err := repo.GetUnreadCounters(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{1: 1}, counters)
+
+	// Скрытое сообщение пропадает только у скрывшего
+	require.NoError(t, repo.HideDialogMessage(ctx, first.ID, 2, 1))
+	assert.ErrorIs(t, repo.HideDialogMessage(ctx, first.ID, 2, 1), repository.ErrMessageNotFound)
+	_, err = repo.GetDialogMessage(ctx, first.ID, 2, 1)
+	assert.ErrorIs(t, err, repository.ErrMessageNotFound)
+	_, err = repo.GetDialogMessage(ctx, first.ID, 1, 2)
+	require.NoError(t, err)
+
+	page, err := repo.GetDialogMessages(ctx, 2, 1, entity.MessagesQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, second.ID, mustID(t, page[0].ID))
+
+	// Откат отправки возвращает список диалогов к предыдущему сообщению
+	// и освобождает client_message_id
+	require.NoError(t, repo.DeleteDialogMessage(ctx, second.ID, 1, 2))
+	require.NoError(t, repo.DeleteDialogMessage(ctx, second.ID, 1, 2))
+
+	dialogs, err = repo.ListDialogs(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	require.Len(t, dialogs, 1)
+	assert.Equal(t, first.ID, mustID(t, dialogs[0].LastMessage.ID))
+
+	resent, err := repo.StoreDialogMessage(ctx, 1, 2, "second", "c-2")
+	require.NoError(t, err)
+	assert.False(t, resent.Duplicate)
+
+	// После удаления всех сообщений диалог пропадает из списка
+	require.NoError(t, repo.DeleteDialogMessage(ctx, resent.ID, 1, 2))
+	require.NoError(t, repo.DeleteDialogMessage(ctx, first.ID, 1, 2))
+	dialogs, err = repo.ListDialogs(ctx, 1, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, dialogs)
+	users, err := repo.ListInboxUsers(ctx, 0, 10)
+	require.NoError(t, err)
+	assert.Empty(t, users)
+}
+
+func mustID(t *testing.T, id string) int64 {
+	t.Helper()
+	value, err := strconv.ParseInt(id, 10, 64)
+	require.NoError(t, err)
+	return value
+}
@@ -0,0 +1,123 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const notifyChannelPrefix = "dialog:notify:"
+
+// MessageNotifier передает сигналы о новых сообщениях через Redis pub/sub,
+// канал dialog:notify:<user_id>. Процесс держит одну подписку на все каналы
+// и раздает сигналы локальным подписчикам. Сигналы, потерянные при разрыве
+// соединения с Redis, подписчики восполняют периодическим перечитыванием
+type MessageNotifier struct {
+	client *redis.Client
+
+	mu   sync.Mutex
+	subs map[int64]map[chan struct{}]struct{}
+}
+
+func NewMessageNotifier(client *redis.Client) *MessageNotifier {
+	return &MessageNotifier{
+		client: client,
+		subs:   make(map[int64]map[chan struct{}]struct{}),
+	}
+}
+
+// Notify публикует сигнал о новом сообщении для каждого пользователя
+func (n *MessageNotifier) Notify(ctx context.Context, userIDs ...int64) error {
+	pipe := n.client.Pipeline()
+	for _, userID := range userIDs {
+		pipe.Publish(ctx, notifyChannel(userID), "")
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to notify users %v: %w", userIDs, err)
+	}
+
+	return nil
+}
+
+// Start подписывается на каналы уведомлений. Сигналы раздаются
+// подписчикам до отмены ctx
+func (n *MessageNotifier) Start(ctx context.Context) error {
+	sub := n.client.PSubscribe(ctx, notifyChannelPrefix+"*")
+	// Дожидаемся подтверждения подписки, чтобы не потерять первые сигналы
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return fmt.Errorf("failed to subscribe to message notifications: %w", err)
+	}
+
+	go n.consume(ctx, sub)
+
+	return nil
+}
+
+// Subscribe регистрирует подписчика userID. Канал буферизован на один
+// сигнал: пока подписчик занят, новые сигналы сливаются с ожидающим
+func (n *MessageNotifier) Subscribe(userID int64) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+
+	n.mu.Lock()
+	if n.subs[userID] == nil {
+		n.subs[userID] = make(map[chan struct{}]struct{})
+	}
+	n.subs[userID][ch] = struct{}{}
+	n.mu.Unlock()
+
+	return ch, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+
+		delete(n.subs[userID], ch)
+		if len(n.subs[userID]) == 0 {
+			delete(n.subs, userID)
+		}
+	}
+}
+
+func (n *MessageNotifier) consume(ctx context.Context, sub *redis.PubSub) {
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			userID, err := strconv.ParseInt(strings.TrimPrefix(msg.Channel, notifyChannelPrefix), 10, 64)
+			if err != nil {
+				log.Printf("Invalid message notification channel %q", msg.Channel)
+				continue
+			}
+
+			n.signal(userID)
+		}
+	}
+}
+
+func (n *MessageNotifier) signal(userID int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	for ch := range n.subs[userID] {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+func notifyChannel(userID int64) string {
+	return notifyChannelPrefix + strconv.FormatInt(userID, 10)
+}
@@ -0,0 +1,37 @@
+package redis
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessageNotifier(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	notifier := NewMessageNotifier(setupRedis(t, ctx))
+	require.NoError(t, notifier.Start(ctx))
+
+	updates, unsubscribe := notifier.Subscribe(1)
+	other, unsubscribeOther := notifier.Subscribe(2)
+	defer unsubscribeOther()
+
+	require.NoError(t, notifier.Notify(ctx, 1))
+
+	select {
+	case <-updates:
+	case <-time.After(5 * time.Second):
+		t.Fatal("notification was not delivered")
+	}
+	assert.Empty(t, other)
+
+	unsubscribe()
+	notifier.mu.Lock()
+	_, subscribed := notifier.subs[1]
+	notifier.mu.Unlock()
+	assert.False(t, subscribed)
+}
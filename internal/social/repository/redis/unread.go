@@ -0,0 +1,155 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	unreadKeyPrefix = "dialog:unread:"
+	// totalField - поле хэша с общим числом непрочитанных
+	totalField = "total"
+
+	defaultUnreadTTL = 24 * time.Hour
+)
+
+// incrementScript меняет счетчик диалога и общий, только если счетчики
+// пользователя загружены: иначе частичный хэш выдавал бы себя за полный
+var incrementScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+local unread = redis.call('HINCRBY', KEYS[1], ARGV[1], ARGV[2])
+if unread <= 0 then
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+local total = redis.call('HINCRBY', KEYS[1], 'total', ARGV[2])
+if total < 0 then
+	redis.call('HSET', KEYS[1], 'total', 0)
+end
+redis.call('EXPIRE', KEYS[1], ARGV[3])
+return 1
+`)
+
+// setDialogScript выставляет счетчик диалога и общий, если счетчики загружены
+var setDialogScript = redis.NewScript(`
+if redis.call('EXISTS', KEYS[1]) == 0 then
+	return 0
+end
+if tonumber(ARGV[2]) > 0 then
+	redis.call('HSET', KEYS[1], ARGV[1], ARGV[2])
+else
+	redis.call('HDEL', KEYS[1], ARGV[1])
+end
+redis.call('HSET', KEYS[1], 'total', ARGV[3])
+redis.call('EXPIRE', KEYS[1], ARGV[4])
+return 1
+`)
+
+// UnreadCounters хранит счетчики непрочитанных пользователя в хэше
+// dialog:unread:<user_id>: поле на каждого собеседника и поле total
+type UnreadCounters struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+// NewUnreadCounters создает хранилище счетчиков. Без обращений счетчики
+// истекают через ttl и при следующем чтении пересобираются из Postgres
+func NewUnreadCounters(client *redis.Client, ttl time.Duration) repository.UnreadCounterRepository {
+	if ttl <= 0 {
+		ttl = defaultUnreadTTL
+	}
+
+	return &UnreadCounters{client: client, ttl: ttl}
+}
+
+func (c *UnreadCounters) Get(ctx context.Context, userID int64) (map[int64]int, int, bool, error) {
+	values, err := c.client.HGetAll(ctx, unreadKey(userID)).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, 0, false, fmt.Errorf("failed to get unread counters of user %d: %w", userID, err)
+	}
+	if len(values) == 0 {
+		return nil, 0, false, nil
+	}
+
+	var total int
+	counters := make(map[int64]int, len(values)-1)
+	for field, value := range values {
+		count, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("invalid unread counter %s of user %d: %w", field, userID, err)
+		}
+		if field == totalField {
+			total = count
+			continue
+		}
+
+		peerID, err := strconv.ParseInt(field, 10, 64)
+		if err != nil {
+			return nil, 0, false, fmt.Errorf("invalid unread counter field %s of user %d: %w", field, userID, err)
+		}
+		counters[peerID] = count
+	}
+
+	return counters, total, true, nil
+}
+
+func (c *UnreadCounters) Set(ctx context.Context, userID int64, counters map[int64]int) error {
+	key := unreadKey(userID)
+
+	var total int
+	values := make(map[string]interface{}, len(counters)+1)
+	for peerID, count := range counters {
+		if count <= 0 {
+			continue
+		}
+		values[strconv.FormatInt(peerID, 10)] = count
+		total += count
+	}
+	values[totalField] = total
+
+	_, err := c.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, key)
+		pipe.HSet(ctx, key, values)
+		pipe.Expire(ctx, key, c.ttl)
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set unread counters of user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (c *UnreadCounters) Increment(ctx context.Context, userID, peerID int64, delta int) error {
+	err := incrementScript.Run(ctx, c.client, []string{unreadKey(userID)},
+		strconv.FormatInt(peerID, 10), delta, int(c.ttl.Seconds()),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to increment unread counter of user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+func (c *UnreadCounters) SetDialog(ctx context.Context, userID, peerID int64, unread, total int) error {
+	err := setDialogScript.Run(ctx, c.client, []string{unreadKey(userID)},
+		strconv.FormatInt(peerID, 10), unread, total, int(c.ttl.Seconds()),
+	).Err()
+	if err != nil {
+		return fmt.Errorf("failed to set unread counter of user %d: %w", userID, err)
+	}
+
+	return nil
+}
+
+func unreadKey(userID int64) string {
+	return unreadKeyPrefix + strconv.FormatInt(userID, 10)
+}
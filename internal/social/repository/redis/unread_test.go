@@ -0,0 +1,70 @@
+package redis
+
+import (
+	"context"
+	"testing"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupRedis(t *testing.T, ctx context.Context) *redis.Client {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: endpoint})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+func TestUnreadCounters(t *testing.T) {
+	ctx := context.Background()
+	counters := NewUnreadCounters(setupRedis(t, ctx), 0)
+
+	// Пока счетчики не загружены, инкремент их не создает
+	require.NoError(t, counters.Increment(ctx, 1, 2, 1))
+	_, _, found, err := counters.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	// Пустые счетчики тоже считаются загруженными
+	require.NoError(t, counters.Set(ctx, 1, map[int64]int{}))
+	dialogs, total, found, err := counters.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Empty(t, dialogs)
+	assert.Zero(t, total)
+
+	require.NoError(t, counters.Increment(ctx, 1, 2, 1))
+	require.NoError(t, counters.Increment(ctx, 1, 2, 1))
+	require.NoError(t, counters.Increment(ctx, 1, 3, 1))
+
+	dialogs, total, _, err = counters.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{2: 2, 3: 1}, dialogs)
+	assert.Equal(t, 3, total)
+
+	// Прочитанный диалог пропадает из счетчиков
+	require.NoError(t, counters.SetDialog(ctx, 1, 2, 0, 1))
+	dialogs, total, _, err = counters.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, map[int64]int{3: 1}, dialogs)
+	assert.Equal(t, 1, total)
+}
@@ -3,40 +3,62 @@ package cachewarmer
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"time"
-
-	"github.com/redis/go-redis/v9"
 )
 
-const hasEmailPref = "has_email_"
+const (
+	hasEmailPref    = "has_email_"
+	warmPendingPref = "warm_pending_"
+
+	defaultWarmDedupWindow = 30 * time.Second
+)
 
 type MessageQueue interface {
 	Push(context.Context, WarmTask) error
 }
 
 type CacheWarmer struct {
-	queue  MessageQueue
-	client *redis.Client
-	prefix string
+	queue       MessageQueue
+	storage     Storage
+	prefix      string
+	dedupWindow time.Duration
 }
 
-func New(queue MessageQueue, client *redis.Client) *CacheWarmer {
+// New создает CacheWarmer. Повторные задачи прогрева для пользователя,
+// чья задача ещё ждёт в очереди, отбрасываются в течение dedupWindow
+func New(queue MessageQueue, storage Storage, dedupWindow time.Duration) *CacheWarmer {
+	if dedupWindow <= 0 {
+		dedupWindow = defaultWarmDedupWindow
+	}
+
 	return &CacheWarmer{
-		queue:  queue,
-		client: client,
-		prefix: "warm",
+		queue:       queue,
+		storage:     storage,
+		prefix:      "warm",
+		dedupWindow: dedupWindow,
 	}
 }
 
 func (w *CacheWarmer) WarmForNewPost(ctx context.Context, authorID int) error {
+	// Задача для автора уже стоит в очереди: воркер прочитает актуальные данные
+	pending, err := w.storage.SetIfAbsent(ctx, w.pendingKey(authorID), []byte("1"), w.dedupWindow)
+	if err != nil {
+		return fmt.Errorf("failed to mark warm task pending: %w", err)
+	}
+	if !pending {
+		log.Printf("Warm task for author %d already pending, skipped\n", authorID)
+		return nil
+	}
+
 	task := WarmTask{
 		UserID: authorID,
 	}
 
 	if err := w.queue.Push(ctx, task); err != nil {
+		_ = w.storage.Delete(ctx, w.pendingKey(authorID))
 		return fmt.Errorf("failed to push new post: %w", err)
 	}
 
@@ -45,6 +67,16 @@ func (w *CacheWarmer) WarmForNewPost(ctx context.Context, authorID int) error {
 	return nil
 }
 
+// ReleaseWarm снимает отметку об ожидающей задаче. Вызывается воркером перед
+// обработкой, чтобы изменения, сделанные во время прогрева, поставили новую задачу
+func (w *CacheWarmer) ReleaseWarm(ctx context.Context, authorID int) error {
+	return w.storage.Delete(ctx, w.pendingKey(authorID))
+}
+
+func (w *CacheWarmer) pendingKey(authorID int) string {
+	return warmPendingPref + strconv.Itoa(authorID)
+}
+
 // Set сохраняет данные в кэш с автоматической JSON сериализацией
 func (w *CacheWarmer) Set(ctx context.Context, key string, value any, ttl time.Duration) error {
 	fullKey := w.prefix + key
@@ -54,26 +86,19 @@ func (w *CacheWarmer) Set(ctx context.Context, key string, value any, ttl time.D
 		return fmt.Errorf("%w: failed to marshal value: %v", ErrInvalidValue, err)
 	}
 
-	if err := w.client.Set(ctx, fullKey, jsonData, ttl).Err(); err != nil {
-		return fmt.Errorf("redis set operation failed: %w", err)
-	}
-
-	return nil
+	return w.storage.Set(ctx, fullKey, jsonData, ttl)
 }
 
 // Get получает данные из кэша с автоматической JSON десериализацией
 func (w *CacheWarmer) Get(ctx context.Context, key string, dest any) error {
 	fullKey := w.prefix + key
 
-	data, err := w.client.Get(ctx, fullKey).Result()
+	data, err := w.storage.Get(ctx, fullKey)
 	if err != nil {
-		if errors.Is(err, redis.Nil) {
-			return ErrCacheMiss
-		}
-		return fmt.Errorf("redis get operation failed: %w", err)
+		return err
 	}
 
-	if err := json.Unmarshal([]byte(data), dest); err != nil {
+	if err := json.Unmarshal(data, dest); err != nil {
 		return fmt.Errorf("%w: failed to unmarshal cached data: %v", ErrInvalidValue, err)
 	}
 
@@ -82,39 +107,17 @@ func (w *CacheWarmer) Get(ctx context.Context, key string, dest any) error {
 
 // HasEmail проверяет и сохраняет email атомарно
 func (w *CacheWarmer) HasEmail(ctx context.Context, email string) (bool, error) {
-	script := `
-		local email_key = KEYS[1]
-		
-		-- Проверяем существование email
-		if redis.call("EXISTS", email_key) == 1 then
-			return {1}
-		end
-		
-		-- Сохраняем email, если его нет
-		redis.call("SET", email_key, 1)
-		return {0}
-	`
-
-	key := hasEmailPref + email
-	result, err := w.client.Eval(ctx, script, []string{key}).Result()
+	stored, err := w.storage.SetIfAbsent(ctx, hasEmailPref+email, []byte("1"), 0)
 	if err != nil {
-		return false, fmt.Errorf("redis script failed: %w", err)
-	}
-
-	// Обработка результата
-	if res, ok := result.([]any); ok && len(res) == 1 {
-		if exists, ok := res[0].(int64); ok {
-			return exists == 1, nil
-		}
+		return false, fmt.Errorf("failed to check email: %w", err)
 	}
 
-	return false, errors.New("unexpected response from redis")
+	return !stored, nil
 }
 
-// DeleteEmail удаляет email из кэша
+// DeleteEmail удаляет отметку email, сохраненную HasEmail
 func (w *CacheWarmer) DeleteEmail(ctx context.Context, email string) error {
-	key := w.prefix + email
-	if err := w.client.Del(ctx, key).Err(); err != nil {
+	if err := w.storage.Delete(ctx, hasEmailPref+email); err != nil {
 		return fmt.Errorf("failed to delete email: %w", err)
 	}
 	return nil
@@ -63,7 +63,7 @@ func TestCacheWarmer_SetAndGet(t *testing.T) {
 
 	// Создаем CacheWarmer с mock для MessageQueue
 	mockQueue := &mockMessageQueue{}
-	warmer := New(mockQueue, client)
+	warmer := New(mockQueue, NewRedisStorage(client), time.Minute)
 
 	t.Run("successful set and get", func(t *testing.T) {
 		key := "test_key"
@@ -4,9 +4,23 @@ import "time"
 
 type Config struct {
 	Enabled         bool          `env:"CACHE_ENABLED" env-default:"true"`
-	Type            string        `env:"CACHE_TYPE" env-default:"redis"` // redis or inmemory
-	TTL             time.Duration `env:"CACHE_TTL" env-default:"24h"`
+	Type            string        `env:"CACHE_TYPE" env-default:"redis"` // redis, inmemory or tiered
+	TTL             time.Duration `env:"CACHE_TTL" env-default:"24h"`    // feed entry ttl for every type, passed to Set
 	CleanupInterval time.Duration `env:"CACHE_CLEANUP_INTERVAL" env-default:"1h"`
-	Size            int           `env:"CACHE_SIZE" env-default:"10000"` // for inmemory cache
+	Size            int           `env:"CACHE_SIZE" env-default:"10000"`   // for inmemory and tiered cache
+	LocalTTL        time.Duration `env:"CACHE_LOCAL_TTL" env-default:"1m"` // for local tier of tiered cache
 	NumWorkers      int           `env:"CACHE_NUM_WORKERS" env-default:"8"`
+	QueueType       string        `env:"CACHE_QUEUE_TYPE" env-default:"redis"` // redis, kafka or memory
+	QueueSize       int           `env:"CACHE_QUEUE_SIZE" env-default:"1000"`  // for memory queue
+	KafkaTopic      string        `env:"CACHE_QUEUE_KAFKA_TOPIC" env-default:"cache_warm_tasks"`
+	KafkaDLQTopic   string        `env:"CACHE_QUEUE_KAFKA_DLQ_TOPIC" env-default:"cache_warm_tasks_dlq"`
+	FeedSoftTTL     time.Duration `env:"CACHE_FEED_SOFT_TTL" env-default:"1m"`
+	WarmDedupWindow time.Duration `env:"CACHE_WARM_DEDUP_WINDOW" env-default:"30s"`
+	MaxRetries      int           `env:"CACHE_WORKER_MAX_RETRIES" env-default:"3"`
+	// Deprecated: общий предел доставок задачи теперь задает MaxRetries
+	// (доставок = MaxRetries+1). Учитывается, только если MaxRetries не задан
+	MaxDeliveries   int           `env:"CACHE_WORKER_MAX_DELIVERIES"`
+	RetryBackoff    time.Duration `env:"CACHE_WORKER_RETRY_BACKOFF" env-default:"500ms"`
+	ReclaimIdle     time.Duration `env:"CACHE_WORKER_RECLAIM_IDLE" env-default:"1m"`
+	ReclaimInterval time.Duration `env:"CACHE_WORKER_RECLAIM_INTERVAL" env-default:"30s"`
 }
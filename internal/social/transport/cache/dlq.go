@@ -0,0 +1,62 @@
+package cachewarmer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// DeadLetter - задача прогрева, перенесённая в DLQ
+type DeadLetter struct {
+	ID     string
+	Values map[string]interface{}
+}
+
+// ListDeadLetters возвращает до count самых старых задач из DLQ
+func ListDeadLetters(ctx context.Context, client *redis.Client, count int64) ([]DeadLetter, error) {
+	messages, err := client.XRangeN(ctx, dlqStream, "-", "+", count).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read DLQ: %w", err)
+	}
+
+	letters := make([]DeadLetter, 0, len(messages))
+	for _, msg := range messages {
+		letters = append(letters, DeadLetter{ID: msg.ID, Values: msg.Values})
+	}
+
+	return letters, nil
+}
+
+// RequeueDeadLetters возвращает до count задач из DLQ в основной стрим.
+// Каждая задача переносится атомарно: добавление в стрим и удаление из DLQ
+func RequeueDeadLetters(ctx context.Context, client *redis.Client, count int64) (int, error) {
+	letters, err := ListDeadLetters(ctx, client, count)
+	if err != nil {
+		return 0, err
+	}
+
+	requeued := 0
+	for _, letter := range letters {
+		userID, ok := letter.Values["user_id"]
+		if !ok {
+			return requeued, fmt.Errorf("dead letter %s has no user_id", letter.ID)
+		}
+
+		_, err := client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream: taskStream,
+				Values: map[string]interface{}{"user_id": userID},
+			})
+			pipe.XDel(ctx, dlqStream, letter.ID)
+			return nil
+		})
+		if err != nil {
+			return requeued, fmt.Errorf("failed to requeue %s: %w", letter.ID, err)
+		}
+
+		requeued++
+	}
+
+	return requeued, nil
+}
@@ -0,0 +1,174 @@
+package cachewarmer
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMemorySize      = 10000
+	defaultCleanupInterval = time.Minute
+)
+
+type memoryEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time // нулевое значение - без срока жизни
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
+}
+
+// MemoryStorage - LRU кэш в памяти процесса с ограничением по размеру и TTL.
+// Записи с ttl <= 0 не истекают и покидают кэш только при вытеснении LRU
+type MemoryStorage struct {
+	mu    sync.Mutex
+	size  int
+	items map[string]*list.Element
+	order *list.List // голова - самый свежий элемент
+	now   func() time.Time
+}
+
+// NewMemoryStorage создает LRU кэш. Просроченные записи вычищаются раз в
+// cleanupInterval до отмены ctx
+func NewMemoryStorage(ctx context.Context, size int, cleanupInterval time.Duration) *MemoryStorage {
+	if size <= 0 {
+		size = defaultMemorySize
+	}
+	if cleanupInterval <= 0 {
+		cleanupInterval = defaultCleanupInterval
+	}
+
+	s := &MemoryStorage{
+		size:  size,
+		items: make(map[string]*list.Element, size),
+		order: list.New(),
+		now:   time.Now,
+	}
+
+	go s.cleanup(ctx, cleanupInterval)
+
+	return s
+}
+
+func (s *MemoryStorage) Get(_ context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	el, ok := s.items[key]
+	if !ok {
+		cacheMisses.WithLabelValues(StorageInMemory).Inc()
+		return nil, ErrCacheMiss
+	}
+
+	entry := el.Value.(*memoryEntry)
+	if entry.expired(s.now()) {
+		s.removeElement(el)
+		cacheMisses.WithLabelValues(StorageInMemory).Inc()
+		return nil, ErrCacheMiss
+	}
+
+	s.order.MoveToFront(el)
+	cacheHits.WithLabelValues(StorageInMemory).Inc()
+
+	return entry.value, nil
+}
+
+func (s *MemoryStorage) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.set(key, value, ttl)
+
+	return nil
+}
+
+func (s *MemoryStorage) SetIfAbsent(_ context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok && !el.Value.(*memoryEntry).expired(s.now()) {
+		return false, nil
+	}
+
+	s.set(key, value, ttl)
+
+	return true, nil
+}
+
+func (s *MemoryStorage) Delete(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if el, ok := s.items[key]; ok {
+		s.removeElement(el)
+	}
+
+	return nil
+}
+
+// Len возвращает текущее количество записей, включая ещё не вычищенные просроченные
+func (s *MemoryStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.order.Len()
+}
+
+func (s *MemoryStorage) set(key string, value []byte, ttl time.Duration) {
+	var expiresAt time.Time
+	if ttl > 0 {
+		expiresAt = s.now().Add(ttl)
+	}
+
+	if el, ok := s.items[key]; ok {
+		entry := el.Value.(*memoryEntry)
+		entry.value = value
+		entry.expiresAt = expiresAt
+		s.order.MoveToFront(el)
+		return
+	}
+
+	s.items[key] = s.order.PushFront(&memoryEntry{key: key, value: value, expiresAt: expiresAt})
+
+	// Вытесняем наименее используемые записи при превышении размера
+	for s.order.Len() > s.size {
+		s.removeElement(s.order.Back())
+	}
+}
+
+func (s *MemoryStorage) removeElement(el *list.Element) {
+	s.order.Remove(el)
+	delete(s.items, el.Value.(*memoryEntry).key)
+}
+
+func (s *MemoryStorage) cleanup(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deleteExpired()
+		}
+	}
+}
+
+func (s *MemoryStorage) deleteExpired() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	for el := s.order.Back(); el != nil; {
+		prev := el.Prev()
+		if el.Value.(*memoryEntry).expired(now) {
+			s.removeElement(el)
+		}
+		el = prev
+	}
+}
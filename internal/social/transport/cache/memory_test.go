@@ -0,0 +1,178 @@
+package cachewarmer
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	t.Run("evicts least recently used", func(t *testing.T) {
+		storage := NewMemoryStorage(ctx, 2, time.Hour)
+
+		require.NoError(t, storage.Set(ctx, "a", []byte("1"), 0))
+		require.NoError(t, storage.Set(ctx, "b", []byte("2"), 0))
+
+		// Обращение к "a" делает "b" самым старым
+		_, err := storage.Get(ctx, "a")
+		require.NoError(t, err)
+
+		require.NoError(t, storage.Set(ctx, "c", []byte("3"), 0))
+
+		_, err = storage.Get(ctx, "b")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+
+		value, err := storage.Get(ctx, "a")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("1"), value)
+		assert.Equal(t, 2, storage.Len())
+	})
+
+	t.Run("expires by ttl", func(t *testing.T) {
+		storage := NewMemoryStorage(ctx, 10, time.Hour)
+		now := time.Now()
+		storage.now = func() time.Time { return now }
+
+		require.NoError(t, storage.Set(ctx, "key", []byte("value"), time.Minute))
+
+		now = now.Add(2 * time.Minute)
+		_, err := storage.Get(ctx, "key")
+		assert.ErrorIs(t, err, ErrCacheMiss)
+	})
+
+	t.Run("zero ttl does not expire", func(t *testing.T) {
+		storage := NewMemoryStorage(ctx, 10, time.Hour)
+		now := time.Now()
+		storage.now = func() time.Time { return now }
+
+		require.NoError(t, storage.Set(ctx, "key", []byte("value"), 0))
+		stored, err := storage.SetIfAbsent(ctx, "email", []byte("1"), -1)
+		require.NoError(t, err)
+		require.True(t, stored)
+
+		now = now.Add(365 * 24 * time.Hour)
+		storage.deleteExpired()
+
+		value, err := storage.Get(ctx, "key")
+		require.NoError(t, err)
+		assert.Equal(t, []byte("value"), value)
+
+		stored, err = storage.SetIfAbsent(ctx, "email", []byte("1"), 0)
+		require.NoError(t, err)
+		assert.False(t, stored)
+	})
+
+	t.Run("set if absent", func(t *testing.T) {
+		storage := NewMemoryStorage(ctx, 10, time.Hour)
+
+		stored, err := storage.SetIfAbsent(ctx, "email", []byte("1"), 0)
+		require.NoError(t, err)
+		assert.True(t, stored)
+
+		stored, err = storage.SetIfAbsent(ctx, "email", []byte("1"), 0)
+		require.NoError(t, err)
+		assert.False(t, stored)
+	})
+}
+
+func TestTieredStorage(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	local := NewMemoryStorage(ctx, 10, time.Hour)
+	remote := NewMemoryStorage(ctx, 10, time.Hour)
+	storage := NewTieredStorage(local, remote, time.Minute)
+
+	// Значение есть только в удалённом уровне - чтение прогревает локальный
+	require.NoError(t, remote.Set(ctx, "feed", []byte("posts"), 0))
+
+	value, err := storage.Get(ctx, "feed")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("posts"), value)
+
+	value, err = local.Get(ctx, "feed")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("posts"), value)
+
+	// Удаление затрагивает оба уровня
+	require.NoError(t, storage.Delete(ctx, "feed"))
+	_, err = storage.Get(ctx, "feed")
+	assert.ErrorIs(t, err, ErrCacheMiss)
+}
+
+func TestCacheWarmer_HasEmailInMemory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	warmer := New(&mockMessageQueue{}, NewMemoryStorage(ctx, 10, time.Hour), time.Minute)
+
+	exists, err := warmer.HasEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = warmer.HasEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, warmer.DeleteEmail(ctx, "user@example.com"))
+
+	exists, err = warmer.HasEmail(ctx, "user@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestCacheWarmer_DeleteEmail(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const email = "user@example.com"
+	storage := NewMemoryStorage(ctx, 10, time.Hour)
+	warmer := New(&mockMessageQueue{}, storage, time.Minute)
+
+	_, err := warmer.HasEmail(ctx, email)
+	require.NoError(t, err)
+	require.NoError(t, storage.Set(ctx, warmer.prefix+email, []byte("feed"), 0))
+
+	// Удаляется отметка HasEmail, а не ключ с префиксом прогрева
+	require.NoError(t, warmer.DeleteEmail(ctx, email))
+
+	_, err = storage.Get(ctx, hasEmailPref+email)
+	assert.ErrorIs(t, err, ErrCacheMiss)
+	value, err := storage.Get(ctx, warmer.prefix+email)
+	require.NoError(t, err)
+	assert.Equal(t, []byte("feed"), value)
+}
+
+func TestCacheWarmer_WarmDedup(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	queue := &countingQueue{}
+	warmer := New(queue, NewMemoryStorage(ctx, 10, time.Hour), time.Minute)
+
+	// Пока задача ждёт в очереди, повторные отбрасываются
+	require.NoError(t, warmer.WarmForNewPost(ctx, 1))
+	require.NoError(t, warmer.WarmForNewPost(ctx, 1))
+	require.NoError(t, warmer.WarmForNewPost(ctx, 2))
+	assert.Equal(t, 2, queue.pushed)
+
+	// После взятия задачи в работу новая ставится снова
+	require.NoError(t, warmer.ReleaseWarm(ctx, 1))
+	require.NoError(t, warmer.WarmForNewPost(ctx, 1))
+	assert.Equal(t, 3, queue.pushed)
+}
+
+type countingQueue struct {
+	pushed int
+}
+
+func (q *countingQueue) Push(context.Context, WarmTask) error {
+	q.pushed++
+	return nil
+}
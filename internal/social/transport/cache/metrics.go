@@ -0,0 +1,26 @@
+package cachewarmer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	cacheHits = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_hits_total",
+			Help: "Total number of cache hits by tier",
+		},
+		[]string{"tier"},
+	)
+
+	cacheMisses = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_misses_total",
+			Help: "Total number of cache misses by tier",
+		},
+		[]string{"tier"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheHits)
+	prometheus.MustRegister(cacheMisses)
+}
@@ -2,29 +2,345 @@ package cachewarmer
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"otus-highload-arh-homework/pkg/clients/kafka"
 
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	QueueRedis  = "redis"
+	QueueKafka  = "kafka"
+	QueueMemory = "memory"
+)
+
+// Delivery - задача, полученная консьюмером из очереди
+type Delivery interface {
+	Task() WarmTask
+	// Attempt - номер доставки задачи, начиная с 1. Учитывает как повторные
+	// постановки через Nack, так и переотправку после падения консьюмера
+	Attempt() int
+	// Ack подтверждает успешную обработку
+	Ack(ctx context.Context) error
+	// Nack возвращает задачу в очередь с увеличенным номером попытки (requeue)
+	// либо переносит её в очередь недоставленных сообщений
+	Nack(ctx context.Context, requeue bool, cause error) error
+}
+
+// TaskQueue - очередь задач прогрева со стороны продюсера и консьюмера
+type TaskQueue interface {
+	MessageQueue
+	// Consume блокируется до появления задач или отмены ctx и возвращает
+	// не более max задач. consumer идентифицирует читателя внутри группы
+	Consume(ctx context.Context, consumer string, max int) ([]Delivery, error)
+	// RemoveConsumer удаляет остановленного читателя из группы, если за ним
+	// не осталось неподтвержденных задач
+	RemoveConsumer(ctx context.Context, consumer string) error
+	Close() error
+}
+
+// NewTaskQueue создает очередь задач прогрева согласно cfg.QueueType
+func NewTaskQueue(cfg *Config, client *redis.Client, kafkaCfg *kafka.Config) (TaskQueue, error) {
+	switch cfg.QueueType {
+	case QueueRedis, "":
+		queue := NewRedisQueue(client)
+		if cfg.ReclaimIdle > 0 {
+			queue.reclaimIdle = cfg.ReclaimIdle
+		}
+		if cfg.ReclaimInterval > 0 {
+			queue.reclaimInterval = cfg.ReclaimInterval
+		}
+		return queue, nil
+	case QueueKafka:
+		return NewKafkaQueue([]string{kafkaCfg.Address}, cfg.KafkaTopic, cfg.KafkaDLQTopic), nil
+	case QueueMemory:
+		return NewMemoryQueue(cfg.QueueSize), nil
+	default:
+		return nil, fmt.Errorf("unknown cache queue type %q", cfg.QueueType)
+	}
+}
+
 type RedisQueue struct {
-	client *redis.Client
-	stream string
+	client          *redis.Client
+	stream          string
+	dlqStream       string
+	group           string
+	reclaimIdle     time.Duration
+	reclaimInterval time.Duration
+
+	groupMu      sync.Mutex
+	groupCreated bool
+	reclaimMu    sync.Mutex
+	lastReclaim  time.Time
 }
 
+// NewRedisQueue создает очередь на Redis Streams. Задачи, которые провисели
+// в PEL дольше reclaimIdle, забираются другими консьюмерами через XAUTOCLAIM
 func NewRedisQueue(client *redis.Client) *RedisQueue {
 	return &RedisQueue{
-		client: client,
-		stream: "cache_warm_tasks",
+		client:          client,
+		stream:          taskStream,
+		dlqStream:       dlqStream,
+		group:           consumerGroup,
+		reclaimIdle:     defaultReclaimIdle,
+		reclaimInterval: defaultReclaimInterval,
 	}
 }
 
 func (q *RedisQueue) Push(ctx context.Context, task WarmTask) error {
-	_, err := q.client.XAdd(ctx, &redis.XAddArgs{
+	return q.push(ctx, q.client, task, 1)
+}
+
+func (q *RedisQueue) Consume(ctx context.Context, consumer string, max int) ([]Delivery, error) {
+	if err := q.ensureGroup(ctx); err != nil {
+		return nil, err
+	}
+
+	// Сначала забираем зависшие задачи упавших консьюмеров
+	if q.reclaimDue() {
+		deliveries, err := q.reclaim(ctx, consumer, max)
+		if err != nil {
+			return nil, err
+		}
+		if len(deliveries) > 0 {
+			return deliveries, nil
+		}
+	}
+
+	result, err := q.client.XReadGroup(ctx, &redis.XReadGroupArgs{
+		Group:    q.group,
+		Consumer: consumer,
+		Streams:  []string{q.stream, ">"},
+		Count:    int64(max),
+		Block:    readBlock,
+	}).Result()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tasks: %w", err)
+	}
+
+	var deliveries []Delivery
+	for _, stream := range result {
+		for _, msg := range stream.Messages {
+			if d := q.newDelivery(ctx, msg, 1); d != nil {
+				deliveries = append(deliveries, d)
+			}
+		}
+	}
+
+	return deliveries, nil
+}
+
+// RemoveConsumer удаляет консьюмера из группы, только если его PEL пуст:
+// XGROUP DELCONSUMER отбрасывает неподтвержденные задачи, а их должен
+// забрать XAUTOCLAIM другого консьюмера
+func (q *RedisQueue) RemoveConsumer(ctx context.Context, consumer string) error {
+	pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Start:    "-",
+		End:      "+",
+		Count:    1,
+		Consumer: consumer,
+	}).Result()
+	if err != nil {
+		return fmt.Errorf("failed to get pending tasks of %s: %w", consumer, err)
+	}
+	if len(pending) > 0 {
+		return nil
+	}
+
+	if err := q.client.XGroupDelConsumer(ctx, q.stream, q.group, consumer).Err(); err != nil {
+		return fmt.Errorf("failed to delete consumer %s: %w", consumer, err)
+	}
+
+	return nil
+}
+
+func (q *RedisQueue) Close() error {
+	return nil
+}
+
+func (q *RedisQueue) ensureGroup(ctx context.Context) error {
+	q.groupMu.Lock()
+	defer q.groupMu.Unlock()
+
+	if q.groupCreated {
+		return nil
+	}
+
+	_, err := q.client.XGroupCreateMkStream(ctx, q.stream, q.group, "0").Result()
+	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
+		return fmt.Errorf("failed to create consumer group: %w", err)
+	}
+	q.groupCreated = true
+
+	return nil
+}
+
+func (q *RedisQueue) reclaimDue() bool {
+	q.reclaimMu.Lock()
+	defer q.reclaimMu.Unlock()
+
+	if time.Since(q.lastReclaim) < q.reclaimInterval {
+		return false
+	}
+	q.lastReclaim = time.Now()
+
+	return true
+}
+
+func (q *RedisQueue) reclaim(ctx context.Context, consumer string, max int) ([]Delivery, error) {
+	messages, _, err := q.client.XAutoClaim(ctx, &redis.XAutoClaimArgs{
+		Stream:   q.stream,
+		Group:    q.group,
+		Consumer: consumer,
+		MinIdle:  q.reclaimIdle,
+		Start:    "0-0",
+		Count:    int64(max),
+	}).Result()
+	if err != nil {
+		return nil, fmt.Errorf("XAUTOCLAIM failed: %w", err)
+	}
+
+	deliveries := make([]Delivery, 0, len(messages))
+	for _, msg := range messages {
+		pending, err := q.client.XPendingExt(ctx, &redis.XPendingExtArgs{
+			Stream: q.stream,
+			Group:  q.group,
+			Start:  msg.ID,
+			End:    msg.ID,
+			Count:  1,
+		}).Result()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get delivery count for %s: %w", msg.ID, err)
+		}
+
+		deliveries64 := int64(1)
+		if len(pending) > 0 {
+			deliveries64 = pending[0].RetryCount
+		}
+
+		if d := q.newDelivery(ctx, msg, int(deliveries64)); d != nil {
+			deliveries = append(deliveries, d)
+		}
+	}
+
+	return deliveries, nil
+}
+
+// newDelivery разбирает сообщение стрима. Нечитаемые сообщения сразу уходят
+// в DLQ, в этом случае возвращается nil
+func (q *RedisQueue) newDelivery(ctx context.Context, msg redis.XMessage, deliveries int) *redisDelivery {
+	d := &redisDelivery{queue: q, msg: msg}
+
+	// Номер попытки = попытка, с которой задача была поставлена,
+	// плюс повторные доставки после падения консьюмеров
+	attempt := 1
+	if raw, ok := msg.Values["attempt"].(string); ok {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			attempt = parsed
+		}
+	}
+	d.attempt = attempt + deliveries - 1
+
+	userID, err := parseUserID(msg.Values["user_id"])
+	if err != nil {
+		log.Printf("Poison task %s: %v", msg.ID, err)
+		if err := d.Nack(ctx, false, err); err != nil {
+			log.Printf("Failed to move task %s to DLQ: %v", msg.ID, err)
+		}
+		return nil
+	}
+	d.task = WarmTask{UserID: userID}
+
+	return d
+}
+
+func (q *RedisQueue) push(ctx context.Context, cmd redis.Cmdable, task WarmTask, attempt int) error {
+	return cmd.XAdd(ctx, &redis.XAddArgs{
 		Stream: q.stream,
 		Values: map[string]interface{}{
 			"user_id": task.UserID,
+			"attempt": attempt,
 		},
-	}).Result()
+	}).Err()
+}
+
+type redisDelivery struct {
+	queue   *RedisQueue
+	msg     redis.XMessage
+	task    WarmTask
+	attempt int
+}
+
+func (d *redisDelivery) Task() WarmTask {
+	return d.task
+}
+
+func (d *redisDelivery) Attempt() int {
+	return d.attempt
+}
+
+func (d *redisDelivery) Ack(ctx context.Context) error {
+	return d.queue.client.XAck(ctx, d.queue.stream, d.queue.group, d.msg.ID).Err()
+}
+
+// Nack атомарно ставит новую запись (в стрим или DLQ) и подтверждает исходную
+func (d *redisDelivery) Nack(ctx context.Context, requeue bool, cause error) error {
+	_, err := d.queue.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		if requeue {
+			if err := d.queue.push(ctx, pipe, d.task, d.attempt+1); err != nil {
+				return err
+			}
+		} else {
+			values := map[string]interface{}{
+				"original_id": d.msg.ID,
+				"attempt":     d.attempt,
+				"error":       errorText(cause),
+				"failed_at":   time.Now().UTC().Format(time.RFC3339),
+			}
+			for k, v := range d.msg.Values {
+				if _, ok := values[k]; !ok {
+					values[k] = v
+				}
+			}
+			pipe.XAdd(ctx, &redis.XAddArgs{Stream: d.queue.dlqStream, Values: values})
+		}
+
+		pipe.XAck(ctx, d.queue.stream, d.queue.group, d.msg.ID)
+		return nil
+	})
 
 	return err
 }
+
+func parseUserID(raw interface{}) (int, error) {
+	userIDStr, ok := raw.(string)
+	if !ok {
+		return 0, errors.New("invalid user_id type")
+	}
+
+	userID, err := strconv.Atoi(userIDStr)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse user_id: %w", err)
+	}
+
+	return userID, nil
+}
+
+func errorText(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	return err.Error()
+}
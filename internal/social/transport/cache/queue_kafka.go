@@ -0,0 +1,167 @@
+package cachewarmer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	kafkaclient "otus-highload-arh-homework/pkg/clients/kafka"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	defaultKafkaTopic    = "cache_warm_tasks"
+	defaultKafkaDLQTopic = "cache_warm_tasks_dlq"
+
+	attemptHeader = "attempt"
+	errorHeader   = "error"
+)
+
+// KafkaQueue - очередь задач прогрева на Kafka. Повторная попытка публикуется
+// в тот же топик с увеличенным заголовком attempt, недоставленные задачи - в DLQ топик
+type KafkaQueue struct {
+	brokers []string
+	topic   string
+
+	producer *kafkaclient.Producer
+	dlq      *kafkaclient.Producer
+
+	mu        sync.Mutex
+	consumers map[string]*kafkaclient.Consumer
+}
+
+func NewKafkaQueue(brokers []string, topic, dlqTopic string) *KafkaQueue {
+	if topic == "" {
+		topic = defaultKafkaTopic
+	}
+	if dlqTopic == "" {
+		dlqTopic = defaultKafkaDLQTopic
+	}
+
+	return &KafkaQueue{
+		brokers:   brokers,
+		topic:     topic,
+		producer:  kafkaclient.NewProducer(brokers, topic),
+		dlq:       kafkaclient.NewProducer(brokers, dlqTopic),
+		consumers: make(map[string]*kafkaclient.Consumer),
+	}
+}
+
+func (q *KafkaQueue) Push(ctx context.Context, task WarmTask) error {
+	return q.publish(ctx, q.producer, task, 1, nil)
+}
+
+// Consume читает по одной задаче. Каждый consumer получает собственного читателя
+// в группе: партиции распределяются между воркерами, а смещения в партиции
+// подтверждаются строго по порядку
+func (q *KafkaQueue) Consume(ctx context.Context, consumer string, _ int) ([]Delivery, error) {
+	c := q.consumer(consumer)
+
+	for {
+		msg, err := c.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, nil
+			}
+			return nil, fmt.Errorf("failed to fetch task: %w", err)
+		}
+
+		d := &kafkaDelivery{queue: q, consumer: c, msg: msg, attempt: 1}
+		if attempt, err := strconv.Atoi(kafkaclient.Header(msg, attemptHeader)); err == nil && attempt > 0 {
+			d.attempt = attempt
+		}
+
+		if err := json.Unmarshal(msg.Value, &d.task); err != nil {
+			log.Printf("Poison task at %s/%d@%d: %v", msg.Topic, msg.Partition, msg.Offset, err)
+			if err := d.Nack(ctx, false, err); err != nil {
+				return nil, fmt.Errorf("failed to move poison task to DLQ: %w", err)
+			}
+			continue
+		}
+
+		return []Delivery{d}, nil
+	}
+}
+
+// RemoveConsumer ничего не делает: читатель покидает группу при Close
+func (q *KafkaQueue) RemoveConsumer(context.Context, string) error {
+	return nil
+}
+
+func (q *KafkaQueue) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var errs []error
+	for _, c := range q.consumers {
+		errs = append(errs, c.Close())
+	}
+	errs = append(errs, q.producer.Close(), q.dlq.Close())
+
+	return errors.Join(errs...)
+}
+
+func (q *KafkaQueue) consumer(name string) *kafkaclient.Consumer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	c, ok := q.consumers[name]
+	if !ok {
+		c = kafkaclient.NewConsumer(q.brokers, q.topic, consumerGroup)
+		q.consumers[name] = c
+	}
+
+	return c
+}
+
+func (q *KafkaQueue) publish(ctx context.Context, producer *kafkaclient.Producer, task WarmTask, attempt int, cause error) error {
+	headers := map[string]string{attemptHeader: strconv.Itoa(attempt)}
+	if cause != nil {
+		headers[errorHeader] = cause.Error()
+		headers["failed_at"] = time.Now().UTC().Format(time.RFC3339)
+	}
+
+	return producer.PublishWithHeaders(ctx, strconv.Itoa(task.UserID), task, headers)
+}
+
+type kafkaDelivery struct {
+	queue    *KafkaQueue
+	consumer *kafkaclient.Consumer
+	msg      kafka.Message
+	task     WarmTask
+	attempt  int
+}
+
+func (d *kafkaDelivery) Task() WarmTask {
+	return d.task
+}
+
+func (d *kafkaDelivery) Attempt() int {
+	return d.attempt
+}
+
+func (d *kafkaDelivery) Ack(ctx context.Context) error {
+	return d.consumer.Commit(ctx, d.msg)
+}
+
+// Nack публикует задачу заново и только затем подтверждает исходное сообщение:
+// при падении между шагами задача будет обработана повторно, но не потеряна
+func (d *kafkaDelivery) Nack(ctx context.Context, requeue bool, cause error) error {
+	var err error
+	if requeue {
+		err = d.queue.publish(ctx, d.queue.producer, d.task, d.attempt+1, nil)
+	} else {
+		err = d.queue.publish(ctx, d.queue.dlq, d.task, d.attempt, cause)
+	}
+	if err != nil {
+		return err
+	}
+
+	return d.consumer.Commit(ctx, d.msg)
+}
@@ -0,0 +1,125 @@
+package cachewarmer
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+const defaultMemoryQueueSize = 1000
+
+var ErrQueueFull = errors.New("task queue is full")
+
+// MemoryQueue - очередь задач в памяти процесса для локального запуска и тестов.
+// Задачи не переживают рестарт, DLQ хранит последние size недоставленных задач
+type MemoryQueue struct {
+	tasks chan memoryTask
+	size  int
+
+	mu   sync.Mutex
+	dead []WarmTask
+}
+
+type memoryTask struct {
+	task    WarmTask
+	attempt int
+}
+
+func NewMemoryQueue(size int) *MemoryQueue {
+	if size <= 0 {
+		size = defaultMemoryQueueSize
+	}
+
+	return &MemoryQueue{
+		tasks: make(chan memoryTask, size),
+		size:  size,
+	}
+}
+
+// Push не блокируется: при переполнении возвращает ErrQueueFull
+func (q *MemoryQueue) Push(_ context.Context, task WarmTask) error {
+	return q.push(memoryTask{task: task, attempt: 1})
+}
+
+func (q *MemoryQueue) Consume(ctx context.Context, _ string, max int) ([]Delivery, error) {
+	var deliveries []Delivery
+
+	select {
+	case <-ctx.Done():
+		return nil, nil
+	case t := <-q.tasks:
+		deliveries = append(deliveries, &memoryDelivery{queue: q, item: t})
+	}
+
+	for len(deliveries) < max {
+		select {
+		case t := <-q.tasks:
+			deliveries = append(deliveries, &memoryDelivery{queue: q, item: t})
+		default:
+			return deliveries, nil
+		}
+	}
+
+	return deliveries, nil
+}
+
+func (q *MemoryQueue) RemoveConsumer(context.Context, string) error {
+	return nil
+}
+
+func (q *MemoryQueue) Close() error {
+	return nil
+}
+
+// DeadLetters возвращает задачи, перенесённые в DLQ
+func (q *MemoryQueue) DeadLetters() []WarmTask {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return append([]WarmTask(nil), q.dead...)
+}
+
+func (q *MemoryQueue) push(t memoryTask) error {
+	select {
+	case q.tasks <- t:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (q *MemoryQueue) deadLetter(task WarmTask) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.dead) == q.size {
+		q.dead = q.dead[1:]
+	}
+	q.dead = append(q.dead, task)
+}
+
+type memoryDelivery struct {
+	queue *MemoryQueue
+	item  memoryTask
+}
+
+func (d *memoryDelivery) Task() WarmTask {
+	return d.item.task
+}
+
+func (d *memoryDelivery) Attempt() int {
+	return d.item.attempt
+}
+
+func (d *memoryDelivery) Ack(context.Context) error {
+	return nil
+}
+
+func (d *memoryDelivery) Nack(_ context.Context, requeue bool, _ error) error {
+	if !requeue {
+		d.queue.deadLetter(d.item.task)
+		return nil
+	}
+
+	return d.queue.push(memoryTask{task: d.item.task, attempt: d.item.attempt + 1})
+}
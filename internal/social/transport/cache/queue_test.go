@@ -82,3 +82,94 @@ func TestRedisQueue_ProcessTasks(t *testing.T) {
 		}
 	}
 }
+
+func TestWorkers_DeadLetterAndRequeue(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, err := setupRedis(ctx)
+	require.NoError(t, err)
+	defer redisContainer.Terminate(ctx)
+
+	opt, err := redis.ParseURL(redisContainer.URI)
+	require.NoError(t, err)
+
+	client := redis.NewClient(opt)
+	defer client.Close()
+
+	workerCtx, cancel := context.WithCancel(ctx)
+	preloader := &failingPreloader{}
+	workers := StartCacheWorkers(workerCtx, NewRedisQueue(client), &Config{
+		NumWorkers:   1,
+		MaxRetries:   1,
+		RetryBackoff: time.Millisecond,
+	}, preloader, &mockReleaser{})
+
+	require.NoError(t, NewRedisQueue(client).Push(ctx, WarmTask{UserID: 42}))
+
+	// Задача, упавшая после всех попыток, попадает в DLQ
+	require.Eventually(t, func() bool {
+		letters, err := ListDeadLetters(ctx, client, 10)
+		return err == nil && len(letters) == 1
+	}, 10*time.Second, 100*time.Millisecond)
+
+	cancel()
+	require.NoError(t, workers.Wait(ctx))
+
+	groupInfo, err := client.XInfoGroups(ctx, taskStream).Result()
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), groupInfo[0].Pending, "failed task must be acked after dead-lettering")
+	assert.Equal(t, int64(0), groupInfo[0].Consumers, "stopped worker must leave the group")
+
+	requeued, err := RequeueDeadLetters(ctx, client, 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requeued)
+
+	letters, err := ListDeadLetters(ctx, client, 10)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
+
+func TestRedisQueue_RemoveConsumer(t *testing.T) {
+	ctx := context.Background()
+
+	redisContainer, err := setupRedis(ctx)
+	require.NoError(t, err)
+	defer redisContainer.Terminate(ctx)
+
+	opt, err := redis.ParseURL(redisContainer.URI)
+	require.NoError(t, err)
+
+	client := redis.NewClient(opt)
+	defer client.Close()
+
+	queue := NewRedisQueue(client)
+	require.NoError(t, queue.Push(ctx, WarmTask{UserID: 1}))
+
+	deliveries, err := queue.Consume(ctx, "busy", 1)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 1)
+
+	// Консьюмер с неподтвержденной задачей остается: её заберет XAUTOCLAIM
+	require.NoError(t, queue.RemoveConsumer(ctx, "busy"))
+	consumers, err := client.XInfoConsumers(ctx, taskStream, consumerGroup).Result()
+	require.NoError(t, err)
+	assert.Len(t, consumers, 1)
+
+	require.NoError(t, deliveries[0].Ack(ctx))
+	require.NoError(t, queue.RemoveConsumer(ctx, "busy"))
+	consumers, err = client.XInfoConsumers(ctx, taskStream, consumerGroup).Result()
+	require.NoError(t, err)
+	assert.Empty(t, consumers)
+}
+
+type failingPreloader struct{}
+
+func (p *failingPreloader) PreloadUserFriendsFeeds(context.Context, int) error {
+	return errors.New("feed storage unavailable")
+}
+
+type mockReleaser struct{}
+
+func (r *mockReleaser) ReleaseWarm(context.Context, int) error {
+	return nil
+}
@@ -0,0 +1,59 @@
+package cachewarmer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStorage хранит данные кэша в Redis
+type RedisStorage struct {
+	client *redis.Client
+}
+
+func NewRedisStorage(client *redis.Client) *RedisStorage {
+	return &RedisStorage{client: client}
+}
+
+func (s *RedisStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.Get(ctx, key).Bytes()
+	if err != nil {
+		if errors.Is(err, redis.Nil) {
+			cacheMisses.WithLabelValues(StorageRedis).Inc()
+			return nil, ErrCacheMiss
+		}
+		return nil, fmt.Errorf("redis get operation failed: %w", err)
+	}
+
+	cacheHits.WithLabelValues(StorageRedis).Inc()
+
+	return data, nil
+}
+
+func (s *RedisStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.client.Set(ctx, key, value, ttl).Err(); err != nil {
+		return fmt.Errorf("redis set operation failed: %w", err)
+	}
+
+	return nil
+}
+
+func (s *RedisStorage) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	ok, err := s.client.SetNX(ctx, key, value, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("redis setnx operation failed: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (s *RedisStorage) Delete(ctx context.Context, key string) error {
+	if err := s.client.Del(ctx, key).Err(); err != nil {
+		return fmt.Errorf("redis del operation failed: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,45 @@
+package cachewarmer
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	StorageRedis    = "redis"
+	StorageInMemory = "inmemory"
+	StorageTiered   = "tiered"
+)
+
+// Storage абстрагирует KV-хранилище, поверх которого работает CacheWarmer
+type Storage interface {
+	// Get возвращает значение по ключу или ErrCacheMiss
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set сохраняет значение; ttl <= 0 означает хранение без срока жизни
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// SetIfAbsent атомарно сохраняет значение, только если ключа ещё нет.
+	// Возвращает true, если значение было записано
+	SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error)
+	// Delete удаляет ключ
+	Delete(ctx context.Context, key string) error
+}
+
+// NewStorage создает хранилище по CACHE_TYPE. Срок жизни записей передается
+// в Set (для лент - CACHE_TTL), своего срока у хранилищ нет. Фоновая
+// очистка in-memory кэша останавливается вместе с ctx
+func NewStorage(ctx context.Context, cfg *Config, client *redis.Client) (Storage, error) {
+	switch cfg.Type {
+	case StorageRedis, "":
+		return NewRedisStorage(client), nil
+	case StorageInMemory:
+		return NewMemoryStorage(ctx, cfg.Size, cfg.CleanupInterval), nil
+	case StorageTiered:
+		local := NewMemoryStorage(ctx, cfg.Size, cfg.CleanupInterval)
+		return NewTieredStorage(local, NewRedisStorage(client), cfg.LocalTTL), nil
+	default:
+		return nil, fmt.Errorf("unknown cache type %q", cfg.Type)
+	}
+}
@@ -1,5 +1,5 @@
 package cachewarmer
 
 type WarmTask struct {
-	UserID int
+	UserID int `json:"user_id"`
 }
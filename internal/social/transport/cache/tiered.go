@@ -0,0 +1,81 @@
+package cachewarmer
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const defaultLocalTTL = time.Minute
+
+// TieredStorage - двухуровневый кэш: локальный LRU перед общим хранилищем (Redis).
+// Горячие ленты отдаются из памяти процесса, промах локального уровня
+// дочитывается из удалённого и прогревает локальный
+type TieredStorage struct {
+	local    Storage
+	remote   Storage
+	localTTL time.Duration
+}
+
+// NewTieredStorage создает двухуровневый кэш. localTTL ограничивает время жизни
+// записей в локальном уровне, чтобы реплики не расходились надолго
+func NewTieredStorage(local, remote Storage, localTTL time.Duration) *TieredStorage {
+	if localTTL <= 0 {
+		localTTL = defaultLocalTTL
+	}
+
+	return &TieredStorage{
+		local:    local,
+		remote:   remote,
+		localTTL: localTTL,
+	}
+}
+
+func (s *TieredStorage) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.local.Get(ctx, key)
+	if err == nil {
+		return data, nil
+	}
+	if !errors.Is(err, ErrCacheMiss) {
+		return nil, err
+	}
+
+	data, err = s.remote.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ошибка локального уровня не должна ломать чтение
+	_ = s.local.Set(ctx, key, data, s.localTTL)
+
+	return data, nil
+}
+
+func (s *TieredStorage) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if err := s.remote.Set(ctx, key, value, ttl); err != nil {
+		return err
+	}
+
+	return s.local.Set(ctx, key, value, s.capLocalTTL(ttl))
+}
+
+// SetIfAbsent выполняется только на удалённом уровне: атомарность нужна между репликами
+func (s *TieredStorage) SetIfAbsent(ctx context.Context, key string, value []byte, ttl time.Duration) (bool, error) {
+	return s.remote.SetIfAbsent(ctx, key, value, ttl)
+}
+
+func (s *TieredStorage) Delete(ctx context.Context, key string) error {
+	if err := s.remote.Delete(ctx, key); err != nil {
+		return err
+	}
+
+	return s.local.Delete(ctx, key)
+}
+
+func (s *TieredStorage) capLocalTTL(ttl time.Duration) time.Duration {
+	if ttl <= 0 || ttl > s.localTTL {
+		return s.localTTL
+	}
+
+	return ttl
+}
@@ -2,62 +2,217 @@ package cachewarmer
 
 import (
 	"context"
-	"errors"
+	"fmt"
 	"log"
+	"os"
 	"strconv"
+	"sync"
 	"time"
+)
+
+const (
+	taskStream    = "cache_warm_tasks"
+	dlqStream     = "cache_warm_tasks:dlq"
+	consumerGroup = "cache_workers"
 
-	"otus-highload-arh-homework/internal/social/transport/service"
+	readBlock      = 5 * time.Second
+	readCount      = 10
+	taskTimeout    = 30 * time.Second
+	releaseTimeout = 5 * time.Second
 
-	"github.com/redis/go-redis/v9"
+	defaultMaxRetries      = 3
+	defaultRetryBackoff    = 500 * time.Millisecond
+	defaultReclaimIdle     = time.Minute
+	defaultReclaimInterval = 30 * time.Second
 )
 
-func StartCacheWorkers(ctx context.Context, client *redis.Client, numWorkers int, postService *service.PostService) {
-	_, err := client.XGroupCreateMkStream(ctx, "cache_warm_tasks", "cache_workers", "0").Result()
-	if err != nil && err.Error() != "BUSYGROUP Consumer Group name already exists" {
-		log.Printf("Failed to create consumer group: %v", err)
+type feedPreloader interface {
+	PreloadUserFriendsFeeds(ctx context.Context, userID int) error
+}
+
+type warmReleaser interface {
+	ReleaseWarm(ctx context.Context, authorID int) error
+}
+
+// Workers - пул воркеров, обрабатывающих задачи прогрева из очереди
+type Workers struct {
+	queue     TaskQueue
+	preloader feedPreloader
+	releaser  warmReleaser
+
+	consumerPrefix string
+	maxAttempts    int
+	retryBackoff   time.Duration
+
+	wg sync.WaitGroup
+}
+
+// StartCacheWorkers запускает воркеры поверх очереди queue.
+// Воркеры перестают брать новые задачи при отмене ctx, текущие дорабатывают до конца
+func StartCacheWorkers(ctx context.Context, queue TaskQueue, cfg *Config, preloader feedPreloader, releaser warmReleaser) *Workers {
+	w := &Workers{
+		queue:          queue,
+		preloader:      preloader,
+		releaser:       releaser,
+		consumerPrefix: consumerPrefix(),
+		maxAttempts:    cfg.MaxRetries + 1,
+		retryBackoff:   cfg.RetryBackoff,
+	}
+	if cfg.MaxRetries <= 0 {
+		w.maxAttempts = defaultMaxRetries + 1
+		if cfg.MaxDeliveries > 0 {
+			log.Printf("CACHE_WORKER_MAX_DELIVERIES is deprecated, use CACHE_WORKER_MAX_RETRIES=%d", cfg.MaxDeliveries-1)
+			w.maxAttempts = cfg.MaxDeliveries
+		}
+	}
+	if w.retryBackoff <= 0 {
+		w.retryBackoff = defaultRetryBackoff
+	}
+
+	numWorkers := cfg.NumWorkers
+	if numWorkers <= 0 {
+		numWorkers = 1
 	}
 
 	for i := 0; i < numWorkers; i++ {
+		w.wg.Add(1)
 		go func(workerID int) {
-			for {
-				// Читаем сообщения из стрима
-				result, err := client.XReadGroup(ctx, &redis.XReadGroupArgs{
-					Group:    "cache_workers",
-					Consumer: string(rune(workerID)),
-					Streams:  []string{"cache_warm_tasks", ">"},
-					Count:    10,
-					Block:    5 * time.Second,
-				}).Result()
-
-				if err != nil && !errors.Is(err, redis.Nil) || len(result) == 0 {
-					time.Sleep(1 * time.Second)
-					continue
-				}
-
-				for _, msg := range result[0].Messages {
-					userIDStr, ok := msg.Values["user_id"].(string)
-					if !ok {
-						log.Printf("Worker %d: invalid user_id type", workerID)
-						continue
-					}
-
-					authorID, err := strconv.Atoi(userIDStr)
-					if err != nil {
-						log.Printf("Worker %d: failed to parse user_id: %v", workerID, err)
-						continue
-					}
-
-					log.Printf("Worker %d: start PreloadUserFriendsFeeds for %d\n", workerID, authorID)
-
-					if err := postService.PreloadUserFriendsFeeds(ctx, authorID); err != nil {
-						log.Printf("Worker %d failed to preload feed for author %d: %v",
-							workerID, authorID, err)
-					}
-
-					client.XAck(ctx, "cache_warm_tasks", "cache_workers", msg.ID)
-				}
-			}
+			defer w.wg.Done()
+			w.run(ctx, workerID)
 		}(i)
 	}
+
+	return w
+}
+
+// Wait дожидается завершения воркеров после отмены контекста запуска
+func (w *Workers) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		w.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("cache workers drain: %w", ctx.Err())
+	}
+}
+
+func (w *Workers) run(ctx context.Context, workerID int) {
+	consumer := w.consumerPrefix + "-" + strconv.Itoa(workerID)
+
+	for {
+		if ctx.Err() != nil {
+			w.removeConsumer(ctx, workerID, consumer)
+			log.Printf("Worker %d: stopped", workerID)
+			return
+		}
+
+		deliveries, err := w.queue.Consume(ctx, consumer, readCount)
+		if err != nil {
+			if ctx.Err() != nil {
+				continue
+			}
+			log.Printf("Worker %d: failed to read tasks: %v", workerID, err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		for _, d := range deliveries {
+			w.process(ctx, workerID, d)
+		}
+	}
+}
+
+// process обрабатывает одну задачу. Неудачная попытка возвращается в очередь
+// после backoff, задача, исчерпавшая попытки, уходит в DLQ
+func (w *Workers) process(ctx context.Context, workerID int, d Delivery) {
+	// Начатая задача дорабатывается и после сигнала остановки
+	taskCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), taskTimeout)
+	defer cancel()
+
+	authorID := d.Task().UserID
+	attempt := d.Attempt()
+
+	// Задача могла превысить лимит, многократно теряясь при падении консьюмеров
+	if attempt > w.maxAttempts {
+		w.deadLetter(taskCtx, workerID, d, fmt.Errorf("delivered %d times", attempt))
+		return
+	}
+
+	// Новые изменения автора с этого момента ставят новую задачу
+	if err := w.releaser.ReleaseWarm(taskCtx, authorID); err != nil {
+		log.Printf("Worker %d: failed to release warm task for %d: %v", workerID, authorID, err)
+	}
+
+	log.Printf("Worker %d: start PreloadUserFriendsFeeds for %d, attempt %d\n", workerID, authorID, attempt)
+
+	err := w.preloader.PreloadUserFriendsFeeds(taskCtx, authorID)
+	if err == nil {
+		if err := d.Ack(taskCtx); err != nil {
+			log.Printf("Worker %d: failed to ack task for %d: %v", workerID, authorID, err)
+		}
+		return
+	}
+
+	log.Printf("Worker %d failed to preload feed for author %d: %v", workerID, authorID, err)
+
+	if attempt >= w.maxAttempts {
+		w.deadLetter(taskCtx, workerID, d, err)
+		return
+	}
+
+	// При остановке не ждём backoff: задача сразу возвращается в очередь
+	sleep(ctx, w.retryBackoff<<(attempt-1))
+
+	if err := d.Nack(taskCtx, true, err); err != nil {
+		log.Printf("Worker %d: failed to requeue task for %d: %v", workerID, authorID, err)
+	}
+}
+
+// removeConsumer убирает консьюмера остановленного воркера из группы:
+// имена уникальны для процесса, и без удаления они копятся после рестартов
+func (w *Workers) removeConsumer(ctx context.Context, workerID int, consumer string) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), releaseTimeout)
+	defer cancel()
+
+	if err := w.queue.RemoveConsumer(ctx, consumer); err != nil {
+		log.Printf("Worker %d: %v", workerID, err)
+	}
+}
+
+func (w *Workers) deadLetter(ctx context.Context, workerID int, d Delivery, cause error) {
+	if err := d.Nack(ctx, false, cause); err != nil {
+		log.Printf("Worker %d: failed to move task for %d to DLQ: %v", workerID, d.Task().UserID, err)
+		return
+	}
+
+	log.Printf("Worker %d: task for %d moved to DLQ after %d attempts: %v", workerID, d.Task().UserID, d.Attempt(), cause)
+}
+
+// consumerPrefix делает имена консьюмеров уникальными между подами и
+// рестартами. Воркер удаляет своего консьюмера при остановке
+func consumerPrefix() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
+
+// sleep ждёт d или отмены ctx. Возвращает false, если ctx отменён
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
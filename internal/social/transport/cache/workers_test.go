@@ -0,0 +1,136 @@
+package cachewarmer
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWorkers_MemoryQueue(t *testing.T) {
+	t.Run("acks processed task", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		queue := NewMemoryQueue(10)
+		preloader := &flakyPreloader{failures: 0}
+		workers := StartCacheWorkers(ctx, queue, &Config{NumWorkers: 2}, preloader, &mockReleaser{})
+
+		require.NoError(t, queue.Push(ctx, WarmTask{UserID: 1}))
+		require.NoError(t, queue.Push(ctx, WarmTask{UserID: 2}))
+
+		require.Eventually(t, func() bool {
+			return preloader.calls() == 2
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, workers.Wait(context.Background()))
+		assert.Empty(t, queue.DeadLetters())
+	})
+
+	t.Run("retries with attempt metadata", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		queue := NewMemoryQueue(10)
+		preloader := &flakyPreloader{failures: 2}
+		workers := StartCacheWorkers(ctx, queue, &Config{
+			NumWorkers:   1,
+			MaxRetries:   3,
+			RetryBackoff: time.Millisecond,
+		}, preloader, &mockReleaser{})
+
+		require.NoError(t, queue.Push(ctx, WarmTask{UserID: 7}))
+
+		require.Eventually(t, func() bool {
+			return preloader.calls() == 3
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, workers.Wait(context.Background()))
+		assert.Empty(t, queue.DeadLetters())
+	})
+
+	t.Run("dead-letters task after max attempts", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		queue := NewMemoryQueue(10)
+		preloader := &flakyPreloader{failures: 100}
+		workers := StartCacheWorkers(ctx, queue, &Config{
+			NumWorkers:   1,
+			MaxRetries:   2,
+			RetryBackoff: time.Millisecond,
+		}, preloader, &mockReleaser{})
+
+		require.NoError(t, queue.Push(ctx, WarmTask{UserID: 42}))
+
+		require.Eventually(t, func() bool {
+			return len(queue.DeadLetters()) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, workers.Wait(context.Background()))
+		assert.Equal(t, 3, preloader.calls())
+		assert.Equal(t, []WarmTask{{UserID: 42}}, queue.DeadLetters())
+	})
+
+	t.Run("falls back to deprecated max deliveries", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		queue := NewMemoryQueue(10)
+		preloader := &flakyPreloader{failures: 100}
+		workers := StartCacheWorkers(ctx, queue, &Config{
+			NumWorkers:    1,
+			MaxDeliveries: 2,
+			RetryBackoff:  time.Millisecond,
+		}, preloader, &mockReleaser{})
+
+		require.NoError(t, queue.Push(ctx, WarmTask{UserID: 42}))
+
+		require.Eventually(t, func() bool {
+			return len(queue.DeadLetters()) == 1
+		}, time.Second, 10*time.Millisecond)
+
+		cancel()
+		require.NoError(t, workers.Wait(context.Background()))
+		assert.Equal(t, 2, preloader.calls())
+	})
+}
+
+func TestMemoryQueue_Full(t *testing.T) {
+	queue := NewMemoryQueue(1)
+
+	require.NoError(t, queue.Push(context.Background(), WarmTask{UserID: 1}))
+	assert.ErrorIs(t, queue.Push(context.Background(), WarmTask{UserID: 2}), ErrQueueFull)
+}
+
+type flakyPreloader struct {
+	mu       sync.Mutex
+	failures int
+	n        int
+}
+
+func (p *flakyPreloader) PreloadUserFriendsFeeds(context.Context, int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.n++
+	if p.n <= p.failures {
+		return errors.New("feed storage unavailable")
+	}
+
+	return nil
+}
+
+func (p *flakyPreloader) calls() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.n
+}
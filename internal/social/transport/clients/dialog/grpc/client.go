@@ -3,12 +3,15 @@ package grpc
 import (
 	"context"
 	"log"
+	"strconv"
 	"time"
 
+	"otus-highload-arh-homework/pkg/auth"
 	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 )
 
@@ -18,11 +21,20 @@ type Client struct {
 	timeout time.Duration
 }
 
-func New(addr string, timeout time.Duration) (*Client, error) {
-	conn, err := grpc.NewClient(addr,
+// New подключается к сервису диалогов. opts дополняют и переопределяют
+// параметры подключения по умолчанию, например включают mTLS. Вызовы идут
+// от имени пользователя из auth.IdentityFromContext
+func New(addr string, timeout time.Duration, opts ...grpc.DialOption) (*Client, error) {
+	conn, err := grpc.NewClient(addr, append([]grpc.DialOption{
 		grpc.WithTransportCredentials(insecure.NewCredentials()),
-		grpc.WithUnaryInterceptor(requestIDInterceptor),
-	)
+		grpc.WithChainUnaryInterceptor(requestIDInterceptor, identityInterceptor),
+		grpc.WithChainStreamInterceptor(streamRequestIDInterceptor, streamIdentityInterceptor),
+		// Пинг только при открытых потоках и не чаще, чем разрешает сервер
+		grpc.WithKeepaliveParams(keepalive.ClientParameters{
+			Time:    30 * time.Second,
+			Timeout: 10 * time.Second,
+		}),
+	}, opts...)...)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 		return nil, err
@@ -39,31 +51,115 @@ func (c *Client) Close() error {
 	return c.conn.Close()
 }
 
-func (c *Client) SendMessage(ctx context.Context, senderID, receiverID, text string) error {
+// SendMessage отправляет сообщение. Повтор с тем же clientMessageID
+// возвращает уже сохраненное сообщение
+func (c *Client) SendMessage(ctx context.Context, senderID, receiverID, text, clientMessageID string) (*dialogv1.SendMessageResponse, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
-	_, err := c.client.SendMessage(ctx, &dialogv1.SendMessageRequest{
-		SenderId:   senderID,
-		ReceiverId: receiverID,
-		Text:       text,
+	return c.client.SendMessage(ctx, &dialogv1.SendMessageRequest{
+		SenderId:        senderID,
+		ReceiverId:      receiverID,
+		Text:            text,
+		ClientMessageId: clientMessageID,
+	})
+}
+
+// StreamMessages открывает поток сообщений пользователя начиная после
+// sinceCursor. Поток живет до отмены ctx, таймаут клиента к нему не применяется
+func (c *Client) StreamMessages(ctx context.Context, userID, sinceCursor string) (dialogv1.DialogService_StreamMessagesClient, error) {
+	return c.client.StreamMessages(ctx, &dialogv1.StreamMessagesRequest{
+		UserId:      userID,
+		SinceCursor: sinceCursor,
 	})
-	return err
 }
 
-func (c *Client) GetMessages(ctx context.Context, userID, otherUserID string) ([]*dialogv1.DialogMessage, error) {
+// GetMessages возвращает страницу истории диалога и курсор следующей страницы
+func (c *Client) GetMessages(ctx context.Context, userID, otherUserID, before, after string, limit int) ([]*dialogv1.DialogMessage, string, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.timeout)
 	defer cancel()
 
 	resp, err := c.client.GetMessages(ctx, &dialogv1.GetMessagesRequest{
 		UserId:      userID,
 		OtherUserId: otherUserID,
+		Before:      before,
+		After:       after,
+		Limit:       int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Messages, resp.NextCursor, nil
+}
+
+// ListDialogs возвращает страницу списка диалогов и курсор следующей страницы
+func (c *Client) ListDialogs(ctx context.Context, userID, cursor string, limit int) ([]*dialogv1.DialogPreview, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListDialogs(ctx, &dialogv1.ListDialogsRequest{
+		UserId: userID,
+		Cursor: cursor,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Dialogs, resp.NextCursor, nil
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника до upToMessageID
+func (c *Client) MarkRead(ctx context.Context, userID, peerID, upToMessageID string) (*dialogv1.MarkReadResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.MarkRead(ctx, &dialogv1.MarkReadRequest{
+		UserId:        userID,
+		PeerId:        peerID,
+		UpToMessageId: upToMessageID,
+	})
+}
+
+// GetUnreadCounters возвращает счетчики непрочитанных сообщений пользователя
+func (c *Client) GetUnreadCounters(ctx context.Context, userID string) (*dialogv1.GetUnreadCountersResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.GetUnreadCounters(ctx, &dialogv1.GetUnreadCountersRequest{UserId: userID})
+}
+
+// EditMessage меняет текст сообщения userID собеседнику peerID
+func (c *Client) EditMessage(ctx context.Context, userID, peerID, messageID, text string) (*dialogv1.DialogMessage, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.EditMessage(ctx, &dialogv1.EditMessageRequest{
+		UserId:    userID,
+		PeerId:    peerID,
+		MessageId: messageID,
+		Text:      text,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	return resp.Messages, nil
+	return resp.Message, nil
+}
+
+// DeleteMessage удаляет сообщение у userID или, с forEveryone, у обоих участников
+func (c *Client) DeleteMessage(ctx context.Context, userID, peerID, messageID string, forEveryone bool) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.DeleteMessage(ctx, &dialogv1.DeleteMessageRequest{
+		UserId:      userID,
+		PeerId:      peerID,
+		MessageId:   messageID,
+		ForEveryone: forEveryone,
+	})
+	return err
 }
 
 func requestIDInterceptor(
@@ -81,3 +177,57 @@ func requestIDInterceptor(
 
 	return invoker(ctx, method, req, reply, cc, opts...)
 }
+
+func streamRequestIDInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	if requestID, ok := ctx.Value("x-request-id").(string); ok {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-request-id", requestID)
+	}
+
+	return streamer(ctx, desc, cc, method, opts...)
+}
+
+// identityInterceptor передает сервису диалогов пользователя запроса: его
+// токен и ID. ID принимается сервисом только от клиента с доверенным
+// сертификатом
+func identityInterceptor(
+	ctx context.Context,
+	method string,
+	req, reply interface{},
+	cc *grpc.ClientConn,
+	invoker grpc.UnaryInvoker,
+	opts ...grpc.CallOption,
+) error {
+	return invoker(withIdentity(ctx), method, req, reply, cc, opts...)
+}
+
+func streamIdentityInterceptor(
+	ctx context.Context,
+	desc *grpc.StreamDesc,
+	cc *grpc.ClientConn,
+	method string,
+	streamer grpc.Streamer,
+	opts ...grpc.CallOption,
+) (grpc.ClientStream, error) {
+	return streamer(withIdentity(ctx), desc, cc, method, opts...)
+}
+
+func withIdentity(ctx context.Context) context.Context {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+
+	pairs := []string{auth.UserIDMetadata, strconv.Itoa(identity.UserID)}
+	if identity.Token != "" {
+		pairs = append(pairs, auth.AuthorizationMetadata, "Bearer "+identity.Token)
+	}
+
+	return metadata.AppendToOutgoingContext(ctx, pairs...)
+}
@@ -0,0 +1,145 @@
+package grpc
+
+import (
+	"context"
+
+	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
+)
+
+// CreateGroup создает группу, создатель становится администратором
+func (c *Client) CreateGroup(ctx context.Context, userID, title string, memberIDs []string) (*dialogv1.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.CreateGroup(ctx, &dialogv1.CreateGroupRequest{
+		UserId:    userID,
+		Title:     title,
+		MemberIds: memberIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Group, nil
+}
+
+// ListGroups возвращает страницу групп пользователя и курсор следующей страницы
+func (c *Client) ListGroups(ctx context.Context, userID, cursor string, limit int) ([]*dialogv1.Group, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListGroups(ctx, &dialogv1.ListGroupsRequest{
+		UserId: userID,
+		Cursor: cursor,
+		Limit:  int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Groups, resp.NextCursor, nil
+}
+
+// ListGroupMembers возвращает участников группы
+func (c *Client) ListGroupMembers(ctx context.Context, userID, groupID string) ([]*dialogv1.GroupMember, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.ListGroupMembers(ctx, &dialogv1.ListGroupMembersRequest{
+		UserId:  userID,
+		GroupId: groupID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.Members, nil
+}
+
+// AddGroupMembers добавляет участников и возвращает добавленных
+func (c *Client) AddGroupMembers(ctx context.Context, userID, groupID string, memberIDs []string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.AddGroupMembers(ctx, &dialogv1.AddGroupMembersRequest{
+		UserId:    userID,
+		GroupId:   groupID,
+		MemberIds: memberIDs,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return resp.AddedIds, nil
+}
+
+// RemoveGroupMember удаляет участника из группы
+func (c *Client) RemoveGroupMember(ctx context.Context, userID, groupID, memberID string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.RemoveGroupMember(ctx, &dialogv1.RemoveGroupMemberRequest{
+		UserId:   userID,
+		GroupId:  groupID,
+		MemberId: memberID,
+	})
+	return err
+}
+
+// SetGroupMemberRole меняет роль участника
+func (c *Client) SetGroupMemberRole(ctx context.Context, userID, groupID, memberID string, role dialogv1.GroupRole) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.SetGroupMemberRole(ctx, &dialogv1.SetGroupMemberRoleRequest{
+		UserId:   userID,
+		GroupId:  groupID,
+		MemberId: memberID,
+		Role:     role,
+	})
+	return err
+}
+
+// LeaveGroup выводит пользователя из группы
+func (c *Client) LeaveGroup(ctx context.Context, userID, groupID string) error {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	_, err := c.client.LeaveGroup(ctx, &dialogv1.LeaveGroupRequest{
+		UserId:  userID,
+		GroupId: groupID,
+	})
+	return err
+}
+
+// SendGroupMessage отправляет сообщение в группу
+func (c *Client) SendGroupMessage(ctx context.Context, senderID, groupID, text, clientMessageID string) (*dialogv1.SendMessageResponse, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	return c.client.SendGroupMessage(ctx, &dialogv1.SendGroupMessageRequest{
+		SenderId:        senderID,
+		GroupId:         groupID,
+		Text:            text,
+		ClientMessageId: clientMessageID,
+	})
+}
+
+// GetGroupMessages возвращает страницу истории группы и курсор следующей страницы
+func (c *Client) GetGroupMessages(ctx context.Context, userID, groupID, before, after string, limit int) ([]*dialogv1.GroupMessage, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	resp, err := c.client.GetGroupMessages(ctx, &dialogv1.GetGroupMessagesRequest{
+		UserId:  userID,
+		GroupId: groupID,
+		Before:  before,
+		After:   after,
+		Limit:   int32(limit),
+	})
+	if err != nil {
+		return nil, "", err
+	}
+
+	return resp.Messages, resp.NextCursor, nil
+}
@@ -0,0 +1,10 @@
+package consumer
+
+import "time"
+
+type Config struct {
+	// Задержки топиков повтора: <topic>_retry_1, <topic>_retry_2, ...
+	RetryDelays   []time.Duration `env:"FEED_UPDATER_RETRY_DELAYS" envSeparator:"," env-default:"5s,30s,5m"`
+	DedupTTL      time.Duration   `env:"FEED_UPDATER_DEDUP_TTL" env-default:"24h"`
+	HandleTimeout time.Duration   `env:"FEED_UPDATER_HANDLE_TIMEOUT" env-default:"10s"`
+}
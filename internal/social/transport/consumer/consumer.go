@@ -0,0 +1,307 @@
+// Package consumer - at-least-once обработка событий Kafka с топиками повтора,
+// DLQ и дедупликацией по event_id
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	kafkaclient "otus-highload-arh-homework/pkg/clients/kafka"
+	"otus-highload-arh-homework/pkg/events"
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	HeaderRetryAttempt = "retry-attempt"
+	HeaderRetryAt      = "retry-at"
+	HeaderRetryError   = "retry-error"
+	HeaderDLQError     = "dlq-error"
+	HeaderDLQSource    = "dlq-source"
+
+	defaultHandleTimeout = 10 * time.Second
+
+	lagInterval     = 5 * time.Second
+	forwardBackoff  = time.Second
+	maxForwardDelay = 30 * time.Second
+)
+
+// Handler обрабатывает событие. Ошибка отправляет событие в топик повтора
+type Handler func(ctx context.Context, env *eventsv1.Envelope) error
+
+type reader interface {
+	Fetch(ctx context.Context) (kafka.Message, error)
+	Commit(ctx context.Context, msgs ...kafka.Message) error
+	Lag() int64
+}
+
+type publisher interface {
+	PublishRaw(ctx context.Context, key string, value []byte, headers map[string]string) error
+}
+
+// stage - основной топик или один из топиков повтора
+type stage struct {
+	topic  string
+	reader reader
+	// delay - задержка перед обработкой сообщения топика повтора
+	delay time.Duration
+	// next - куда отправить сообщение после ошибки обработчика
+	next      publisher
+	nextDelay time.Duration
+}
+
+// Consumer читает основной топик и топики повтора. Смещение фиксируется
+// только после обработки, передачи в следующий топик или DLQ
+type Consumer struct {
+	stages        []*stage
+	dlq           publisher
+	dedup         Deduplicator
+	handler       Handler
+	handleTimeout time.Duration
+
+	closers []func() error
+}
+
+// NewKafkaConsumer создает Consumer для topic и топиков повтора <topic>_retry_N,
+// по одному на каждую задержку из cfg.RetryDelays
+func NewKafkaConsumer(brokers []string, topic, dlqTopic, group string, cfg *Config, dedup Deduplicator, handler Handler) *Consumer {
+	dlq := kafkaclient.NewProducer(brokers, dlqTopic)
+
+	c := &Consumer{
+		dlq:           dlq,
+		dedup:         dedup,
+		handler:       handler,
+		handleTimeout: cfg.HandleTimeout,
+		closers:       []func() error{dlq.Close},
+	}
+	if c.handleTimeout <= 0 {
+		c.handleTimeout = defaultHandleTimeout
+	}
+
+	topics := []string{topic}
+	for i := range cfg.RetryDelays {
+		topics = append(topics, RetryTopic(topic, i+1))
+	}
+
+	for i, t := range topics {
+		r := kafkaclient.NewConsumer(brokers, t, group)
+		c.closers = append(c.closers, r.Close)
+
+		st := &stage{topic: t, reader: r, next: c.dlq}
+		if i > 0 {
+			st.delay = cfg.RetryDelays[i-1]
+		}
+		if i+1 < len(topics) {
+			p := kafkaclient.NewProducer(brokers, topics[i+1])
+			c.closers = append(c.closers, p.Close)
+			st.next = p
+			st.nextDelay = cfg.RetryDelays[i]
+		}
+		c.stages = append(c.stages, st)
+	}
+
+	return c
+}
+
+// RetryTopic возвращает имя n-го топика повтора
+func RetryTopic(topic string, n int) string {
+	return topic + "_retry_" + strconv.Itoa(n)
+}
+
+// Run обрабатывает все топики до отмены ctx
+func (c *Consumer) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+
+	for _, st := range c.stages {
+		wg.Add(1)
+		go func(st *stage) {
+			defer wg.Done()
+			c.runStage(ctx, st)
+		}(st)
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		c.reportLag(ctx)
+	}()
+
+	wg.Wait()
+}
+
+func (c *Consumer) Close() error {
+	var errs []error
+	for _, closeFn := range c.closers {
+		errs = append(errs, closeFn())
+	}
+
+	return errors.Join(errs...)
+}
+
+func (c *Consumer) runStage(ctx context.Context, st *stage) {
+	for {
+		msg, err := st.reader.Fetch(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Consumer %s: failed to fetch message: %v", st.topic, err)
+			sleep(ctx, time.Second)
+			continue
+		}
+
+		if !c.handleMessage(ctx, st, msg) {
+			// Остановка до фиксации: сообщение будет прочитано повторно
+			return
+		}
+	}
+}
+
+// handleMessage обрабатывает сообщение и фиксирует смещение.
+// Возвращает false, если обработка прервана отменой ctx
+func (c *Consumer) handleMessage(ctx context.Context, st *stage, msg kafka.Message) bool {
+	headers := kafkaclient.Headers(msg)
+
+	// Топик повтора: ждём наступления времени повтора
+	if st.delay > 0 {
+		if retryAt, err := strconv.ParseInt(headers[HeaderRetryAt], 10, 64); err == nil {
+			if wait := time.Until(time.UnixMilli(retryAt)); wait > 0 && !sleep(ctx, wait) {
+				return false
+			}
+		}
+	}
+
+	start := time.Now()
+	result, ok := c.process(ctx, st, msg, headers)
+	if !ok {
+		return false
+	}
+
+	processingDuration.WithLabelValues(st.topic, result).Observe(time.Since(start).Seconds())
+	consumedEvents.WithLabelValues(st.topic, result).Inc()
+
+	if err := st.reader.Commit(context.WithoutCancel(ctx), msg); err != nil {
+		// Сообщение придёт повторно и будет отброшено дедупликацией
+		log.Printf("Consumer %s: failed to commit offset %d: %v", st.topic, msg.Offset, err)
+	}
+
+	return true
+}
+
+func (c *Consumer) process(ctx context.Context, st *stage, msg kafka.Message, headers map[string]string) (string, bool) {
+	env, err := events.Unmarshal(msg.Value, headers)
+	if err != nil {
+		// Повтор не поможет: неизвестная версия, тип или битое сообщение
+		log.Printf("Consumer %s: rejected message at offset %d: %v", st.topic, msg.Offset, err)
+		return resultDeadLettered, c.deadLetter(ctx, msg, headers, err)
+	}
+
+	eventID := env.GetEventId()
+	if eventID != "" {
+		seen, err := c.dedup.Seen(ctx, eventID)
+		if err != nil {
+			// Без дедупликации событие может быть обработано дважды, но не потеряно
+			log.Printf("Consumer %s: dedup check for %s failed: %v", st.topic, eventID, err)
+		}
+		if seen {
+			return resultDuplicate, true
+		}
+	}
+
+	handleCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.handleTimeout)
+	err = c.handler(handleCtx, env)
+	cancel()
+
+	if err == nil {
+		if eventID != "" {
+			if err := c.dedup.MarkProcessed(context.WithoutCancel(ctx), eventID); err != nil {
+				log.Printf("Consumer %s: failed to mark %s processed: %v", st.topic, eventID, err)
+			}
+		}
+		return resultProcessed, true
+	}
+
+	log.Printf("Consumer %s: failed to handle event %s: %v", st.topic, eventID, err)
+
+	if st.next == c.dlq {
+		return resultDeadLettered, c.deadLetter(ctx, msg, headers, err)
+	}
+
+	attempt, _ := strconv.Atoi(headers[HeaderRetryAttempt])
+	retryHeaders := copyHeaders(headers)
+	retryHeaders[HeaderRetryAttempt] = strconv.Itoa(attempt + 1)
+	retryHeaders[HeaderRetryAt] = strconv.FormatInt(time.Now().Add(st.nextDelay).UnixMilli(), 10)
+	retryHeaders[HeaderRetryError] = err.Error()
+
+	return resultRetried, c.forward(ctx, st.next, msg, retryHeaders)
+}
+
+func (c *Consumer) deadLetter(ctx context.Context, msg kafka.Message, headers map[string]string, cause error) bool {
+	dlqHeaders := copyHeaders(headers)
+	dlqHeaders[HeaderDLQError] = cause.Error()
+	dlqHeaders[HeaderDLQSource] = fmt.Sprintf("%s/%d@%d", msg.Topic, msg.Partition, msg.Offset)
+
+	return c.forward(ctx, c.dlq, msg, dlqHeaders)
+}
+
+// forward публикует сообщение в следующий топик, повторяя попытки до успеха:
+// без этого смещение нельзя зафиксировать, не потеряв сообщение
+func (c *Consumer) forward(ctx context.Context, next publisher, msg kafka.Message, headers map[string]string) bool {
+	delay := forwardBackoff
+	for {
+		err := next.PublishRaw(ctx, string(msg.Key), msg.Value, headers)
+		if err == nil {
+			return true
+		}
+
+		log.Printf("Consumer: failed to forward message at offset %d: %v", msg.Offset, err)
+		if !sleep(ctx, delay) {
+			return false
+		}
+		delay = min(delay*2, maxForwardDelay)
+	}
+}
+
+func (c *Consumer) reportLag(ctx context.Context) {
+	ticker := time.NewTicker(lagInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			for _, st := range c.stages {
+				consumerLag.WithLabelValues(st.topic).Set(float64(st.reader.Lag()))
+			}
+		}
+	}
+}
+
+func copyHeaders(headers map[string]string) map[string]string {
+	copied := make(map[string]string, len(headers)+3)
+	for k, v := range headers {
+		copied[k] = v
+	}
+
+	return copied
+}
+
+// sleep ждёт d или отмены ctx. Возвращает false, если ctx отменён
+func sleep(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
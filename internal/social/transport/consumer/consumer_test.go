@@ -0,0 +1,155 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	kafkaclient "otus-highload-arh-homework/pkg/clients/kafka"
+	"otus-highload-arh-homework/pkg/events"
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumer_HandleMessage(t *testing.T) {
+	ctx := context.Background()
+
+	newConsumer := func(handler Handler) (*Consumer, *stage, *stage, *fakePublisher) {
+		dlq := &fakePublisher{}
+		retry := &fakePublisher{}
+		c := &Consumer{dlq: dlq, dedup: newMemoryDedup(), handler: handler, handleTimeout: time.Second}
+
+		main := &stage{topic: "feed", reader: &fakeReader{}, next: retry, nextDelay: time.Minute}
+		last := &stage{topic: "feed_retry_1", reader: &fakeReader{}, delay: time.Millisecond, next: dlq}
+		c.stages = []*stage{main, last}
+
+		return c, main, last, dlq
+	}
+
+	t.Run("processes and deduplicates", func(t *testing.T) {
+		calls := 0
+		c, main, _, _ := newConsumer(func(context.Context, *eventsv1.Envelope) error {
+			calls++
+			return nil
+		})
+
+		msg := postMessage(t, "1")
+		require.True(t, c.handleMessage(ctx, main, msg))
+		require.True(t, c.handleMessage(ctx, main, msg))
+
+		assert.Equal(t, 1, calls, "redelivered event must be skipped")
+		assert.Len(t, main.reader.(*fakeReader).committed, 2)
+	})
+
+	t.Run("forwards failed event to retry topic", func(t *testing.T) {
+		c, main, _, _ := newConsumer(func(context.Context, *eventsv1.Envelope) error {
+			return errors.New("db unavailable")
+		})
+
+		require.True(t, c.handleMessage(ctx, main, postMessage(t, "2")))
+
+		retry := main.next.(*fakePublisher)
+		require.Len(t, retry.headers, 1)
+		assert.Equal(t, "1", retry.headers[0][HeaderRetryAttempt])
+		assert.Equal(t, "db unavailable", retry.headers[0][HeaderRetryError])
+		assert.NotEmpty(t, retry.headers[0][HeaderRetryAt])
+		assert.Len(t, main.reader.(*fakeReader).committed, 1)
+	})
+
+	t.Run("dead-letters after last retry", func(t *testing.T) {
+		c, _, last, dlq := newConsumer(func(context.Context, *eventsv1.Envelope) error {
+			return errors.New("db unavailable")
+		})
+
+		require.True(t, c.handleMessage(ctx, last, postMessage(t, "3")))
+
+		require.Len(t, dlq.headers, 1)
+		assert.Equal(t, "db unavailable", dlq.headers[0][HeaderDLQError])
+		assert.Len(t, last.reader.(*fakeReader).committed, 1)
+	})
+
+	t.Run("dead-letters unknown major version without retries", func(t *testing.T) {
+		calls := 0
+		c, main, _, dlq := newConsumer(func(context.Context, *eventsv1.Envelope) error {
+			calls++
+			return nil
+		})
+
+		msg := postMessage(t, "4")
+		for i, h := range msg.Headers {
+			if h.Key == events.HeaderContentType {
+				msg.Headers[i].Value = []byte(events.ContentTypeJSON)
+			}
+		}
+		msg.Value = []byte(`{"eventId":"4","type":"post.created","version":"2.0"}`)
+
+		require.True(t, c.handleMessage(ctx, main, msg))
+
+		assert.Zero(t, calls)
+		require.Len(t, dlq.headers, 1)
+		assert.Contains(t, dlq.headers[0][HeaderDLQError], events.ErrUnsupportedVersion.Error())
+		assert.Empty(t, main.next.(*fakePublisher).headers)
+	})
+}
+
+func postMessage(t *testing.T, id string) kafka.Message {
+	env, err := events.New(id, events.TypePostCreated, time.Now(), nil, &eventsv1.PostEvent{PostId: "p" + id, AuthorId: 1})
+	require.NoError(t, err)
+
+	value, headers, err := events.Marshal(env, events.ContentTypeProtobuf)
+	require.NoError(t, err)
+
+	msg := kafka.Message{Topic: "feed", Value: value}
+	for k, v := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+	require.Equal(t, id, kafkaclient.Headers(msg)[events.HeaderEventID])
+
+	return msg
+}
+
+type fakeReader struct {
+	committed []kafka.Message
+}
+
+func (r *fakeReader) Fetch(ctx context.Context) (kafka.Message, error) {
+	<-ctx.Done()
+	return kafka.Message{}, ctx.Err()
+}
+
+func (r *fakeReader) Commit(_ context.Context, msgs ...kafka.Message) error {
+	r.committed = append(r.committed, msgs...)
+	return nil
+}
+
+func (r *fakeReader) Lag() int64 {
+	return 0
+}
+
+type fakePublisher struct {
+	headers []map[string]string
+}
+
+func (p *fakePublisher) PublishRaw(_ context.Context, _ string, _ []byte, headers map[string]string) error {
+	p.headers = append(p.headers, headers)
+	return nil
+}
+
+type memoryDedup map[string]bool
+
+func newMemoryDedup() memoryDedup {
+	return memoryDedup{}
+}
+
+func (d memoryDedup) Seen(_ context.Context, eventID string) (bool, error) {
+	return d[eventID], nil
+}
+
+func (d memoryDedup) MarkProcessed(_ context.Context, eventID string) error {
+	d[eventID] = true
+	return nil
+}
@@ -0,0 +1,52 @@
+package consumer
+
+import (
+	"context"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	processedPrefix = "event_processed:"
+	defaultDedupTTL = 24 * time.Hour
+)
+
+// Deduplicator помнит обработанные события в течение TTL
+type Deduplicator interface {
+	Seen(ctx context.Context, eventID string) (bool, error)
+	MarkProcessed(ctx context.Context, eventID string) error
+}
+
+type RedisDeduplicator struct {
+	client *redis.Client
+	group  string
+	ttl    time.Duration
+}
+
+// NewRedisDeduplicator создает дедупликатор. Ключи разделены по группе
+// консьюмеров: одно событие независимо обрабатывается разными группами
+func NewRedisDeduplicator(client *redis.Client, group string, ttl time.Duration) *RedisDeduplicator {
+	if ttl <= 0 {
+		ttl = defaultDedupTTL
+	}
+
+	return &RedisDeduplicator{client: client, group: group, ttl: ttl}
+}
+
+func (d *RedisDeduplicator) Seen(ctx context.Context, eventID string) (bool, error) {
+	n, err := d.client.Exists(ctx, d.key(eventID)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+func (d *RedisDeduplicator) MarkProcessed(ctx context.Context, eventID string) error {
+	return d.client.Set(ctx, d.key(eventID), 1, d.ttl).Err()
+}
+
+func (d *RedisDeduplicator) key(eventID string) string {
+	return processedPrefix + d.group + ":" + eventID
+}
@@ -0,0 +1,43 @@
+package consumer
+
+import "github.com/prometheus/client_golang/prometheus"
+
+const (
+	resultProcessed    = "processed"
+	resultDuplicate    = "duplicate"
+	resultRetried      = "retried"
+	resultDeadLettered = "dead_lettered"
+)
+
+var (
+	consumerLag = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "event_consumer_lag",
+			Help: "Number of messages the consumer is behind the end of the topic",
+		},
+		[]string{"topic"},
+	)
+
+	processingDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "event_processing_duration_seconds",
+			Help:    "Time spent handling a consumed event",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"topic", "result"},
+	)
+
+	consumedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "events_consumed_total",
+			Help: "Total number of consumed events by result",
+		},
+		[]string{"topic", "result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(consumerLag)
+	prometheus.MustRegister(processingDuration)
+	prometheus.MustRegister(consumedEvents)
+}
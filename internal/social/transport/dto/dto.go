@@ -33,6 +33,21 @@ type UserResponse struct {
 	City      string        `json:"city"`
 	CreatedAt time.Time     `json:"created_at"`
 	IsAdult   bool          `json:"is_adult"`
+	// LastSeenAt - время последней активности, если пользователь его не скрыл
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// PresenceResponse - присутствие пользователя. Если пользователь скрыл
+// присутствие от запрашивающего, online=false и last_seen_at отсутствует
+type PresenceResponse struct {
+	UserID     int        `json:"user_id"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"last_seen_at,omitempty"`
+}
+
+// PrivacyRequest - настройки приватности
+type PrivacyRequest struct {
+	LastSeenVisibility entity.Visibility `json:"last_seen_visibility" binding:"required" example:"friends"`
 }
 
 // Успешный ответ
@@ -74,16 +89,31 @@ type PostResponse struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-type FeedUpdateEvent struct {
-	PostID    string `json:"post_id"`
-	AuthorID  int    `json:"author_id"`
-	Action    string `json:"action"` // "create", "update", "delete"
-	Text      string `json:"text,omitempty"`
-	Timestamp int64  `json:"timestamp"`
-}
+// FeedUpdateEvent публикуется через outbox, поэтому определён в entity
+type FeedUpdateEvent = entity.FeedUpdateEvent
 
 type SendMessageRequest struct {
 	Text string `json:"text" binding:"required,min=1,max=1000"`
+	// ClientMessageID - необязательный идентификатор от клиента для
+	// безопасного повтора: повтор не создает второе сообщение
+	ClientMessageID string `json:"client_message_id,omitempty" binding:"max=64"`
+}
+
+// SentMessageV2 - отправленное сообщение. duplicate=true - сообщение с этим
+// client_message_id уже было отправлено раньше
+type SentMessageV2 struct {
+	ID              string    `json:"id"`
+	ClientMessageID string    `json:"client_message_id,omitempty"`
+	SentAt          time.Time `json:"sent_at"`
+	Duplicate       bool      `json:"duplicate"`
+}
+
+// MessagesPageQuery - курсоры истории диалога. Курсор следующей страницы
+// возвращается в заголовке X-Next-Cursor
+type MessagesPageQuery struct {
+	Before string `form:"before"`
+	After  string `form:"after"`
+	Limit  int    `form:"limit"`
 }
 
 type DialogMessage struct {
@@ -109,6 +139,94 @@ type DialogMessageV2 struct {
 	Text       string    `json:"text"`
 	SentAt     time.Time `json:"sent_at"`
 	IsOwn      bool      `json:"is_own"`
+	// EditedAt - время последнего редактирования
+	EditedAt *time.Time `json:"edited_at,omitempty"`
+	// Deleted - сообщение удалено для всех, text пустой
+	Deleted bool `json:"deleted"`
+}
+
+// EditMessageRequest - новый текст сообщения
+type EditMessageRequest struct {
+	Text string `json:"text" binding:"required,min=1,max=1000"`
+}
+
+// DialogsPageQuery - курсор списка диалогов, следующий возвращается в X-Next-Cursor
+type DialogsPageQuery struct {
+	Cursor string `form:"cursor"`
+	Limit  int    `form:"limit"`
+}
+
+// DialogPreviewV2 - диалог в списке: собеседник, последнее сообщение и число непрочитанных
+type DialogPreviewV2 struct {
+	PeerID      string          `json:"peer_id"`
+	LastMessage DialogMessageV2 `json:"last_message"`
+	UnreadCount int             `json:"unread_count"`
+}
+
+// MarkReadRequest - отметка прочтения сообщений собеседника
+type MarkReadRequest struct {
+	UpToMessageID string `json:"up_to_message_id" binding:"required"`
+}
+
+// MarkReadResponseV2 - сколько сообщений отмечено и сколько осталось непрочитанных
+type MarkReadResponseV2 struct {
+	Marked      int `json:"marked"`
+	UnreadCount int `json:"unread_count"`
+	TotalUnread int `json:"total_unread"`
+}
+
+// UnreadCountersV2 - непрочитанные сообщения по собеседникам и всего
+type UnreadCountersV2 struct {
+	TotalUnread int            `json:"total_unread"`
+	Dialogs     map[string]int `json:"dialogs"`
+}
+
+// CreateGroupRequest - новая группа: название и участники кроме создателя
+type CreateGroupRequest struct {
+	Title     string   `json:"title" binding:"required,max=100"`
+	MemberIDs []string `json:"member_ids" binding:"max=199"`
+}
+
+// GroupV2 - групповой чат; role - роль текущего пользователя
+type GroupV2 struct {
+	ID          string           `json:"id"`
+	Title       string           `json:"title"`
+	CreatedBy   string           `json:"created_by"`
+	CreatedAt   time.Time        `json:"created_at"`
+	Role        entity.GroupRole `json:"role"`
+	MemberCount int              `json:"member_count"`
+}
+
+// GroupMemberV2 - участник группы
+type GroupMemberV2 struct {
+	UserID   string           `json:"user_id"`
+	Role     entity.GroupRole `json:"role"`
+	JoinedAt time.Time        `json:"joined_at"`
+}
+
+// AddGroupMembersRequest - добавляемые участники
+type AddGroupMembersRequest struct {
+	MemberIDs []string `json:"member_ids" binding:"required,min=1,max=199"`
+}
+
+// AddGroupMembersResponseV2 - добавленные участники, уже состоявшие в группе не включаются
+type AddGroupMembersResponseV2 struct {
+	AddedIDs []string `json:"added_ids"`
+}
+
+// SetGroupRoleRequest - новая роль участника
+type SetGroupRoleRequest struct {
+	Role entity.GroupRole `json:"role" binding:"required,oneof=admin member" example:"admin"`
+}
+
+// GroupMessageV2 - сообщение группового чата
+type GroupMessageV2 struct {
+	ID       string    `json:"id"`
+	GroupID  string    `json:"group_id"`
+	SenderID string    `json:"sender_id"`
+	Text     string    `json:"text"`
+	SentAt   time.Time `json:"sent_at"`
+	IsOwn    bool      `json:"is_own"`
 }
 
 type ErrorResponseV2 struct {
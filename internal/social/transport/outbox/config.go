@@ -0,0 +1,14 @@
+package outbox
+
+import "time"
+
+type Config struct {
+	BatchSize       int           `env:"OUTBOX_BATCH_SIZE" env-default:"100"`
+	PollInterval    time.Duration `env:"OUTBOX_POLL_INTERVAL" env-default:"500ms"`
+	Retention       time.Duration `env:"OUTBOX_RETENTION" env-default:"24h"` // sent events are deleted after retention
+	CleanupInterval time.Duration `env:"OUTBOX_CLEANUP_INTERVAL" env-default:"10m"`
+	MaxAttempts     int           `env:"OUTBOX_MAX_ATTEMPTS" env-default:"20"` // encoding failures before the event is marked failed; broker errors are retried without limit
+	MetricsPort     string        `env:"OUTBOX_METRICS_PORT" env-default:":9102"`
+	Source          string        `env:"OUTBOX_SOURCE" env-default:"social"`                       // event id prefix, unique per outbox database
+	ContentType     string        `env:"OUTBOX_CONTENT_TYPE" env-default:"application/x-protobuf"` // application/x-protobuf or application/json
+}
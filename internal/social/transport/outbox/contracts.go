@@ -0,0 +1,146 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/pkg/events"
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"google.golang.org/protobuf/proto"
+)
+
+var postActions = map[string]eventsv1.PostAction{
+	entity.FeedActionCreate: eventsv1.PostAction_POST_ACTION_CREATED,
+	entity.FeedActionUpdate: eventsv1.PostAction_POST_ACTION_UPDATED,
+	entity.FeedActionDelete: eventsv1.PostAction_POST_ACTION_DELETED,
+}
+
+var friendshipActions = map[string]eventsv1.FriendshipAction{
+	entity.FriendshipActionAdd:    eventsv1.FriendshipAction_FRIENDSHIP_ACTION_ADDED,
+	entity.FriendshipActionRemove: eventsv1.FriendshipAction_FRIENDSHIP_ACTION_REMOVED,
+}
+
+// toEnvelope переводит событие outbox в контракт топика. event_id -
+// "<source>:<id>": ID события стабилен между повторными публикациями, а
+// source отличает outbox разных баз, в которых ID выдаются независимо
+func toEnvelope(event *entity.OutboxEvent, source string) (*eventsv1.Envelope, error) {
+	var payload proto.Message
+
+	switch event.AggregateType {
+	case entity.AggregatePost:
+		var e entity.FeedUpdateEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal post event %d: %w", event.ID, err)
+		}
+		payload = &eventsv1.PostEvent{
+			PostId:   e.PostID,
+			AuthorId: int64(e.AuthorID),
+			Action:   postActions[e.Action],
+			Text:     e.Text,
+		}
+	case entity.AggregateFriendship:
+		var e entity.FriendshipEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal friendship event %d: %w", event.ID, err)
+		}
+		payload = &eventsv1.FriendshipEvent{
+			UserId:   int64(e.UserID),
+			FriendId: int64(e.FriendID),
+			Action:   friendshipActions[e.Action],
+		}
+	case entity.AggregateDialog:
+		var err error
+		if payload, err = dialogPayload(event); err != nil {
+			return nil, err
+		}
+	case entity.AggregateGroup:
+		var e entity.GroupMessageEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal group event %d: %w", event.ID, err)
+		}
+		payload = &eventsv1.GroupMessageEvent{
+			MessageId: e.MessageID,
+			GroupId:   e.GroupID,
+			SenderId:  e.SenderID,
+			Text:      e.Text,
+			MemberIds: e.MemberIDs,
+		}
+	default:
+		return nil, fmt.Errorf("unknown aggregate type %q", event.AggregateType)
+	}
+
+	var trace *eventsv1.TraceContext
+	if event.RequestID != "" || event.TraceParent != "" {
+		trace = &eventsv1.TraceContext{
+			RequestId:   event.RequestID,
+			Traceparent: event.TraceParent,
+		}
+	}
+
+	return events.New(source+":"+strconv.FormatInt(event.ID, 10), event.EventType, event.CreatedAt, trace, payload)
+}
+
+// dialogPayload переводит события диалогов: в агрегате несколько типов событий
+func dialogPayload(event *entity.OutboxEvent) (proto.Message, error) {
+	switch event.EventType {
+	case entity.EventMessageCreated:
+		var e entity.DialogMessageEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal dialog event %d: %w", event.ID, err)
+		}
+		return &eventsv1.DialogMessageEvent{
+			MessageId:  e.MessageID,
+			SenderId:   e.SenderID,
+			ReceiverId: e.ReceiverID,
+			Text:       e.Text,
+		}, nil
+	case entity.EventMessageRead:
+		var e entity.DialogReadEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal read event %d: %w", event.ID, err)
+		}
+		return &eventsv1.DialogReadEvent{
+			ReaderId:      e.ReaderID,
+			PeerId:        e.PeerID,
+			UpToMessageId: e.UpToMessageID,
+		}, nil
+	case entity.EventUnreadChanged:
+		var e entity.UnreadChangedEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal unread event %d: %w", event.ID, err)
+		}
+		return &eventsv1.UnreadChangedEvent{
+			UserId:      e.UserID,
+			PeerId:      e.PeerID,
+			UnreadCount: int32(e.UnreadCount),
+			TotalUnread: int32(e.TotalUnread),
+		}, nil
+	case entity.EventMessageEdited:
+		var e entity.DialogMessageEditedEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal edited event %d: %w", event.ID, err)
+		}
+		return &eventsv1.DialogMessageEditedEvent{
+			MessageId:  e.MessageID,
+			SenderId:   e.SenderID,
+			ReceiverId: e.ReceiverID,
+			Text:       e.Text,
+			EditedAt:   e.EditedAt,
+		}, nil
+	case entity.EventMessageDeleted:
+		var e entity.DialogMessageDeletedEvent
+		if err := json.Unmarshal(event.Payload, &e); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal deleted event %d: %w", event.ID, err)
+		}
+		return &eventsv1.DialogMessageDeletedEvent{
+			MessageId:  e.MessageID,
+			SenderId:   e.SenderID,
+			ReceiverId: e.ReceiverID,
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown dialog event type %q", event.EventType)
+	}
+}
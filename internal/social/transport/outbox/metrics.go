@@ -0,0 +1,59 @@
+package outbox
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	pendingEvents = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_pending_events",
+			Help: "Number of outbox events not yet published",
+		},
+	)
+
+	oldestPendingAge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_oldest_pending_age_seconds",
+			Help: "Age of the oldest unpublished outbox event",
+		},
+	)
+
+	publishedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_published_total",
+			Help: "Total number of published outbox events by aggregate type",
+		},
+		[]string{"aggregate_type"},
+	)
+
+	publishErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_publish_errors_total",
+			Help: "Total number of failed outbox publish attempts by aggregate type",
+		},
+		[]string{"aggregate_type"},
+	)
+
+	failedEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "outbox_failed_total",
+			Help: "Total number of outbox events given up after max publish attempts by aggregate type",
+		},
+		[]string{"aggregate_type"},
+	)
+
+	isLeader = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "outbox_relay_leader",
+			Help: "1 if this relay instance holds the leader lock",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(pendingEvents)
+	prometheus.MustRegister(oldestPendingAge)
+	prometheus.MustRegister(publishedEvents)
+	prometheus.MustRegister(publishErrors)
+	prometheus.MustRegister(failedEvents)
+	prometheus.MustRegister(isLeader)
+}
@@ -0,0 +1,287 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+	"otus-highload-arh-homework/pkg/events"
+)
+
+const (
+	defaultBatchSize       = 100
+	defaultPollInterval    = 500 * time.Millisecond
+	defaultRetention       = 24 * time.Hour
+	defaultCleanupInterval = 10 * time.Minute
+	defaultMaxAttempts     = 20
+	defaultSource          = "social"
+
+	publishTimeout = 10 * time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// Publisher публикует событие в топик
+type Publisher interface {
+	PublishRaw(ctx context.Context, key string, value []byte, headers map[string]string) error
+}
+
+type leaderLock interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// Relay публикует события из outbox в Kafka. Среди запущенных экземпляров
+// работает только держатель leader-блокировки, события одного агрегата
+// публикуются строго в порядке записи. Доставка at-least-once: при сбое
+// между публикацией и отметкой sent_at событие будет опубликовано повторно.
+// Событие, которое maxAttempts раз не удалось закодировать, помечается
+// failed и пропускается, чтобы не блокировать остальные события агрегата.
+// Недоступность брокера попытки не расходует
+type Relay struct {
+	repo       repository.OutboxRepository
+	lock       leaderLock
+	publishers map[string]Publisher
+
+	contentType     string
+	source          string
+	batchSize       int
+	pollInterval    time.Duration
+	retention       time.Duration
+	cleanupInterval time.Duration
+	maxAttempts     int
+	lastCleanup     time.Time
+	// backoff - пауза сверх pollInterval, пока брокер отклоняет все события
+	backoff time.Duration
+}
+
+// NewRelay создает Relay. publishers сопоставляет тип агрегата с топиком
+func NewRelay(repo repository.OutboxRepository, lock leaderLock, publishers map[string]Publisher, cfg *Config) *Relay {
+	r := &Relay{
+		repo:            repo,
+		lock:            lock,
+		publishers:      publishers,
+		contentType:     cfg.ContentType,
+		source:          cfg.Source,
+		batchSize:       cfg.BatchSize,
+		pollInterval:    cfg.PollInterval,
+		retention:       cfg.Retention,
+		cleanupInterval: cfg.CleanupInterval,
+		maxAttempts:     cfg.MaxAttempts,
+	}
+	if r.batchSize <= 0 {
+		r.batchSize = defaultBatchSize
+	}
+	if r.pollInterval <= 0 {
+		r.pollInterval = defaultPollInterval
+	}
+	if r.retention <= 0 {
+		r.retention = defaultRetention
+	}
+	if r.cleanupInterval <= 0 {
+		r.cleanupInterval = defaultCleanupInterval
+	}
+	if r.maxAttempts <= 0 {
+		r.maxAttempts = defaultMaxAttempts
+	}
+	if r.source == "" {
+		r.source = defaultSource
+	}
+
+	return r
+}
+
+// Run работает до отмены ctx
+func (r *Relay) Run(ctx context.Context) error {
+	defer func() {
+		isLeader.Set(0)
+		if err := r.lock.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Outbox relay: %v", err)
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(r.pollInterval + r.backoff):
+		}
+
+		leader, err := r.lock.TryAcquire(ctx)
+		if err != nil {
+			log.Printf("Outbox relay: %v", err)
+			continue
+		}
+		if !leader {
+			isLeader.Set(0)
+			continue
+		}
+		isLeader.Set(1)
+
+		if err := r.tick(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Outbox relay: %v", err)
+		}
+	}
+}
+
+func (r *Relay) tick(ctx context.Context) error {
+	// Вычитываем outbox, пока публикуются полные пачки. Неполная пачка
+	// значит, что outbox пуст или агрегаты заблокированы ошибками: их
+	// повторяет следующий проход, а не этот цикл
+	for {
+		published, err := r.RelayBatch(ctx)
+		if err != nil {
+			return err
+		}
+		if published < r.batchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if time.Since(r.lastCleanup) >= r.cleanupInterval {
+		deleted, err := r.repo.DeleteSent(ctx, time.Now().Add(-r.retention))
+		if err != nil {
+			return err
+		}
+		r.lastCleanup = time.Now()
+		if deleted > 0 {
+			log.Printf("Outbox relay: deleted %d sent events", deleted)
+		}
+	}
+
+	return r.updateStats(ctx)
+}
+
+// RelayBatch публикует одну пачку событий и возвращает число опубликованных.
+// Событие, которое не удалось закодировать или которому не назначен топик,
+// учитывается в попытках, остальные события его агрегата в пачке
+// пропускаются, чтобы не нарушить порядок. Ошибка брокера останавливает
+// пачку без учета попыток: события будут повторены после паузы
+func (r *Relay) RelayBatch(ctx context.Context) (int, error) {
+	events, err := r.repo.FetchPending(ctx, r.batchSize)
+	if err != nil {
+		return 0, err
+	}
+	if len(events) == 0 {
+		r.backoff = 0
+		return 0, nil
+	}
+
+	blocked := make(map[string]bool)
+	sent := make([]int64, 0, len(events))
+	brokerFailed := false
+
+	for _, event := range events {
+		aggregate := event.AggregateType + ":" + strconv.FormatInt(event.AggregateID, 10)
+		if blocked[aggregate] {
+			continue
+		}
+
+		msg, err := r.encode(event)
+		if err != nil {
+			blocked[aggregate] = true
+			publishErrors.WithLabelValues(event.AggregateType).Inc()
+			log.Printf("Outbox relay: failed to encode event %d: %v", event.ID, err)
+			r.markFailed(ctx, event, err)
+			continue
+		}
+
+		if err := r.publish(ctx, msg); err != nil {
+			brokerFailed = true
+			publishErrors.WithLabelValues(event.AggregateType).Inc()
+			log.Printf("Outbox relay: failed to publish event %d: %v", event.ID, err)
+			break
+		}
+
+		sent = append(sent, event.ID)
+		publishedEvents.WithLabelValues(event.AggregateType).Inc()
+	}
+
+	r.updateBackoff(len(sent) > 0 && !brokerFailed)
+
+	if err := r.repo.MarkSent(ctx, sent); err != nil {
+		return 0, err
+	}
+
+	return len(sent), nil
+}
+
+// markFailed учитывает попытку события с неисправимой ошибкой
+func (r *Relay) markFailed(ctx context.Context, event *entity.OutboxEvent, cause error) {
+	failed, err := r.repo.MarkFailed(ctx, event.ID, cause.Error(), r.maxAttempts)
+	if err != nil {
+		log.Printf("Outbox relay: %v", err)
+		return
+	}
+	if failed {
+		failedEvents.WithLabelValues(event.AggregateType).Inc()
+		log.Printf("Outbox relay: event %d failed after %d attempts, skipped", event.ID, r.maxAttempts)
+	}
+}
+
+// updateBackoff удваивает паузу, пока не публикуется ни одно событие
+func (r *Relay) updateBackoff(progress bool) {
+	switch {
+	case progress:
+		r.backoff = 0
+	case r.backoff == 0:
+		r.backoff = r.pollInterval
+	default:
+		r.backoff = min(2*r.backoff, maxBackoff)
+	}
+}
+
+// message - событие, готовое к отправке в топик
+type message struct {
+	pub     Publisher
+	key     string
+	value   []byte
+	headers map[string]string
+}
+
+// encode переводит событие в сообщение топика. Ошибки кодирования повтор не исправит
+func (r *Relay) encode(event *entity.OutboxEvent) (*message, error) {
+	pub, ok := r.publishers[event.AggregateType]
+	if !ok {
+		return nil, fmt.Errorf("no topic for aggregate type %q", event.AggregateType)
+	}
+
+	env, err := toEnvelope(event, r.source)
+	if err != nil {
+		return nil, err
+	}
+
+	value, headers, err := events.Marshal(env, r.contentType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Ключ - id агрегата: события агрегата попадают в одну партицию
+	return &message{pub: pub, key: strconv.FormatInt(event.AggregateID, 10), value: value, headers: headers}, nil
+}
+
+func (r *Relay) publish(ctx context.Context, msg *message) error {
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+
+	return msg.pub.PublishRaw(ctx, msg.key, msg.value, msg.headers)
+}
+
+func (r *Relay) updateStats(ctx context.Context) error {
+	pending, oldest, err := r.repo.Stats(ctx)
+	if err != nil {
+		return err
+	}
+
+	pendingEvents.Set(float64(pending))
+	if pending == 0 {
+		oldestPendingAge.Set(0)
+	} else {
+		oldestPendingAge.Set(time.Since(oldest).Seconds())
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/pkg/events"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRelay_RelayBatch(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &memoryRepo{events: []*entity.OutboxEvent{
+		{ID: 1, AggregateType: entity.AggregatePost, AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+		{ID: 2, AggregateType: entity.AggregatePost, AggregateID: 20, EventType: entity.EventPostCreated, Payload: []byte("{")},
+		{ID: 3, AggregateType: entity.AggregatePost, AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+		{ID: 4, AggregateType: entity.AggregatePost, AggregateID: 20, EventType: entity.EventPostCreated, Payload: postPayload(20)},
+	}}
+	pub := &recordingPublisher{}
+	relay := NewRelay(repo, nil, map[string]Publisher{entity.AggregatePost: pub}, &Config{BatchSize: 10})
+
+	published, err := relay.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, published)
+
+	// Событие 2 не кодируется: следующее событие агрегата 20 не уходит раньше него
+	assert.Equal(t, []string{"social:1", "social:3"}, pub.eventIDs)
+	assert.ElementsMatch(t, []int64{1, 3}, repo.sent)
+	assert.Equal(t, []int64{2}, repo.failed)
+
+	// Событие исправлено - события агрегата уходят по порядку
+	repo.events[1].Payload = postPayload(20)
+	_, err = relay.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"social:1", "social:3", "social:2", "social:4"}, pub.eventIDs)
+}
+
+func TestRelay_EventIDSource(t *testing.T) {
+	repo := &memoryRepo{events: []*entity.OutboxEvent{
+		{ID: 1, AggregateType: entity.AggregatePost, AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+	}}
+	pub := &recordingPublisher{}
+	relay := NewRelay(repo, nil, map[string]Publisher{entity.AggregatePost: pub}, &Config{Source: "dialog"})
+
+	// ID outbox выделенной базы не пересекаются с ID общей
+	_, err := relay.RelayBatch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"dialog:1"}, pub.eventIDs)
+}
+
+func TestRelay_BrokerOutage(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &memoryRepo{events: []*entity.OutboxEvent{
+		{ID: 1, AggregateType: entity.AggregatePost, AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+		{ID: 2, AggregateType: entity.AggregatePost, AggregateID: 20, EventType: entity.EventPostCreated, Payload: postPayload(20)},
+		{ID: 3, AggregateType: entity.AggregatePost, AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+	}}
+	pub := &recordingPublisher{failKey: "20"}
+	relay := NewRelay(repo, nil, map[string]Publisher{entity.AggregatePost: pub},
+		&Config{BatchSize: 3, PollInterval: time.Second, MaxAttempts: 2})
+
+	// Ошибка брокера останавливает пачку, проход доходит до очистки
+	require.NoError(t, relay.tick(ctx))
+	assert.Equal(t, []string{"social:1"}, pub.eventIDs)
+	assert.Equal(t, 1, repo.cleanups)
+	assert.Equal(t, time.Second, relay.backoff)
+
+	// Долгий сбой не расходует попытки и не помечает события failed
+	for range 5 {
+		require.NoError(t, relay.tick(ctx))
+	}
+	assert.Empty(t, repo.failed)
+	assert.Empty(t, repo.dead)
+	assert.Equal(t, maxBackoff, relay.backoff)
+
+	// После восстановления события уходят по порядку, пауза сбрасывается
+	pub.failKey = ""
+	require.NoError(t, relay.tick(ctx))
+	assert.Equal(t, []string{"social:1", "social:2", "social:3"}, pub.eventIDs)
+	assert.Zero(t, relay.backoff)
+}
+
+func TestRelay_MaxAttempts(t *testing.T) {
+	ctx := context.Background()
+
+	repo := &memoryRepo{events: []*entity.OutboxEvent{
+		{ID: 1, AggregateType: "unknown", AggregateID: 10, EventType: entity.EventPostCreated, Payload: postPayload(10)},
+		{ID: 2, AggregateType: entity.AggregatePost, AggregateID: 20, EventType: entity.EventPostCreated, Payload: postPayload(20)},
+	}}
+	pub := &recordingPublisher{}
+	relay := NewRelay(repo, nil, map[string]Publisher{entity.AggregatePost: pub}, &Config{BatchSize: 10, MaxAttempts: 2})
+
+	_, err := relay.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, repo.dead)
+
+	// После последней попытки событие больше не читается
+	_, err = relay.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, []int64{1}, repo.dead)
+
+	published, err := relay.RelayBatch(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, published)
+	assert.Equal(t, []int64{1, 1}, repo.failed)
+	assert.Equal(t, []string{"social:2"}, pub.eventIDs)
+}
+
+type recordingPublisher struct {
+	failKey  string
+	eventIDs []string
+}
+
+func (p *recordingPublisher) PublishRaw(_ context.Context, key string, value []byte, headers map[string]string) error {
+	if key == p.failKey {
+		return errors.New("broker unavailable")
+	}
+
+	// Публикуется валидный конверт текущей версии
+	env, err := events.Unmarshal(value, headers)
+	if err != nil {
+		return err
+	}
+
+	p.eventIDs = append(p.eventIDs, env.GetEventId())
+	return nil
+}
+
+func postPayload(authorID int) []byte {
+	return []byte(fmt.Sprintf(`{"post_id":"p-%d","author_id":%d,"action":"create","text":"hi"}`, authorID, authorID))
+}
+
+type memoryRepo struct {
+	events   []*entity.OutboxEvent
+	sent     []int64
+	failed   []int64
+	dead     []int64
+	cleanups int
+}
+
+func (r *memoryRepo) Add(_ context.Context, event *entity.OutboxEvent) error {
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *memoryRepo) FetchPending(_ context.Context, limit int) ([]*entity.OutboxEvent, error) {
+	var pending []*entity.OutboxEvent
+	for _, e := range r.events {
+		if !r.isSent(e.ID) && !slices.Contains(r.dead, e.ID) && len(pending) < limit {
+			pending = append(pending, e)
+		}
+	}
+	return pending, nil
+}
+
+func (r *memoryRepo) MarkSent(_ context.Context, ids []int64) error {
+	r.sent = append(r.sent, ids...)
+	return nil
+}
+
+func (r *memoryRepo) MarkFailed(_ context.Context, id int64, _ string, maxAttempts int) (bool, error) {
+	r.failed = append(r.failed, id)
+	for _, e := range r.events {
+		if e.ID == id {
+			e.Attempts++
+			if e.Attempts >= maxAttempts {
+				r.dead = append(r.dead, id)
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepo) Stats(context.Context) (int64, time.Time, error) {
+	return 0, time.Time{}, nil
+}
+
+func (r *memoryRepo) DeleteSent(context.Context, time.Time) (int64, error) {
+	r.cleanups++
+	return 0, nil
+}
+
+func (r *memoryRepo) isSent(id int64) bool {
+	for _, s := range r.sent {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
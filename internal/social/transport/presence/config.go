@@ -0,0 +1,9 @@
+package presence
+
+import "time"
+
+type Config struct {
+	// Пользователь считается online, пока активность (запрос к API, pong
+	// websocket) была не раньше TTL назад. Должен быть больше интервала ping
+	TTL time.Duration `env:"PRESENCE_TTL" env-default:"90s"`
+}
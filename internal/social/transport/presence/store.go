@@ -0,0 +1,119 @@
+package presence
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	onlinePrefix   = "presence:online:"
+	lastSeenPrefix = "presence:last_seen:"
+
+	defaultTTL = 90 * time.Second
+)
+
+// touchScript продлевает online и запоминает время визита.
+// Возвращает 1, если пользователь только что стал online
+var touchScript = redis.NewScript(`
+local existed = redis.call('EXISTS', KEYS[1])
+redis.call('SET', KEYS[1], ARGV[1], 'EX', ARGV[2])
+redis.call('SET', KEYS[2], ARGV[1])
+return 1 - existed
+`)
+
+// Status - присутствие пользователя
+type Status struct {
+	UserID int
+	Online bool
+	// LastSeenAt - время последней активности, нулевое, если её не было
+	LastSeenAt time.Time
+}
+
+// Store хранит присутствие в Redis: ключ online истекает через TTL после
+// последней активности, время последнего визита хранится бессрочно
+type Store struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewStore(client *redis.Client, ttl time.Duration) *Store {
+	if ttl <= 0 {
+		ttl = defaultTTL
+	}
+
+	return &Store{client: client, ttl: ttl}
+}
+
+// Touch отмечает активность пользователя. Возвращает true, если до этого
+// пользователь был offline
+func (s *Store) Touch(ctx context.Context, userID int, at time.Time) (bool, error) {
+	cameOnline, err := touchScript.Run(ctx, s.client,
+		[]string{onlineKey(userID), lastSeenKey(userID)},
+		at.Unix(), int(s.ttl.Seconds()),
+	).Int()
+	if err != nil {
+		return false, fmt.Errorf("failed to touch presence of user %d: %w", userID, err)
+	}
+
+	return cameOnline == 1, nil
+}
+
+// SetOffline снимает статус online. Возвращает true, если пользователь был online
+func (s *Store) SetOffline(ctx context.Context, userID int, at time.Time) (bool, error) {
+	var del *redis.IntCmd
+	_, err := s.client.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		del = pipe.Del(ctx, onlineKey(userID))
+		pipe.Set(ctx, lastSeenKey(userID), at.Unix(), 0)
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to set user %d offline: %w", userID, err)
+	}
+
+	return del.Val() > 0, nil
+}
+
+// Get возвращает присутствие пользователей в порядке ids
+func (s *Store) Get(ctx context.Context, ids []int) ([]Status, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	keys := make([]string, 0, 2*len(ids))
+	for _, id := range ids {
+		keys = append(keys, onlineKey(id))
+	}
+	for _, id := range ids {
+		keys = append(keys, lastSeenKey(id))
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return nil, fmt.Errorf("failed to get presence: %w", err)
+	}
+
+	statuses := make([]Status, len(ids))
+	for i, id := range ids {
+		statuses[i] = Status{UserID: id, Online: values[i] != nil}
+		if raw, ok := values[len(ids)+i].(string); ok {
+			if unix, err := strconv.ParseInt(raw, 10, 64); err == nil {
+				statuses[i].LastSeenAt = time.Unix(unix, 0).UTC()
+			}
+		}
+	}
+
+	return statuses, nil
+}
+
+func onlineKey(userID int) string {
+	return onlinePrefix + strconv.Itoa(userID)
+}
+
+func lastSeenKey(userID int) string {
+	return lastSeenPrefix + strconv.Itoa(userID)
+}
@@ -0,0 +1,180 @@
+package presence
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/transport/websocket"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupRedis(t *testing.T, ctx context.Context) *redis.Client {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: endpoint})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+type fakeUsers struct {
+	friends    map[int][]int
+	visibility map[int]entity.Visibility
+}
+
+func (f *fakeUsers) GetFriendsIDs(_ context.Context, userID int) ([]int, error) {
+	return f.friends[userID], nil
+}
+
+func (f *fakeUsers) GetLastSeenVisibility(_ context.Context, ids []int) (map[int]entity.Visibility, error) {
+	result := make(map[int]entity.Visibility, len(ids))
+	for _, id := range ids {
+		if v, ok := f.visibility[id]; ok {
+			result[id] = v
+		}
+	}
+	return result, nil
+}
+
+type relayed struct {
+	userID  int
+	payload websocket.PresencePayload
+}
+
+type fakeRelayer struct {
+	mu     sync.Mutex
+	events []relayed
+}
+
+func (f *fakeRelayer) Relay(_ context.Context, userID int, _ string, payload interface{}) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, relayed{userID: userID, payload: payload.(websocket.PresencePayload)})
+	return nil
+}
+
+func (f *fakeRelayer) take() []relayed {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	events := f.events
+	f.events = nil
+	return events
+}
+
+type fakeConnections map[int][]string
+
+func (f fakeConnections) Nodes(_ context.Context, userID int) ([]string, error) {
+	return f[userID], nil
+}
+
+func TestStore_TouchExpiresAfterTTL(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(setupRedis(t, ctx), time.Second)
+
+	at := time.Unix(1_700_000_000, 0).UTC()
+	cameOnline, err := store.Touch(ctx, 1, at)
+	require.NoError(t, err)
+	assert.True(t, cameOnline)
+
+	cameOnline, err = store.Touch(ctx, 1, at.Add(time.Second))
+	require.NoError(t, err)
+	assert.False(t, cameOnline, "повторная активность не меняет статус")
+
+	statuses, err := store.Get(ctx, []int{1, 2})
+	require.NoError(t, err)
+	assert.Equal(t, []Status{
+		{UserID: 1, Online: true, LastSeenAt: at.Add(time.Second)},
+		{UserID: 2},
+	}, statuses)
+
+	// Без активности online истекает, время визита остается
+	require.Eventually(t, func() bool {
+		statuses, err := store.Get(ctx, []int{1})
+		return err == nil && !statuses[0].Online
+	}, 5*time.Second, 100*time.Millisecond)
+
+	statuses, err = store.Get(ctx, []int{1})
+	require.NoError(t, err)
+	assert.Equal(t, at.Add(time.Second), statuses[0].LastSeenAt)
+}
+
+func TestTracker_NotifiesFriends(t *testing.T) {
+	ctx := context.Background()
+	store := NewStore(setupRedis(t, ctx), time.Minute)
+	users := &fakeUsers{
+		friends: map[int][]int{1: {2, 3}, 4: {2}},
+		visibility: map[int]entity.Visibility{
+			1: entity.VisibilityFriends,
+			4: entity.VisibilityNobody,
+		},
+	}
+	router := &fakeRelayer{}
+	registry := fakeConnections{}
+	tracker := NewTracker(store, users, router, registry)
+
+	tracker.Online(1)
+	events := router.take()
+	require.Len(t, events, 2)
+	assert.ElementsMatch(t, []int{2, 3}, []int{events[0].userID, events[1].userID})
+	assert.True(t, events[0].payload.Online)
+	assert.Equal(t, 1, events[0].payload.UserID)
+
+	// Повторная активность не рассылается
+	tracker.Online(1)
+	assert.Empty(t, router.take())
+
+	// Пока соединение открыто на другом узле, пользователь остается online
+	registry[1] = []string{"node-b"}
+	tracker.Offline(1)
+	assert.Empty(t, router.take())
+
+	delete(registry, 1)
+	tracker.Offline(1)
+	events = router.take()
+	require.Len(t, events, 2)
+	assert.False(t, events[0].payload.Online)
+
+	// Скрывший присутствие пользователь не рассылает изменения
+	tracker.Online(4)
+	assert.Empty(t, router.take())
+}
+
+func TestTracker_ThrottlesActivity(t *testing.T) {
+	tracker := NewTracker(NewStore(nil, time.Minute), &fakeUsers{}, &fakeRelayer{}, fakeConnections{})
+	now := time.Unix(1_700_000_000, 0)
+
+	assert.True(t, tracker.takeActivity(1, now))
+	assert.False(t, tracker.takeActivity(1, now.Add(29*time.Second)), "чаще TTL/2 присутствие не продлевается")
+	assert.True(t, tracker.takeActivity(2, now.Add(29*time.Second)), "интервал считается по пользователю")
+	assert.True(t, tracker.takeActivity(1, now.Add(30*time.Second)))
+
+	// Неудавшуюся запись повторяет следующий запрос
+	tracker.forgetActivity(1)
+	assert.True(t, tracker.takeActivity(1, now.Add(31*time.Second)))
+
+	// Старые отметки удаляются
+	assert.True(t, tracker.takeActivity(3, now.Add(2*time.Minute)))
+	assert.Len(t, tracker.activeAt, 1)
+}
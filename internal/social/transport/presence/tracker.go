@@ -0,0 +1,208 @@
+package presence
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/transport/websocket"
+)
+
+const (
+	trackTimeout = 5 * time.Second
+
+	// activityWorkers ограничивает число одновременных записей активности из API
+	activityWorkers = 64
+)
+
+type userRepository interface {
+	GetFriendsIDs(ctx context.Context, userID int) ([]int, error)
+	GetLastSeenVisibility(ctx context.Context, ids []int) (map[int]entity.Visibility, error)
+}
+
+// relayer доставляет эфемерные события в открытые соединения пользователя
+type relayer interface {
+	Relay(ctx context.Context, userID int, eventType string, payload interface{}) error
+}
+
+// connections сообщает, на каких узлах открыты websocket-соединения пользователя
+type connections interface {
+	Nodes(ctx context.Context, userID int) ([]string, error)
+}
+
+// Tracker обновляет присутствие по активности пользователя и рассылает
+// изменения статуса друзьям, которые сейчас подключены
+type Tracker struct {
+	store    *Store
+	users    userRepository
+	router   relayer
+	registry connections
+
+	// Активность из API пишется не чаще раза в activityInterval на пользователя
+	activityInterval time.Duration
+	activitySlots    chan struct{}
+	activityMu       sync.Mutex
+	activeAt         map[int]time.Time
+	sweptAt          time.Time
+}
+
+// NewTracker создает трекер. registry может быть nil: тогда отключение
+// последнего соединения на узле сразу переводит пользователя в offline
+func NewTracker(store *Store, users userRepository, router relayer, registry connections) *Tracker {
+	return &Tracker{
+		store:    store,
+		users:    users,
+		router:   router,
+		registry: registry,
+
+		activityInterval: store.ttl / 2,
+		activitySlots:    make(chan struct{}, activityWorkers),
+		activeAt:         make(map[int]time.Time),
+	}
+}
+
+// Active отмечает активность из запроса к API и не блокирует запрос.
+// Присутствие продлевается не чаще раза в TTL/2, записи идут не более чем в
+// activityWorkers горутинах, а без свободной горутины отметку повторит
+// следующий запрос.
+//
+// Запросы к API только продлевают присутствие: offline ставит закрытие
+// последнего соединения, а у пользователя без соединений ключ online просто
+// истекает через TTL, и друзья не получают события об этом
+func (t *Tracker) Active(userID int) {
+	if !t.takeActivity(userID, time.Now()) {
+		return
+	}
+
+	select {
+	case t.activitySlots <- struct{}{}:
+	default:
+		t.forgetActivity(userID)
+		return
+	}
+
+	go func() {
+		defer func() { <-t.activitySlots }()
+
+		if !t.touch(userID) {
+			t.forgetActivity(userID)
+		}
+	}()
+}
+
+// takeActivity решает, пора ли снова продлить присутствие пользователя,
+// и заодно удаляет отметки старше интервала
+func (t *Tracker) takeActivity(userID int, now time.Time) bool {
+	t.activityMu.Lock()
+	defer t.activityMu.Unlock()
+
+	if now.Sub(t.sweptAt) >= t.activityInterval {
+		for id, at := range t.activeAt {
+			if now.Sub(at) >= t.activityInterval {
+				delete(t.activeAt, id)
+			}
+		}
+		t.sweptAt = now
+	}
+
+	if at, ok := t.activeAt[userID]; ok && now.Sub(at) < t.activityInterval {
+		return false
+	}
+	t.activeAt[userID] = now
+
+	return true
+}
+
+// forgetActivity снимает отметку, чтобы неудавшуюся запись повторил
+// следующий запрос
+func (t *Tracker) forgetActivity(userID int) {
+	t.activityMu.Lock()
+	defer t.activityMu.Unlock()
+
+	delete(t.activeAt, userID)
+}
+
+// Online отмечает активность пользователя в websocket: подключение или heartbeat
+func (t *Tracker) Online(userID int) {
+	t.touch(userID)
+}
+
+// touch продлевает присутствие и рассылает переход в online.
+// Возвращает false, если присутствие не записано
+func (t *Tracker) touch(userID int) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), trackTimeout)
+	defer cancel()
+
+	now := time.Now()
+	cameOnline, err := t.store.Touch(ctx, userID, now)
+	if err != nil {
+		log.Printf("Presence: %v", err)
+		return false
+	}
+
+	if cameOnline {
+		t.notify(ctx, userID, true, now)
+	}
+
+	return true
+}
+
+// Offline вызывается при закрытии последнего соединения пользователя на узле
+func (t *Tracker) Offline(userID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), trackTimeout)
+	defer cancel()
+
+	// Соединения на других узлах держат пользователя online
+	if t.registry != nil {
+		nodes, err := t.registry.Nodes(ctx, userID)
+		if err != nil {
+			log.Printf("Presence: %v", err)
+			return
+		}
+		if len(nodes) > 0 {
+			return
+		}
+	}
+
+	now := time.Now()
+	wasOnline, err := t.store.SetOffline(ctx, userID, now)
+	if err != nil {
+		log.Printf("Presence: %v", err)
+		return
+	}
+
+	if wasOnline {
+		t.notify(ctx, userID, false, now)
+	}
+}
+
+func (t *Tracker) notify(ctx context.Context, userID int, online bool, at time.Time) {
+	visibility, err := t.users.GetLastSeenVisibility(ctx, []int{userID})
+	if err != nil {
+		log.Printf("Presence: %v", err)
+		return
+	}
+	// Друзьям статус виден при любой настройке, кроме nobody
+	if v, ok := visibility[userID]; !ok || v == entity.VisibilityNobody {
+		return
+	}
+
+	friendIDs, err := t.users.GetFriendsIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Presence: failed to get friends of user %d: %v", userID, err)
+		return
+	}
+
+	payload := websocket.PresencePayload{
+		UserID:     userID,
+		Online:     online,
+		LastSeenAt: at.Unix(),
+	}
+	for _, friendID := range friendIDs {
+		if err := t.router.Relay(ctx, friendID, websocket.EventPresenceChanged, payload); err != nil {
+			log.Printf("Presence: failed to notify user %d: %v", friendID, err)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var deprecatedRequestsTotal = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_deprecated_requests_total",
+		Help: "Total number of requests to deprecated routes",
+	},
+	[]string{"method", "path"},
+)
+
+func init() {
+	prometheus.MustRegister(deprecatedRequestsTotal)
+}
+
+// Deprecation - даты вывода версии API из эксплуатации
+type Deprecation struct {
+	// DeprecatedAt - с какого момента версия устарела
+	DeprecatedAt time.Time
+	// Sunset - после какого момента версия может быть удалена
+	Sunset time.Time
+	// Successor - путь версии, которая ее заменяет
+	Successor string
+}
+
+// DeprecationMiddleware помечает ответы устаревших ручек заголовками
+// Deprecation (RFC 9745), Sunset (RFC 8594) и Link на замену и считает
+// обращения к каждой ручке, чтобы видеть, когда ее можно удалить
+func DeprecationMiddleware(d Deprecation) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		deprecatedRequestsTotal.WithLabelValues(c.Request.Method, c.FullPath()).Inc()
+
+		header := c.Writer.Header()
+		if !d.DeprecatedAt.IsZero() {
+			header.Set("Deprecation", "@"+strconv.FormatInt(d.DeprecatedAt.Unix(), 10))
+		}
+		if !d.Sunset.IsZero() {
+			header.Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+		}
+		if d.Successor != "" {
+			header.Set("Link", "<"+d.Successor+`>; rel="successor-version"`)
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,207 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"slices"
+	"strconv"
+	"strings"
+
+	"otus-highload-arh-homework/pkg/auth"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// TokenValidator проверяет токен пользователя и возвращает его ID
+type TokenValidator interface {
+	ValidateToken(token string) (int, error)
+}
+
+// Auth - проверка вызывающих. Пользователь, от имени которого выполняется
+// вызов, берется из учетных данных, а не из полей запроса:
+//   - из токена пользователя в метаданных authorization;
+//   - у клиента с сертификатом, подписанным CA, с именем (CN или DNS SAN)
+//     из TrustedClients - из метаданных x-user-id.
+//
+// Без TLS сертификаты не проверяются и принимаются только токены
+type Auth struct {
+	Tokens         TokenValidator
+	TLS            *tls.Config
+	TrustedClients []string
+}
+
+// publicMethodPrefix - методы без проверки: health-check оркестратора
+const publicMethodPrefix = "/grpc.health.v1.Health/"
+
+type actingUserKey struct{}
+
+type authenticator struct {
+	tokens         TokenValidator
+	trustedClients []string
+}
+
+func newAuthenticator(a Auth) *authenticator {
+	return &authenticator{tokens: a.Tokens, trustedClients: a.TrustedClients}
+}
+
+// authenticate возвращает пользователя из учетных данных вызова
+func (a *authenticator) authenticate(ctx context.Context) (int64, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if token := firstValue(md, auth.AuthorizationMetadata); token != "" {
+		if a.tokens == nil {
+			return 0, status.Error(codes.Unauthenticated, "tokens are not accepted")
+		}
+		userID, err := a.tokens.ValidateToken(strings.TrimPrefix(token, "Bearer "))
+		if err != nil || userID <= 0 {
+			return 0, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return int64(userID), nil
+	}
+
+	names, ok := clientNames(ctx)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "user token or client certificate required")
+	}
+	if !slices.ContainsFunc(names, func(name string) bool { return slices.Contains(a.trustedClients, name) }) {
+		return 0, status.Error(codes.PermissionDenied, "client is not allowed to act for users")
+	}
+
+	userID, err := strconv.ParseInt(firstValue(md, auth.UserIDMetadata), 10, 64)
+	if err != nil || userID <= 0 {
+		return 0, status.Error(codes.Unauthenticated, "x-user-id required")
+	}
+
+	return userID, nil
+}
+
+func (a *authenticator) unary(
+	ctx context.Context,
+	req interface{},
+	info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler,
+) (resp interface{}, err error) {
+	if strings.HasPrefix(info.FullMethod, publicMethodPrefix) {
+		return handler(ctx, req)
+	}
+
+	userID, err := a.authenticate(ctx)
+	if err != nil {
+		logDenied(ctx, info.FullMethod, err)
+		return nil, err
+	}
+	if err := checkRequestUser(req, userID); err != nil {
+		logDenied(ctx, info.FullMethod, err)
+		return nil, err
+	}
+
+	return handler(context.WithValue(ctx, actingUserKey{}, userID), req)
+}
+
+func (a *authenticator) stream(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	if strings.HasPrefix(info.FullMethod, publicMethodPrefix) {
+		return handler(srv, ss)
+	}
+
+	userID, err := a.authenticate(ss.Context())
+	if err != nil {
+		logDenied(ss.Context(), info.FullMethod, err)
+		return err
+	}
+
+	return handler(srv, &authStream{
+		ServerStream: &contextStream{
+			ServerStream: ss,
+			ctx:          context.WithValue(ss.Context(), actingUserKey{}, userID),
+		},
+		userID: userID,
+	})
+}
+
+// authStream проверяет поле пользователя в запросах потока
+type authStream struct {
+	grpc.ServerStream
+	userID int64
+}
+
+func (s *authStream) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return checkRequestUser(m, s.userID)
+}
+
+// actingUser возвращает пользователя, проверенного перехватчиком
+func actingUser(ctx context.Context) (int64, error) {
+	userID, ok := ctx.Value(actingUserKey{}).(int64)
+	if !ok {
+		return 0, status.Error(codes.Unauthenticated, "unauthenticated")
+	}
+
+	return userID, nil
+}
+
+// checkRequestUser отклоняет запрос, в котором user_id или sender_id
+// указывает не на пользователя из учетных данных. Пустое поле допустимо
+func checkRequestUser(req interface{}, userID int64) error {
+	var claimed string
+	switch r := req.(type) {
+	case interface{ GetUserId() string }:
+		claimed = r.GetUserId()
+	case interface{ GetSenderId() string }:
+		claimed = r.GetSenderId()
+	}
+
+	if claimed != "" && claimed != strconv.FormatInt(userID, 10) {
+		return status.Error(codes.PermissionDenied, "request user does not match credentials")
+	}
+
+	return nil
+}
+
+// clientNames возвращает CN и DNS SAN проверенного сертификата клиента
+func clientNames(ctx context.Context) ([]string, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return nil, false
+	}
+
+	cert := info.State.VerifiedChains[0][0]
+	names := slices.DeleteFunc(append([]string{cert.Subject.CommonName}, cert.DNSNames...), func(name string) bool {
+		return name == ""
+	})
+
+	return names, true
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func logDenied(ctx context.Context, method string, err error) {
+	fields := logrus.Fields{"method": method}
+	if requestID, ok := ctx.Value("x-request-id").(string); ok {
+		fields["x-request-id"] = requestID
+	}
+
+	logrus.WithFields(fields).WithError(err).Warn("gRPC call denied")
+}
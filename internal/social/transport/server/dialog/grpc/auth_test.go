@@ -0,0 +1,184 @@
+package grpc
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"errors"
+	"testing"
+
+	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeTokens map[string]int
+
+func (f fakeTokens) ValidateToken(token string) (int, error) {
+	if userID, ok := f[token]; ok {
+		return userID, nil
+	}
+	return 0, errors.New("invalid token")
+}
+
+// withClientCert - вызов по mTLS от клиента с проверенным сертификатом name
+func withClientCert(ctx context.Context, name string) context.Context {
+	cert := &x509.Certificate{Subject: pkix.Name{CommonName: name}}
+	return peer.NewContext(ctx, &peer.Peer{AuthInfo: credentials.TLSInfo{
+		State: tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}},
+	}})
+}
+
+func withMetadata(ctx context.Context, kv ...string) context.Context {
+	return metadata.NewIncomingContext(ctx, metadata.Pairs(kv...))
+}
+
+func TestAuthenticator_Unary(t *testing.T) {
+	a := newAuthenticator(Auth{Tokens: fakeTokens{"token-1": 1}, TrustedClients: []string{"app"}})
+	info := &grpc.UnaryServerInfo{FullMethod: dialogv1.DialogService_GetMessages_FullMethodName}
+
+	cases := []struct {
+		name string
+		ctx  context.Context
+		req  interface{}
+		code codes.Code
+		user int64
+	}{
+		{
+			name: "user token",
+			ctx:  withMetadata(context.Background(), "authorization", "Bearer token-1"),
+			req:  &dialogv1.GetMessagesRequest{OtherUserId: "2"},
+			user: 1,
+		},
+		{
+			name: "user token with matching user_id",
+			ctx:  withMetadata(context.Background(), "authorization", "token-1"),
+			req:  &dialogv1.GetMessagesRequest{UserId: "1", OtherUserId: "2"},
+			user: 1,
+		},
+		{
+			name: "user_id of another user",
+			ctx:  withMetadata(context.Background(), "authorization", "Bearer token-1"),
+			req:  &dialogv1.GetMessagesRequest{UserId: "2", OtherUserId: "3"},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "sender_id of another user",
+			ctx:  withMetadata(context.Background(), "authorization", "Bearer token-1"),
+			req:  &dialogv1.SendMessageRequest{SenderId: "2", ReceiverId: "3"},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "invalid token",
+			ctx:  withMetadata(context.Background(), "authorization", "Bearer forged"),
+			req:  &dialogv1.GetMessagesRequest{},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "no credentials",
+			ctx:  withMetadata(context.Background(), "x-user-id", "1"),
+			req:  &dialogv1.GetMessagesRequest{UserId: "1"},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "trusted client acts for user",
+			ctx:  withClientCert(withMetadata(context.Background(), "x-user-id", "7"), "app"),
+			req:  &dialogv1.GetMessagesRequest{OtherUserId: "2"},
+			user: 7,
+		},
+		{
+			name: "trusted client without user",
+			ctx:  withClientCert(context.Background(), "app"),
+			req:  &dialogv1.GetMessagesRequest{UserId: "7"},
+			code: codes.Unauthenticated,
+		},
+		{
+			name: "untrusted client",
+			ctx:  withClientCert(withMetadata(context.Background(), "x-user-id", "7"), "crawler"),
+			req:  &dialogv1.GetMessagesRequest{},
+			code: codes.PermissionDenied,
+		},
+		{
+			name: "client without name",
+			ctx:  withClientCert(withMetadata(context.Background(), "x-user-id", "7"), ""),
+			req:  &dialogv1.GetMessagesRequest{},
+			code: codes.PermissionDenied,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var got int64
+			_, err := a.unary(tc.ctx, tc.req, info, func(ctx context.Context, _ interface{}) (interface{}, error) {
+				var err error
+				got, err = actingUser(ctx)
+				return nil, err
+			})
+
+			assert.Equal(t, tc.code, status.Code(err), err)
+			assert.Equal(t, tc.user, got)
+		})
+	}
+}
+
+func TestAuthenticator_HealthIsPublic(t *testing.T) {
+	a := newAuthenticator(Auth{Tokens: fakeTokens{}})
+	info := &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}
+
+	called := false
+	_, err := a.unary(context.Background(), nil, info, func(context.Context, interface{}) (interface{}, error) {
+		called = true
+		return nil, nil
+	})
+	require.NoError(t, err)
+	assert.True(t, called)
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	userID string
+}
+
+func (f *fakeServerStream) Context() context.Context {
+	return f.ctx
+}
+
+func (f *fakeServerStream) RecvMsg(m interface{}) error {
+	m.(*dialogv1.StreamMessagesRequest).UserId = f.userID
+	return nil
+}
+
+func TestAuthenticator_Stream(t *testing.T) {
+	a := newAuthenticator(Auth{Tokens: fakeTokens{"token-1": 1}})
+	info := &grpc.StreamServerInfo{FullMethod: dialogv1.DialogService_StreamMessages_FullMethodName, IsServerStream: true}
+	ctx := withMetadata(context.Background(), "authorization", "Bearer token-1")
+
+	handler := func(_ interface{}, ss grpc.ServerStream) error {
+		var req dialogv1.StreamMessagesRequest
+		if err := ss.RecvMsg(&req); err != nil {
+			return err
+		}
+		userID, err := actingUser(ss.Context())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), userID)
+		return nil
+	}
+
+	err := a.stream(nil, &fakeServerStream{ctx: ctx}, info, handler)
+	require.NoError(t, err)
+
+	err = a.stream(nil, &fakeServerStream{ctx: ctx, userID: "2"}, info, handler)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	err = a.stream(nil, &fakeServerStream{ctx: context.Background()}, info, handler)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
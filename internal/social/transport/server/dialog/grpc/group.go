@@ -0,0 +1,304 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/usecase/user"
+	"otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var groupRoles = map[entity.GroupRole]dialogv1.GroupRole{
+	entity.GroupRoleAdmin:  dialogv1.GroupRole_GROUP_ROLE_ADMIN,
+	entity.GroupRoleMember: dialogv1.GroupRole_GROUP_ROLE_MEMBER,
+}
+
+func (s *DialogService) CreateGroup(ctx context.Context, req *dialogv1.CreateGroupRequest) (*dialogv1.CreateGroupResponse, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := parseIDs(req.MemberIds)
+	if err != nil {
+		return nil, err
+	}
+
+	group, err := s.groups.CreateGroup(ctx, userID, req.Title, memberIDs)
+	if err != nil {
+		return nil, groupError(err, "failed to create group")
+	}
+
+	return &dialogv1.CreateGroupResponse{Group: toPBGroup(group)}, nil
+}
+
+func (s *DialogService) ListGroups(ctx context.Context, req *dialogv1.ListGroupsRequest) (*dialogv1.ListGroupsResponse, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := parseCursor(req.Cursor)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	page, err := s.groups.ListGroups(ctx, userID, cursor, int(req.Limit))
+	if err != nil {
+		return nil, groupError(err, "failed to list groups")
+	}
+
+	pbGroups := make([]*dialogv1.Group, 0, len(page.Groups))
+	for _, group := range page.Groups {
+		pbGroups = append(pbGroups, toPBGroup(group))
+	}
+
+	return &dialogv1.ListGroupsResponse{
+		Groups:     pbGroups,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+func (s *DialogService) ListGroupMembers(ctx context.Context, req *dialogv1.ListGroupMembersRequest) (*dialogv1.ListGroupMembersResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	members, err := s.groups.ListMembers(ctx, userID, groupID)
+	if err != nil {
+		return nil, groupError(err, "failed to list group members")
+	}
+
+	pbMembers := make([]*dialogv1.GroupMember, 0, len(members))
+	for _, member := range members {
+		pbMembers = append(pbMembers, &dialogv1.GroupMember{
+			UserId:   strconv.FormatInt(member.UserID, 10),
+			Role:     groupRoles[member.Role],
+			JoinedAt: timestamppb.New(member.JoinedAt),
+		})
+	}
+
+	return &dialogv1.ListGroupMembersResponse{Members: pbMembers}, nil
+}
+
+func (s *DialogService) AddGroupMembers(ctx context.Context, req *dialogv1.AddGroupMembersRequest) (*dialogv1.AddGroupMembersResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs, err := parseIDs(req.MemberIds)
+	if err != nil {
+		return nil, err
+	}
+
+	added, err := s.groups.AddMembers(ctx, userID, groupID, memberIDs)
+	if err != nil {
+		return nil, groupError(err, "failed to add group members")
+	}
+
+	addedIDs := make([]string, 0, len(added))
+	for _, id := range added {
+		addedIDs = append(addedIDs, strconv.FormatInt(id, 10))
+	}
+
+	return &dialogv1.AddGroupMembersResponse{AddedIds: addedIDs}, nil
+}
+
+func (s *DialogService) RemoveGroupMember(ctx context.Context, req *dialogv1.RemoveGroupMemberRequest) (*dialogv1.RemoveGroupMemberResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	memberID, err := parseID(req.MemberId, "member ID")
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.groups.RemoveMember(ctx, userID, groupID, memberID); err != nil {
+		return nil, groupError(err, "failed to remove group member")
+	}
+
+	return &dialogv1.RemoveGroupMemberResponse{}, nil
+}
+
+func (s *DialogService) SetGroupMemberRole(ctx context.Context, req *dialogv1.SetGroupMemberRoleRequest) (*dialogv1.SetGroupMemberRoleResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	memberID, err := parseID(req.MemberId, "member ID")
+	if err != nil {
+		return nil, err
+	}
+
+	// Неизвестная роль остается пустой и отклоняется use case
+	var role entity.GroupRole
+	switch req.Role {
+	case dialogv1.GroupRole_GROUP_ROLE_ADMIN:
+		role = entity.GroupRoleAdmin
+	case dialogv1.GroupRole_GROUP_ROLE_MEMBER:
+		role = entity.GroupRoleMember
+	}
+
+	if err := s.groups.SetMemberRole(ctx, userID, groupID, memberID, role); err != nil {
+		return nil, groupError(err, "failed to set group member role")
+	}
+
+	return &dialogv1.SetGroupMemberRoleResponse{}, nil
+}
+
+func (s *DialogService) LeaveGroup(ctx context.Context, req *dialogv1.LeaveGroupRequest) (*dialogv1.LeaveGroupResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.groups.LeaveGroup(ctx, userID, groupID); err != nil {
+		return nil, groupError(err, "failed to leave group")
+	}
+
+	return &dialogv1.LeaveGroupResponse{}, nil
+}
+
+func (s *DialogService) SendGroupMessage(ctx context.Context, req *dialogv1.SendGroupMessageRequest) (*dialogv1.SendMessageResponse, error) {
+	senderID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	sent, err := s.groups.SendGroupMessage(ctx, senderID, groupID, req.Text, req.ClientMessageId)
+	if err != nil {
+		return nil, groupError(err, "failed to send group message")
+	}
+
+	return &dialogv1.SendMessageResponse{
+		Success:   true,
+		MessageId: strconv.FormatInt(sent.ID, 10),
+		SentAt:    timestamppb.New(sent.SentAt),
+		Duplicate: sent.Duplicate,
+	}, nil
+}
+
+func (s *DialogService) GetGroupMessages(ctx context.Context, req *dialogv1.GetGroupMessagesRequest) (*dialogv1.GetGroupMessagesResponse, error) {
+	userID, groupID, err := parseGroupRef(ctx, req.GroupId)
+	if err != nil {
+		return nil, err
+	}
+
+	before, err := parseCursor(req.Before)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid before cursor")
+	}
+
+	after, err := parseCursor(req.After)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid after cursor")
+	}
+
+	page, err := s.groups.GetGroupMessages(ctx, userID, groupID, entity.MessagesQuery{
+		Before: before,
+		After:  after,
+		Limit:  int(req.Limit),
+	})
+	if err != nil {
+		return nil, groupError(err, "failed to get group messages")
+	}
+
+	pbMessages := make([]*dialogv1.GroupMessage, 0, len(page.Messages))
+	for _, msg := range page.Messages {
+		pbMessages = append(pbMessages, &dialogv1.GroupMessage{
+			MessageId: strconv.FormatInt(msg.ID, 10),
+			GroupId:   strconv.FormatInt(msg.GroupID, 10),
+			SenderId:  strconv.FormatInt(msg.SenderID, 10),
+			Text:      msg.Text,
+			SentAt:    timestamppb.New(msg.SentAt),
+		})
+	}
+
+	return &dialogv1.GetGroupMessagesResponse{
+		Messages:   pbMessages,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+// groupError переводит ошибки групповых чатов в статусы gRPC. Группа, в
+// которой пользователь не состоит, для него не существует
+func groupError(err error, msg string) error {
+	switch {
+	case errors.Is(err, user.ErrGroupNotFound),
+		errors.Is(err, user.ErrGroupMemberNotFound),
+		errors.Is(err, user.ErrUserNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, user.ErrNotGroupAdmin):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, user.ErrLastGroupAdmin):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.Is(err, user.ErrInvalidGroupTitle),
+		errors.Is(err, user.ErrInvalidGroupRole),
+		errors.Is(err, user.ErrTooManyGroupMembers),
+		errors.Is(err, user.ErrInvalidCursor),
+		errors.Is(err, user.ErrEmptyMessage),
+		errors.Is(err, user.ErrInvalidClientMessageID),
+		errors.Is(err, user.ErrSelfOperation):
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	return status.Error(codes.Internal, msg)
+}
+
+func toPBGroup(group *entity.Group) *dialogv1.Group {
+	return &dialogv1.Group{
+		GroupId:     strconv.FormatInt(group.ID, 10),
+		Title:       group.Title,
+		CreatedBy:   strconv.FormatInt(group.CreatedBy, 10),
+		CreatedAt:   timestamppb.New(group.CreatedAt),
+		Role:        groupRoles[group.Role],
+		MemberCount: int32(group.MemberCount),
+	}
+}
+
+func parseGroupRef(ctx context.Context, groupID string) (int64, int64, error) {
+	uid, err := actingUser(ctx)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	gid, err := parseID(groupID, "group ID")
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uid, gid, nil
+}
+
+func parseID(value, name string) (int64, error) {
+	id, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || id <= 0 {
+		return 0, status.Error(codes.InvalidArgument, "invalid "+name)
+	}
+
+	return id, nil
+}
+
+func parseIDs(values []string) ([]int64, error) {
+	ids := make([]int64, 0, len(values))
+	for _, value := range values {
+		id, err := parseID(value, "member ID")
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+
+	return ids, nil
+}
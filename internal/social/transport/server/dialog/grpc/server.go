@@ -3,43 +3,87 @@ package grpc
 import (
 	"context"
 	"net"
+	"time"
 
 	userUC "otus-highload-arh-homework/internal/social/usecase/user"
 	"otus-highload-arh-homework/pkg/proto/dialog/v1"
 
 	"github.com/sirupsen/logrus"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/health"
 	"google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/keepalive"
 	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/reflection"
 )
 
+const (
+	defaultKeepaliveTime    = 30 * time.Second
+	defaultKeepaliveTimeout = 10 * time.Second
+	// minClientPingInterval - клиенты, пингующие чаще, отключаются
+	minClientPingInterval = 10 * time.Second
+)
+
+// Keepalive - проверка живости соединений. Сервер пингует соединение,
+// простаивающее Time, и закрывает его, если ответа нет дольше Timeout:
+// так освобождаются потоки сообщений клиентов, пропавших без закрытия
+type Keepalive struct {
+	Time    time.Duration
+	Timeout time.Duration
+}
+
 type Server struct {
 	server       *grpc.Server
 	lis          net.Listener
 	healthServer *health.Server
 }
 
-func New(uc *userUC.UserUseCase, port string) (*Server, error) {
+func New(uc *userUC.DialogUseCase, groups *userUC.GroupUseCase, port string, ka Keepalive, a Auth) (*Server, error) {
 	lis, err := net.Listen("tcp", port)
 	if err != nil {
 		return nil, err
 	}
 
-	srv := grpc.NewServer(
+	if ka.Time <= 0 {
+		ka.Time = defaultKeepaliveTime
+	}
+	if ka.Timeout <= 0 {
+		ka.Timeout = defaultKeepaliveTimeout
+	}
+
+	authn := newAuthenticator(a)
+	opts := []grpc.ServerOption{
 		grpc.ChainUnaryInterceptor(
 			requestIDInterceptor,
 			loggingInterceptor,
+			authn.unary,
+		),
+		grpc.ChainStreamInterceptor(
+			streamRequestIDInterceptor,
+			streamLoggingInterceptor,
+			authn.stream,
 		),
-	)
+		grpc.KeepaliveParams(keepalive.ServerParameters{
+			Time:    ka.Time,
+			Timeout: ka.Timeout,
+		}),
+		grpc.KeepaliveEnforcementPolicy(keepalive.EnforcementPolicy{
+			MinTime:             minClientPingInterval,
+			PermitWithoutStream: true,
+		}),
+	}
+	if a.TLS != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(a.TLS)))
+	}
+	srv := grpc.NewServer(opts...)
 
 	healthServer := health.NewServer()
 	healthServer.SetServingStatus("", grpc_health_v1.HealthCheckResponse_SERVING)
 	grpc_health_v1.RegisterHealthServer(srv, healthServer)
 
 	// Регистрируем сервис
-	dialogv1.RegisterDialogServiceServer(srv, NewDialogService(uc))
+	dialogv1.RegisterDialogServiceServer(srv, NewDialogService(uc, groups))
 
 	// Для разработки - reflection API
 	reflection.Register(srv)
@@ -107,3 +151,65 @@ func requestIDInterceptor(
 	// Продолжаем выполнение
 	return handler(ctx, req)
 }
+
+func streamLoggingInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	fields := logrus.Fields{
+		"method": info.FullMethod,
+	}
+	if requestID, ok := ss.Context().Value("x-request-id").(string); ok {
+		fields["x-request-id"] = requestID
+	}
+
+	logrus.WithFields(fields).Info("gRPC stream opened")
+	start := time.Now()
+
+	err := handler(srv, ss)
+
+	fields["duration"] = time.Since(start).String()
+	logrus.WithFields(fields).WithError(err).Info("gRPC stream closed")
+
+	return err
+}
+
+func streamRequestIDInterceptor(
+	srv interface{},
+	ss grpc.ServerStream,
+	info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler,
+) error {
+	md, ok := metadata.FromIncomingContext(ss.Context())
+	if ok {
+		requestIDs := md.Get("x-request-id")
+		if len(requestIDs) > 0 {
+			requestID := requestIDs[0]
+
+			logrus.WithFields(logrus.Fields{
+				"x-request-id": requestID,
+				"method":       info.FullMethod,
+			}).Info("gRPC stream request")
+
+			// Контекст потока нельзя заменить, поэтому оборачиваем поток
+			ss = &contextStream{
+				ServerStream: ss,
+				ctx:          context.WithValue(ss.Context(), "x-request-id", requestID),
+			}
+		}
+	}
+
+	return handler(srv, ss)
+}
+
+// contextStream - поток с контекстом, дополненным перехватчиком
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"strconv"
 
+	"otus-highload-arh-homework/internal/social/entity"
 	"otus-highload-arh-homework/internal/social/usecase/user"
 	"otus-highload-arh-homework/pkg/proto/dialog/v1"
 
@@ -15,11 +16,12 @@ import (
 
 type DialogService struct {
 	dialogv1.UnimplementedDialogServiceServer
-	uc *user.UserUseCase
+	uc     *user.DialogUseCase
+	groups *user.GroupUseCase
 }
 
-func NewDialogService(uc *user.UserUseCase) *DialogService {
-	return &DialogService{uc: uc}
+func NewDialogService(uc *user.DialogUseCase, groups *user.GroupUseCase) *DialogService {
+	return &DialogService{uc: uc, groups: groups}
 }
 
 func (s *DialogService) SendMessage(ctx context.Context, req *dialogv1.SendMessageRequest) (*dialogv1.SendMessageResponse, error) {
@@ -28,9 +30,9 @@ func (s *DialogService) SendMessage(ctx context.Context, req *dialogv1.SendMessa
 		return nil, status.Error(codes.InvalidArgument, "message text cannot be empty")
 	}
 
-	senderID, err := strconv.Atoi(req.SenderId)
+	senderID, err := actingUser(ctx)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid sender ID")
+		return nil, err
 	}
 
 	receiverID, err := strconv.Atoi(req.ReceiverId)
@@ -39,23 +41,29 @@ func (s *DialogService) SendMessage(ctx context.Context, req *dialogv1.SendMessa
 	}
 
 	// Вызов use case
-	err = s.uc.SendDialogMessage(ctx, int64(senderID), int64(receiverID), req.Text)
+	sent, err := s.uc.SendDialogMessage(ctx, senderID, int64(receiverID), req.Text, req.ClientMessageId)
 	if err != nil {
-		if errors.Is(err, user.ErrUserNotFound) {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
 			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, user.ErrInvalidClientMessageID):
+			return nil, status.Error(codes.InvalidArgument, "client_message_id is too long")
 		}
 		return nil, status.Error(codes.Internal, "failed to send message")
 	}
 
 	return &dialogv1.SendMessageResponse{
-		Success: true,
+		Success:   true,
+		MessageId: strconv.FormatInt(sent.ID, 10),
+		SentAt:    timestamppb.New(sent.SentAt),
+		Duplicate: sent.Duplicate,
 	}, nil
 }
 
 func (s *DialogService) GetMessages(ctx context.Context, req *dialogv1.GetMessagesRequest) (*dialogv1.GetMessagesResponse, error) {
-	userID, err := strconv.Atoi(req.UserId)
+	userID, err := actingUser(ctx)
 	if err != nil {
-		return nil, status.Error(codes.InvalidArgument, "invalid user ID")
+		return nil, err
 	}
 
 	otherUserID, err := strconv.Atoi(req.OtherUserId)
@@ -63,28 +71,218 @@ func (s *DialogService) GetMessages(ctx context.Context, req *dialogv1.GetMessag
 		return nil, status.Error(codes.InvalidArgument, "invalid other user ID")
 	}
 
+	before, err := parseCursor(req.Before)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid before cursor")
+	}
+
+	after, err := parseCursor(req.After)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid after cursor")
+	}
+
 	// Вызов use case
-	messages, err := s.uc.GetDialogMessages(ctx, int64(userID), int64(otherUserID))
+	page, err := s.uc.GetDialogMessages(ctx, userID, int64(otherUserID), entity.MessagesQuery{
+		Before: before,
+		After:  after,
+		Limit:  int(req.Limit),
+	})
 	if err != nil {
-		if errors.Is(err, user.ErrUserNotFound) {
+		switch {
+		case errors.Is(err, user.ErrUserNotFound):
 			return nil, status.Error(codes.NotFound, "user not found")
+		case errors.Is(err, user.ErrInvalidCursor):
+			return nil, status.Error(codes.InvalidArgument, "invalid cursor: set either before or after")
 		}
 		return nil, status.Error(codes.Internal, "failed to get messages")
 	}
 
 	// Конвертация в protobuf
-	pbMessages := make([]*dialogv1.DialogMessage, 0, len(messages))
-	for _, msg := range messages {
-		pbMessages = append(pbMessages, &dialogv1.DialogMessage{
-			MessageId:  msg.ID,
-			SenderId:   msg.SenderID,
-			ReceiverId: msg.ReceiverID,
-			Text:       msg.Text,
-			SentAt:     timestamppb.New(msg.SentAt),
-		})
+	pbMessages := make([]*dialogv1.DialogMessage, 0, len(page.Messages))
+	for _, msg := range page.Messages {
+		pbMessages = append(pbMessages, toPBMessage(msg))
 	}
 
 	return &dialogv1.GetMessagesResponse{
-		Messages: pbMessages,
+		Messages:   pbMessages,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+func (s *DialogService) ListDialogs(ctx context.Context, req *dialogv1.ListDialogsRequest) (*dialogv1.ListDialogsResponse, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := parseCursor(req.Cursor)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+	}
+
+	page, err := s.uc.ListDialogs(ctx, userID, cursor, int(req.Limit))
+	if err != nil {
+		if errors.Is(err, user.ErrInvalidCursor) {
+			return nil, status.Error(codes.InvalidArgument, "invalid cursor")
+		}
+		return nil, status.Error(codes.Internal, "failed to list dialogs")
+	}
+
+	pbDialogs := make([]*dialogv1.DialogPreview, 0, len(page.Dialogs))
+	for _, dialog := range page.Dialogs {
+		pbDialogs = append(pbDialogs, &dialogv1.DialogPreview{
+			PeerId:      strconv.FormatInt(dialog.PeerID, 10),
+			LastMessage: toPBMessage(&dialog.LastMessage),
+			UnreadCount: int32(dialog.UnreadCount),
+		})
+	}
+
+	return &dialogv1.ListDialogsResponse{
+		Dialogs:    pbDialogs,
+		NextCursor: page.NextCursor,
+	}, nil
+}
+
+func (s *DialogService) MarkRead(ctx context.Context, req *dialogv1.MarkReadRequest) (*dialogv1.MarkReadResponse, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	peerID, err := strconv.Atoi(req.PeerId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid peer ID")
+	}
+
+	upTo, err := parseCursor(req.UpToMessageId)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, "invalid up_to_message_id")
+	}
+
+	result, err := s.uc.MarkRead(ctx, userID, int64(peerID), upTo)
+	if err != nil {
+		switch {
+		case errors.Is(err, user.ErrInvalidCursor):
+			return nil, status.Error(codes.InvalidArgument, "invalid up_to_message_id")
+		case errors.Is(err, user.ErrSelfOperation):
+			return nil, status.Error(codes.InvalidArgument, "cannot mark own dialog")
+		}
+		return nil, status.Error(codes.Internal, "failed to mark messages read")
+	}
+
+	return &dialogv1.MarkReadResponse{
+		Marked:      int32(result.Marked),
+		UnreadCount: int32(result.Unread),
+		TotalUnread: int32(result.Total),
+	}, nil
+}
+
+func (s *DialogService) GetUnreadCounters(ctx context.Context, req *dialogv1.GetUnreadCountersRequest) (*dialogv1.GetUnreadCountersResponse, error) {
+	userID, err := actingUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	counters, err := s.uc.GetUnreadCounters(ctx, userID)
+	if err != nil {
+		return nil, status.Error(codes.Internal, "failed to get unread counters")
+	}
+
+	dialogs := make(map[string]int32, len(counters.Dialogs))
+	for peerID, count := range counters.Dialogs {
+		dialogs[strconv.FormatInt(peerID, 10)] = int32(count)
+	}
+
+	return &dialogv1.GetUnreadCountersResponse{
+		TotalUnread: int32(counters.Total),
+		Dialogs:     dialogs,
 	}, nil
 }
+
+func (s *DialogService) EditMessage(ctx context.Context, req *dialogv1.EditMessageRequest) (*dialogv1.EditMessageResponse, error) {
+	userID, peerID, messageID, err := parseMessageRef(ctx, req.PeerId, req.MessageId)
+	if err != nil {
+		return nil, err
+	}
+
+	msg, err := s.uc.EditMessage(ctx, userID, peerID, messageID, req.Text)
+	if err != nil {
+		return nil, messageError(err, "failed to edit message")
+	}
+
+	return &dialogv1.EditMessageResponse{Message: toPBMessage(msg)}, nil
+}
+
+func (s *DialogService) DeleteMessage(ctx context.Context, req *dialogv1.DeleteMessageRequest) (*dialogv1.DeleteMessageResponse, error) {
+	userID, peerID, messageID, err := parseMessageRef(ctx, req.PeerId, req.MessageId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.uc.DeleteMessage(ctx, userID, peerID, messageID, req.ForEveryone); err != nil {
+		return nil, messageError(err, "failed to delete message")
+	}
+
+	return &dialogv1.DeleteMessageResponse{}, nil
+}
+
+// parseMessageRef разбирает идентификаторы пользователя, собеседника и сообщения
+func parseMessageRef(ctx context.Context, peerID, messageID string) (int64, int64, int64, error) {
+	uid, err := actingUser(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+
+	pid, err := strconv.ParseInt(peerID, 10, 64)
+	if err != nil {
+		return 0, 0, 0, status.Error(codes.InvalidArgument, "invalid peer ID")
+	}
+
+	mid, err := strconv.ParseInt(messageID, 10, 64)
+	if err != nil || mid <= 0 {
+		return 0, 0, 0, status.Error(codes.InvalidArgument, "invalid message ID")
+	}
+
+	return uid, pid, mid, nil
+}
+
+// messageError переводит ошибки изменения сообщения в статусы gRPC
+func messageError(err error, msg string) error {
+	switch {
+	case errors.Is(err, user.ErrEmptyMessage):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, user.ErrMessageNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, user.ErrNotMessageSender):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, user.ErrEditWindowExpired):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+
+	return status.Error(codes.Internal, msg)
+}
+
+func toPBMessage(msg *entity.DialogMessage) *dialogv1.DialogMessage {
+	pb := &dialogv1.DialogMessage{
+		MessageId:  msg.ID,
+		SenderId:   msg.SenderID,
+		ReceiverId: msg.ReceiverID,
+		Text:       msg.Text,
+		SentAt:     timestamppb.New(msg.SentAt),
+		Deleted:    msg.Deleted,
+	}
+	if msg.EditedAt != nil {
+		pb.EditedAt = timestamppb.New(*msg.EditedAt)
+	}
+
+	return pb
+}
+
+// parseCursor разбирает курсор message_id, пустой курсор - 0
+func parseCursor(cursor string) (int64, error) {
+	if cursor == "" {
+		return 0, nil
+	}
+
+	return strconv.ParseInt(cursor, 10, 64)
+}
@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"errors"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/usecase/user"
+	"otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// StreamMessages отдает сообщения пользователя после since_cursor, затем
+// новые. Send блокируется, пока клиент не освободит окно HTTP/2, и поток
+// не читает следующие сообщения - так медленный клиент сдерживает чтение
+func (s *DialogService) StreamMessages(req *dialogv1.StreamMessagesRequest, stream dialogv1.DialogService_StreamMessagesServer) error {
+	userID, err := actingUser(stream.Context())
+	if err != nil {
+		return err
+	}
+
+	since, err := parseCursor(req.SinceCursor)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, "invalid since cursor")
+	}
+
+	ctx := stream.Context()
+	err = s.uc.StreamMessages(ctx, userID, since, func(msg *entity.DialogMessage) error {
+		return stream.Send(toPBMessage(msg))
+	})
+	switch {
+	case errors.Is(err, user.ErrInvalidCursor):
+		return status.Error(codes.InvalidArgument, "invalid since cursor")
+	case ctx.Err() != nil:
+		return status.FromContextError(ctx.Err()).Err()
+	case err != nil:
+		if _, ok := status.FromError(err); ok {
+			// Ошибка Send уже содержит статус транспорта
+			return err
+		}
+		logrus.WithError(err).WithField("user_id", userID).Error("message stream failed")
+		return status.Error(codes.Internal, "failed to stream messages")
+	}
+
+	return nil
+}
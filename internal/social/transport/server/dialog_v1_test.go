@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	dialogClient "otus-highload-arh-homework/internal/social/transport/clients/dialog/grpc"
+	"otus-highload-arh-homework/internal/social/transport/service"
+	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Контрактные тесты /api/v1/dialog: ручки работают через сервис диалогов,
+// а ответы должны совпадать с прежними байт в байт
+
+type fakeDialogServer struct {
+	dialogv1.UnimplementedDialogServiceServer
+	sent     []*dialogv1.SendMessageRequest
+	sentMD   metadata.MD
+	sendErr  error
+	messages []*dialogv1.DialogMessage
+	cursor   string
+	getErr   error
+}
+
+func (f *fakeDialogServer) SendMessage(ctx context.Context, req *dialogv1.SendMessageRequest) (*dialogv1.SendMessageResponse, error) {
+	if f.sendErr != nil {
+		return nil, f.sendErr
+	}
+	f.sent = append(f.sent, req)
+	f.sentMD, _ = metadata.FromIncomingContext(ctx)
+
+	return &dialogv1.SendMessageResponse{Success: true, MessageId: "10", SentAt: timestamppb.Now()}, nil
+}
+
+func (f *fakeDialogServer) GetMessages(context.Context, *dialogv1.GetMessagesRequest) (*dialogv1.GetMessagesResponse, error) {
+	if f.getErr != nil {
+		return nil, f.getErr
+	}
+
+	return &dialogv1.GetMessagesResponse{Messages: f.messages, NextCursor: f.cursor}, nil
+}
+
+type fakeUsers struct {
+	missing map[int]bool
+}
+
+func (f *fakeUsers) GetByID(_ context.Context, id int) (*entity.User, error) {
+	if f.missing[id] {
+		return nil, nil
+	}
+	return &entity.User{}, nil
+}
+
+func (f *fakeUsers) Search(context.Context, string, string) ([]*entity.User, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) GetLastSeenVisibility(context.Context, []int) (map[int]entity.Visibility, error) {
+	return nil, nil
+}
+
+func (f *fakeUsers) SetLastSeenVisibility(context.Context, int, entity.Visibility) error {
+	return nil
+}
+
+var testDeprecation = Deprecation{
+	DeprecatedAt: time.Date(2025, 11, 3, 0, 0, 0, 0, time.UTC),
+	Sunset:       time.Date(2026, 5, 1, 0, 0, 0, 0, time.UTC),
+	Successor:    "/api/v2/dialog",
+}
+
+type dialogV1Env struct {
+	dialogs *fakeDialogServer
+	users   *fakeUsers
+	router  *gin.Engine
+	token   string
+}
+
+// newDialogV1Env поднимает сервер API с клиентом диалогов, подключенным к
+// fakeDialogServer в памяти. Текущий пользователь - 1
+func newDialogV1Env(t *testing.T) *dialogV1Env {
+	gin.SetMode(gin.TestMode)
+
+	env := &dialogV1Env{dialogs: &fakeDialogServer{}, users: &fakeUsers{}}
+
+	lis := bufconn.Listen(1 << 20)
+	grpcServer := grpc.NewServer()
+	dialogv1.RegisterDialogServiceServer(grpcServer, env.dialogs)
+	go func() { _ = grpcServer.Serve(lis) }()
+	t.Cleanup(grpcServer.Stop)
+
+	client, err := dialogClient.New("passthrough:///bufnet", time.Second,
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = client.Close() })
+
+	jwtService := service.NewJWTGenerator("secret", time.Hour)
+	env.token, err = jwtService.GenerateToken(1)
+	require.NoError(t, err)
+
+	userService := service.NewUserService(env.users, nil, nil, client)
+	env.router = New(nil, userService, nil, jwtService, nil, testDeprecation).router
+
+	return env
+}
+
+func (e *dialogV1Env) do(method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", e.token)
+	req.Header.Set("Content-Type", "application/json")
+
+	rec := httptest.NewRecorder()
+	e.router.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func TestDialogV1_Send(t *testing.T) {
+	t.Run("sent", func(t *testing.T) {
+		env := newDialogV1Env(t)
+
+		rec := env.do(http.MethodPost, "/api/v1/dialog/2/send", `{"text":"hi","client_message_id":"c-1"}`)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, `{"status":"success","message":"Message sent successfully"}`, rec.Body.String())
+
+		require.Len(t, env.dialogs.sent, 1)
+		assert.Equal(t, "1", env.dialogs.sent[0].SenderId)
+		assert.Equal(t, "2", env.dialogs.sent[0].ReceiverId)
+		assert.Equal(t, "hi", env.dialogs.sent[0].Text)
+		assert.Equal(t, "c-1", env.dialogs.sent[0].ClientMessageId)
+
+		// Пользователь передается сервису диалогов учетными данными
+		assert.Equal(t, []string{"Bearer " + env.token}, env.dialogs.sentMD.Get("authorization"))
+		assert.Equal(t, []string{"1"}, env.dialogs.sentMD.Get("x-user-id"))
+	})
+
+	cases := []struct {
+		name    string
+		path    string
+		body    string
+		missing bool
+		sendErr error
+		code    int
+		want    string
+	}{
+		{
+			name: "invalid recipient",
+			path: "/api/v1/dialog/abc/send",
+			body: `{"text":"hi"}`,
+			code: http.StatusBadRequest,
+			want: `{"error":"Invalid recipient ID format","details":"User ID must be a number"}`,
+		},
+		{
+			name: "blank text",
+			path: "/api/v1/dialog/2/send",
+			body: `{"text":"   "}`,
+			code: http.StatusBadRequest,
+			want: `{"error":"Message text cannot be empty","details":""}`,
+		},
+		{
+			name:    "recipient not found",
+			path:    "/api/v1/dialog/2/send",
+			body:    `{"text":"hi"}`,
+			missing: true,
+			code:    http.StatusInternalServerError,
+			want:    `{"error":"Failed to send message","details":"receiver not found"}`,
+		},
+		{
+			name:    "recipient not found by dialog service",
+			path:    "/api/v1/dialog/2/send",
+			body:    `{"text":"hi"}`,
+			sendErr: status.Error(codes.NotFound, "user not found"),
+			code:    http.StatusInternalServerError,
+			want:    `{"error":"Failed to send message","details":"receiver not found"}`,
+		},
+		{
+			name:    "invalid client message id",
+			path:    "/api/v1/dialog/2/send",
+			body:    `{"text":"hi"}`,
+			sendErr: status.Error(codes.InvalidArgument, "client_message_id is too long"),
+			code:    http.StatusInternalServerError,
+			want:    `{"error":"Failed to send message","details":"invalid client message id"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newDialogV1Env(t)
+			env.users.missing = map[int]bool{2: tc.missing}
+			env.dialogs.sendErr = tc.sendErr
+
+			rec := env.do(http.MethodPost, tc.path, tc.body)
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, tc.want, rec.Body.String())
+			assert.Empty(t, env.dialogs.sent)
+		})
+	}
+}
+
+func TestDialogV1_List(t *testing.T) {
+	sentAt := time.Date(2025, 10, 1, 12, 30, 45, 0, time.UTC)
+	editedAt := sentAt.Add(time.Minute)
+
+	env := newDialogV1Env(t)
+	env.dialogs.messages = []*dialogv1.DialogMessage{
+		{MessageId: "12", SenderId: "2", ReceiverId: "1", Text: "hello", SentAt: timestamppb.New(sentAt.Add(time.Second))},
+		{MessageId: "11", SenderId: "1", ReceiverId: "2", Text: "hi", SentAt: timestamppb.New(sentAt), EditedAt: timestamppb.New(editedAt)},
+	}
+	env.dialogs.cursor = "11"
+
+	rec := env.do(http.MethodGet, "/api/v1/dialog/2/list?limit=2", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "11", rec.Header().Get("X-Next-Cursor"))
+
+	// Прежний формат: без message_id и правок, sent_at без зоны в локальном времени
+	want := fmt.Sprintf(`[`+
+		`{"sender_id":"2","receiver_id":"1","text":"hello","sent_at":%q,"is_own":false},`+
+		`{"sender_id":"1","receiver_id":"2","text":"hi","sent_at":%q,"is_own":true}]`,
+		sentAt.Add(time.Second).Local().Format("2006-01-02 15:04:05"),
+		sentAt.Local().Format("2006-01-02 15:04:05"))
+	assert.Equal(t, want, rec.Body.String())
+
+	// Те же сообщения, что и у v2, в прежнем представлении
+	recV2 := env.do(http.MethodGet, "/api/v2/dialog/2/list?limit=2", "")
+	require.Equal(t, http.StatusOK, recV2.Code)
+
+	var v1, v2 []map[string]any
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &v1))
+	require.NoError(t, json.Unmarshal(recV2.Body.Bytes(), &v2))
+	require.Len(t, v1, len(v2))
+	for i := range v2 {
+		for _, field := range []string{"sender_id", "receiver_id", "text", "is_own"} {
+			assert.Equal(t, v2[i][field], v1[i][field], field)
+		}
+	}
+}
+
+func TestDialogV1_ListErrors(t *testing.T) {
+	cases := []struct {
+		name   string
+		path   string
+		getErr error
+		code   int
+		want   string
+	}{
+		{
+			name: "invalid user",
+			path: "/api/v1/dialog/abc/list",
+			code: http.StatusBadRequest,
+			want: `{"error":"Invalid user ID format","details":"User ID must be a numeric value"}`,
+		},
+		{
+			name: "both cursors",
+			path: "/api/v1/dialog/2/list?before=5&after=3",
+			code: http.StatusBadRequest,
+			want: `{"error":"Invalid pagination parameters","details":"invalid pagination parameters: set either before or after"}`,
+		},
+		{
+			name:   "cursor rejected by dialog service",
+			path:   "/api/v1/dialog/2/list?before=5",
+			getErr: status.Error(codes.InvalidArgument, "invalid cursor: set either before or after"),
+			code:   http.StatusBadRequest,
+			want:   `{"error":"Invalid pagination parameters","details":"invalid pagination parameters: set either before or after"}`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			env := newDialogV1Env(t)
+			env.dialogs.getErr = tc.getErr
+
+			rec := env.do(http.MethodGet, tc.path, "")
+			assert.Equal(t, tc.code, rec.Code)
+			assert.Equal(t, tc.want, rec.Body.String())
+		})
+	}
+
+	t.Run("dialog service failure", func(t *testing.T) {
+		env := newDialogV1Env(t)
+		env.dialogs.getErr = status.Error(codes.Internal, "failed to get messages")
+
+		rec := env.do(http.MethodGet, "/api/v1/dialog/2/list", "")
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+		var body map[string]string
+		require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &body))
+		assert.Equal(t, "Failed to get dialog messages", body["error"])
+		assert.True(t, strings.HasPrefix(body["details"], "failed to get dialog: "), body["details"])
+	})
+}
+
+func TestDialogV1_Deprecation(t *testing.T) {
+	env := newDialogV1Env(t)
+	counter := deprecatedRequestsTotal.WithLabelValues(http.MethodGet, "/api/v1/dialog/:user_id/list")
+	before := testutil.ToFloat64(counter)
+
+	rec := env.do(http.MethodGet, "/api/v1/dialog/2/list", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "@1762128000", rec.Header().Get("Deprecation"))
+	assert.Equal(t, "Fri, 01 May 2026 00:00:00 GMT", rec.Header().Get("Sunset"))
+	assert.Equal(t, `</api/v2/dialog>; rel="successor-version"`, rec.Header().Get("Link"))
+	assert.Equal(t, before+1, testutil.ToFloat64(counter))
+
+	// Отказ авторизации тоже отвечает заголовками и учитывается
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/dialog/2/list", nil)
+	unauthorized := httptest.NewRecorder()
+	env.router.ServeHTTP(unauthorized, req)
+	assert.Equal(t, http.StatusUnauthorized, unauthorized.Code)
+	assert.NotEmpty(t, unauthorized.Header().Get("Sunset"))
+	assert.Equal(t, before+2, testutil.ToFloat64(counter))
+
+	// v2 не устарела
+	recV2 := env.do(http.MethodGet, "/api/v2/dialog/2/list", "")
+	assert.Empty(t, recV2.Header().Get("Deprecation"))
+}
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"otus-highload-arh-homework/pkg/tracing"
+
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
 )
@@ -8,16 +10,22 @@ import (
 func RequestIDMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Получаем или генерируем request-id
-		requestID := c.GetHeader("x-request-id")
+		requestID := c.GetHeader(tracing.RequestIDHeader)
 		if requestID == "" {
 			requestID = uuid.New().String()
 		}
 
 		// Сохраняем в контекст Gin
-		c.Set("x-request-id", requestID)
+		c.Set(tracing.RequestIDHeader, requestID)
+
+		// И в контекст запроса: он попадает в события, записанные в outbox
+		c.Request = c.Request.WithContext(tracing.WithTrace(c.Request.Context(), tracing.Trace{
+			RequestID:   requestID,
+			TraceParent: c.GetHeader(tracing.TraceParentHeader),
+		}))
 
 		// Добавляем в заголовки ответа
-		c.Writer.Header().Set("x-request-id", requestID)
+		c.Writer.Header().Set(tracing.RequestIDHeader, requestID)
 
 		c.Next()
 	}
@@ -31,6 +31,8 @@ func New(
 	userService *service.UserService,
 	postService *service.PostService,
 	jwtService *service.JWTGenerator,
+	activity http.ActivityTracker,
+	dialogV1 Deprecation,
 ) *Server {
 	router := gin.Default()
 
@@ -64,24 +66,28 @@ func New(
 			authGroup.POST("/login", authHandler.Login)
 		}
 
+		// Устаревшие ручки диалогов: работают через сервис диалогов, как и
+		// /api/v2/dialog, и сохраняют прежний формат ответов
 		dialogGroup := api.Group("/dialog")
-		dialogGroup.Use(http.AuthMiddleware(jwtService))
+		dialogGroup.Use(DeprecationMiddleware(dialogV1), http.AuthMiddleware(jwtService, activity))
 		{
 			dialogGroup.POST("/:user_id/send", userHandler.SendDialogMessage)
 			dialogGroup.GET("/:user_id/list", userHandler.GetDialogMessages)
 		}
 
 		userGroup := api.Group("/user")
-		userGroup.Use(http.AuthMiddleware(jwtService))
+		userGroup.Use(http.AuthMiddleware(jwtService, activity))
 		{
 			userGroup.GET("/get/:id", userHandler.GetUser)
 			userGroup.GET("/search", userHandler.SearchUsers)
+			userGroup.GET("/presence", userHandler.GetPresence)
+			userGroup.PUT("/privacy", userHandler.SetPrivacy)
 		}
 	}
 
 	// Друзья
 	friendGroup := api.Group("/friend")
-	friendGroup.Use(http.AuthMiddleware(jwtService))
+	friendGroup.Use(http.AuthMiddleware(jwtService, activity))
 	{
 		friendGroup.PUT("/set/:user_id", userHandler.SetFriend)
 		friendGroup.PUT("/delete/:user_id", userHandler.DeleteFriend)
@@ -89,7 +95,7 @@ func New(
 
 	// Посты
 	postGroup := api.Group("/post")
-	postGroup.Use(http.AuthMiddleware(jwtService))
+	postGroup.Use(http.AuthMiddleware(jwtService, activity))
 	{
 		postGroup.POST("/create", postHandler.CreatePost)
 		postGroup.PUT("/update", postHandler.UpdatePost)
@@ -102,10 +108,29 @@ func New(
 	v2 := router.Group("/api/v2")
 	{
 		dialogGroup := v2.Group("/dialog")
-		dialogGroup.Use(http.AuthMiddleware(jwtService))
+		dialogGroup.Use(http.AuthMiddleware(jwtService, activity))
 		{
 			dialogGroup.POST("/:user_id/send", userHandler.SendDialogMessageV2)
 			dialogGroup.GET("/:user_id/list", userHandler.GetDialogMessagesV2)
+			dialogGroup.GET("/list", userHandler.ListDialogsV2)
+			dialogGroup.POST("/:user_id/read", userHandler.MarkReadV2)
+			dialogGroup.GET("/unread", userHandler.GetUnreadCountersV2)
+			dialogGroup.PATCH("/:user_id/messages/:message_id", userHandler.EditMessageV2)
+			dialogGroup.DELETE("/:user_id/messages/:message_id", userHandler.DeleteMessageV2)
+		}
+
+		groupsGroup := v2.Group("/groups")
+		groupsGroup.Use(http.AuthMiddleware(jwtService, activity))
+		{
+			groupsGroup.POST("", userHandler.CreateGroupV2)
+			groupsGroup.GET("", userHandler.ListGroupsV2)
+			groupsGroup.GET("/:group_id/members", userHandler.ListGroupMembersV2)
+			groupsGroup.POST("/:group_id/members", userHandler.AddGroupMembersV2)
+			groupsGroup.DELETE("/:group_id/members/:user_id", userHandler.RemoveGroupMemberV2)
+			groupsGroup.PATCH("/:group_id/members/:user_id", userHandler.SetGroupMemberRoleV2)
+			groupsGroup.POST("/:group_id/leave", userHandler.LeaveGroupV2)
+			groupsGroup.POST("/:group_id/send", userHandler.SendGroupMessageV2)
+			groupsGroup.GET("/:group_id/messages", userHandler.GetGroupMessagesV2)
 		}
 	}
 
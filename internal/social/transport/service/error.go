@@ -12,6 +12,23 @@ var (
 	ErrDatabaseOperation = errors.New("database operation failed")
 )
 
+var (
+	ErrInvalidPresenceQuery = errors.New("invalid presence query")
+	ErrInvalidVisibility    = errors.New("invalid last seen visibility")
+)
+
+var (
+	ErrMessageNotFound   = errors.New("message not found")
+	ErrNotMessageSender  = errors.New("only the sender can change the message")
+	ErrEditWindowExpired = errors.New("message edit window has expired")
+)
+
+var (
+	ErrGroupNotFound  = errors.New("group not found")
+	ErrNotGroupAdmin  = errors.New("only group admins can manage members")
+	ErrLastGroupAdmin = errors.New("group must keep at least one admin")
+)
+
 var (
 	ErrPostNotFound            = errors.New("post not found")
 	ErrNotPostOwner            = errors.New("not post owner")
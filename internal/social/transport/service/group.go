@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/transport/dto"
+	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var groupRoles = map[dialogv1.GroupRole]entity.GroupRole{
+	dialogv1.GroupRole_GROUP_ROLE_ADMIN:  entity.GroupRoleAdmin,
+	dialogv1.GroupRole_GROUP_ROLE_MEMBER: entity.GroupRoleMember,
+}
+
+// CreateGroupV2 создает групповой чат через сервис диалогов
+func (s *UserService) CreateGroupV2(ctx context.Context, currentUserID int, req dto.CreateGroupRequest) (*dto.GroupV2, error) {
+	if strings.TrimSpace(req.Title) == "" {
+		return nil, fmt.Errorf("%w: group title cannot be empty", ErrValidation)
+	}
+	if err := validateIDs(req.MemberIDs); err != nil {
+		return nil, err
+	}
+
+	group, err := s.dialogClient.CreateGroup(ctx, strconv.Itoa(currentUserID), req.Title, req.MemberIDs)
+	if err != nil {
+		return nil, groupStatusError(err, "gRPC CreateGroup failed")
+	}
+
+	result := toGroupV2(group)
+	return &result, nil
+}
+
+// ListGroupsV2 возвращает страницу групп текущего пользователя
+func (s *UserService) ListGroupsV2(ctx context.Context, currentUserID int, page dto.DialogsPageQuery) ([]dto.GroupV2, string, error) {
+	if page.Cursor != "" {
+		if cursor, err := strconv.ParseInt(page.Cursor, 10, 64); err != nil || cursor <= 0 {
+			return nil, "", fmt.Errorf("%w: invalid cursor", ErrInvalidPaginationParams)
+		}
+	}
+	if page.Limit < 0 {
+		return nil, "", fmt.Errorf("%w: limit must be positive", ErrInvalidPaginationParams)
+	}
+
+	groups, nextCursor, err := s.dialogClient.ListGroups(ctx, strconv.Itoa(currentUserID), page.Cursor, page.Limit)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidPaginationParams, status.Convert(err).Message())
+		}
+		return nil, "", fmt.Errorf("gRPC ListGroups failed: %w", err)
+	}
+
+	result := make([]dto.GroupV2, 0, len(groups))
+	for _, group := range groups {
+		result = append(result, toGroupV2(group))
+	}
+
+	return result, nextCursor, nil
+}
+
+// ListGroupMembersV2 возвращает участников группы
+func (s *UserService) ListGroupMembersV2(ctx context.Context, currentUserID int, groupID string) ([]dto.GroupMemberV2, error) {
+	if err := validateIDs([]string{groupID}); err != nil {
+		return nil, err
+	}
+
+	members, err := s.dialogClient.ListGroupMembers(ctx, strconv.Itoa(currentUserID), groupID)
+	if err != nil {
+		return nil, groupStatusError(err, "gRPC ListGroupMembers failed")
+	}
+
+	result := make([]dto.GroupMemberV2, 0, len(members))
+	for _, member := range members {
+		result = append(result, dto.GroupMemberV2{
+			UserID:   member.UserId,
+			Role:     groupRoles[member.Role],
+			JoinedAt: member.JoinedAt.AsTime(),
+		})
+	}
+
+	return result, nil
+}
+
+// AddGroupMembersV2 добавляет участников от имени администратора группы
+func (s *UserService) AddGroupMembersV2(ctx context.Context, currentUserID int, groupID string, memberIDs []string) (*dto.AddGroupMembersResponseV2, error) {
+	if err := validateIDs(append([]string{groupID}, memberIDs...)); err != nil {
+		return nil, err
+	}
+
+	added, err := s.dialogClient.AddGroupMembers(ctx, strconv.Itoa(currentUserID), groupID, memberIDs)
+	if err != nil {
+		return nil, groupStatusError(err, "gRPC AddGroupMembers failed")
+	}
+
+	return &dto.AddGroupMembersResponseV2{AddedIDs: added}, nil
+}
+
+// RemoveGroupMemberV2 удаляет участника от имени администратора группы
+func (s *UserService) RemoveGroupMemberV2(ctx context.Context, currentUserID int, groupID, memberID string) error {
+	if err := validateIDs([]string{groupID, memberID}); err != nil {
+		return err
+	}
+
+	if err := s.dialogClient.RemoveGroupMember(ctx, strconv.Itoa(currentUserID), groupID, memberID); err != nil {
+		return groupStatusError(err, "gRPC RemoveGroupMember failed")
+	}
+
+	return nil
+}
+
+// SetGroupMemberRoleV2 меняет роль участника от имени администратора группы
+func (s *UserService) SetGroupMemberRoleV2(ctx context.Context, currentUserID int, groupID, memberID string, role entity.GroupRole) error {
+	if err := validateIDs([]string{groupID, memberID}); err != nil {
+		return err
+	}
+
+	pbRole := dialogv1.GroupRole_GROUP_ROLE_UNSPECIFIED
+	for pb, r := range groupRoles {
+		if r == role {
+			pbRole = pb
+		}
+	}
+	if pbRole == dialogv1.GroupRole_GROUP_ROLE_UNSPECIFIED {
+		return fmt.Errorf("%w: invalid role", ErrValidation)
+	}
+
+	if err := s.dialogClient.SetGroupMemberRole(ctx, strconv.Itoa(currentUserID), groupID, memberID, pbRole); err != nil {
+		return groupStatusError(err, "gRPC SetGroupMemberRole failed")
+	}
+
+	return nil
+}
+
+// LeaveGroupV2 выводит текущего пользователя из группы
+func (s *UserService) LeaveGroupV2(ctx context.Context, currentUserID int, groupID string) error {
+	if err := validateIDs([]string{groupID}); err != nil {
+		return err
+	}
+
+	if err := s.dialogClient.LeaveGroup(ctx, strconv.Itoa(currentUserID), groupID); err != nil {
+		return groupStatusError(err, "gRPC LeaveGroup failed")
+	}
+
+	return nil
+}
+
+// SendGroupMessageV2 отправляет сообщение в группу. Повтор с тем же
+// clientMessageID возвращает уже отправленное сообщение
+func (s *UserService) SendGroupMessageV2(ctx context.Context, currentUserID int, groupID, text, clientMessageID string) (*dto.SentMessageV2, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%w: message text cannot be empty", ErrValidation)
+	}
+	if err := validateIDs([]string{groupID}); err != nil {
+		return nil, err
+	}
+
+	resp, err := s.dialogClient.SendGroupMessage(ctx, strconv.Itoa(currentUserID), groupID, text, clientMessageID)
+	if err != nil {
+		return nil, groupStatusError(err, "gRPC SendGroupMessage failed")
+	}
+
+	return &dto.SentMessageV2{
+		ID:              resp.MessageId,
+		ClientMessageID: clientMessageID,
+		SentAt:          resp.SentAt.AsTime(),
+		Duplicate:       resp.Duplicate,
+	}, nil
+}
+
+// GetGroupMessagesV2 возвращает страницу истории группы
+func (s *UserService) GetGroupMessagesV2(ctx context.Context, currentUserID int, groupID string, page dto.MessagesPageQuery) ([]dto.GroupMessageV2, string, error) {
+	if err := validateIDs([]string{groupID}); err != nil {
+		return nil, "", err
+	}
+	if _, err := parseMessagesPage(page); err != nil {
+		return nil, "", err
+	}
+
+	currentUserIDStr := strconv.Itoa(currentUserID)
+	messages, nextCursor, err := s.dialogClient.GetGroupMessages(ctx, currentUserIDStr, groupID, page.Before, page.After, page.Limit)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidPaginationParams, status.Convert(err).Message())
+		}
+		return nil, "", groupStatusError(err, "gRPC GetGroupMessages failed")
+	}
+
+	result := make([]dto.GroupMessageV2, 0, len(messages))
+	for _, msg := range messages {
+		result = append(result, dto.GroupMessageV2{
+			ID:       msg.MessageId,
+			GroupID:  msg.GroupId,
+			SenderID: msg.SenderId,
+			Text:     msg.Text,
+			SentAt:   msg.SentAt.AsTime(),
+			IsOwn:    msg.SenderId == currentUserIDStr,
+		})
+	}
+
+	return result, nextCursor, nil
+}
+
+// groupStatusError переводит статусы gRPC групповых чатов в ошибки сервиса
+func groupStatusError(err error, msg string) error {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", ErrValidation, status.Convert(err).Message())
+	case codes.NotFound:
+		return fmt.Errorf("%w: %s", ErrGroupNotFound, status.Convert(err).Message())
+	case codes.PermissionDenied:
+		return ErrNotGroupAdmin
+	case codes.FailedPrecondition:
+		return ErrLastGroupAdmin
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func toGroupV2(group *dialogv1.Group) dto.GroupV2 {
+	return dto.GroupV2{
+		ID:          group.GetGroupId(),
+		Title:       group.GetTitle(),
+		CreatedBy:   group.GetCreatedBy(),
+		CreatedAt:   group.GetCreatedAt().AsTime(),
+		Role:        groupRoles[group.GetRole()],
+		MemberCount: int(group.GetMemberCount()),
+	}
+}
+
+// validateIDs проверяет, что идентификаторы - положительные числа
+func validateIDs(ids []string) error {
+	for _, id := range ids {
+		if n, err := strconv.ParseInt(id, 10, 64); err != nil || n <= 0 {
+			return fmt.Errorf("%w: invalid ID %q", ErrValidation, id)
+		}
+	}
+
+	return nil
+}
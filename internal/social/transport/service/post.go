@@ -5,12 +5,23 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"strconv"
 	"time"
 
 	"otus-highload-arh-homework/internal/social/entity"
 	"otus-highload-arh-homework/internal/social/transport/dto"
 	postUC "otus-highload-arh-homework/internal/social/usecase/post"
+
+	"golang.org/x/sync/singleflight"
+)
+
+const (
+	// feedCacheSize - сколько последних постов ленты держим в кэше
+	feedCacheSize = 1000
+
+	defaultFeedSoftTTL = time.Minute
+	defaultFeedHardTTL = 24 * time.Hour
+
+	feedLoadTimeout = 10 * time.Second
 )
 
 type postUseCase interface {
@@ -27,28 +38,46 @@ type cacheWarmer interface {
 	Get(ctx context.Context, key string, dest any) error
 }
 
-type postFeeder interface {
-	Publish(ctx context.Context, key string, value any) error
+// FeedCacheConfig задает время жизни закэшированной ленты.
+// После SoftTTL лента считается устаревшей: она продолжает отдаваться,
+// но в фоне перестраивается одним запросом. После HardTTL запись удаляется из кэша
+type FeedCacheConfig struct {
+	SoftTTL time.Duration
+	HardTTL time.Duration
+}
+
+// feedCacheEntry - закэшированная лента пользователя
+type feedCacheEntry struct {
+	Posts     []dto.PostResponse `json:"posts"`
+	RefreshAt time.Time          `json:"refresh_at"`
 }
 
 type PostService struct {
 	postUC      postUseCase
 	friendUC    friendUseCase
 	cacheWarmer cacheWarmer
-	postFeeder  postFeeder
+	cacheCfg    FeedCacheConfig
+	feedLoads   singleflight.Group
 }
 
 func NewPostService(
 	postUC postUseCase,
 	friendUC friendUseCase,
 	warmer cacheWarmer,
-	feeder postFeeder,
+	cacheCfg FeedCacheConfig,
 ) *PostService {
+	if cacheCfg.SoftTTL <= 0 {
+		cacheCfg.SoftTTL = defaultFeedSoftTTL
+	}
+	if cacheCfg.HardTTL <= 0 {
+		cacheCfg.HardTTL = defaultFeedHardTTL
+	}
+
 	return &PostService{
 		postUC:      postUC,
 		friendUC:    friendUC,
 		cacheWarmer: warmer,
-		postFeeder:  feeder,
+		cacheCfg:    cacheCfg,
 	}
 }
 
@@ -69,24 +98,6 @@ func (s *PostService) CreatePost(ctx context.Context, authorID int, text string)
 		return "", fmt.Errorf("failed to create post: %w", err)
 	}
 
-	// Обновление ленты ws
-	go func() {
-		event := dto.FeedUpdateEvent{
-			PostID:    postID,
-			AuthorID:  authorID,
-			Action:    "create",
-			Text:      text,
-			Timestamp: time.Now().Unix(),
-		}
-
-		// Используем authorID как ключ для партиционирования
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-		defer cancel()
-		if err := s.postFeeder.Publish(ctx, strconv.Itoa(authorID), event); err != nil {
-			log.Printf("failed to publish feed update event: %v", err)
-		}
-	}()
-
 	// Прогрев кеша
 	go s.warmCache(authorID)
 
@@ -120,23 +131,6 @@ func (s *PostService) UpdatePost(ctx context.Context, authorID int, postID, text
 		}
 	}
 
-	// Отправка в ленту ws
-	go func() {
-		event := dto.FeedUpdateEvent{
-			PostID:    postID,
-			AuthorID:  authorID,
-			Action:    "update",
-			Text:      text,
-			Timestamp: time.Now().Unix(),
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-		defer cancel()
-		if err := s.postFeeder.Publish(ctx, strconv.Itoa(authorID), event); err != nil {
-			log.Printf("failed to publish feed update event: %v", err)
-		}
-	}()
-
 	// Прогрев кеша
 	go s.warmCache(authorID)
 
@@ -163,22 +157,6 @@ func (s *PostService) DeletePost(ctx context.Context, authorID int, postID strin
 		}
 	}
 
-	// Отправка в ленту по ws
-	go func() {
-		event := dto.FeedUpdateEvent{
-			PostID:    postID,
-			AuthorID:  authorID,
-			Action:    "delete",
-			Timestamp: time.Now().Unix(),
-		}
-
-		ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
-		defer cancel()
-		if err := s.postFeeder.Publish(ctx, strconv.Itoa(authorID), event); err != nil {
-			log.Printf("failed to publish feed update event: %v", err)
-		}
-	}()
-
 	// Прогрев кеша
 	go s.warmCache(authorID)
 
@@ -225,12 +203,74 @@ func (s *PostService) GetFeed(ctx context.Context, userID, offset, limit int) ([
 	}
 
 	// Пробуем получить из кэша
-	cachedPosts, err := s.getFromCache(ctx, userID, offset, limit)
+	entry, err := s.getFromCache(ctx, userID)
 	if err == nil {
-		return cachedPosts, nil
+		// Устаревшую ленту отдаём сразу, а перестраиваем в фоне
+		if time.Now().After(entry.RefreshAt) {
+			s.refreshFeed(userID)
+		}
+
+		if page, ok := paginateFeed(entry.Posts, offset, limit); ok {
+			return page, nil
+		}
+
+		// Страница за пределами закэшированного окна - идём в БД напрямую
+		return s.loadFeedPage(ctx, userID, offset, limit)
 	}
 
-	posts, err := s.postUC.GetFeed(ctx, userID, offset, limit)
+	// Промах кэша: все конкурентные запросы ждут одну загрузку из БД
+	posts, err := s.loadFeed(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if page, ok := paginateFeed(posts, offset, limit); ok {
+		return page, nil
+	}
+
+	return s.loadFeedPage(ctx, userID, offset, limit)
+}
+
+// loadFeed загружает ленту из БД и кладёт её в кэш.
+// Одновременные вызовы для одного пользователя объединяются в один запрос
+func (s *PostService) loadFeed(ctx context.Context, userID int) ([]dto.PostResponse, error) {
+	ch := s.feedLoads.DoChan(s.feedCacheKey(userID), func() (any, error) {
+		// Загрузка не должна прерываться, если первый из ожидающих клиентов ушёл
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), feedLoadTimeout)
+		defer cancel()
+
+		return s.buildFeedCache(ctx, userID)
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-ch:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return res.Val.([]dto.PostResponse), nil
+	}
+}
+
+// refreshFeed перестраивает устаревшую ленту в фоне, не более одной перестройки на пользователя
+func (s *PostService) refreshFeed(userID int) {
+	s.feedLoads.DoChan(s.feedCacheKey(userID), func() (any, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), feedLoadTimeout)
+		defer cancel()
+
+		posts, err := s.buildFeedCache(ctx, userID)
+		if err != nil {
+			log.Printf("feed refresh for user %d failed: %v", userID, err)
+		}
+
+		return posts, err
+	})
+}
+
+// buildFeedCache читает последние посты ленты из БД и сохраняет их в кэш
+func (s *PostService) buildFeedCache(ctx context.Context, userID int) ([]dto.PostResponse, error) {
+	posts, err := s.postUC.GetFeed(ctx, userID, 0, feedCacheSize)
 	if err != nil {
 		if errors.Is(err, postUC.ErrDatabaseOperation) {
 			return nil, fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
@@ -238,22 +278,28 @@ func (s *PostService) GetFeed(ctx context.Context, userID, offset, limit int) ([
 		return nil, fmt.Errorf("failed to get feed: %w", err)
 	}
 
-	var response []dto.PostResponse
-	for _, post := range posts {
-		response = append(response, dto.PostResponse{
-			ID:        post.ID,
-			AuthorID:  post.AuthorID,
-			Text:      post.Text,
-			CreatedAt: post.CreatedAt,
-			UpdatedAt: post.UpdatedAt,
-		})
-	}
+	response := convertPosts(posts)
 
-	go s.warmCache(userID)
+	if err := s.cacheFeed(ctx, userID, response); err != nil {
+		log.Printf("failed to cache feed for user %d: %v", userID, err)
+	}
 
 	return response, nil
 }
 
+// loadFeedPage читает одну страницу ленты из БД в обход кэша
+func (s *PostService) loadFeedPage(ctx context.Context, userID, offset, limit int) ([]dto.PostResponse, error) {
+	posts, err := s.postUC.GetFeed(ctx, userID, offset, limit)
+	if err != nil {
+		if errors.Is(err, postUC.ErrDatabaseOperation) {
+			return nil, fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
+		}
+		return nil, fmt.Errorf("failed to get feed: %w", err)
+	}
+
+	return convertPosts(posts), nil
+}
+
 func (s *PostService) warmCache(authorID int) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -265,35 +311,27 @@ func (s *PostService) warmCache(authorID int) {
 	}
 }
 
-// getFromCache получает посты из кэша
-func (s *PostService) getFromCache(ctx context.Context, userID, offset, limit int) ([]dto.PostResponse, error) {
+// getFromCache получает ленту из кэша
+func (s *PostService) getFromCache(ctx context.Context, userID int) (*feedCacheEntry, error) {
 	key := s.feedCacheKey(userID)
 
-	var cached []dto.PostResponse
+	var cached feedCacheEntry
 	if err := s.cacheWarmer.Get(ctx, key, &cached); err != nil {
 		return nil, ErrCacheMiss
 	}
 
-	// Применяем пагинацию
-	if offset >= len(cached) {
-		return []dto.PostResponse{}, nil
-	}
-
-	end := offset + limit
-	if end > len(cached) {
-		end = len(cached)
-	}
-
-	return cached[offset:end], nil
+	return &cached, nil
 }
 
 // cacheFeed сохраняет фид в кэш
-func (s *PostService) cacheFeed(userID int, posts []dto.PostResponse) error {
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
-	defer cancel()
+func (s *PostService) cacheFeed(ctx context.Context, userID int, posts []dto.PostResponse) error {
+	entry := feedCacheEntry{
+		Posts:     posts,
+		RefreshAt: time.Now().Add(s.cacheCfg.SoftTTL),
+	}
 
 	key := s.feedCacheKey(userID)
-	return s.cacheWarmer.Set(ctx, key, posts, time.Hour*24)
+	return s.cacheWarmer.Set(ctx, key, entry, s.cacheCfg.HardTTL)
 }
 
 // feedCacheKey генерирует ключ для кэша фида
@@ -310,30 +348,48 @@ func (s *PostService) PreloadUserFriendsFeeds(ctx context.Context, userID int) e
 
 	for _, friendID := range friendIDs {
 		log.Printf("Start warm for %d\n", friendID)
-		// Получаем 1000 последних постов
-		posts, err := s.postUC.GetFeed(ctx, friendID, 0, 1000)
-		if err != nil {
-			return fmt.Errorf("failed to get feed for preload: %w", err)
-		}
 
-		// Конвертируем в DTO
-		response := make([]dto.PostResponse, 0, len(posts))
-		for _, post := range posts {
-			response = append(response, dto.PostResponse{
-				ID:        post.ID,
-				AuthorID:  post.AuthorID,
-				Text:      post.Text,
-				CreatedAt: post.CreatedAt,
-				UpdatedAt: post.UpdatedAt,
-			})
-		}
+		// Загрузка, начатая до появления нового поста, не должна попасть в кэш
+		s.feedLoads.Forget(s.feedCacheKey(friendID))
 
-		// Сохраняем в кэш
-		if err := s.cacheFeed(friendID, response); err != nil {
-			return fmt.Errorf("failed to cache feed: %w", err)
+		if _, err := s.loadFeed(ctx, friendID); err != nil {
+			return fmt.Errorf("failed to preload feed: %w", err)
 		}
-
 	}
 
 	return nil
 }
+
+// paginateFeed вырезает страницу из закэшированной ленты.
+// Возвращает false, если страница выходит за пределы закэшированного окна
+func paginateFeed(posts []dto.PostResponse, offset, limit int) ([]dto.PostResponse, bool) {
+	end := offset + limit
+	if end > len(posts) && len(posts) >= feedCacheSize {
+		return nil, false
+	}
+
+	if offset >= len(posts) {
+		return []dto.PostResponse{}, true
+	}
+
+	if end > len(posts) {
+		end = len(posts)
+	}
+
+	return posts[offset:end], true
+}
+
+func convertPosts(posts []*entity.Post) []dto.PostResponse {
+	response := make([]dto.PostResponse, 0, len(posts))
+	for _, post := range posts {
+		response = append(response, dto.PostResponse{
+			ID:        post.ID,
+			AuthorID:  post.AuthorID,
+			Text:      post.Text,
+			CreatedAt: post.CreatedAt,
+			UpdatedAt: post.UpdatedAt,
+		})
+	}
+
+	return response
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/transport/dto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// blockingPostUC отдает ленту из posts после закрытия release и считает загрузки
+type blockingPostUC struct {
+	postUseCase
+
+	loads   atomic.Int32
+	started chan struct{}
+	release chan struct{}
+	posts   []*entity.Post
+}
+
+func newBlockingPostUC(posts ...*entity.Post) *blockingPostUC {
+	return &blockingPostUC{
+		started: make(chan struct{}, 1),
+		release: make(chan struct{}),
+		posts:   posts,
+	}
+}
+
+func (u *blockingPostUC) GetFeed(ctx context.Context, _, _, _ int) ([]*entity.Post, error) {
+	u.loads.Add(1)
+	select {
+	case u.started <- struct{}{}:
+	default:
+	}
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-u.release:
+		return u.posts, nil
+	}
+}
+
+// memoryCache хранит записи в JSON, как CacheWarmer
+type memoryCache struct {
+	mu    sync.Mutex
+	items map[string][]byte
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{items: make(map[string][]byte)}
+}
+
+func (c *memoryCache) WarmForNewPost(context.Context, int) error {
+	return nil
+}
+
+func (c *memoryCache) Set(_ context.Context, key string, value any, _ time.Duration) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items[key] = data
+	return nil
+}
+
+func (c *memoryCache) Get(_ context.Context, key string, dest any) error {
+	c.mu.Lock()
+	data, ok := c.items[key]
+	c.mu.Unlock()
+	if !ok {
+		return errors.New("cache miss")
+	}
+
+	return json.Unmarshal(data, dest)
+}
+
+func feedIDs(posts []dto.PostResponse) []string {
+	ids := make([]string, 0, len(posts))
+	for _, post := range posts {
+		ids = append(ids, post.ID)
+	}
+	return ids
+}
+
+func TestPostService_GetFeed_MissLoadsOnce(t *testing.T) {
+	const userID, clients = 1, 20
+
+	uc := newBlockingPostUC(&entity.Post{ID: "p-1"}, &entity.Post{ID: "p-2"})
+	cache := newMemoryCache()
+	svc := NewPostService(uc, nil, cache, FeedCacheConfig{SoftTTL: time.Minute, HardTTL: time.Hour})
+
+	var wg sync.WaitGroup
+	results := make([][]dto.PostResponse, clients)
+	errs := make([]error, clients)
+	for i := range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i], errs[i] = svc.GetFeed(context.Background(), userID, 0, 10)
+		}()
+	}
+
+	// Пока загрузка не завершена, остальные клиенты присоединяются к ней
+	<-uc.started
+	time.Sleep(50 * time.Millisecond)
+	close(uc.release)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), uc.loads.Load(), "одна загрузка из БД на все промахи")
+	for i := range clients {
+		require.NoError(t, errs[i])
+		assert.Equal(t, []string{"p-1", "p-2"}, feedIDs(results[i]))
+	}
+
+	// Загруженная лента попала в кэш
+	_, err := svc.GetFeed(context.Background(), userID, 0, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), uc.loads.Load())
+}
+
+func TestPostService_GetFeed_StaleRefreshesOnce(t *testing.T) {
+	const userID, clients = 1, 20
+
+	uc := newBlockingPostUC(&entity.Post{ID: "new"})
+	cache := newMemoryCache()
+	svc := NewPostService(uc, nil, cache, FeedCacheConfig{SoftTTL: time.Minute, HardTTL: time.Hour})
+
+	stale := feedCacheEntry{
+		Posts:     []dto.PostResponse{{ID: "old"}},
+		RefreshAt: time.Now().Add(-time.Second),
+	}
+	require.NoError(t, cache.Set(context.Background(), svc.feedCacheKey(userID), stale, time.Hour))
+
+	// Устаревшая лента отдается сразу, хотя перестройка еще идет
+	var wg sync.WaitGroup
+	for range clients {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			posts, err := svc.GetFeed(context.Background(), userID, 0, 10)
+			assert.NoError(t, err)
+			assert.Equal(t, []string{"old"}, feedIDs(posts))
+		}()
+	}
+	wg.Wait()
+
+	<-uc.started
+	assert.Equal(t, int32(1), uc.loads.Load(), "одна фоновая перестройка")
+
+	close(uc.release)
+	assert.Eventually(t, func() bool {
+		posts, err := svc.GetFeed(context.Background(), userID, 0, 10)
+		return err == nil && len(posts) == 1 && posts[0].ID == "new"
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int32(1), uc.loads.Load())
+}
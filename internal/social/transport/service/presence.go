@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/transport/dto"
+	userUC "otus-highload-arh-homework/internal/social/usecase/user"
+)
+
+// maxPresenceIDs ограничивает размер пакетного запроса присутствия
+const maxPresenceIDs = 100
+
+// GetPresence возвращает присутствие пользователей из списка ids через запятую.
+// Несуществующие пользователи пропускаются
+func (s *UserService) GetPresence(ctx context.Context, viewerID int, idsStr string) ([]dto.PresenceResponse, error) {
+	ids, err := parseIDs(idsStr)
+	if err != nil {
+		return nil, err
+	}
+
+	visibility, err := s.userUC.GetLastSeenVisibility(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presence settings: %w", err)
+	}
+
+	existing := ids[:0]
+	for _, id := range ids {
+		if _, ok := visibility[id]; ok {
+			existing = append(existing, id)
+		}
+	}
+
+	return s.visiblePresence(ctx, viewerID, existing, visibility)
+}
+
+// SetLastSeenVisibility меняет настройку приватности присутствия
+func (s *UserService) SetLastSeenVisibility(ctx context.Context, userID int, visibility entity.Visibility) error {
+	err := s.userUC.SetLastSeenVisibility(ctx, userID, visibility)
+	switch {
+	case errors.Is(err, userUC.ErrInvalidVisibility):
+		return ErrInvalidVisibility
+	case errors.Is(err, userUC.ErrUserNotFound):
+		return ErrUserNotFound
+	case err != nil:
+		return fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
+	}
+
+	return nil
+}
+
+// visiblePresence возвращает присутствие ids с учетом их настроек приватности
+func (s *UserService) visiblePresence(ctx context.Context, viewerID int, ids []int, visibility map[int]entity.Visibility) ([]dto.PresenceResponse, error) {
+	statuses, err := s.presence.Get(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get presence: %w", err)
+	}
+
+	// Список друзей нужен, только если кто-то показывает присутствие лишь друзьям
+	var friends map[int]bool
+	for _, id := range ids {
+		if visibility[id] == entity.VisibilityFriends && id != viewerID {
+			friendIDs, err := s.friendUC.GetFriendsIDs(ctx, viewerID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get friends: %w", err)
+			}
+			friends = make(map[int]bool, len(friendIDs))
+			for _, friendID := range friendIDs {
+				friends[friendID] = true
+			}
+			break
+		}
+	}
+
+	result := make([]dto.PresenceResponse, 0, len(statuses))
+	for _, status := range statuses {
+		response := dto.PresenceResponse{UserID: status.UserID}
+		if visibility[status.UserID].VisibleTo(status.UserID, viewerID, friends[status.UserID]) {
+			response.Online = status.Online
+			if !status.LastSeenAt.IsZero() {
+				lastSeenAt := status.LastSeenAt
+				response.LastSeenAt = &lastSeenAt
+			}
+		}
+		result = append(result, response)
+	}
+
+	return result, nil
+}
+
+func parseIDs(idsStr string) ([]int, error) {
+	if strings.TrimSpace(idsStr) == "" {
+		return nil, fmt.Errorf("%w: ids is required", ErrInvalidPresenceQuery)
+	}
+
+	parts := strings.Split(idsStr, ",")
+	if len(parts) > maxPresenceIDs {
+		return nil, fmt.Errorf("%w: at most %d ids allowed", ErrInvalidPresenceQuery, maxPresenceIDs)
+	}
+
+	seen := make(map[int]bool, len(parts))
+	ids := make([]int, 0, len(parts))
+	for _, part := range parts {
+		id, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || id <= 0 {
+			return nil, fmt.Errorf("%w: invalid id %q", ErrInvalidPresenceQuery, part)
+		}
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+
+	return ids, nil
+}
@@ -10,14 +10,23 @@ import (
 	"otus-highload-arh-homework/internal/social/entity"
 	"otus-highload-arh-homework/internal/social/transport/clients/dialog/grpc"
 	"otus-highload-arh-homework/internal/social/transport/dto"
+	"otus-highload-arh-homework/internal/social/transport/presence"
 	userUC "otus-highload-arh-homework/internal/social/usecase/user"
+	dialogv1 "otus-highload-arh-homework/pkg/proto/dialog/v1"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type userUserCase interface {
 	GetByID(ctx context.Context, id int) (*entity.User, error)
 	Search(ctx context.Context, firstName, lastName string) ([]*entity.User, error)
-	SendDialogMessage(ctx context.Context, senderID, receiverID int64, text string) error
-	GetDialogMessages(ctx context.Context, user1ID, user2ID int64) ([]*entity.DialogMessage, error)
+	GetLastSeenVisibility(ctx context.Context, ids []int) (map[int]entity.Visibility, error)
+	SetLastSeenVisibility(ctx context.Context, userID int, visibility entity.Visibility) error
+}
+
+type presenceStore interface {
+	Get(ctx context.Context, ids []int) ([]presence.Status, error)
 }
 
 type friendUseCase interface {
@@ -30,17 +39,20 @@ type friendUseCase interface {
 type UserService struct {
 	userUC       userUserCase
 	friendUC     friendUseCase
+	presence     presenceStore
 	dialogClient *grpc.Client
 }
 
 func NewUserService(
 	userUC userUserCase,
 	friendUC friendUseCase,
+	presence presenceStore,
 	dialogClient *grpc.Client,
 ) *UserService {
 	return &UserService{
 		userUC:       userUC,
 		friendUC:     friendUC,
+		presence:     presence,
 		dialogClient: dialogClient,
 	}
 }
@@ -67,6 +79,13 @@ func (s *UserService) GetUserByID(ctx context.Context, subID int, requestID stri
 
 	// Преобразование в DTO
 	response := dto.ConvertUserToResponse(user)
+
+	statuses, err := s.visiblePresence(ctx, subID, []int{userID}, map[int]entity.Visibility{userID: user.LastSeenVisibility})
+	if err != nil {
+		return nil, err
+	}
+	response.LastSeenAt = statuses[0].LastSeenAt
+
 	return &response, nil
 }
 
@@ -152,7 +171,13 @@ func (s *UserService) DeleteFriend(ctx context.Context, userID int, friendIDStr
 	return nil
 }
 
-func (s *UserService) SendDialogMessage(ctx context.Context, senderID, receiverID int64, text string) error {
+// v1SentAtLayout - формат sent_at в ответах /api/v1/dialog
+const v1SentAtLayout = "2006-01-02 15:04:05"
+
+// SendDialogMessage отправляет сообщение для /api/v1 через сервис диалогов.
+// Ошибки повторяют прежнюю работу с usecase напрямую, от них зависят
+// ответы ручки v1
+func (s *UserService) SendDialogMessage(ctx context.Context, senderID, receiverID int64, text, clientMessageID string) error {
 	// Валидация
 	if len(text) == 0 {
 		return errors.New("message text cannot be empty")
@@ -164,76 +189,282 @@ func (s *UserService) SendDialogMessage(ctx context.Context, senderID, receiverI
 		return errors.New("receiver not found")
 	}
 
-	return s.userUC.SendDialogMessage(ctx, senderID, receiverID, text)
+	_, err = s.dialogClient.SendMessage(ctx, strconv.FormatInt(senderID, 10), strconv.FormatInt(receiverID, 10), text, clientMessageID)
+	switch status.Code(err) {
+	case codes.OK:
+		return nil
+	case codes.NotFound:
+		return errors.New("receiver not found")
+	case codes.InvalidArgument:
+		// Пустой текст и неверные ID отсеяны выше, остается client_message_id
+		return userUC.ErrInvalidClientMessageID
+	}
+
+	return fmt.Errorf("gRPC SendMessage failed: %w", err)
 }
 
-func (s *UserService) GetDialogMessages(ctx context.Context, currentUserID, otherUserID int64) ([]dto.DialogMessage, error) {
-	// Получаем сообщения из репозитория
-	messages, err := s.userUC.GetDialogMessages(ctx, currentUserID, otherUserID)
+// GetDialogMessages возвращает страницу диалога для /api/v1 через сервис
+// диалогов и курсор следующей страницы
+func (s *UserService) GetDialogMessages(ctx context.Context, currentUserID, otherUserID int64, page dto.MessagesPageQuery) ([]dto.DialogMessage, string, error) {
+	if _, err := parseMessagesPage(page); err != nil {
+		return nil, "", err
+	}
+
+	currentUserIDStr := strconv.FormatInt(currentUserID, 10)
+	messages, nextCursor, err := s.dialogClient.GetMessages(ctx, currentUserIDStr, strconv.FormatInt(otherUserID, 10), page.Before, page.After, page.Limit)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get dialog: %w", err)
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, "", fmt.Errorf("%w: set either before or after", ErrInvalidPaginationParams)
+		}
+		return nil, "", fmt.Errorf("failed to get dialog: %w", err)
 	}
 
 	// Конвертируем в DTO и устанавливаем флаг IsOwn
-	currentUserIDStr := strconv.FormatInt(currentUserID, 10)
 	result := make([]dto.DialogMessage, 0, len(messages))
-
 	for _, msg := range messages {
 		result = append(result, dto.DialogMessage{
-			SenderID:   msg.SenderID,
-			ReceiverID: msg.ReceiverID,
-			Text:       msg.Text,
-			SentAtStr:  msg.SentAt.Format("2006-01-02 15:04:05"),
-			IsOwn:      msg.SenderID == currentUserIDStr,
+			SenderID:   msg.GetSenderId(),
+			ReceiverID: msg.GetReceiverId(),
+			Text:       msg.GetText(),
+			// Время без зоны, как его отдавал pgx: в локальной зоне процесса
+			SentAtStr: msg.GetSentAt().AsTime().Local().Format(v1SentAtLayout),
+			IsOwn:     msg.GetSenderId() == currentUserIDStr,
 		})
 	}
 
-	return result, nil
+	return result, nextCursor, nil
 }
 
-func (s *UserService) SendDialogMessageV2(ctx context.Context, senderID int, receiverIDStr, text string) error {
+// SendDialogMessageV2 отправляет сообщение через сервис диалогов. Повтор с
+// тем же clientMessageID возвращает id и время уже отправленного сообщения
+func (s *UserService) SendDialogMessageV2(ctx context.Context, senderID int, receiverIDStr, text, clientMessageID string) (*dto.SentMessageV2, error) {
 	// Валидация параметров
 	if strings.TrimSpace(text) == "" {
-		return errors.New("message text cannot be empty")
+		return nil, fmt.Errorf("%w: message text cannot be empty", ErrValidation)
 	}
 
 	if _, err := strconv.Atoi(receiverIDStr); err != nil {
-		return fmt.Errorf("invalid receiver ID: %w", err)
+		return nil, fmt.Errorf("%w: invalid receiver ID", ErrValidation)
 	}
 
 	// Вызов gRPC клиента
-	err := s.dialogClient.SendMessage(ctx, strconv.Itoa(senderID), receiverIDStr, text)
+	resp, err := s.dialogClient.SendMessage(ctx, strconv.Itoa(senderID), receiverIDStr, text, clientMessageID)
 	if err != nil {
-		return fmt.Errorf("gRPC SendMessage failed: %w", err)
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, fmt.Errorf("%w: %s", ErrValidation, status.Convert(err).Message())
+		}
+		return nil, fmt.Errorf("gRPC SendMessage failed: %w", err)
 	}
 
-	return nil
+	return &dto.SentMessageV2{
+		ID:              resp.MessageId,
+		ClientMessageID: clientMessageID,
+		SentAt:          resp.SentAt.AsTime(),
+		Duplicate:       resp.Duplicate,
+	}, nil
 }
 
-func (s *UserService) GetDialogMessagesV2(ctx context.Context, currentUserID int, otherUserIDStr string) ([]dto.DialogMessageV2, error) {
+func (s *UserService) GetDialogMessagesV2(ctx context.Context, currentUserID int, otherUserIDStr string, page dto.MessagesPageQuery) ([]dto.DialogMessageV2, string, error) {
 	// Валидация ID собеседника
 	if _, err := strconv.Atoi(otherUserIDStr); err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, "", fmt.Errorf("invalid user ID: %w", err)
+	}
+
+	if _, err := parseMessagesPage(page); err != nil {
+		return nil, "", err
 	}
 
 	// Вызов gRPC клиента
-	messages, err := s.dialogClient.GetMessages(ctx, strconv.Itoa(currentUserID), otherUserIDStr)
+	messages, nextCursor, err := s.dialogClient.GetMessages(ctx, strconv.Itoa(currentUserID), otherUserIDStr, page.Before, page.After, page.Limit)
 	if err != nil {
-		return nil, fmt.Errorf("gRPC GetMessages failed: %w", err)
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidPaginationParams, status.Convert(err).Message())
+		}
+		return nil, "", fmt.Errorf("gRPC GetMessages failed: %w", err)
 	}
 
 	// Конвертация protobuf -> DTO
 	result := make([]dto.DialogMessageV2, 0, len(messages))
 	for _, msg := range messages {
-		result = append(result, dto.DialogMessageV2{
-			ID:         msg.MessageId,
-			SenderID:   msg.SenderId,
-			ReceiverID: msg.ReceiverId,
-			Text:       msg.Text,
-			SentAt:     msg.SentAt.AsTime(),
-			IsOwn:      msg.SenderId == strconv.Itoa(currentUserID),
+		result = append(result, toDialogMessageV2(msg, strconv.Itoa(currentUserID)))
+	}
+
+	return result, nextCursor, nil
+}
+
+// ListDialogsV2 возвращает страницу списка диалогов через сервис диалогов
+func (s *UserService) ListDialogsV2(ctx context.Context, currentUserID int, page dto.DialogsPageQuery) ([]dto.DialogPreviewV2, string, error) {
+	if page.Cursor != "" {
+		if cursor, err := strconv.ParseInt(page.Cursor, 10, 64); err != nil || cursor <= 0 {
+			return nil, "", fmt.Errorf("%w: invalid cursor", ErrInvalidPaginationParams)
+		}
+	}
+	if page.Limit < 0 {
+		return nil, "", fmt.Errorf("%w: limit must be positive", ErrInvalidPaginationParams)
+	}
+
+	currentUserIDStr := strconv.Itoa(currentUserID)
+	dialogs, nextCursor, err := s.dialogClient.ListDialogs(ctx, currentUserIDStr, page.Cursor, page.Limit)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, "", fmt.Errorf("%w: %s", ErrInvalidPaginationParams, status.Convert(err).Message())
+		}
+		return nil, "", fmt.Errorf("gRPC ListDialogs failed: %w", err)
+	}
+
+	result := make([]dto.DialogPreviewV2, 0, len(dialogs))
+	for _, dialog := range dialogs {
+		result = append(result, dto.DialogPreviewV2{
+			PeerID:      dialog.PeerId,
+			LastMessage: toDialogMessageV2(dialog.LastMessage, currentUserIDStr),
+			UnreadCount: int(dialog.UnreadCount),
 		})
 	}
 
-	return result, nil
+	return result, nextCursor, nil
+}
+
+// MarkReadV2 отмечает прочитанными сообщения собеседника через сервис диалогов
+func (s *UserService) MarkReadV2(ctx context.Context, currentUserID int, peerIDStr, upToMessageID string) (*dto.MarkReadResponseV2, error) {
+	if _, err := strconv.Atoi(peerIDStr); err != nil {
+		return nil, fmt.Errorf("%w: invalid user ID", ErrValidation)
+	}
+	if id, err := strconv.ParseInt(upToMessageID, 10, 64); err != nil || id <= 0 {
+		return nil, fmt.Errorf("%w: invalid up_to_message_id", ErrValidation)
+	}
+
+	resp, err := s.dialogClient.MarkRead(ctx, strconv.Itoa(currentUserID), peerIDStr, upToMessageID)
+	if err != nil {
+		if status.Code(err) == codes.InvalidArgument {
+			return nil, fmt.Errorf("%w: %s", ErrValidation, status.Convert(err).Message())
+		}
+		return nil, fmt.Errorf("gRPC MarkRead failed: %w", err)
+	}
+
+	return &dto.MarkReadResponseV2{
+		Marked:      int(resp.Marked),
+		UnreadCount: int(resp.UnreadCount),
+		TotalUnread: int(resp.TotalUnread),
+	}, nil
+}
+
+// GetUnreadCountersV2 возвращает счетчики непрочитанных через сервис диалогов
+func (s *UserService) GetUnreadCountersV2(ctx context.Context, currentUserID int) (*dto.UnreadCountersV2, error) {
+	resp, err := s.dialogClient.GetUnreadCounters(ctx, strconv.Itoa(currentUserID))
+	if err != nil {
+		return nil, fmt.Errorf("gRPC GetUnreadCounters failed: %w", err)
+	}
+
+	dialogs := make(map[string]int, len(resp.Dialogs))
+	for peerID, count := range resp.Dialogs {
+		dialogs[peerID] = int(count)
+	}
+
+	return &dto.UnreadCountersV2{
+		TotalUnread: int(resp.TotalUnread),
+		Dialogs:     dialogs,
+	}, nil
+}
+
+// EditMessageV2 меняет текст своего сообщения через сервис диалогов
+func (s *UserService) EditMessageV2(ctx context.Context, currentUserID int, peerIDStr, messageID, text string) (*dto.DialogMessageV2, error) {
+	if err := validateMessageRef(peerIDStr, messageID); err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("%w: message text cannot be empty", ErrValidation)
+	}
+
+	currentUserIDStr := strconv.Itoa(currentUserID)
+	msg, err := s.dialogClient.EditMessage(ctx, currentUserIDStr, peerIDStr, messageID, text)
+	if err != nil {
+		return nil, messageStatusError(err, "gRPC EditMessage failed")
+	}
+
+	result := toDialogMessageV2(msg, currentUserIDStr)
+	return &result, nil
+}
+
+// DeleteMessageV2 удаляет сообщение у текущего пользователя или, с
+// forEveryone, у обоих участников диалога
+func (s *UserService) DeleteMessageV2(ctx context.Context, currentUserID int, peerIDStr, messageID string, forEveryone bool) error {
+	if err := validateMessageRef(peerIDStr, messageID); err != nil {
+		return err
+	}
+
+	if err := s.dialogClient.DeleteMessage(ctx, strconv.Itoa(currentUserID), peerIDStr, messageID, forEveryone); err != nil {
+		return messageStatusError(err, "gRPC DeleteMessage failed")
+	}
+
+	return nil
+}
+
+func validateMessageRef(peerIDStr, messageID string) error {
+	if _, err := strconv.Atoi(peerIDStr); err != nil {
+		return fmt.Errorf("%w: invalid user ID", ErrValidation)
+	}
+	if id, err := strconv.ParseInt(messageID, 10, 64); err != nil || id <= 0 {
+		return fmt.Errorf("%w: invalid message ID", ErrValidation)
+	}
+
+	return nil
+}
+
+// messageStatusError переводит статусы gRPC изменения сообщения в ошибки сервиса
+func messageStatusError(err error, msg string) error {
+	switch status.Code(err) {
+	case codes.InvalidArgument:
+		return fmt.Errorf("%w: %s", ErrValidation, status.Convert(err).Message())
+	case codes.NotFound:
+		return ErrMessageNotFound
+	case codes.PermissionDenied:
+		return ErrNotMessageSender
+	case codes.FailedPrecondition:
+		return ErrEditWindowExpired
+	}
+
+	return fmt.Errorf("%s: %w", msg, err)
+}
+
+func toDialogMessageV2(msg *dialogv1.DialogMessage, currentUserIDStr string) dto.DialogMessageV2 {
+	result := dto.DialogMessageV2{
+		ID:         msg.GetMessageId(),
+		SenderID:   msg.GetSenderId(),
+		ReceiverID: msg.GetReceiverId(),
+		Text:       msg.GetText(),
+		SentAt:     msg.GetSentAt().AsTime(),
+		IsOwn:      msg.GetSenderId() == currentUserIDStr,
+		Deleted:    msg.GetDeleted(),
+	}
+	if msg.GetEditedAt() != nil {
+		editedAt := msg.GetEditedAt().AsTime()
+		result.EditedAt = &editedAt
+	}
+
+	return result
+}
+
+// parseMessagesPage разбирает курсоры истории диалога
+func parseMessagesPage(page dto.MessagesPageQuery) (entity.MessagesQuery, error) {
+	query := entity.MessagesQuery{Limit: page.Limit}
+
+	var err error
+	if page.Before != "" {
+		if query.Before, err = strconv.ParseInt(page.Before, 10, 64); err != nil || query.Before <= 0 {
+			return query, fmt.Errorf("%w: invalid before cursor", ErrInvalidPaginationParams)
+		}
+	}
+	if page.After != "" {
+		if query.After, err = strconv.ParseInt(page.After, 10, 64); err != nil || query.After <= 0 {
+			return query, fmt.Errorf("%w: invalid after cursor", ErrInvalidPaginationParams)
+		}
+	}
+	if query.Before > 0 && query.After > 0 {
+		return query, fmt.Errorf("%w: set either before or after", ErrInvalidPaginationParams)
+	}
+	if page.Limit < 0 {
+		return query, fmt.Errorf("%w: limit must be positive", ErrInvalidPaginationParams)
+	}
+
+	return query, nil
 }
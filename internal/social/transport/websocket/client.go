@@ -0,0 +1,330 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// client - одно соединение пользователя (websocket или SSE). Запись в
+// соединение выполняет одна горутина, а отправители кладут события в
+// ограниченную очередь send. gorilla/websocket не допускает конкурентной записи
+type client struct {
+	server *Server
+	conn   *websocket.Conn // nil для SSE
+	userID int
+
+	send      chan Message
+	done      chan struct{}
+	closeOnce sync.Once
+
+	mu     sync.Mutex
+	topics map[string]bool
+	// Пока идет догрузка пропущенных событий, живые события копятся в pending
+	replaying bool
+	pending   []Event
+	lastID    uint64
+}
+
+func newClient(server *Server, conn *websocket.Conn, userID int, topics []string) *client {
+	c := &client{
+		server: server,
+		conn:   conn,
+		userID: userID,
+		send:   make(chan Message, server.sendQueueSize),
+		done:   make(chan struct{}),
+		topics: make(map[string]bool, len(topics)),
+	}
+	for _, topic := range topics {
+		c.topics[topic] = true
+	}
+
+	return c
+}
+
+// enqueue ставит событие в очередь, если клиент подписан на его тему.
+// Не блокируется; возвращает false, если очередь переполнена
+func (c *client) enqueue(ev Event) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.replaying {
+		if len(c.pending) >= c.server.sendQueueSize {
+			return false
+		}
+		c.pending = append(c.pending, ev)
+		return true
+	}
+
+	return c.enqueueLocked(ev)
+}
+
+func (c *client) enqueueLocked(ev Event) bool {
+	// Событие уже отправлено при догрузке
+	if ev.Seq != 0 && ev.Seq <= c.lastID {
+		return true
+	}
+	if !c.topics[ev.Topic()] {
+		return true
+	}
+
+	if !c.push(ev.message()) {
+		return false
+	}
+	if ev.Seq != 0 {
+		c.lastID = ev.Seq
+	}
+
+	return true
+}
+
+// reply отправляет ответ на команду клиента
+func (c *client) reply(msg Message) {
+	if !c.push(msg) {
+		log.Printf("Slow consumer of user %d, disconnecting", c.userID)
+		c.close()
+	}
+}
+
+func (c *client) push(msg Message) bool {
+	select {
+	case <-c.done:
+		return true
+	case c.send <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// subscribedLocked возвращает текущие подписки в стабильном порядке
+func (c *client) subscribedLocked() []string {
+	topics := make([]string, 0, len(c.topics))
+	for topic := range c.topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	return topics
+}
+
+// replay отправляет события после lastID из истории, затем накопленные
+// за это время живые события, и переводит клиента в обычный режим
+func (c *client) replay(lastID uint64) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.server.writeWait)
+	events, err := c.server.history.Since(ctx, c.userID, lastID)
+	cancel()
+	if err != nil {
+		log.Printf("Failed to replay events for user %d: %v", c.userID, err)
+	}
+
+	c.mu.Lock()
+	c.lastID = lastID
+	c.mu.Unlock()
+
+	for _, ev := range events {
+		c.mu.Lock()
+		subscribed := c.topics[ev.Topic()]
+		c.lastID = ev.Seq
+		c.mu.Unlock()
+		if !subscribed {
+			continue
+		}
+
+		// История может быть больше очереди, поэтому здесь ждем писателя
+		select {
+		case <-c.done:
+			return
+		case c.send <- ev.message():
+		case <-time.After(c.server.writeWait):
+			log.Printf("Slow websocket consumer of user %d during replay, disconnecting", c.userID)
+			c.close()
+			return
+		}
+	}
+
+	c.mu.Lock()
+	pending := c.pending
+	c.pending = nil
+	c.replaying = false
+	for _, ev := range pending {
+		if !c.enqueueLocked(ev) {
+			c.mu.Unlock()
+			c.close()
+			return
+		}
+	}
+	c.mu.Unlock()
+}
+
+// close снимает соединение с учета и закрывает его. Безопасен для повторного вызова
+func (c *client) close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.server.unregister(c)
+		if c.conn != nil {
+			_ = c.conn.Close()
+		}
+	})
+}
+
+// readPump читает команды клиента и обрабатывает pong и закрытие.
+// Соединение без pong дольше pongWait считается мертвым
+func (c *client) readPump() {
+	defer c.close()
+
+	c.conn.SetReadLimit(maxMessageSize)
+	_ = c.conn.SetReadDeadline(time.Now().Add(c.server.pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		c.server.touch(c.userID)
+		return c.conn.SetReadDeadline(time.Now().Add(c.server.pongWait))
+	})
+
+	for {
+		_, data, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var cmd Message
+		if err := json.Unmarshal(data, &cmd); err != nil {
+			c.reply(errorMessage("", ErrorBadRequest, "message is not a valid envelope"))
+			continue
+		}
+
+		c.reply(c.handleCommand(cmd))
+	}
+}
+
+// handleCommand выполняет команду клиента и возвращает ответ на неё
+func (c *client) handleCommand(cmd Message) Message {
+	switch cmd.Type {
+	case CommandPing:
+		return Message{Type: MessageAck, ID: cmd.ID}
+	case CommandSubscribe, CommandUnsubscribe:
+		var payload SubscriptionPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil || len(payload.Topics) == 0 {
+			return errorMessage(cmd.ID, ErrorBadRequest, "payload.topics is required")
+		}
+		for _, topic := range payload.Topics {
+			if !knownTopics[topic] {
+				return errorMessage(cmd.ID, ErrorUnknownTopic, "unknown topic "+strconv.Quote(topic))
+			}
+		}
+
+		c.mu.Lock()
+		for _, topic := range payload.Topics {
+			if cmd.Type == CommandSubscribe {
+				c.topics[topic] = true
+			} else {
+				delete(c.topics, topic)
+			}
+		}
+		topics := c.subscribedLocked()
+		c.mu.Unlock()
+
+		return ackMessage(cmd.ID, topics)
+	case CommandTyping:
+		var payload TypingPayload
+		if err := json.Unmarshal(cmd.Payload, &payload); err != nil || payload.UserID <= 0 {
+			return errorMessage(cmd.ID, ErrorBadRequest, "payload.user_id is required")
+		}
+		if payload.UserID == c.userID {
+			return errorMessage(cmd.ID, ErrorBadRequest, "cannot send typing to yourself")
+		}
+
+		if err := c.server.relay(payload.UserID, EventDialogTyping, TypingPayload{UserID: c.userID}); err != nil {
+			log.Printf("Failed to relay typing from user %d to %d: %v", c.userID, payload.UserID, err)
+			return errorMessage(cmd.ID, ErrorInternal, "failed to relay typing")
+		}
+
+		return Message{Type: MessageAck, ID: cmd.ID}
+	default:
+		return errorMessage(cmd.ID, ErrorUnknownType, "unknown message type "+strconv.Quote(cmd.Type))
+	}
+}
+
+// writePump - единственный писатель в websocket: события из очереди и ping
+func (c *client) writePump() {
+	ticker := time.NewTicker(c.server.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case msg := <-c.send:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.server.writeWait))
+			if err := c.conn.WriteJSON(msg); err != nil {
+				log.Printf("Failed to write to websocket of user %d: %v", c.userID, err)
+				return
+			}
+		case <-ticker.C:
+			_ = c.conn.SetWriteDeadline(time.Now().Add(c.server.writeWait))
+			if err := c.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// ssePump - писатель SSE-потока. Работает в горутине HTTP-обработчика
+// до закрытия клиента или разрыва запроса
+func (c *client) ssePump(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+	ticker := time.NewTicker(c.server.pingPeriod)
+	defer func() {
+		ticker.Stop()
+		c.close()
+	}()
+
+	write := func(chunk string) bool {
+		_ = rc.SetWriteDeadline(time.Now().Add(c.server.writeWait))
+		if _, err := fmt.Fprint(w, chunk); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-r.Context().Done():
+			return
+		case msg := <-c.send:
+			data, err := json.Marshal(msg)
+			if err != nil {
+				log.Printf("Failed to marshal event for user %d: %v", c.userID, err)
+				continue
+			}
+
+			// Тип события дублируется в поле event, чтобы клиент мог
+			// подписаться через EventSource.addEventListener
+			chunk := "event: " + msg.Type + "\ndata: " + string(data) + "\n\n"
+			if msg.ID != "" {
+				chunk = "id: " + msg.ID + "\n" + chunk
+			}
+			if !write(chunk) {
+				return
+			}
+		case <-ticker.C:
+			// Комментарий поддерживает соединение через прокси
+			if !write(": ping\n\n") {
+				return
+			}
+			c.server.touch(c.userID)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	defaultHistorySize = 100
+	defaultHistoryTTL  = 10 * time.Minute
+)
+
+// appendScript атомарно выдает следующий номер и кладет событие в стрим
+// пользователя с этим номером в качестве ID записи (0-<seq>). Счетчик не
+// истекает, чтобы номера не начинались заново после простоя
+var appendScript = redis.NewScript(`
+local seq = redis.call('INCR', KEYS[1])
+redis.call('XADD', KEYS[2], 'MAXLEN', '~', ARGV[3], '0-' .. seq, 'type', ARGV[1], 'payload', ARGV[2])
+redis.call('EXPIRE', KEYS[2], ARGV[4])
+return seq
+`)
+
+// History хранит последние события пользователя в коротком Redis-стриме
+type History struct {
+	client *redis.Client
+	size   int
+	ttl    time.Duration
+}
+
+func NewHistory(client *redis.Client, size int, ttl time.Duration) *History {
+	if size <= 0 {
+		size = defaultHistorySize
+	}
+	if ttl <= 0 {
+		ttl = defaultHistoryTTL
+	}
+
+	return &History{client: client, size: size, ttl: ttl}
+}
+
+// Append присваивает событию очередной номер и сохраняет его
+func (h *History) Append(ctx context.Context, userID int, eventType string, payload []byte) (Event, error) {
+	seq, err := appendScript.Run(ctx, h.client,
+		[]string{historySeqKey(userID), historyStreamKey(userID)},
+		eventType, payload, h.size, int(h.ttl.Seconds()),
+	).Int64()
+	if err != nil {
+		return Event{}, fmt.Errorf("failed to append event for user %d: %w", userID, err)
+	}
+
+	return Event{Seq: uint64(seq), Type: eventType, Payload: payload}, nil
+}
+
+// Since возвращает сохраненные события с номером больше lastID. Если часть
+// событий уже вытеснена, клиент увидит разрыв в номерах
+func (h *History) Since(ctx context.Context, userID int, lastID uint64) ([]Event, error) {
+	messages, err := h.client.XRange(ctx, historyStreamKey(userID),
+		"0-"+strconv.FormatUint(lastID+1, 10), "+").Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read events of user %d: %w", userID, err)
+	}
+
+	events := make([]Event, 0, len(messages))
+	for _, msg := range messages {
+		seq, err := strconv.ParseUint(strings.TrimPrefix(msg.ID, "0-"), 10, 64)
+		if err != nil {
+			continue
+		}
+		eventType, _ := msg.Values["type"].(string)
+		payload, _ := msg.Values["payload"].(string)
+		events = append(events, Event{Seq: seq, Type: eventType, Payload: json.RawMessage(payload)})
+	}
+
+	return events, nil
+}
+
+func historySeqKey(userID int) string {
+	return userKeyPrefix + strconv.Itoa(userID) + ":seq"
+}
+
+func historyStreamKey(userID int) string {
+	return userKeyPrefix + strconv.Itoa(userID) + ":events"
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const registryTimeout = 5 * time.Second
+
+// Node связывает локальный Server с кластером: регистрирует соединения
+// пользователей в Registry и доставляет сообщения из канала узла
+type Node struct {
+	id       string
+	server   *Server
+	registry *Registry
+	client   *redis.Client
+}
+
+// NewNode создает узел. Пустой nodeID заменяется на hostname-pid
+func NewNode(client *redis.Client, server *Server, registry *Registry, nodeID string) *Node {
+	if nodeID == "" {
+		nodeID = defaultNodeID()
+	}
+
+	n := &Node{
+		id:       nodeID,
+		server:   server,
+		registry: registry,
+		client:   client,
+	}
+	server.setListener(n)
+
+	return n
+}
+
+func (n *Node) ID() string {
+	return n.id
+}
+
+// Start подписывается на канал узла и запускает heartbeat.
+// Узел работает до отмены ctx, после чего снимает признак жизни
+func (n *Node) Start(ctx context.Context) error {
+	if err := n.registry.Heartbeat(ctx, n.id); err != nil {
+		return fmt.Errorf("failed to register node %s: %w", n.id, err)
+	}
+
+	sub := n.client.Subscribe(ctx, nodeChannel(n.id))
+	// Дожидаемся подтверждения подписки, чтобы не потерять первые сообщения
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return fmt.Errorf("failed to subscribe node %s: %w", n.id, err)
+	}
+
+	go n.heartbeat(ctx)
+	go n.consume(ctx, sub)
+
+	return nil
+}
+
+func (n *Node) heartbeat(ctx context.Context) {
+	ticker := time.NewTicker(n.registry.ttl / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			leaveCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), registryTimeout)
+			if err := n.registry.Leave(leaveCtx, n.id); err != nil {
+				log.Printf("Node %s: failed to leave registry: %v", n.id, err)
+			}
+			cancel()
+			return
+		case <-ticker.C:
+			if err := n.registry.Heartbeat(ctx, n.id); err != nil {
+				log.Printf("Node %s: heartbeat failed: %v", n.id, err)
+			}
+		}
+	}
+}
+
+func (n *Node) consume(ctx context.Context, sub *redis.PubSub) {
+	defer sub.Close()
+
+	ch := sub.Channel()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg, ok := <-ch:
+			if !ok {
+				return
+			}
+
+			var routed routedMessage
+			if err := json.Unmarshal([]byte(msg.Payload), &routed); err != nil {
+				log.Printf("Node %s: invalid routed message: %v", n.id, err)
+				continue
+			}
+
+			n.server.deliver(routed.UserID, routed.Event)
+		}
+	}
+}
+
+func (n *Node) userConnected(userID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	if err := n.registry.Add(ctx, userID, n.id); err != nil {
+		log.Printf("Node %s: %v", n.id, err)
+	}
+}
+
+func (n *Node) userDisconnected(userID int) {
+	ctx, cancel := context.WithTimeout(context.Background(), registryTimeout)
+	defer cancel()
+
+	if err := n.registry.Remove(ctx, userID, n.id); err != nil {
+		log.Printf("Node %s: %v", n.id, err)
+	}
+}
+
+func defaultNodeID() string {
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "unknown"
+	}
+
+	return host + "-" + strconv.Itoa(os.Getpid())
+}
@@ -0,0 +1,207 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+func setupRedis(t *testing.T, ctx context.Context) *redis.Client {
+	testcontainers.SkipIfProviderIsNotHealthy(t)
+
+	container, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: testcontainers.ContainerRequest{
+			Image:        "redis:latest",
+			ExposedPorts: []string{"6379/tcp"},
+			WaitingFor:   wait.ForLog("Ready to accept connections"),
+		},
+		Started: true,
+	})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = container.Terminate(context.Background()) })
+
+	endpoint, err := container.Endpoint(ctx, "")
+	require.NoError(t, err)
+
+	client := redis.NewClient(&redis.Options{Addr: endpoint})
+	t.Cleanup(func() { _ = client.Close() })
+
+	return client
+}
+
+// startNode поднимает узел с HTTP-сервером, принимающим user_id из query
+func startNode(t *testing.T, ctx context.Context, client *redis.Client, registry *Registry, nodeID string) *httptest.Server {
+	server := NewServer()
+	server.SetHistory(NewHistory(client, 0, 0))
+	node := NewNode(client, server, registry, nodeID)
+	require.NoError(t, node.Start(ctx))
+
+	return serve(t, server)
+}
+
+func waitNodes(t *testing.T, registry *Registry, userID int, expected ...string) {
+	require.Eventually(t, func() bool {
+		nodes, err := registry.Nodes(context.Background(), userID)
+		if err != nil || len(nodes) != len(expected) {
+			return false
+		}
+		sort.Strings(nodes)
+		sort.Strings(expected)
+		return assert.ObjectsAreEqual(expected, nodes)
+	}, 5*time.Second, 50*time.Millisecond)
+}
+
+func TestRouter_DeliversToNodeHoldingUser(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := setupRedis(t, ctx)
+	registry := NewRegistry(client, time.Second)
+
+	nodeA := startNode(t, ctx, client, registry, "node-a")
+	nodeB := startNode(t, ctx, client, registry, "node-b")
+
+	connA := dial(t, nodeA, 1)
+	connB := dial(t, nodeB, 2)
+	waitNodes(t, registry, 1, "node-a")
+	waitNodes(t, registry, 2, "node-b")
+
+	// Router не держит соединений, как и консьюмер в отдельном процессе
+	router := NewRouter(client, registry, NewHistory(client, 0, 0))
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 1}))
+	require.NoError(t, router.SendToUser(ctx, 2, EventFeedPostCreated, map[string]int{"n": 2}))
+
+	assert.Equal(t, 1, readPayload(t, connA)["n"])
+	assert.Equal(t, 2, readPayload(t, connB)["n"])
+
+	// Отключенный пользователь пропадает из реестра
+	require.NoError(t, connA.Close())
+	waitNodes(t, registry, 1)
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 3}))
+}
+
+func TestRouter_ReplaysMissedEvents(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := setupRedis(t, ctx)
+	registry := NewRegistry(client, time.Second)
+	router := NewRouter(client, registry, NewHistory(client, 0, 0))
+
+	nodeA := startNode(t, ctx, client, registry, "node-a")
+	nodeB := startNode(t, ctx, client, registry, "node-b")
+
+	conn := dial(t, nodeA, 1)
+	waitNodes(t, registry, 1, "node-a")
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 1}))
+	first := readMessage(t, conn)
+	require.NoError(t, conn.Close())
+	waitNodes(t, registry, 1)
+
+	// Пока пользователь offline, события сохраняются в истории
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 2}))
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 3}))
+
+	// Переподключение к другому узлу догружает пропуск, затем идут живые события
+	conn = dialFrom(t, nodeB, 1, first.ID)
+	waitNodes(t, registry, 1, "node-b")
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 4}))
+
+	firstID, err := strconv.ParseUint(first.ID, 10, 64)
+	require.NoError(t, err)
+
+	var ids []uint64
+	for _, expected := range []int{2, 3, 4} {
+		ev := readMessage(t, conn)
+		var payload map[string]int
+		require.NoError(t, json.Unmarshal(ev.Payload, &payload))
+		assert.Equal(t, expected, payload["n"])
+
+		id, err := strconv.ParseUint(ev.ID, 10, 64)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	assert.Equal(t, []uint64{firstID + 1, firstID + 2, firstID + 3}, ids)
+}
+
+func TestServer_SSEReplaysFromLastEventID(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	client := setupRedis(t, ctx)
+	registry := NewRegistry(client, time.Second)
+	history := NewHistory(client, 0, 0)
+	router := NewRouter(client, registry, history)
+
+	for i := 1; i <= 3; i++ {
+		_, err := history.Append(ctx, 1, EventFeedPostCreated, []byte(`{"n":`+strconv.Itoa(i)+`}`))
+		require.NoError(t, err)
+	}
+
+	server := NewServer()
+	server.SetHistory(history)
+	require.NoError(t, NewNode(client, server, registry, "node-sse").Start(ctx))
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleSSE(w, r, 1)
+	}))
+	t.Cleanup(ts.Close)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ts.URL, nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	waitNodes(t, registry, 1, "node-sse")
+	require.NoError(t, router.SendToUser(ctx, 1, EventFeedPostCreated, map[string]int{"n": 4}))
+
+	reader := bufio.NewReader(resp.Body)
+	var ids []string
+	for len(ids) < 3 {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		if strings.HasPrefix(line, "id: ") {
+			ids = append(ids, strings.TrimSpace(strings.TrimPrefix(line, "id: ")))
+		}
+	}
+	assert.Equal(t, []string{"2", "3", "4"}, ids)
+}
+
+func TestRegistry_SkipsDeadNodes(t *testing.T) {
+	ctx := context.Background()
+
+	client := setupRedis(t, ctx)
+	registry := NewRegistry(client, 200*time.Millisecond)
+
+	// Узел упал, не убрав за собой записи пользователей
+	require.NoError(t, registry.Heartbeat(ctx, "node-dead"))
+	require.NoError(t, registry.Add(ctx, 1, "node-dead"))
+	require.NoError(t, registry.Heartbeat(ctx, "node-live"))
+	require.NoError(t, registry.Add(ctx, 1, "node-live"))
+
+	time.Sleep(300 * time.Millisecond)
+	require.NoError(t, registry.Heartbeat(ctx, "node-live"))
+
+	nodes, err := registry.Nodes(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node-live"}, nodes)
+
+	members, err := client.SMembers(ctx, userNodesKey(1)).Result()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"node-live"}, members)
+}
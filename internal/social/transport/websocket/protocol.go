@@ -0,0 +1,218 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// Протокол описан JSON-схемой docs/websocket-protocol.schema.json.
+// Все сообщения в обе стороны - конверт {type, id, payload}
+
+// Темы подписки
+const (
+	TopicFeed          = "feed"
+	TopicDialogs       = "dialogs"
+	TopicNotifications = "notifications"
+	TopicPresence      = "presence"
+)
+
+// Команды клиента
+const (
+	CommandSubscribe   = "subscribe"
+	CommandUnsubscribe = "unsubscribe"
+	CommandPing        = "ping"
+	// CommandTyping пересылается собеседнику событием dialogs.typing без сохранения
+	CommandTyping = "typing"
+)
+
+// Служебные сообщения сервера
+const (
+	MessageAck   = "ack"
+	MessageError = "error"
+)
+
+// Коды ошибок в payload сообщения error
+const (
+	ErrorBadRequest   = "bad_request"
+	ErrorUnknownType  = "unknown_type"
+	ErrorUnknownTopic = "unknown_topic"
+	ErrorInternal     = "internal"
+)
+
+// Типы событий, тема - часть до точки
+const (
+	EventFeedPostCreated = TopicFeed + ".post_created"
+	EventFeedPostUpdated = TopicFeed + ".post_updated"
+	EventFeedPostDeleted = TopicFeed + ".post_deleted"
+
+	EventDialogMessageCreated = TopicDialogs + ".message_created"
+	EventDialogTyping         = TopicDialogs + ".typing"
+	EventDialogMessageRead    = TopicDialogs + ".message_read"
+	EventDialogUnreadChanged  = TopicDialogs + ".unread_changed"
+	EventDialogMessageEdited  = TopicDialogs + ".message_edited"
+	EventDialogMessageDeleted = TopicDialogs + ".message_deleted"
+
+	EventDialogGroupMessageCreated = TopicDialogs + ".group_message_created"
+
+	EventPresenceChanged = TopicPresence + ".changed"
+)
+
+var knownTopics = map[string]bool{
+	TopicFeed:          true,
+	TopicDialogs:       true,
+	TopicNotifications: true,
+	TopicPresence:      true,
+}
+
+// defaultTopics - подписки нового соединения, если клиент не указал topics
+var defaultTopics = []string{TopicFeed}
+
+// Message - конверт протокола. Для событий id - порядковый номер события
+// пользователя, для ответов на команды - id команды
+type Message struct {
+	Type    string          `json:"type"`
+	ID      string          `json:"id,omitempty"`
+	Payload json.RawMessage `json:"payload,omitempty"`
+
+	seq uint64
+}
+
+// SubscriptionPayload - payload команд subscribe/unsubscribe и ответа на них
+type SubscriptionPayload struct {
+	Topics []string `json:"topics"`
+}
+
+// ErrorPayload - payload сообщения error
+type ErrorPayload struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FeedPostPayload - payload событий темы feed
+type FeedPostPayload struct {
+	PostID       string `json:"post_id"`
+	PostText     string `json:"post_text,omitempty"`
+	AuthorUserID int64  `json:"author_user_id"`
+	CreatedAt    int64  `json:"created_at"`
+}
+
+// DialogMessagePayload - payload события dialogs.message_created
+type DialogMessagePayload struct {
+	MessageID  string `json:"message_id"`
+	SenderID   int64  `json:"sender_id"`
+	ReceiverID int64  `json:"receiver_id"`
+	Text       string `json:"text"`
+	SentAt     int64  `json:"sent_at"`
+}
+
+// MessageEditedPayload - payload события dialogs.message_edited
+type MessageEditedPayload struct {
+	MessageID  string `json:"message_id"`
+	SenderID   int64  `json:"sender_id"`
+	ReceiverID int64  `json:"receiver_id"`
+	Text       string `json:"text"`
+	EditedAt   int64  `json:"edited_at"`
+}
+
+// MessageDeletedPayload - payload события dialogs.message_deleted:
+// сообщение удалено для обоих участников
+type MessageDeletedPayload struct {
+	MessageID  string `json:"message_id"`
+	SenderID   int64  `json:"sender_id"`
+	ReceiverID int64  `json:"receiver_id"`
+}
+
+// GroupMessagePayload - payload события dialogs.group_message_created,
+// приходит всем участникам группы, включая отправителя
+type GroupMessagePayload struct {
+	MessageID string `json:"message_id"`
+	GroupID   int64  `json:"group_id"`
+	SenderID  int64  `json:"sender_id"`
+	Text      string `json:"text"`
+	SentAt    int64  `json:"sent_at"`
+}
+
+// MessageReadPayload - payload события dialogs.message_read: reader_id
+// прочитал сообщения получателя события до up_to_message_id
+type MessageReadPayload struct {
+	ReaderID      int64  `json:"reader_id"`
+	UpToMessageID string `json:"up_to_message_id"`
+}
+
+// UnreadPayload - payload события dialogs.unread_changed
+type UnreadPayload struct {
+	PeerID      int64 `json:"peer_id"`
+	UnreadCount int32 `json:"unread_count"`
+	TotalUnread int32 `json:"total_unread"`
+}
+
+// TypingPayload - payload команды typing (user_id - собеседник)
+// и события dialogs.typing (user_id - кто печатает)
+type TypingPayload struct {
+	UserID int `json:"user_id"`
+}
+
+// PresencePayload - payload события presence.changed
+type PresencePayload struct {
+	UserID     int   `json:"user_id"`
+	Online     bool  `json:"online"`
+	LastSeenAt int64 `json:"last_seen_at"`
+}
+
+// Event - уведомление пользователю. Seq - порядковый номер в рамках
+// пользователя; по нему клиент запрашивает пропущенные события
+type Event struct {
+	Seq     uint64          `json:"seq,omitempty"`
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// Topic возвращает тему события
+func (e Event) Topic() string {
+	topic, _, _ := strings.Cut(e.Type, ".")
+	return topic
+}
+
+func (e Event) message() Message {
+	msg := Message{Type: e.Type, Payload: e.Payload, seq: e.Seq}
+	if e.Seq != 0 {
+		msg.ID = strconv.FormatUint(e.Seq, 10)
+	}
+
+	return msg
+}
+
+func ackMessage(id string, topics []string) Message {
+	payload, _ := json.Marshal(SubscriptionPayload{Topics: topics})
+	return Message{Type: MessageAck, ID: id, Payload: payload}
+}
+
+func errorMessage(id, code, text string) Message {
+	payload, _ := json.Marshal(ErrorPayload{Code: code, Message: text})
+	return Message{Type: MessageError, ID: id, Payload: payload}
+}
+
+// parseTopics разбирает список тем через запятую. Пустой список - темы по умолчанию
+func parseTopics(raw string) ([]string, error) {
+	if raw == "" {
+		return defaultTopics, nil
+	}
+
+	topics := strings.Split(raw, ",")
+	for _, topic := range topics {
+		if !knownTopics[topic] {
+			return nil, &unknownTopicError{topic: topic}
+		}
+	}
+
+	return topics, nil
+}
+
+type unknownTopicError struct {
+	topic string
+}
+
+func (e *unknownTopicError) Error() string {
+	return "unknown topic " + strconv.Quote(e.topic)
+}
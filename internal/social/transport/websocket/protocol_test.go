@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"otus-highload-arh-homework/docs"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func command(t *testing.T, conn *websocket.Conn, cmdType, id string, payload interface{}) Message {
+	msg := map[string]interface{}{"type": cmdType, "id": id}
+	if payload != nil {
+		msg["payload"] = payload
+	}
+	require.NoError(t, conn.WriteJSON(msg))
+
+	reply := readMessage(t, conn)
+	assert.Equal(t, id, reply.ID)
+
+	return reply
+}
+
+func TestProtocol_SubscriptionCommands(t *testing.T) {
+	server := NewServer()
+	ts := serve(t, server)
+
+	conn := dial(t, ts, 1)
+	waitConnections(t, server, 1, 1)
+
+	reply := command(t, conn, CommandSubscribe, "1", SubscriptionPayload{Topics: []string{TopicDialogs}})
+	assert.Equal(t, MessageAck, reply.Type)
+	assert.JSONEq(t, `{"topics":["dialogs","feed"]}`, string(reply.Payload))
+
+	reply = command(t, conn, CommandUnsubscribe, "2", SubscriptionPayload{Topics: []string{TopicFeed}})
+	assert.Equal(t, MessageAck, reply.Type)
+	assert.JSONEq(t, `{"topics":["dialogs"]}`, string(reply.Payload))
+
+	// События тем без подписки не доставляются
+	require.NoError(t, server.BroadcastToUser(1, EventFeedPostCreated, map[string]int{"n": 1}))
+	require.NoError(t, server.BroadcastToUser(1, TopicDialogs+".message_created", map[string]int{"n": 2}))
+
+	msg := readMessage(t, conn)
+	assert.Equal(t, TopicDialogs+".message_created", msg.Type)
+	assert.JSONEq(t, `{"n":2}`, string(msg.Payload))
+
+	reply = command(t, conn, CommandPing, "3", nil)
+	assert.Equal(t, MessageAck, reply.Type)
+}
+
+func TestProtocol_TypingRelay(t *testing.T) {
+	server := NewServer()
+	ts := serve(t, server)
+
+	sender := dial(t, ts, 1)
+	receiver := dialURL(t, "ws"+strings.TrimPrefix(ts.URL, "http")+"?user_id=2&topics=dialogs")
+	waitConnections(t, server, 2, 1)
+
+	reply := command(t, sender, CommandTyping, "1", TypingPayload{UserID: 2})
+	assert.Equal(t, MessageAck, reply.Type)
+
+	msg := readMessage(t, receiver)
+	assert.Equal(t, EventDialogTyping, msg.Type)
+	assert.Empty(t, msg.ID, "typing is not persisted and has no sequence")
+	assert.JSONEq(t, `{"user_id":1}`, string(msg.Payload))
+
+	reply = command(t, sender, CommandTyping, "2", TypingPayload{UserID: 1})
+	assert.Equal(t, MessageError, reply.Type)
+}
+
+func TestProtocol_Errors(t *testing.T) {
+	server := NewServer()
+	ts := serve(t, server)
+
+	conn := dial(t, ts, 1)
+
+	tests := []struct {
+		name    string
+		cmdType string
+		payload interface{}
+		code    string
+	}{
+		{"unknown topic", CommandSubscribe, SubscriptionPayload{Topics: []string{"stocks"}}, ErrorUnknownTopic},
+		{"missing topics", CommandSubscribe, nil, ErrorBadRequest},
+		{"unknown type", "publish", nil, ErrorUnknownType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reply := command(t, conn, tt.cmdType, tt.name, tt.payload)
+			assert.Equal(t, MessageError, reply.Type)
+
+			var payload ErrorPayload
+			require.NoError(t, json.Unmarshal(reply.Payload, &payload))
+			assert.Equal(t, tt.code, payload.Code)
+		})
+	}
+
+	require.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte("not json")))
+	reply := readMessage(t, conn)
+	assert.Equal(t, MessageError, reply.Type)
+}
+
+func TestProtocol_SchemaCoversConstants(t *testing.T) {
+	var schema struct {
+		Defs struct {
+			Topic struct {
+				Enum []string `json:"enum"`
+			} `json:"Topic"`
+		} `json:"$defs"`
+	}
+	require.NoError(t, json.Unmarshal(docs.WebsocketSchema, &schema))
+
+	topics := make([]string, 0, len(knownTopics))
+	for topic := range knownTopics {
+		topics = append(topics, topic)
+	}
+	assert.ElementsMatch(t, topics, schema.Defs.Topic.Enum)
+
+	for _, eventType := range []string{
+		EventFeedPostCreated, EventFeedPostUpdated, EventFeedPostDeleted,
+		EventDialogMessageCreated, EventDialogTyping, CommandTyping,
+		EventDialogMessageRead, EventDialogUnreadChanged, EventPresenceChanged,
+		EventDialogMessageEdited, EventDialogMessageDeleted, EventDialogGroupMessageCreated,
+	} {
+		assert.Contains(t, string(docs.WebsocketSchema), `"`+eventType+`"`)
+	}
+}
@@ -0,0 +1,110 @@
+package websocket
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	userKeyPrefix   = "ws:user:"
+	nodeAlivePrefix = "ws:node:"
+
+	defaultNodeTTL = 30 * time.Second
+)
+
+// Registry хранит в Redis, на каких узлах открыты соединения пользователя.
+// Узел подтверждает, что жив, ключом с TTL: записи упавших узлов
+// отбрасываются при чтении
+type Registry struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func NewRegistry(client *redis.Client, nodeTTL time.Duration) *Registry {
+	if nodeTTL <= 0 {
+		nodeTTL = defaultNodeTTL
+	}
+
+	return &Registry{client: client, ttl: nodeTTL}
+}
+
+// Add отмечает, что у пользователя есть соединение на узле
+func (r *Registry) Add(ctx context.Context, userID int, nodeID string) error {
+	if err := r.client.SAdd(ctx, userNodesKey(userID), nodeID).Err(); err != nil {
+		return fmt.Errorf("failed to register user %d on node %s: %w", userID, nodeID, err)
+	}
+
+	return nil
+}
+
+// Remove вызывается, когда на узле закрыто последнее соединение пользователя
+func (r *Registry) Remove(ctx context.Context, userID int, nodeID string) error {
+	if err := r.client.SRem(ctx, userNodesKey(userID), nodeID).Err(); err != nil {
+		return fmt.Errorf("failed to unregister user %d from node %s: %w", userID, nodeID, err)
+	}
+
+	return nil
+}
+
+// Nodes возвращает живые узлы с соединениями пользователя
+func (r *Registry) Nodes(ctx context.Context, userID int) ([]string, error) {
+	nodes, err := r.client.SMembers(ctx, userNodesKey(userID)).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes of user %d: %w", userID, err)
+	}
+	if len(nodes) == 0 {
+		return nil, nil
+	}
+
+	pipe := r.client.Pipeline()
+	alive := make([]*redis.IntCmd, len(nodes))
+	for i, node := range nodes {
+		alive[i] = pipe.Exists(ctx, nodeAliveKey(node))
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return nil, fmt.Errorf("failed to check nodes of user %d: %w", userID, err)
+	}
+
+	live := nodes[:0]
+	var dead []interface{}
+	for i, node := range nodes {
+		if alive[i].Val() > 0 {
+			live = append(live, node)
+		} else {
+			dead = append(dead, node)
+		}
+	}
+
+	// Чистим записи упавших узлов
+	if len(dead) > 0 {
+		_ = r.client.SRem(ctx, userNodesKey(userID), dead...).Err()
+	}
+
+	return live, nil
+}
+
+// Heartbeat продлевает признак жизни узла
+func (r *Registry) Heartbeat(ctx context.Context, nodeID string) error {
+	return r.client.Set(ctx, nodeAliveKey(nodeID), 1, r.ttl).Err()
+}
+
+// Leave снимает признак жизни узла при остановке
+func (r *Registry) Leave(ctx context.Context, nodeID string) error {
+	return r.client.Del(ctx, nodeAliveKey(nodeID)).Err()
+}
+
+func userNodesKey(userID int) string {
+	return userKeyPrefix + strconv.Itoa(userID) + ":nodes"
+}
+
+func nodeAliveKey(nodeID string) string {
+	return nodeAlivePrefix + nodeID + ":alive"
+}
+
+func nodeChannel(nodeID string) string {
+	return nodeAlivePrefix + nodeID
+}
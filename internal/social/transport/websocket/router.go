@@ -0,0 +1,80 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// routedMessage - событие пользователя, передаваемое узлу через pub/sub
+type routedMessage struct {
+	UserID int   `json:"user_id"`
+	Event  Event `json:"event"`
+}
+
+// Router доставляет сообщения пользователю на узлы, где открыты его
+// соединения. Может использоваться в любом процессе, не только на узле
+type Router struct {
+	client   *redis.Client
+	registry *Registry
+	history  *History
+}
+
+func NewRouter(client *redis.Client, registry *Registry, history *History) *Router {
+	return &Router{client: client, registry: registry, history: history}
+}
+
+// SendToUser сохраняет событие в истории пользователя и публикует его
+// в канал каждого узла пользователя. Неподключенный пользователь получит
+// событие при переподключении с last_event_id
+func (r *Router) SendToUser(ctx context.Context, userID int, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	event, err := r.history.Append(ctx, userID, eventType, data)
+	if err != nil {
+		return err
+	}
+
+	return r.publish(ctx, userID, event)
+}
+
+// Relay доставляет эфемерное событие (например, набор текста) только в
+// открытые соединения пользователя: без номера и сохранения в истории
+func (r *Router) Relay(ctx context.Context, userID int, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	return r.publish(ctx, userID, Event{Type: eventType, Payload: data})
+}
+
+func (r *Router) publish(ctx context.Context, userID int, event Event) error {
+	nodes, err := r.registry.Nodes(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if len(nodes) == 0 {
+		return nil
+	}
+
+	routed, err := json.Marshal(routedMessage{UserID: userID, Event: event})
+	if err != nil {
+		return fmt.Errorf("failed to marshal routed message: %w", err)
+	}
+
+	pipe := r.client.Pipeline()
+	for _, node := range nodes {
+		pipe.Publish(ctx, nodeChannel(node), routed)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		return fmt.Errorf("failed to route message to user %d: %w", userID, err)
+	}
+
+	return nil
+}
@@ -1,17 +1,63 @@
 package websocket
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"strconv"
 	"sync"
+	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+const (
+	// Время на запись одного сообщения клиенту
+	defaultWriteWait = 10 * time.Second
+	// Время ожидания pong от клиента, после которого соединение считается мертвым
+	defaultPongWait = 60 * time.Second
+	// Размер очереди отправки одного соединения; клиент, не успевающий
+	// её разбирать, отключается
+	defaultSendQueueSize = 64
+	// Команды клиента небольшие
+	maxMessageSize = 4096
+)
+
+// presenceListener получает уведомления о подключении и отключении
+// пользователей к этому серверу
+type presenceListener interface {
+	userConnected(userID int)
+	userDisconnected(userID int)
+}
+
+// Server держит websocket- и SSE-соединения пользователей. У пользователя может
+// быть несколько соединений (вкладок), запись в каждое выполняет одна горутина
+// PresenceTracker получает активность пользователей: подключения и heartbeat
+// (Online) и закрытие последнего соединения на этом сервере (Offline)
+type PresenceTracker interface {
+	Online(userID int)
+	Offline(userID int)
+}
+
 type Server struct {
 	upgrader  websocket.Upgrader
-	clients   map[int]*websocket.Conn
+	clients   map[int]map[*client]struct{}
 	clientsMu sync.RWMutex
+	listener  presenceListener
+	history   *History
+	router    *Router
+	presence  PresenceTracker
+
+	// listenerLocks упорядочивают уведомления listener по пользователю
+	listenerLocksMu sync.Mutex
+	listenerLocks   map[int]*userLock
+
+	writeWait     time.Duration
+	pongWait      time.Duration
+	pingPeriod    time.Duration
+	sendQueueSize int
 }
 
 func NewServer() *Server {
@@ -23,46 +69,284 @@ func NewServer() *Server {
 				return true // В production нужно реализовать проверку origin
 			},
 		},
-		clients: make(map[int]*websocket.Conn),
+		clients:       make(map[int]map[*client]struct{}),
+		listenerLocks: make(map[int]*userLock),
+		writeWait:     defaultWriteWait,
+		pongWait:      defaultPongWait,
+		pingPeriod:    defaultPongWait * 9 / 10,
+		sendQueueSize: defaultSendQueueSize,
 	}
 }
 
+// SetHistory включает догрузку пропущенных событий по last_event_id
+func (s *Server) SetHistory(history *History) {
+	s.history = history
+}
+
+// SetPresence включает учет присутствия по соединениям
+func (s *Server) SetPresence(presence PresenceTracker) {
+	s.presence = presence
+}
+
+// SetRouter включает пересылку эфемерных событий между узлами. Без него
+// они доставляются только в соединения на этом узле
+func (s *Server) SetRouter(router *Router) {
+	s.router = router
+}
+
+// relay пересылает эфемерное событие пользователю
+func (s *Server) relay(userID int, eventType string, payload interface{}) error {
+	if s.router == nil {
+		return s.BroadcastToUser(userID, eventType, payload)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.writeWait)
+	defer cancel()
+
+	return s.router.Relay(ctx, userID, eventType, payload)
+}
+
+// HandleConnection открывает websocket. Начальные подписки задаются
+// параметром topics, далее меняются командами клиента. Если передан
+// last_event_id, клиент сначала получает пропущенные события, затем живые
 func (s *Server) HandleConnection(w http.ResponseWriter, r *http.Request, userID int) {
+	lastID, resume, err := parseLastEventID(r.URL.Query().Get("last_event_id"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	topics, err := parseTopics(r.URL.Query().Get("topics"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
 	conn, err := s.upgrader.Upgrade(w, r, nil)
 	if err != nil {
 		log.Printf("Failed to upgrade to websocket: %v", err)
 		return
 	}
 
-	// Регистрируем соединение
-	s.clientsMu.Lock()
-	s.clients[userID] = conn
-	s.clientsMu.Unlock()
+	c := newClient(s, conn, userID, topics)
+	s.attach(c, lastID, resume)
 
 	log.Printf("Connected to user %d", userID)
 
-	// Обработка закрытия соединения
-	go func() {
-		for {
-			if _, _, err := conn.NextReader(); err != nil {
-				s.clientsMu.Lock()
-				delete(s.clients, userID)
-				s.clientsMu.Unlock()
-				conn.Close()
-				break
-			}
-		}
-	}()
+	go c.writePump()
+	go c.readPump()
+}
+
+// HandleSSE отдает события пользователя потоком Server-Sent Events с той же
+// семантикой догрузки: номер последнего события берется из заголовка
+// Last-Event-ID или параметра last_event_id. Команд в SSE нет, подписки
+// задаются только параметром topics. Блокируется до разрыва соединения
+func (s *Server) HandleSSE(w http.ResponseWriter, r *http.Request, userID int) {
+	raw := r.Header.Get("Last-Event-ID")
+	if raw == "" {
+		raw = r.URL.Query().Get("last_event_id")
+	}
+	lastID, resume, err := parseLastEventID(raw)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	topics, err := parseTopics(r.URL.Query().Get("topics"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	if err := http.NewResponseController(w).Flush(); err != nil {
+		log.Printf("SSE is not supported by response writer: %v", err)
+		return
+	}
+
+	c := newClient(s, nil, userID, topics)
+	s.attach(c, lastID, resume)
+
+	c.ssePump(w, r)
+}
+
+// BroadcastToUser отправляет событие в соединения пользователя только на
+// этом узле, без номера и сохранения в истории. Для доставки в кластере
+// используется Router
+func (s *Server) BroadcastToUser(userID int, eventType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to marshal payload: %w", err)
+	}
+
+	s.deliver(userID, Event{Type: eventType, Payload: data})
+
+	return nil
 }
 
-func (s *Server) BroadcastToUser(userID int, message interface{}) error {
+// deliver ставит событие в очереди соединений пользователя, подписанных на
+// его тему. Не блокируется: соединения с переполненной очередью закрываются
+func (s *Server) deliver(userID int, ev Event) {
 	s.clientsMu.RLock()
-	conn, ok := s.clients[userID]
+	conns := make([]*client, 0, len(s.clients[userID]))
+	for c := range s.clients[userID] {
+		conns = append(conns, c)
+	}
 	s.clientsMu.RUnlock()
 
+	for _, c := range conns {
+		if !c.enqueue(ev) {
+			log.Printf("Slow consumer of user %d, disconnecting", userID)
+			c.close()
+		}
+	}
+}
+
+// attach регистрирует клиента и при необходимости запускает догрузку.
+// Клиент регистрируется до чтения истории, чтобы не потерять живые события
+func (s *Server) attach(c *client, lastID uint64, resume bool) {
+	if resume && s.history != nil {
+		c.replaying = true
+	}
+
+	s.register(c)
+
+	if c.replaying {
+		go c.replay(lastID)
+	}
+}
+
+// Connections возвращает число открытых соединений пользователя
+func (s *Server) Connections(userID int) int {
+	s.clientsMu.RLock()
+	defer s.clientsMu.RUnlock()
+
+	return len(s.clients[userID])
+}
+
+func (s *Server) register(c *client) {
+	s.clientsMu.Lock()
+	conns, ok := s.clients[c.userID]
+	if !ok {
+		conns = make(map[*client]struct{})
+		s.clients[c.userID] = conns
+	}
+	conns[c] = struct{}{}
+	first := len(conns) == 1
+	s.clientsMu.Unlock()
+
+	if first {
+		s.syncListener(c.userID)
+	}
+	s.touch(c.userID)
+}
+
+func (s *Server) unregister(c *client) {
+	s.clientsMu.Lock()
+	conns, ok := s.clients[c.userID]
+	if !ok {
+		s.clientsMu.Unlock()
+		return
+	}
+	if _, ok := conns[c]; !ok {
+		s.clientsMu.Unlock()
+		return
+	}
+	delete(conns, c)
+	last := len(conns) == 0
+	if last {
+		delete(s.clients, c.userID)
+	}
+	s.clientsMu.Unlock()
+
+	if !last {
+		return
+	}
+	s.syncListener(c.userID)
+	if s.presence != nil {
+		go func() {
+			// Пользователь мог переподключиться, пока шло снятие с учета
+			if s.Connections(c.userID) == 0 {
+				s.presence.Offline(c.userID)
+			}
+		}()
+	}
+}
+
+// userLock - блокировка пользователя; refs - сколько горутин ее ждут или держат
+type userLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+// syncListener сообщает listener, есть ли у пользователя соединения.
+// Вызовы по одному пользователю идут по очереди, а наличие соединений
+// читается под блокировкой: иначе удаление из реестра при закрытии
+// последнего соединения может выполниться после добавления при новом
+// подключении. Последний вызов всегда видит актуальное состояние, лишние
+// повторы Add и Remove безвредны
+func (s *Server) syncListener(userID int) {
+	if s.listener == nil {
+		return
+	}
+
+	lock := s.lockUser(userID)
+	defer s.unlockUser(userID, lock)
+
+	if s.Connections(userID) > 0 {
+		s.listener.userConnected(userID)
+	} else {
+		s.listener.userDisconnected(userID)
+	}
+}
+
+func (s *Server) lockUser(userID int) *userLock {
+	s.listenerLocksMu.Lock()
+	lock, ok := s.listenerLocks[userID]
 	if !ok {
-		return nil // Пользователь не подключен
+		lock = &userLock{}
+		s.listenerLocks[userID] = lock
+	}
+	lock.refs++
+	s.listenerLocksMu.Unlock()
+
+	lock.mu.Lock()
+	return lock
+}
+
+func (s *Server) unlockUser(userID int, lock *userLock) {
+	lock.mu.Unlock()
+
+	s.listenerLocksMu.Lock()
+	lock.refs--
+	if lock.refs == 0 {
+		delete(s.listenerLocks, userID)
+	}
+	s.listenerLocksMu.Unlock()
+}
+
+// touch отмечает активность пользователя, не блокируя соединение
+func (s *Server) touch(userID int) {
+	if s.presence != nil {
+		go s.presence.Online(userID)
+	}
+}
+
+func (s *Server) setListener(l presenceListener) {
+	s.listener = l
+}
+
+func parseLastEventID(raw string) (uint64, bool, error) {
+	if raw == "" {
+		return 0, false, nil
+	}
+
+	id, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("invalid last_event_id %q", raw)
 	}
 
-	return conn.WriteJSON(message)
+	return id, true, nil
 }
@@ -0,0 +1,234 @@
+package websocket
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func serve(t *testing.T, server *Server) *httptest.Server {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userID, err := strconv.Atoi(r.URL.Query().Get("user_id"))
+		if err != nil {
+			http.Error(w, "bad user_id", http.StatusBadRequest)
+			return
+		}
+		server.HandleConnection(w, r, userID)
+	}))
+	t.Cleanup(ts.Close)
+
+	return ts
+}
+
+func dial(t *testing.T, ts *httptest.Server, userID int) *websocket.Conn {
+	return dialURL(t, "ws"+strings.TrimPrefix(ts.URL, "http")+"?user_id="+strconv.Itoa(userID))
+}
+
+// dialFrom подключается с догрузкой событий после lastID
+func dialFrom(t *testing.T, ts *httptest.Server, userID int, lastID string) *websocket.Conn {
+	return dialURL(t, "ws"+strings.TrimPrefix(ts.URL, "http")+"?user_id="+strconv.Itoa(userID)+
+		"&last_event_id="+lastID)
+}
+
+func dialURL(t *testing.T, url string) *websocket.Conn {
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return conn
+}
+
+func readMessage(t *testing.T, conn *websocket.Conn) Message {
+	var ev Message
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	require.NoError(t, conn.ReadJSON(&ev))
+
+	return ev
+}
+
+func readPayload(t *testing.T, conn *websocket.Conn) map[string]int {
+	ev := readMessage(t, conn)
+
+	var payload map[string]int
+	require.NoError(t, json.Unmarshal(ev.Payload, &payload))
+
+	return payload
+}
+
+func waitConnections(t *testing.T, server *Server, userID, expected int) {
+	require.Eventually(t, func() bool {
+		return server.Connections(userID) == expected
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestServer_BroadcastToAllConnectionsOfUser(t *testing.T) {
+	server := NewServer()
+	ts := serve(t, server)
+
+	first := dial(t, ts, 1)
+	second := dial(t, ts, 1)
+	waitConnections(t, server, 1, 2)
+
+	// Конкурентные отправки не должны приводить к конкурентной записи в соединение
+	for i := 0; i < 10; i++ {
+		go func(i int) {
+			_ = server.BroadcastToUser(1, EventFeedPostCreated, map[string]int{"n": i})
+		}(i)
+	}
+
+	for _, conn := range []*websocket.Conn{first, second} {
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+		seen := make(map[int]bool)
+		for i := 0; i < 10; i++ {
+			seen[readPayload(t, conn)["n"]] = true
+		}
+		assert.Len(t, seen, 10)
+	}
+
+	// Закрытие одной вкладки не отключает другую
+	require.NoError(t, first.Close())
+	waitConnections(t, server, 1, 1)
+
+	require.NoError(t, server.BroadcastToUser(1, EventFeedPostCreated, map[string]int{"n": 42}))
+	assert.Equal(t, 42, readPayload(t, second)["n"])
+}
+
+func TestServer_DisconnectsSlowConsumer(t *testing.T) {
+	server := NewServer()
+	server.sendQueueSize = 2
+	ts := serve(t, server)
+
+	// Клиент ничего не читает: буферы сокета заполняются, запись блокируется
+	dial(t, ts, 1)
+	waitConnections(t, server, 1, 1)
+
+	payload := strings.Repeat("x", 1<<20)
+	require.Eventually(t, func() bool {
+		_ = server.BroadcastToUser(1, EventFeedPostCreated, payload)
+		return server.Connections(1) == 0
+	}, 10*time.Second, time.Millisecond)
+}
+
+// blockingListener запоминает последнее уведомление; первое снятие с учета
+// ждет закрытия release
+type blockingListener struct {
+	mu        sync.Mutex
+	connected bool
+
+	removing chan struct{}
+	release  chan struct{}
+	once     sync.Once
+}
+
+func (l *blockingListener) userConnected(int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.connected = true
+}
+
+func (l *blockingListener) userDisconnected(int) {
+	l.once.Do(func() {
+		close(l.removing)
+		<-l.release
+	})
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.connected = false
+}
+
+func TestServer_ListenerKeepsReconnectOrder(t *testing.T) {
+	server := NewServer()
+	listener := &blockingListener{removing: make(chan struct{}), release: make(chan struct{})}
+	server.setListener(listener)
+
+	first := newClient(server, nil, 1, nil)
+	server.register(first)
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		server.unregister(first)
+	}()
+
+	// Снятие с учета задержалось, а пользователь уже переподключился
+	<-listener.removing
+	go func() {
+		defer wg.Done()
+		server.register(newClient(server, nil, 1, nil))
+	}()
+	waitConnections(t, server, 1, 1)
+
+	close(listener.release)
+	wg.Wait()
+
+	assert.True(t, listener.connected, "пользователь остается в реестре")
+}
+
+func TestServer_ReapsDeadConnections(t *testing.T) {
+	server := NewServer()
+	server.pongWait = 200 * time.Millisecond
+	server.pingPeriod = 50 * time.Millisecond
+	ts := serve(t, server)
+
+	// Читающий клиент отвечает на ping и остается подключенным
+	alive := dial(t, ts, 1)
+	go func() {
+		for {
+			if _, _, err := alive.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	// Клиент без чтения не отвечает на ping
+	dial(t, ts, 2)
+
+	waitConnections(t, server, 1, 1)
+	waitConnections(t, server, 2, 0)
+
+	time.Sleep(400 * time.Millisecond)
+	assert.Equal(t, 1, server.Connections(1))
+}
+
+func TestServer_SSEStreamsLiveEvents(t *testing.T) {
+	server := NewServer()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		server.HandleSSE(w, r, 1)
+	}))
+	t.Cleanup(ts.Close)
+
+	resp, err := http.Get(ts.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	waitConnections(t, server, 1, 1)
+
+	require.NoError(t, server.BroadcastToUser(1, EventFeedPostCreated, map[string]int{"n": 7}))
+
+	reader := bufio.NewReader(resp.Body)
+	for _, expected := range []string{
+		"event: feed.post_created\n",
+		"data: {\"type\":\"feed.post_created\",\"payload\":{\"n\":7}}\n",
+	} {
+		line, err := reader.ReadString('\n')
+		require.NoError(t, err)
+		assert.Equal(t, expected, line)
+	}
+
+	// Некорректный номер события отклоняется до открытия потока
+	resp, err = http.Get(ts.URL + "?last_event_id=abc")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
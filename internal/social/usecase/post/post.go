@@ -11,15 +11,24 @@ import (
 )
 
 type PostUseCase struct {
-	postRepo repository.PostRepository
+	postRepo   repository.PostRepository
+	txManager  repository.TxManager
+	outboxRepo repository.OutboxRepository
 }
 
-func NewPostUseCase(postRepo repository.PostRepository) *PostUseCase {
+func NewPostUseCase(
+	postRepo repository.PostRepository,
+	txManager repository.TxManager,
+	outboxRepo repository.OutboxRepository,
+) *PostUseCase {
 	return &PostUseCase{
-		postRepo: postRepo,
+		postRepo:   postRepo,
+		txManager:  txManager,
+		outboxRepo: outboxRepo,
 	}
 }
 
+// Create сохраняет пост и событие для лент друзей в одной транзакции
 func (uc *PostUseCase) Create(ctx context.Context, authorID int, text string) (string, error) {
 	post := &entity.Post{
 		AuthorID:  authorID,
@@ -28,7 +37,16 @@ func (uc *PostUseCase) Create(ctx context.Context, authorID int, text string) (s
 		UpdatedAt: time.Now(),
 	}
 
-	id, err := uc.postRepo.Create(ctx, post)
+	var id string
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		id, err = uc.postRepo.Create(ctx, post)
+		if err != nil {
+			return err
+		}
+
+		return uc.addFeedEvent(ctx, post, entity.EventPostCreated, entity.FeedActionCreate)
+	})
 	if err != nil {
 		return "", fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
 	}
@@ -52,7 +70,13 @@ func (uc *PostUseCase) Update(ctx context.Context, postID string, authorID int,
 	post.Text = text
 	post.UpdatedAt = time.Now()
 
-	err = uc.postRepo.Update(ctx, post)
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.postRepo.Update(ctx, post); err != nil {
+			return err
+		}
+
+		return uc.addFeedEvent(ctx, post, entity.EventPostUpdated, entity.FeedActionUpdate)
+	})
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
 	}
@@ -73,7 +97,13 @@ func (uc *PostUseCase) Delete(ctx context.Context, postID string, authorID int)
 		return ErrNotPostOwner
 	}
 
-	err = uc.postRepo.Delete(ctx, postID)
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.postRepo.Delete(ctx, postID); err != nil {
+			return err
+		}
+
+		return uc.addFeedEvent(ctx, post, entity.EventPostDeleted, entity.FeedActionDelete)
+	})
 	if err != nil {
 		return fmt.Errorf("%w: %v", ErrDatabaseOperation, err)
 	}
@@ -101,3 +131,22 @@ func (uc *PostUseCase) GetFeed(ctx context.Context, userID, offset, limit int) (
 
 	return posts, nil
 }
+
+func (uc *PostUseCase) addFeedEvent(ctx context.Context, post *entity.Post, eventType, action string) error {
+	payload := entity.FeedUpdateEvent{
+		PostID:    post.ID,
+		AuthorID:  post.AuthorID,
+		Action:    action,
+		Timestamp: time.Now().Unix(),
+	}
+	if action != entity.FeedActionDelete {
+		payload.Text = post.Text
+	}
+
+	event, err := entity.NewOutboxEvent(entity.AggregatePost, int64(post.AuthorID), eventType, payload)
+	if err != nil {
+		return err
+	}
+
+	return uc.outboxRepo.Add(ctx, event)
+}
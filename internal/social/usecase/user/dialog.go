@@ -2,18 +2,565 @@ package user
 
 import (
 	"context"
+	"errors"
+	"log"
+	"strconv"
+	"strings"
+	"time"
 
 	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+	"otus-highload-arh-homework/pkg/saga"
 )
 
-func (uc *UserUseCase) SendDialogMessage(ctx context.Context, senderID, receiverID int64, text string) error {
-	_, err := uc.repo.StoreDialogMessage(ctx, senderID, receiverID, text)
+const (
+	DefaultMessagesLimit = 50
+	MaxMessagesLimit     = 100
 
-	return err
+	// MaxClientMessageIDLength - предел длины client_message_id
+	MaxClientMessageIDLength = 64
+
+	defaultEditWindow = 15 * time.Minute
+
+	// streamPollInterval - как часто поток сообщений перечитывает хранилище
+	// без уведомлений: восполняет сигналы, потерянные при сбоях pub/sub
+	streamPollInterval = 30 * time.Second
+)
+
+type DialogUseCase struct {
+	repo       repository.DialogRepository
+	txManager  repository.TxManager
+	outboxRepo repository.OutboxRepository
+	unread     repository.UnreadCounterRepository
+	notifier   repository.MessageNotifier
+	editWindow time.Duration
+
+	// storageInTx - записи repo откатываются вместе с транзакцией txManager
+	storageInTx bool
+}
+
+// NewDialogUseCase создает use case диалогов. editWindow - сколько после
+// отправки сообщение можно редактировать, по умолчанию 15 минут
+func NewDialogUseCase(
+	repo repository.DialogRepository,
+	txManager repository.TxManager,
+	outboxRepo repository.OutboxRepository,
+	unread repository.UnreadCounterRepository,
+	notifier repository.MessageNotifier,
+	editWindow time.Duration,
+) *DialogUseCase {
+	if editWindow <= 0 {
+		editWindow = defaultEditWindow
+	}
+
+	_, nonTx := repo.(repository.NonTransactional)
+
+	return &DialogUseCase{
+		repo:        repo,
+		txManager:   txManager,
+		outboxRepo:  outboxRepo,
+		unread:      unread,
+		notifier:    notifier,
+		editWindow:  editWindow,
+		storageInTx: !nonTx,
+	}
+}
+
+// SendDialogMessage сохраняет сообщение и в той же транзакции пишет в
+// outbox события message.created и unread.changed. Хранилище вне
+// транзакций (Redis) пишется до нее, и при ошибке транзакции сообщение
+// удаляется. Счетчик непрочитанных в Redis - кэш: ошибка его обновления не
+// отменяет отправку, расхождение исправляет UnreadReconciler.
+//
+// clientMessageID делает отправку идемпотентной: повтор возвращает уже
+// сохраненное сообщение и не пишет события повторно - они зафиксированы
+// вместе с сообщением. Счетчик непрочитанных при повторе выставляется из
+// dialog_inbox: первая попытка могла прерваться до его увеличения
+func (uc *DialogUseCase) SendDialogMessage(ctx context.Context, senderID, receiverID int64, text, clientMessageID string) (*entity.SentMessage, error) {
+	if len(clientMessageID) > MaxClientMessageIDLength {
+		return nil, ErrInvalidClientMessageID
+	}
+
+	var (
+		sent *entity.SentMessage
+		err  error
+	)
+	if uc.storageInTx {
+		sent, err = uc.storeMessageInTx(ctx, senderID, receiverID, text, clientMessageID)
+	} else {
+		sent, err = uc.storeMessageBeforeTx(ctx, senderID, receiverID, text, clientMessageID)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if sent.Duplicate {
+		uc.resyncUnread(ctx, receiverID, senderID)
+		return sent, nil
+	}
+
+	if err := uc.unread.Increment(ctx, receiverID, senderID, 1); err != nil {
+		log.Printf("Dialog: %v", err)
+	}
+
+	// Потоки сообщений перечитают хранилище по опросу, если сигнал потерян
+	if err := uc.notifier.Notify(ctx, senderID, receiverID); err != nil {
+		log.Printf("failed to notify message streams: %v", err)
+	}
+
+	return sent, nil
+}
+
+// storeMessageInTx сохраняет сообщение и пишет его события одной транзакцией
+func (uc *DialogUseCase) storeMessageInTx(ctx context.Context, senderID, receiverID int64, text, clientMessageID string) (*entity.SentMessage, error) {
+	var sent *entity.SentMessage
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if sent, err = uc.repo.StoreDialogMessage(ctx, senderID, receiverID, text, clientMessageID); err != nil {
+			return err
+		}
+		if sent.Duplicate {
+			return nil
+		}
+
+		return uc.addMessageCreated(ctx, sent, senderID, receiverID, text)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sent, nil
+}
+
+// storeMessageBeforeTx сохраняет сообщение в хранилище вне транзакций и
+// затем пишет его события. Если транзакция не зафиксирована, сообщение
+// удаляется: иначе повтор с тем же clientMessageID попадет в дубликат и
+// события так и не будут записаны
+func (uc *DialogUseCase) storeMessageBeforeTx(ctx context.Context, senderID, receiverID int64, text, clientMessageID string) (*entity.SentMessage, error) {
+	var sent *entity.SentMessage
+	err := saga.Run(ctx,
+		saga.Step{
+			Name: "store message",
+			Action: func(ctx context.Context) error {
+				var err error
+				sent, err = uc.repo.StoreDialogMessage(ctx, senderID, receiverID, text, clientMessageID)
+				return err
+			},
+			Compensate: func(ctx context.Context) error {
+				// Дубликат сохранен прежней отправкой вместе с событиями
+				if sent.Duplicate {
+					return nil
+				}
+				return uc.repo.DeleteDialogMessage(ctx, sent.ID, senderID, receiverID)
+			},
+		},
+		saga.Step{
+			Name: "write events",
+			Action: func(ctx context.Context) error {
+				if sent.Duplicate {
+					return nil
+				}
+				return uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+					return uc.addMessageCreated(ctx, sent, senderID, receiverID, text)
+				})
+			},
+		},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return sent, nil
 }
 
-func (uc *UserUseCase) GetDialogMessages(ctx context.Context, user1ID, user2ID int64) ([]*entity.DialogMessage, error) {
-	// todo вообще storage должен возвращать DAO
-	// а тут уже все конвертации
-	return uc.repo.GetDialogMessages(ctx, user1ID, user2ID)
+// addMessageCreated пишет в outbox события нового сообщения: message.created
+// и новый счетчик непрочитанных получателя
+func (uc *DialogUseCase) addMessageCreated(ctx context.Context, sent *entity.SentMessage, senderID, receiverID int64, text string) error {
+	// Агрегат - получатель: события одного получателя публикуются по порядку
+	event, err := entity.NewOutboxEvent(entity.AggregateDialog, receiverID, entity.EventMessageCreated, entity.DialogMessageEvent{
+		MessageID:  sent.ID,
+		SenderID:   senderID,
+		ReceiverID: receiverID,
+		Text:       text,
+		Timestamp:  sent.SentAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := uc.outboxRepo.Add(ctx, event); err != nil {
+		return err
+	}
+
+	unread, total, err := uc.repo.GetUnreadCount(ctx, receiverID, senderID)
+	if err != nil {
+		return err
+	}
+
+	return uc.addUnreadChanged(ctx, receiverID, senderID, unread, total)
+}
+
+// StreamMessages передает в send сообщения userID во всех диалогах по
+// возрастанию message_id: сначала после since, затем новые по мере
+// сохранения. since=0 - без догрузки, с последнего сообщения пользователя.
+// Сообщения читаются из хранилища пачками по курсору, сигнал уведомителя
+// лишь будит чтение, поэтому медленный получатель, блокирующий send, не
+// копит очередь в памяти. Работает до отмены ctx или ошибки send
+func (uc *DialogUseCase) StreamMessages(ctx context.Context, userID, since int64, send func(*entity.DialogMessage) error) error {
+	if since < 0 {
+		return ErrInvalidCursor
+	}
+
+	// Подписка до чтения курсора: сообщение, сохраненное между ними, не потеряется
+	updates, unsubscribe := uc.notifier.Subscribe(userID)
+	defer unsubscribe()
+
+	cursor := since
+	if cursor == 0 {
+		var err error
+		if cursor, err = uc.repo.GetLastUserMessageID(ctx, userID); err != nil {
+			return err
+		}
+	}
+
+	ticker := time.NewTicker(streamPollInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		if cursor, err = uc.sendMessagesAfter(ctx, userID, cursor, send); err != nil {
+			return err
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-updates:
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendMessagesAfter передает все сообщения после cursor и возвращает новый курсор
+func (uc *DialogUseCase) sendMessagesAfter(ctx context.Context, userID, cursor int64, send func(*entity.DialogMessage) error) (int64, error) {
+	for {
+		messages, err := uc.repo.GetUserMessagesAfter(ctx, userID, cursor, MaxMessagesLimit)
+		if err != nil {
+			return cursor, err
+		}
+
+		for _, msg := range messages {
+			if err := send(msg); err != nil {
+				return cursor, err
+			}
+			if cursor, err = strconv.ParseInt(msg.ID, 10, 64); err != nil {
+				return cursor, err
+			}
+		}
+
+		if len(messages) < MaxMessagesLimit {
+			return cursor, nil
+		}
+	}
+}
+
+// MarkRead отмечает прочитанными сообщения собеседника до upToMessageID.
+// Отправитель получает событие message.read, читатель - unread.changed
+func (uc *DialogUseCase) MarkRead(ctx context.Context, userID, peerID, upToMessageID int64) (*entity.UnreadCount, error) {
+	if upToMessageID <= 0 {
+		return nil, ErrInvalidCursor
+	}
+	if userID == peerID {
+		return nil, ErrSelfOperation
+	}
+
+	var result entity.UnreadCount
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		marked, err := uc.repo.MarkDialogRead(ctx, userID, peerID, upToMessageID)
+		if err != nil {
+			return err
+		}
+
+		result.Marked = marked
+		result.PeerID = peerID
+		if result.Unread, result.Total, err = uc.repo.GetUnreadCount(ctx, userID, peerID); err != nil {
+			return err
+		}
+		if marked == 0 {
+			return nil
+		}
+
+		event, err := entity.NewOutboxEvent(entity.AggregateDialog, peerID, entity.EventMessageRead, entity.DialogReadEvent{
+			ReaderID:      userID,
+			PeerID:        peerID,
+			UpToMessageID: upToMessageID,
+			Timestamp:     time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := uc.outboxRepo.Add(ctx, event); err != nil {
+			return err
+		}
+
+		return uc.addUnreadChanged(ctx, userID, peerID, result.Unread, result.Total)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Счетчик выставляется абсолютным значением из dialog_inbox, поэтому
+	// компенсации не нужны: ошибку исправит следующая отметка или сверка
+	if result.Marked > 0 {
+		if err := uc.unread.SetDialog(ctx, userID, peerID, result.Unread, result.Total); err != nil {
+			log.Printf("Dialog: %v", err)
+		}
+	}
+
+	return &result, nil
+}
+
+// GetUnreadCounters возвращает счетчики непрочитанных пользователя. Счетчики
+// читаются из Redis, а при их отсутствии пересобираются из dialog_inbox
+func (uc *DialogUseCase) GetUnreadCounters(ctx context.Context, userID int64) (*entity.UnreadCounters, error) {
+	counters, total, found, err := uc.unread.Get(ctx, userID)
+	if err != nil {
+		log.Printf("Dialog: %v", err)
+	}
+	if found {
+		return &entity.UnreadCounters{Dialogs: counters, Total: total}, nil
+	}
+
+	counters, err = uc.repo.GetUnreadCounters(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uc.unread.Set(ctx, userID, counters); err != nil {
+		log.Printf("Dialog: %v", err)
+	}
+
+	result := &entity.UnreadCounters{Dialogs: counters}
+	for _, count := range counters {
+		result.Total += count
+	}
+
+	return result, nil
+}
+
+// EditMessage меняет текст сообщения. Редактировать может только
+// отправитель в течение editWindow после отправки
+func (uc *DialogUseCase) EditMessage(ctx context.Context, userID, peerID, messageID int64, text string) (*entity.DialogMessage, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyMessage
+	}
+
+	msg, err := uc.ownMessage(ctx, userID, peerID, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if time.Since(msg.SentAt) > uc.editWindow {
+		return nil, ErrEditWindowExpired
+	}
+
+	editedAt := time.Now().UTC()
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.EditDialogMessage(ctx, messageID, userID, text, editedAt); err != nil {
+			return err
+		}
+
+		event, err := entity.NewOutboxEvent(entity.AggregateDialog, peerID, entity.EventMessageEdited, entity.DialogMessageEditedEvent{
+			MessageID:  messageID,
+			SenderID:   userID,
+			ReceiverID: peerID,
+			Text:       text,
+			EditedAt:   editedAt.Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return uc.outboxRepo.Add(ctx, event)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	msg.Text = text
+	msg.EditedAt = &editedAt
+
+	return msg, nil
+}
+
+// DeleteMessage удаляет сообщение у userID, а с forEveryone - у обоих
+// участников. Удалить для всех может только отправитель: текст стирается,
+// в истории остается надгробие, получатель получает событие message.deleted
+func (uc *DialogUseCase) DeleteMessage(ctx context.Context, userID, peerID, messageID int64, forEveryone bool) error {
+	if !forEveryone {
+		err := uc.repo.HideDialogMessage(ctx, messageID, userID, peerID)
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return ErrMessageNotFound
+		}
+		return err
+	}
+
+	msg, err := uc.ownMessage(ctx, userID, peerID, messageID)
+	if err != nil {
+		return err
+	}
+
+	var unread, total int
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.TombstoneDialogMessage(ctx, messageID, userID, peerID); err != nil {
+			return err
+		}
+
+		event, err := entity.NewOutboxEvent(entity.AggregateDialog, peerID, entity.EventMessageDeleted, entity.DialogMessageDeletedEvent{
+			MessageID:  messageID,
+			SenderID:   userID,
+			ReceiverID: peerID,
+			Timestamp:  time.Now().Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		if err := uc.outboxRepo.Add(ctx, event); err != nil {
+			return err
+		}
+		if msg.IsRead {
+			return nil
+		}
+
+		// Непрочитанное сообщение уходит из счетчика получателя
+		if unread, total, err = uc.repo.GetUnreadCount(ctx, peerID, userID); err != nil {
+			return err
+		}
+
+		return uc.addUnreadChanged(ctx, peerID, userID, unread, total)
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return ErrMessageNotFound
+		}
+		return err
+	}
+
+	if !msg.IsRead {
+		if err := uc.unread.SetDialog(ctx, peerID, userID, unread, total); err != nil {
+			log.Printf("Dialog: %v", err)
+		}
+	}
+
+	return nil
+}
+
+// resyncUnread выставляет счетчик диалога в Redis по dialog_inbox. Ошибка
+// только логируется: повтор отправки уже успешен, счетчик исправит сверка
+func (uc *DialogUseCase) resyncUnread(ctx context.Context, userID, peerID int64) {
+	unread, total, err := uc.repo.GetUnreadCount(ctx, userID, peerID)
+	if err == nil {
+		err = uc.unread.SetDialog(ctx, userID, peerID, unread, total)
+	}
+	if err != nil {
+		log.Printf("Dialog: %v", err)
+	}
+}
+
+// ownMessage возвращает неудаленное сообщение, отправленное userID собеседнику peerID
+func (uc *DialogUseCase) ownMessage(ctx context.Context, userID, peerID, messageID int64) (*entity.DialogMessage, error) {
+	msg, err := uc.repo.GetDialogMessage(ctx, messageID, userID, peerID)
+	if err != nil {
+		if errors.Is(err, repository.ErrMessageNotFound) {
+			return nil, ErrMessageNotFound
+		}
+		return nil, err
+	}
+
+	if msg.Deleted {
+		return nil, ErrMessageNotFound
+	}
+	if msg.SenderID != strconv.FormatInt(userID, 10) {
+		return nil, ErrNotMessageSender
+	}
+
+	return msg, nil
+}
+
+// addUnreadChanged пишет в outbox новый счетчик непрочитанных пользователя
+func (uc *DialogUseCase) addUnreadChanged(ctx context.Context, userID, peerID int64, unread, total int) error {
+	event, err := entity.NewOutboxEvent(entity.AggregateDialog, userID, entity.EventUnreadChanged, entity.UnreadChangedEvent{
+		UserID:      userID,
+		PeerID:      peerID,
+		UnreadCount: unread,
+		TotalUnread: total,
+		Timestamp:   time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.outboxRepo.Add(ctx, event)
+}
+
+// GetDialogMessages возвращает страницу истории диалога. Лимит приводится
+// к диапазону [1, MaxMessagesLimit], по умолчанию DefaultMessagesLimit
+func (uc *DialogUseCase) GetDialogMessages(ctx context.Context, user1ID, user2ID int64, query entity.MessagesQuery) (*entity.MessagesPage, error) {
+	if query.Before < 0 || query.After < 0 || (query.Before > 0 && query.After > 0) {
+		return nil, ErrInvalidCursor
+	}
+
+	limit := query.Limit
+	switch {
+	case limit <= 0:
+		limit = DefaultMessagesLimit
+	case limit > MaxMessagesLimit:
+		limit = MaxMessagesLimit
+	}
+
+	// Лишнее сообщение показывает, что за страницей есть еще
+	query.Limit = limit + 1
+	messages, err := uc.repo.GetDialogMessages(ctx, user1ID, user2ID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.MessagesPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = page.Messages[limit-1].ID
+	}
+
+	return page, nil
+}
+
+// ListDialogs возвращает страницу списка диалогов пользователя
+func (uc *DialogUseCase) ListDialogs(ctx context.Context, userID, before int64, limit int) (*entity.DialogsPage, error) {
+	if before < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	switch {
+	case limit <= 0:
+		limit = DefaultMessagesLimit
+	case limit > MaxMessagesLimit:
+		limit = MaxMessagesLimit
+	}
+
+	dialogs, err := uc.repo.ListDialogs(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.DialogsPage{Dialogs: dialogs}
+	if len(dialogs) > limit {
+		page.Dialogs = dialogs[:limit]
+		page.NextCursor = page.Dialogs[limit-1].LastMessage.ID
+	}
+
+	return page, nil
 }
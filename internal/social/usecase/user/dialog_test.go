@@ -0,0 +1,657 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+	"otus-highload-arh-homework/pkg/saga"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var errInjected = errors.New("injected failure")
+
+// fakeDialogRepo хранит сообщения в памяти, остальные методы не нужны
+type fakeDialogRepo struct {
+	repository.DialogRepository
+
+	nextID    int64
+	messages  map[int64]bool
+	clientIDs map[string]int64
+	unread    map[[2]int64]int
+
+	failStore  bool
+	failDelete bool
+	failCount  bool
+
+	inboxUsers []int64
+	recounted  map[int64]map[int64]int
+}
+
+func newFakeDialogRepo() *fakeDialogRepo {
+	return &fakeDialogRepo{
+		messages:  make(map[int64]bool),
+		clientIDs: make(map[string]int64),
+		unread:    make(map[[2]int64]int),
+	}
+}
+
+func (r *fakeDialogRepo) StoreDialogMessage(_ context.Context, senderID, recipientID int64, _, clientMessageID string) (*entity.SentMessage, error) {
+	if r.failStore {
+		return nil, errInjected
+	}
+	key := fmt.Sprintf("%d:%s", senderID, clientMessageID)
+	if id, ok := r.clientIDs[key]; ok && clientMessageID != "" && r.messages[id] {
+		return &entity.SentMessage{ID: id, Duplicate: true}, nil
+	}
+	r.nextID++
+	r.messages[r.nextID] = true
+	r.clientIDs[key] = r.nextID
+	r.unread[[2]int64{recipientID, senderID}]++
+	return &entity.SentMessage{ID: r.nextID, SentAt: time.Now()}, nil
+}
+
+func (r *fakeDialogRepo) DeleteDialogMessage(_ context.Context, messageID, senderID, recipientID int64) error {
+	if r.failDelete {
+		return errInjected
+	}
+	delete(r.messages, messageID)
+	r.unread[[2]int64{recipientID, senderID}]--
+	return nil
+}
+
+func (r *fakeDialogRepo) GetUnreadCount(_ context.Context, userID, peerID int64) (int, int, error) {
+	if r.failCount {
+		return 0, 0, errInjected
+	}
+	var total int
+	for key, count := range r.unread {
+		if key[0] == userID {
+			total += count
+		}
+	}
+	return r.unread[[2]int64{userID, peerID}], total, nil
+}
+
+func (r *fakeDialogRepo) ListInboxUsers(_ context.Context, afterUserID int64, limit int) ([]int64, error) {
+	var users []int64
+	for _, userID := range r.inboxUsers {
+		if userID > afterUserID && len(users) < limit {
+			users = append(users, userID)
+		}
+	}
+	return users, nil
+}
+
+func (r *fakeDialogRepo) RecountUnread(_ context.Context, userID int64) (map[int64]int, error) {
+	return r.recounted[userID], nil
+}
+
+// snapshot и restore откатывают сообщения неудачной транзакции
+func (r *fakeDialogRepo) snapshot() *fakeDialogRepo {
+	return &fakeDialogRepo{
+		nextID:    r.nextID,
+		messages:  maps.Clone(r.messages),
+		clientIDs: maps.Clone(r.clientIDs),
+		unread:    maps.Clone(r.unread),
+	}
+}
+
+func (r *fakeDialogRepo) restore(saved *fakeDialogRepo) {
+	r.nextID, r.messages, r.clientIDs, r.unread = saved.nextID, saved.messages, saved.clientIDs, saved.unread
+}
+
+// fakeTxManager откатывает события outbox и, если задан repo, сообщения,
+// записанные в неудачной транзакции
+type fakeTxManager struct {
+	outbox *fakeOutbox
+	repo   *fakeDialogRepo
+}
+
+func (m fakeTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	written := len(m.outbox.events)
+	var saved *fakeDialogRepo
+	if m.repo != nil {
+		saved = m.repo.snapshot()
+	}
+
+	err := fn(ctx)
+	if err != nil {
+		m.outbox.events = m.outbox.events[:written]
+		if m.repo != nil {
+			m.repo.restore(saved)
+		}
+	}
+	return err
+}
+
+type fakeOutbox struct {
+	repository.OutboxRepository
+
+	events []*entity.OutboxEvent
+	fail   bool
+}
+
+func (o *fakeOutbox) Add(_ context.Context, event *entity.OutboxEvent) error {
+	if o.fail {
+		return errInjected
+	}
+	o.events = append(o.events, event)
+	return nil
+}
+
+// fakeNotifier раздает сигналы подписчикам в памяти и запоминает уведомления
+type fakeNotifier struct {
+	mu       sync.Mutex
+	subs     map[int64][]chan struct{}
+	notified []int64
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{subs: make(map[int64][]chan struct{})}
+}
+
+func (n *fakeNotifier) Notify(_ context.Context, userIDs ...int64) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.notified = append(n.notified, userIDs...)
+	for _, userID := range userIDs {
+		for _, ch := range n.subs[userID] {
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+	return nil
+}
+
+func (n *fakeNotifier) Subscribe(userID int64) (<-chan struct{}, func()) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	ch := make(chan struct{}, 1)
+	n.subs[userID] = append(n.subs[userID], ch)
+	return ch, func() {}
+}
+
+// fakeCounters - счетчики, загруженные для всех пользователей
+type fakeCounters struct {
+	counters      map[int64]map[int64]int
+	failIncrement bool
+}
+
+func newFakeCounters() *fakeCounters {
+	return &fakeCounters{counters: make(map[int64]map[int64]int)}
+}
+
+func (c *fakeCounters) Get(_ context.Context, userID int64) (map[int64]int, int, bool, error) {
+	counters, ok := c.counters[userID]
+	var total int
+	for _, count := range counters {
+		total += count
+	}
+	return counters, total, ok, nil
+}
+
+func (c *fakeCounters) Set(_ context.Context, userID int64, counters map[int64]int) error {
+	c.counters[userID] = counters
+	return nil
+}
+
+func (c *fakeCounters) Increment(_ context.Context, userID, peerID int64, delta int) error {
+	if c.failIncrement && delta > 0 {
+		return errInjected
+	}
+	if c.counters[userID] == nil {
+		c.counters[userID] = make(map[int64]int)
+	}
+	c.counters[userID][peerID] += delta
+	return nil
+}
+
+func (c *fakeCounters) SetDialog(_ context.Context, userID, peerID int64, unread, _ int) error {
+	if c.counters[userID] == nil {
+		c.counters[userID] = make(map[int64]int)
+	}
+	c.counters[userID][peerID] = unread
+	return nil
+}
+
+func TestSendDialogMessage_Saga(t *testing.T) {
+	const sender, receiver = 1, 2
+
+	tests := []struct {
+		name   string
+		inject func(repo *fakeDialogRepo, counters *fakeCounters, outbox *fakeOutbox)
+
+		wantErr      bool
+		wantMessages int
+		wantUnread   int
+		wantEvents   []string
+	}{
+		{
+			name:         "success",
+			inject:       func(*fakeDialogRepo, *fakeCounters, *fakeOutbox) {},
+			wantMessages: 1,
+			wantUnread:   1,
+			wantEvents:   []string{entity.EventMessageCreated, entity.EventUnreadChanged},
+		},
+		{
+			name:    "store message fails",
+			inject:  func(repo *fakeDialogRepo, _ *fakeCounters, _ *fakeOutbox) { repo.failStore = true },
+			wantErr: true,
+		},
+		{
+			name:    "outbox fails: message is not stored",
+			inject:  func(_ *fakeDialogRepo, _ *fakeCounters, outbox *fakeOutbox) { outbox.fail = true },
+			wantErr: true,
+		},
+		{
+			name:    "unread count fails: message is not stored",
+			inject:  func(repo *fakeDialogRepo, _ *fakeCounters, _ *fakeOutbox) { repo.failCount = true },
+			wantErr: true,
+		},
+		{
+			name:         "counter increment fails: message is sent, counter is left to reconciler",
+			inject:       func(_ *fakeDialogRepo, counters *fakeCounters, _ *fakeOutbox) { counters.failIncrement = true },
+			wantMessages: 1,
+			wantEvents:   []string{entity.EventMessageCreated, entity.EventUnreadChanged},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newFakeDialogRepo()
+			counters := newFakeCounters()
+			outbox := &fakeOutbox{}
+			tt.inject(repo, counters, outbox)
+
+			uc := NewDialogUseCase(repo, fakeTxManager{outbox: outbox, repo: repo}, outbox, counters, newFakeNotifier(), 0)
+			_, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "")
+
+			if !tt.wantErr {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, errInjected)
+			}
+			assert.Len(t, repo.messages, tt.wantMessages)
+			assert.Equal(t, tt.wantUnread, counters.counters[receiver][sender], "счетчик в Redis")
+			var eventTypes []string
+			for _, event := range outbox.events {
+				eventTypes = append(eventTypes, event.EventType)
+			}
+			assert.Equal(t, tt.wantEvents, eventTypes)
+			if tt.wantMessages == 0 {
+				assert.Zero(t, repo.unread[[2]int64{receiver, sender}], "счетчик в dialog_inbox")
+			}
+		})
+	}
+}
+
+func TestSendDialogMessage_Idempotent(t *testing.T) {
+	const sender, receiver = 1, 2
+
+	repo := newFakeDialogRepo()
+	counters := newFakeCounters()
+	outbox := &fakeOutbox{}
+	uc := NewDialogUseCase(repo, fakeTxManager{outbox: outbox}, outbox, counters, newFakeNotifier(), 0)
+
+	first, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+	require.NoError(t, err)
+	assert.False(t, first.Duplicate)
+
+	replay, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+	require.NoError(t, err)
+	assert.True(t, replay.Duplicate)
+	assert.Equal(t, first.ID, replay.ID)
+
+	assert.Len(t, repo.messages, 1)
+	assert.Equal(t, 1, counters.counters[receiver][sender], "повтор не меняет счетчик")
+	assert.Len(t, outbox.events, 2, "повтор не публикует события")
+
+	// Первая попытка прервалась до счетчика: повтор восстанавливает его
+	delete(counters.counters, receiver)
+	_, err = uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, counters.counters[receiver][sender], "повтор восстанавливает счетчик")
+	assert.Len(t, outbox.events, 2)
+	assert.Equal(t, []int64{sender, receiver}, uc.notifier.(*fakeNotifier).notified, "повтор не будит потоки")
+
+	_, err = uc.SendDialogMessage(context.Background(), sender, receiver, "hi", strings.Repeat("x", MaxClientMessageIDLength+1))
+	assert.ErrorIs(t, err, ErrInvalidClientMessageID)
+}
+
+// nonTxDialogRepo - хранилище вне транзакций, как Redis: fakeTxManager без
+// repo не откатывает его записи
+type nonTxDialogRepo struct {
+	*fakeDialogRepo
+}
+
+func (nonTxDialogRepo) NonTransactional() {}
+
+func TestSendDialogMessage_NonTransactional(t *testing.T) {
+	const sender, receiver = 1, 2
+
+	t.Run("outbox fails: message is deleted and retry writes events", func(t *testing.T) {
+		repo := newFakeDialogRepo()
+		outbox := &fakeOutbox{fail: true}
+		uc := NewDialogUseCase(nonTxDialogRepo{repo}, fakeTxManager{outbox: outbox}, outbox, newFakeCounters(), newFakeNotifier(), 0)
+
+		_, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+		require.ErrorIs(t, err, errInjected)
+		assert.Empty(t, repo.messages)
+		assert.Zero(t, repo.unread[[2]int64{receiver, sender}])
+
+		outbox.fail = false
+		sent, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+		require.NoError(t, err)
+		assert.False(t, sent.Duplicate)
+		assert.Len(t, repo.messages, 1)
+		assert.Len(t, outbox.events, 2)
+	})
+
+	t.Run("delete fails: compensation error is reported", func(t *testing.T) {
+		repo := newFakeDialogRepo()
+		repo.failDelete = true
+		outbox := &fakeOutbox{fail: true}
+		uc := NewDialogUseCase(nonTxDialogRepo{repo}, fakeTxManager{outbox: outbox}, outbox, newFakeCounters(), newFakeNotifier(), 0)
+
+		_, err := uc.SendDialogMessage(context.Background(), sender, receiver, "hi", "retry-1")
+		require.ErrorIs(t, err, errInjected)
+		require.ErrorIs(t, err, saga.ErrCompensation)
+		assert.Len(t, repo.messages, 1)
+	})
+}
+
+func TestUnreadReconciler_Reconcile(t *testing.T) {
+	repo := newFakeDialogRepo()
+	repo.inboxUsers = []int64{1, 2, 3}
+	repo.recounted = map[int64]map[int64]int{
+		1: {5: 2},
+		2: {},
+		3: {5: 1, 6: 4},
+	}
+
+	counters := newFakeCounters()
+	// Счетчики пользователя 1 разошлись, пользователя 3 не загружены
+	counters.counters[1] = map[int64]int{5: 7}
+	counters.counters[2] = map[int64]int{5: 1}
+
+	reconciler := NewUnreadReconciler(repo, counters, nil, 0, 2)
+	checked, err := reconciler.Reconcile(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, 3, checked)
+	assert.Equal(t, map[int64]int{5: 2}, counters.counters[1])
+	assert.Empty(t, counters.counters[2])
+	assert.NotContains(t, counters.counters, int64(3), "незагруженные счетчики не создаются")
+}
+
+// fakeMessageRepo хранит отправленные сообщения для редактирования и удаления
+type fakeMessageRepo struct {
+	*fakeDialogRepo
+
+	stored map[int64]*entity.DialogMessage
+	hidden map[int64]bool
+}
+
+func (r *fakeMessageRepo) GetDialogMessage(_ context.Context, messageID, _, _ int64) (*entity.DialogMessage, error) {
+	msg, ok := r.stored[messageID]
+	if !ok || r.hidden[messageID] {
+		return nil, repository.ErrMessageNotFound
+	}
+	copied := *msg
+	return &copied, nil
+}
+
+func (r *fakeMessageRepo) EditDialogMessage(_ context.Context, messageID, _ int64, text string, editedAt time.Time) error {
+	r.stored[messageID].Text = text
+	r.stored[messageID].EditedAt = &editedAt
+	return nil
+}
+
+func (r *fakeMessageRepo) TombstoneDialogMessage(_ context.Context, messageID, senderID, recipientID int64) error {
+	msg := r.stored[messageID]
+	if !msg.IsRead {
+		r.unread[[2]int64{recipientID, senderID}]--
+	}
+	msg.Text, msg.Deleted, msg.IsRead = "", true, true
+	return nil
+}
+
+func (r *fakeMessageRepo) HideDialogMessage(_ context.Context, messageID, _, _ int64) error {
+	if _, ok := r.stored[messageID]; !ok {
+		return repository.ErrMessageNotFound
+	}
+	r.hidden[messageID] = true
+	return nil
+}
+
+func newMessageUseCase(t *testing.T) (*DialogUseCase, *fakeMessageRepo, *fakeOutbox, *fakeCounters) {
+	t.Helper()
+
+	repo := &fakeMessageRepo{
+		fakeDialogRepo: newFakeDialogRepo(),
+		stored: map[int64]*entity.DialogMessage{
+			1: {ID: "1", SenderID: "1", ReceiverID: "2", Text: "hello", SentAt: time.Now()},
+			2: {ID: "2", SenderID: "1", ReceiverID: "2", Text: "old", SentAt: time.Now().Add(-time.Hour), IsRead: true},
+		},
+		hidden: make(map[int64]bool),
+	}
+	repo.unread[[2]int64{2, 1}] = 1
+
+	outbox := &fakeOutbox{}
+	counters := newFakeCounters()
+	uc := NewDialogUseCase(repo, fakeTxManager{outbox: outbox}, outbox, counters, newFakeNotifier(), 0)
+
+	return uc, repo, outbox, counters
+}
+
+func TestDialogUseCase_EditMessage(t *testing.T) {
+	tests := []struct {
+		name      string
+		userID    int64
+		messageID int64
+		text      string
+		wantErr   error
+	}{
+		{"sender within window", 1, 1, "edited", nil},
+		{"empty text", 1, 1, "  ", ErrEmptyMessage},
+		{"not sender", 2, 1, "edited", ErrNotMessageSender},
+		{"window expired", 1, 2, "edited", ErrEditWindowExpired},
+		{"unknown message", 1, 42, "edited", ErrMessageNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, repo, outbox, _ := newMessageUseCase(t)
+
+			msg, err := uc.EditMessage(context.Background(), tt.userID, 2, tt.messageID, tt.text)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Empty(t, outbox.events)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, "edited", msg.Text)
+			assert.NotNil(t, msg.EditedAt)
+			assert.Equal(t, "edited", repo.stored[tt.messageID].Text)
+			require.Len(t, outbox.events, 1)
+			assert.Equal(t, entity.EventMessageEdited, outbox.events[0].EventType)
+		})
+	}
+}
+
+func TestDialogUseCase_DeleteMessage(t *testing.T) {
+	t.Run("for me hides only", func(t *testing.T) {
+		uc, repo, outbox, _ := newMessageUseCase(t)
+
+		require.NoError(t, uc.DeleteMessage(context.Background(), 2, 1, 1, false))
+		assert.True(t, repo.hidden[1])
+		assert.False(t, repo.stored[1].Deleted)
+		assert.Empty(t, outbox.events)
+	})
+
+	t.Run("for everyone by receiver", func(t *testing.T) {
+		uc, _, _, _ := newMessageUseCase(t)
+
+		err := uc.DeleteMessage(context.Background(), 2, 1, 1, true)
+		assert.ErrorIs(t, err, ErrNotMessageSender)
+	})
+
+	t.Run("for everyone unread message", func(t *testing.T) {
+		uc, repo, outbox, counters := newMessageUseCase(t)
+
+		require.NoError(t, uc.DeleteMessage(context.Background(), 1, 2, 1, true))
+		assert.True(t, repo.stored[1].Deleted)
+		assert.Empty(t, repo.stored[1].Text)
+		assert.Equal(t, 0, counters.counters[2][1])
+
+		require.Len(t, outbox.events, 2)
+		assert.Equal(t, entity.EventMessageDeleted, outbox.events[0].EventType)
+		assert.Equal(t, entity.EventUnreadChanged, outbox.events[1].EventType)
+
+		err := uc.DeleteMessage(context.Background(), 1, 2, 1, true)
+		assert.ErrorIs(t, err, ErrMessageNotFound, "tombstone cannot be deleted again")
+	})
+
+	t.Run("for everyone read message", func(t *testing.T) {
+		uc, _, outbox, _ := newMessageUseCase(t)
+
+		require.NoError(t, uc.DeleteMessage(context.Background(), 1, 2, 2, true))
+		require.Len(t, outbox.events, 1)
+		assert.Equal(t, entity.EventMessageDeleted, outbox.events[0].EventType)
+	})
+}
+
+// fakeStreamRepo - сообщения пользователя для потока, пишутся из теста
+// параллельно с чтением потока
+type fakeStreamRepo struct {
+	repository.DialogRepository
+
+	mu       sync.Mutex
+	messages []*entity.DialogMessage
+	// lastRead получает сигнал, когда поток прочитал стартовый курсор
+	lastRead chan struct{}
+}
+
+func (r *fakeStreamRepo) add(id int64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, &entity.DialogMessage{ID: strconv.FormatInt(id, 10), SenderID: "2", ReceiverID: "1"})
+}
+
+func (r *fakeStreamRepo) GetUserMessagesAfter(_ context.Context, _, after int64, limit int) ([]*entity.DialogMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var result []*entity.DialogMessage
+	for _, msg := range r.messages {
+		if id, _ := strconv.ParseInt(msg.ID, 10, 64); id > after && len(result) < limit {
+			result = append(result, msg)
+		}
+	}
+	return result, nil
+}
+
+func (r *fakeStreamRepo) GetLastUserMessageID(_ context.Context, _ int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	defer func() { r.lastRead <- struct{}{} }()
+
+	if len(r.messages) == 0 {
+		return 0, nil
+	}
+	return strconv.ParseInt(r.messages[len(r.messages)-1].ID, 10, 64)
+}
+
+func TestDialogUseCase_StreamMessages(t *testing.T) {
+	start := func(t *testing.T, since int64) (*fakeStreamRepo, *fakeNotifier, <-chan string, <-chan error, context.CancelFunc) {
+		t.Helper()
+
+		repo := &fakeStreamRepo{lastRead: make(chan struct{}, 1)}
+		for id := int64(1); id <= MaxMessagesLimit+2; id++ {
+			repo.add(id)
+		}
+		notifier := newFakeNotifier()
+		uc := NewDialogUseCase(repo, nil, nil, nil, notifier, 0)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		received := make(chan string, 2*MaxMessagesLimit)
+		done := make(chan error, 1)
+		go func() {
+			done <- uc.StreamMessages(ctx, 1, since, func(msg *entity.DialogMessage) error {
+				received <- msg.ID
+				return nil
+			})
+		}()
+
+		return repo, notifier, received, done, cancel
+	}
+
+	next := func(t *testing.T, received <-chan string) string {
+		t.Helper()
+		select {
+		case id := <-received:
+			return id
+		case <-time.After(time.Second):
+			t.Fatal("message was not streamed")
+			return ""
+		}
+	}
+
+	t.Run("replays from cursor then pushes new", func(t *testing.T) {
+		repo, notifier, received, done, cancel := start(t, 1)
+
+		// Догрузка идет пачками до конца
+		for id := 2; id <= MaxMessagesLimit+2; id++ {
+			assert.Equal(t, strconv.Itoa(id), next(t, received))
+		}
+
+		repo.add(MaxMessagesLimit + 3)
+		require.NoError(t, notifier.Notify(context.Background(), 1))
+		assert.Equal(t, strconv.Itoa(MaxMessagesLimit+3), next(t, received))
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("empty cursor streams only new", func(t *testing.T) {
+		repo, notifier, received, done, cancel := start(t, 0)
+		defer cancel()
+
+		<-repo.lastRead
+		repo.add(MaxMessagesLimit + 3)
+		require.NoError(t, notifier.Notify(context.Background(), 1))
+		assert.Equal(t, strconv.Itoa(MaxMessagesLimit+3), next(t, received))
+
+		cancel()
+		assert.ErrorIs(t, <-done, context.Canceled)
+	})
+
+	t.Run("send error stops stream", func(t *testing.T) {
+		repo := &fakeStreamRepo{}
+		repo.add(1)
+		repo.add(2)
+		uc := NewDialogUseCase(repo, nil, nil, nil, newFakeNotifier(), 0)
+
+		err := uc.StreamMessages(context.Background(), 1, -1, nil)
+		assert.ErrorIs(t, err, ErrInvalidCursor)
+
+		err = uc.StreamMessages(context.Background(), 1, 1, func(*entity.DialogMessage) error { return errInjected })
+		assert.ErrorIs(t, err, errInjected)
+	})
+}
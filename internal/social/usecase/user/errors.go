@@ -3,8 +3,25 @@ package user
 import "errors"
 
 var (
-	ErrUserNotFound   = errors.New("user not found")
-	ErrSelfOperation  = errors.New("cannot perform operation on yourself")
-	ErrAlreadyFriends = errors.New("users are already friends")
-	ErrNotFriends     = errors.New("users are not friends")
+	ErrUserNotFound           = errors.New("user not found")
+	ErrSelfOperation          = errors.New("cannot perform operation on yourself")
+	ErrAlreadyFriends         = errors.New("users are already friends")
+	ErrNotFriends             = errors.New("users are not friends")
+	ErrInvalidVisibility      = errors.New("invalid last seen visibility")
+	ErrInvalidCursor          = errors.New("invalid messages cursor")
+	ErrEmptyMessage           = errors.New("message text cannot be empty")
+	ErrMessageNotFound        = errors.New("message not found")
+	ErrNotMessageSender       = errors.New("only the sender can change the message")
+	ErrEditWindowExpired      = errors.New("message edit window has expired")
+	ErrInvalidClientMessageID = errors.New("invalid client message id")
+)
+
+var (
+	ErrGroupNotFound       = errors.New("group not found")
+	ErrGroupMemberNotFound = errors.New("group member not found")
+	ErrNotGroupAdmin       = errors.New("only group admins can manage members")
+	ErrInvalidGroupTitle   = errors.New("invalid group title")
+	ErrInvalidGroupRole    = errors.New("invalid group role")
+	ErrTooManyGroupMembers = errors.New("too many group members")
+	ErrLastGroupAdmin      = errors.New("group must keep at least one admin")
 )
@@ -4,19 +4,27 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
+	"otus-highload-arh-homework/internal/social/entity"
 	"otus-highload-arh-homework/internal/social/repository"
 )
 
 type FriendUseCase struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	txManager  repository.TxManager
+	outboxRepo repository.OutboxRepository
 }
 
 func NewFriendUseCase(
 	userRepo repository.UserRepository,
+	txManager repository.TxManager,
+	outboxRepo repository.OutboxRepository,
 ) *FriendUseCase {
 	return &FriendUseCase{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		txManager:  txManager,
+		outboxRepo: outboxRepo,
 	}
 }
 
@@ -44,8 +52,14 @@ func (uc *FriendUseCase) AddFriend(ctx context.Context, userID, friendID int) er
 		return ErrAlreadyFriends
 	}
 
-	// Добавляем в друзья
-	return uc.userRepo.AddFriend(ctx, userID, friendID)
+	// Добавляем в друзья вместе с событием в outbox
+	return uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.userRepo.AddFriend(ctx, userID, friendID); err != nil {
+			return err
+		}
+
+		return uc.addFriendshipEvent(ctx, userID, friendID, entity.EventFriendAdded, entity.FriendshipActionAdd)
+	})
 }
 
 // RemoveFriend удаляет пользователя из друзей (бизнес-логика)
@@ -69,7 +83,14 @@ func (uc *FriendUseCase) RemoveFriend(ctx context.Context, userID, friendID int)
 		return ErrNotFriends
 	}
 
-	if err := uc.userRepo.RemoveFriend(ctx, userID, friendID); err != nil {
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.userRepo.RemoveFriend(ctx, userID, friendID); err != nil {
+			return err
+		}
+
+		return uc.addFriendshipEvent(ctx, userID, friendID, entity.EventFriendRemoved, entity.FriendshipActionRemove)
+	})
+	if err != nil {
 		return fmt.Errorf("failed to remove friend: %w", err)
 	}
 
@@ -99,3 +120,17 @@ func (uc *FriendUseCase) GetFriendsIDs(ctx context.Context, userID int) ([]int,
 
 	return friendsIDs, nil
 }
+
+func (uc *FriendUseCase) addFriendshipEvent(ctx context.Context, userID, friendID int, eventType, action string) error {
+	event, err := entity.NewOutboxEvent(entity.AggregateFriendship, int64(userID), eventType, entity.FriendshipEvent{
+		UserID:    userID,
+		FriendID:  friendID,
+		Action:    action,
+		Timestamp: time.Now().Unix(),
+	})
+	if err != nil {
+		return err
+	}
+
+	return uc.outboxRepo.Add(ctx, event)
+}
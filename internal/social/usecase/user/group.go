@@ -0,0 +1,360 @@
+package user
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+)
+
+const (
+	// MaxGroupMembers - предел участников группы: сообщение доставляется
+	// каждому участнику, размер группы ограничивает рассылку
+	MaxGroupMembers = 200
+	// MaxGroupTitleLength - предел длины названия группы в символах
+	MaxGroupTitleLength = 100
+)
+
+// GroupUseCase - групповые чаты. Участники с ролью admin добавляют и
+// удаляют участников и меняют роли, в группе всегда остается хотя бы один admin
+type GroupUseCase struct {
+	repo       repository.GroupRepository
+	txManager  repository.TxManager
+	outboxRepo repository.OutboxRepository
+}
+
+func NewGroupUseCase(
+	repo repository.GroupRepository,
+	txManager repository.TxManager,
+	outboxRepo repository.OutboxRepository,
+) *GroupUseCase {
+	return &GroupUseCase{
+		repo:       repo,
+		txManager:  txManager,
+		outboxRepo: outboxRepo,
+	}
+}
+
+// CreateGroup создает группу, создатель становится ее администратором
+func (uc *GroupUseCase) CreateGroup(ctx context.Context, creatorID int64, title string, memberIDs []int64) (*entity.Group, error) {
+	title = strings.TrimSpace(title)
+	if title == "" || utf8.RuneCountInString(title) > MaxGroupTitleLength {
+		return nil, ErrInvalidGroupTitle
+	}
+
+	memberIDs = uniqueMembers(memberIDs, creatorID)
+	if len(memberIDs)+1 > MaxGroupMembers {
+		return nil, ErrTooManyGroupMembers
+	}
+
+	var group *entity.Group
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if group, err = uc.repo.CreateGroup(ctx, title, creatorID); err != nil {
+			return err
+		}
+		if _, err := uc.repo.AddMembers(ctx, group.ID, []int64{creatorID}, entity.GroupRoleAdmin); err != nil {
+			return err
+		}
+
+		added, err := uc.addMembers(ctx, group.ID, memberIDs)
+		group.MemberCount = 1 + len(added)
+		return err
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, ErrUserNotFound
+		}
+		return nil, err
+	}
+
+	group.Role = entity.GroupRoleAdmin
+
+	return group, nil
+}
+
+// GetGroup возвращает группу, если userID ее участник
+func (uc *GroupUseCase) GetGroup(ctx context.Context, userID, groupID int64) (*entity.Group, error) {
+	group, err := uc.repo.GetGroup(ctx, groupID, userID)
+	if err != nil {
+		if errors.Is(err, repository.ErrGroupNotFound) {
+			return nil, ErrGroupNotFound
+		}
+		return nil, err
+	}
+
+	return group, nil
+}
+
+// ListGroups возвращает страницу групп пользователя от новых к старым
+func (uc *GroupUseCase) ListGroups(ctx context.Context, userID, before int64, limit int) (*entity.GroupsPage, error) {
+	if before < 0 {
+		return nil, ErrInvalidCursor
+	}
+
+	limit = pageLimit(limit)
+	groups, err := uc.repo.ListUserGroups(ctx, userID, before, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.GroupsPage{Groups: groups}
+	if len(groups) > limit {
+		page.Groups = groups[:limit]
+		page.NextCursor = strconv.FormatInt(page.Groups[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// ListMembers возвращает участников группы, если userID ее участник
+func (uc *GroupUseCase) ListMembers(ctx context.Context, userID, groupID int64) ([]*entity.GroupMember, error) {
+	if _, err := uc.GetGroup(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	return uc.repo.ListMembers(ctx, groupID)
+}
+
+// AddMembers добавляет участников от имени администратора actorID.
+// Возвращает добавленных, уже состоящие в группе пропускаются
+func (uc *GroupUseCase) AddMembers(ctx context.Context, actorID, groupID int64, memberIDs []int64) ([]int64, error) {
+	group, err := uc.adminGroup(ctx, actorID, groupID)
+	if err != nil {
+		return nil, err
+	}
+
+	memberIDs = uniqueMembers(memberIDs, actorID)
+	if group.MemberCount+len(memberIDs) > MaxGroupMembers {
+		return nil, ErrTooManyGroupMembers
+	}
+
+	var added []int64
+	err = uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		added, err = uc.addMembers(ctx, groupID, memberIDs)
+		return err
+	})
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil, ErrUserNotFound
+	}
+
+	return added, err
+}
+
+// RemoveMember удаляет участника memberID от имени администратора actorID.
+// Себя участник удаляет через LeaveGroup
+func (uc *GroupUseCase) RemoveMember(ctx context.Context, actorID, groupID, memberID int64) error {
+	if actorID == memberID {
+		return ErrSelfOperation
+	}
+	if _, err := uc.adminGroup(ctx, actorID, groupID); err != nil {
+		return err
+	}
+
+	err := uc.repo.RemoveMember(ctx, groupID, memberID)
+	if errors.Is(err, repository.ErrGroupMemberNotFound) {
+		return ErrGroupMemberNotFound
+	}
+
+	return err
+}
+
+// LeaveGroup выводит userID из группы. Если уходит последний администратор,
+// администратором становится участник, вступивший раньше остальных
+func (uc *GroupUseCase) LeaveGroup(ctx context.Context, userID, groupID int64) error {
+	group, err := uc.GetGroup(ctx, userID, groupID)
+	if err != nil {
+		return err
+	}
+
+	return uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.RemoveMember(ctx, groupID, userID); err != nil {
+			if errors.Is(err, repository.ErrGroupMemberNotFound) {
+				return ErrGroupNotFound
+			}
+			return err
+		}
+		if group.Role != entity.GroupRoleAdmin {
+			return nil
+		}
+
+		members, err := uc.repo.ListMembers(ctx, groupID)
+		if err != nil || len(members) == 0 || hasAdmin(members) {
+			return err
+		}
+
+		return uc.repo.SetMemberRole(ctx, groupID, members[0].UserID, entity.GroupRoleAdmin)
+	})
+}
+
+// SetMemberRole меняет роль участника от имени администратора actorID
+func (uc *GroupUseCase) SetMemberRole(ctx context.Context, actorID, groupID, memberID int64, role entity.GroupRole) error {
+	if !role.Valid() {
+		return ErrInvalidGroupRole
+	}
+	if _, err := uc.adminGroup(ctx, actorID, groupID); err != nil {
+		return err
+	}
+
+	return uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		if err := uc.repo.SetMemberRole(ctx, groupID, memberID, role); err != nil {
+			if errors.Is(err, repository.ErrGroupMemberNotFound) {
+				return ErrGroupMemberNotFound
+			}
+			return err
+		}
+		if role == entity.GroupRoleAdmin {
+			return nil
+		}
+
+		members, err := uc.repo.ListMembers(ctx, groupID)
+		if err != nil {
+			return err
+		}
+		if !hasAdmin(members) {
+			return ErrLastGroupAdmin
+		}
+
+		return nil
+	})
+}
+
+// SendGroupMessage сохраняет сообщение и в той же транзакции пишет событие
+// group.message.created со списком участников для доставки в реальном
+// времени. Повтор с тем же clientMessageID возвращает уже сохраненное сообщение
+func (uc *GroupUseCase) SendGroupMessage(ctx context.Context, senderID, groupID int64, text, clientMessageID string) (*entity.SentMessage, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyMessage
+	}
+	if len(clientMessageID) > MaxClientMessageIDLength {
+		return nil, ErrInvalidClientMessageID
+	}
+	if _, err := uc.GetGroup(ctx, senderID, groupID); err != nil {
+		return nil, err
+	}
+
+	var sent *entity.SentMessage
+	err := uc.txManager.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		if sent, err = uc.repo.StoreGroupMessage(ctx, groupID, senderID, text, clientMessageID); err != nil || sent.Duplicate {
+			return err
+		}
+
+		members, err := uc.repo.ListMembers(ctx, groupID)
+		if err != nil {
+			return err
+		}
+
+		memberIDs := make([]int64, 0, len(members))
+		for _, member := range members {
+			memberIDs = append(memberIDs, member.UserID)
+		}
+
+		// Агрегат - группа: события группы публикуются по порядку
+		event, err := entity.NewOutboxEvent(entity.AggregateGroup, groupID, entity.EventGroupMessageCreated, entity.GroupMessageEvent{
+			MessageID: sent.ID,
+			GroupID:   groupID,
+			SenderID:  senderID,
+			Text:      text,
+			MemberIDs: memberIDs,
+			Timestamp: sent.SentAt.Unix(),
+		})
+		if err != nil {
+			return err
+		}
+
+		return uc.outboxRepo.Add(ctx, event)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sent, nil
+}
+
+// GetGroupMessages возвращает страницу истории группы, если userID ее участник
+func (uc *GroupUseCase) GetGroupMessages(ctx context.Context, userID, groupID int64, query entity.MessagesQuery) (*entity.GroupMessagesPage, error) {
+	if query.Before < 0 || query.After < 0 || (query.Before > 0 && query.After > 0) {
+		return nil, ErrInvalidCursor
+	}
+	if _, err := uc.GetGroup(ctx, userID, groupID); err != nil {
+		return nil, err
+	}
+
+	limit := pageLimit(query.Limit)
+	query.Limit = limit + 1
+	messages, err := uc.repo.GetGroupMessages(ctx, groupID, query)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &entity.GroupMessagesPage{Messages: messages}
+	if len(messages) > limit {
+		page.Messages = messages[:limit]
+		page.NextCursor = strconv.FormatInt(page.Messages[limit-1].ID, 10)
+	}
+
+	return page, nil
+}
+
+// adminGroup возвращает группу, если actorID ее администратор
+func (uc *GroupUseCase) adminGroup(ctx context.Context, actorID, groupID int64) (*entity.Group, error) {
+	group, err := uc.GetGroup(ctx, actorID, groupID)
+	if err != nil {
+		return nil, err
+	}
+	if group.Role != entity.GroupRoleAdmin {
+		return nil, ErrNotGroupAdmin
+	}
+
+	return group, nil
+}
+
+func (uc *GroupUseCase) addMembers(ctx context.Context, groupID int64, memberIDs []int64) ([]int64, error) {
+	if len(memberIDs) == 0 {
+		return nil, nil
+	}
+
+	return uc.repo.AddMembers(ctx, groupID, memberIDs, entity.GroupRoleMember)
+}
+
+// uniqueMembers убирает повторы, некорректные id и самого пользователя
+func uniqueMembers(memberIDs []int64, selfID int64) []int64 {
+	seen := make(map[int64]bool, len(memberIDs))
+	unique := make([]int64, 0, len(memberIDs))
+	for _, id := range memberIDs {
+		if id <= 0 || id == selfID || seen[id] {
+			continue
+		}
+		seen[id] = true
+		unique = append(unique, id)
+	}
+
+	return unique
+}
+
+func hasAdmin(members []*entity.GroupMember) bool {
+	for _, member := range members {
+		if member.Role == entity.GroupRoleAdmin {
+			return true
+		}
+	}
+
+	return false
+}
+
+// pageLimit приводит размер страницы к [1, MaxMessagesLimit]
+func pageLimit(limit int) int {
+	switch {
+	case limit <= 0:
+		return DefaultMessagesLimit
+	case limit > MaxMessagesLimit:
+		return MaxMessagesLimit
+	}
+
+	return limit
+}
@@ -0,0 +1,190 @@
+package user
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/entity"
+	"otus-highload-arh-homework/internal/social/repository"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGroupRepo хранит группы и участников в памяти в порядке вступления
+type fakeGroupRepo struct {
+	repository.GroupRepository
+
+	nextID    int64
+	members   map[int64][]*entity.GroupMember
+	clientIDs map[string]int64
+}
+
+func newFakeGroupRepo() *fakeGroupRepo {
+	return &fakeGroupRepo{
+		members:   make(map[int64][]*entity.GroupMember),
+		clientIDs: make(map[string]int64),
+	}
+}
+
+func (r *fakeGroupRepo) CreateGroup(_ context.Context, title string, createdBy int64) (*entity.Group, error) {
+	r.nextID++
+	r.members[r.nextID] = nil
+	return &entity.Group{ID: r.nextID, Title: title, CreatedBy: createdBy, CreatedAt: time.Now()}, nil
+}
+
+func (r *fakeGroupRepo) GetGroup(_ context.Context, groupID, userID int64) (*entity.Group, error) {
+	for _, member := range r.members[groupID] {
+		if member.UserID == userID {
+			return &entity.Group{ID: groupID, Role: member.Role, MemberCount: len(r.members[groupID])}, nil
+		}
+	}
+	return nil, repository.ErrGroupNotFound
+}
+
+func (r *fakeGroupRepo) AddMembers(_ context.Context, groupID int64, userIDs []int64, role entity.GroupRole) ([]int64, error) {
+	var added []int64
+	for _, id := range userIDs {
+		if _, err := r.GetGroup(context.Background(), groupID, id); err == nil {
+			continue
+		}
+		r.members[groupID] = append(r.members[groupID], &entity.GroupMember{UserID: id, Role: role})
+		added = append(added, id)
+	}
+	return added, nil
+}
+
+func (r *fakeGroupRepo) RemoveMember(_ context.Context, groupID, userID int64) error {
+	for i, member := range r.members[groupID] {
+		if member.UserID == userID {
+			r.members[groupID] = append(r.members[groupID][:i], r.members[groupID][i+1:]...)
+			return nil
+		}
+	}
+	return repository.ErrGroupMemberNotFound
+}
+
+func (r *fakeGroupRepo) SetMemberRole(_ context.Context, groupID, userID int64, role entity.GroupRole) error {
+	for _, member := range r.members[groupID] {
+		if member.UserID == userID {
+			member.Role = role
+			return nil
+		}
+	}
+	return repository.ErrGroupMemberNotFound
+}
+
+func (r *fakeGroupRepo) ListMembers(_ context.Context, groupID int64) ([]*entity.GroupMember, error) {
+	return r.members[groupID], nil
+}
+
+func (r *fakeGroupRepo) StoreGroupMessage(_ context.Context, groupID, senderID int64, _, clientMessageID string) (*entity.SentMessage, error) {
+	key := fmt.Sprintf("%d:%d:%s", groupID, senderID, clientMessageID)
+	if id, ok := r.clientIDs[key]; ok && clientMessageID != "" {
+		return &entity.SentMessage{ID: id, Duplicate: true}, nil
+	}
+	r.nextID++
+	r.clientIDs[key] = r.nextID
+	return &entity.SentMessage{ID: r.nextID, SentAt: time.Now()}, nil
+}
+
+func newGroupUseCase() (*GroupUseCase, *fakeGroupRepo, *fakeOutbox) {
+	repo := newFakeGroupRepo()
+	outbox := &fakeOutbox{}
+	return NewGroupUseCase(repo, fakeTxManager{outbox: outbox}, outbox), repo, outbox
+}
+
+func TestGroupUseCase_CreateGroup(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newGroupUseCase()
+
+	group, err := uc.CreateGroup(ctx, 1, "  team  ", []int64{2, 3, 2, 1, 0})
+	require.NoError(t, err)
+	assert.Equal(t, "team", group.Title)
+	assert.Equal(t, entity.GroupRoleAdmin, group.Role)
+	assert.Equal(t, 3, group.MemberCount)
+	assert.Equal(t, entity.GroupRoleAdmin, repo.members[group.ID][0].Role)
+
+	_, err = uc.CreateGroup(ctx, 1, " ", nil)
+	assert.ErrorIs(t, err, ErrInvalidGroupTitle)
+
+	tooMany := make([]int64, MaxGroupMembers)
+	for i := range tooMany {
+		tooMany[i] = int64(i + 2)
+	}
+	_, err = uc.CreateGroup(ctx, 1, "crowd", tooMany)
+	assert.ErrorIs(t, err, ErrTooManyGroupMembers)
+}
+
+func TestGroupUseCase_AdminOnly(t *testing.T) {
+	ctx := context.Background()
+	uc, _, _ := newGroupUseCase()
+
+	group, err := uc.CreateGroup(ctx, 1, "team", []int64{2})
+	require.NoError(t, err)
+
+	_, err = uc.AddMembers(ctx, 2, group.ID, []int64{3})
+	assert.ErrorIs(t, err, ErrNotGroupAdmin)
+	assert.ErrorIs(t, uc.RemoveMember(ctx, 2, group.ID, 1), ErrNotGroupAdmin)
+	assert.ErrorIs(t, uc.SetMemberRole(ctx, 2, group.ID, 2, entity.GroupRoleAdmin), ErrNotGroupAdmin)
+
+	// Не участнику группа не видна
+	_, err = uc.AddMembers(ctx, 3, group.ID, []int64{4})
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+
+	added, err := uc.AddMembers(ctx, 1, group.ID, []int64{2, 3})
+	require.NoError(t, err)
+	assert.Equal(t, []int64{3}, added)
+	assert.NoError(t, uc.RemoveMember(ctx, 1, group.ID, 3))
+}
+
+func TestGroupUseCase_LastAdmin(t *testing.T) {
+	ctx := context.Background()
+	uc, repo, _ := newGroupUseCase()
+
+	group, err := uc.CreateGroup(ctx, 1, "team", []int64{2, 3})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, uc.SetMemberRole(ctx, 1, group.ID, 1, entity.GroupRoleMember), ErrLastGroupAdmin)
+
+	// Фейк не откатывает роль при ошибке транзакции, уход проверяем на новой группе
+	group, err = uc.CreateGroup(ctx, 1, "team", []int64{2, 3})
+	require.NoError(t, err)
+
+	// Уход последнего администратора передает роль самому давнему участнику
+	require.NoError(t, uc.LeaveGroup(ctx, 1, group.ID))
+	members := repo.members[group.ID]
+	require.Len(t, members, 2)
+	assert.Equal(t, int64(2), members[0].UserID)
+	assert.Equal(t, entity.GroupRoleAdmin, members[0].Role)
+	assert.Equal(t, entity.GroupRoleMember, members[1].Role)
+
+	assert.ErrorIs(t, uc.LeaveGroup(ctx, 1, group.ID), ErrGroupNotFound)
+}
+
+func TestGroupUseCase_SendGroupMessage(t *testing.T) {
+	ctx := context.Background()
+	uc, _, outbox := newGroupUseCase()
+
+	group, err := uc.CreateGroup(ctx, 1, "team", []int64{2, 3})
+	require.NoError(t, err)
+
+	sent, err := uc.SendGroupMessage(ctx, 2, group.ID, "hi", "c-1")
+	require.NoError(t, err)
+	assert.False(t, sent.Duplicate)
+	require.Len(t, outbox.events, 1)
+	assert.Equal(t, entity.AggregateGroup, outbox.events[0].AggregateType)
+	assert.Contains(t, string(outbox.events[0].Payload), `"member_ids":[1,2,3]`)
+
+	// Повтор не создает новое сообщение и событие
+	again, err := uc.SendGroupMessage(ctx, 2, group.ID, "hi", "c-1")
+	require.NoError(t, err)
+	assert.True(t, again.Duplicate)
+	assert.Equal(t, sent.ID, again.ID)
+	assert.Len(t, outbox.events, 1)
+
+	_, err = uc.SendGroupMessage(ctx, 4, group.ID, "hi", "")
+	assert.ErrorIs(t, err, ErrGroupNotFound)
+}
@@ -0,0 +1,134 @@
+package user
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"otus-highload-arh-homework/internal/social/repository"
+)
+
+const (
+	defaultReconcileInterval  = 10 * time.Minute
+	defaultReconcileBatchSize = 500
+)
+
+type leaderLock interface {
+	TryAcquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// UnreadReconciler периодически пересчитывает счетчики непрочитанных по
+// messages.read_at: исправляет dialog_inbox и загруженные счетчики в Redis,
+// разошедшиеся после сбоев обновления счетчиков при отправке. Среди экземпляров
+// сервиса работает только держатель leader-блокировки
+type UnreadReconciler struct {
+	repo      repository.DialogRepository
+	unread    repository.UnreadCounterRepository
+	lock      leaderLock
+	interval  time.Duration
+	batchSize int
+}
+
+func NewUnreadReconciler(
+	repo repository.DialogRepository,
+	unread repository.UnreadCounterRepository,
+	lock leaderLock,
+	interval time.Duration,
+	batchSize int,
+) *UnreadReconciler {
+	if interval <= 0 {
+		interval = defaultReconcileInterval
+	}
+	if batchSize <= 0 {
+		batchSize = defaultReconcileBatchSize
+	}
+
+	return &UnreadReconciler{
+		repo:      repo,
+		unread:    unread,
+		lock:      lock,
+		interval:  interval,
+		batchSize: batchSize,
+	}
+}
+
+// Run сверяет счетчики раз в interval до отмены ctx
+func (r *UnreadReconciler) Run(ctx context.Context) {
+	defer func() {
+		if err := r.lock.Release(context.WithoutCancel(ctx)); err != nil {
+			log.Printf("Unread reconciler: %v", err)
+		}
+	}()
+
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		leader, err := r.lock.TryAcquire(ctx)
+		if err != nil {
+			log.Printf("Unread reconciler: %v", err)
+			continue
+		}
+		if !leader {
+			continue
+		}
+
+		users, err := r.Reconcile(ctx)
+		if err != nil {
+			if ctx.Err() == nil {
+				log.Printf("Unread reconciler: %v", err)
+			}
+			continue
+		}
+		log.Printf("Unread reconciler: checked %d users", users)
+	}
+}
+
+// Reconcile проходит по всем владельцам списков диалогов пачками и
+// возвращает число проверенных пользователей
+func (r *UnreadReconciler) Reconcile(ctx context.Context) (int, error) {
+	var (
+		after   int64
+		checked int
+	)
+	for {
+		users, err := r.repo.ListInboxUsers(ctx, after, r.batchSize)
+		if err != nil {
+			return checked, err
+		}
+
+		for _, userID := range users {
+			if err := r.reconcileUser(ctx, userID); err != nil {
+				return checked, err
+			}
+			checked++
+		}
+
+		if len(users) < r.batchSize {
+			return checked, nil
+		}
+		after = users[len(users)-1]
+	}
+}
+
+func (r *UnreadReconciler) reconcileUser(ctx context.Context, userID int64) error {
+	counters, err := r.repo.RecountUnread(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	// Незагруженные счетчики пересоберутся из dialog_inbox при чтении
+	_, _, found, err := r.unread.Get(ctx, userID)
+	if err != nil || !found {
+		return err
+	}
+
+	return r.unread.Set(ctx, userID, counters)
+}
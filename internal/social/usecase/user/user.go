@@ -40,3 +40,22 @@ func (uc *UserUseCase) Search(ctx context.Context, firstName, lastName string) (
 
 	return users, nil
 }
+
+// GetLastSeenVisibility возвращает настройки видимости присутствия пользователей
+func (uc *UserUseCase) GetLastSeenVisibility(ctx context.Context, ids []int) (map[int]entity.Visibility, error) {
+	return uc.repo.GetLastSeenVisibility(ctx, ids)
+}
+
+// SetLastSeenVisibility меняет, кому виден статус online и время визита
+func (uc *UserUseCase) SetLastSeenVisibility(ctx context.Context, userID int, visibility entity.Visibility) error {
+	if !visibility.IsValid() {
+		return ErrInvalidVisibility
+	}
+
+	err := uc.repo.SetLastSeenVisibility(ctx, userID, visibility)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return ErrUserNotFound
+	}
+
+	return err
+}
@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+-- События для публикации в Kafka, записываются в одной транзакции с изменением агрегата.
+-- Распределяется по aggregate_id (id пользователя) и колоцируется с users
+CREATE TABLE outbox (
+                        id BIGSERIAL,
+                        aggregate_type TEXT NOT NULL,
+                        aggregate_id BIGINT NOT NULL,
+                        event_type TEXT NOT NULL,
+                        payload JSONB NOT NULL,
+                        created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+                        sent_at TIMESTAMPTZ,
+                        attempts INT NOT NULL DEFAULT 0,
+                        last_error TEXT,
+                        PRIMARY KEY (id, aggregate_id)
+);
+
+-- Relay читает только неопубликованные события
+CREATE INDEX idx_outbox_pending ON outbox (id) WHERE sent_at IS NULL;
+CREATE INDEX idx_outbox_sent_at ON outbox (sent_at) WHERE sent_at IS NOT NULL;
+
+SELECT create_distributed_table('outbox', 'aggregate_id', colocate_with => 'users');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+-- +goose StatementEnd
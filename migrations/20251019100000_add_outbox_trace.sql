@@ -0,0 +1,12 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Контекст запроса, породившего событие, передаётся в конверт события
+ALTER TABLE outbox ADD COLUMN request_id TEXT;
+ALTER TABLE outbox ADD COLUMN traceparent TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE outbox DROP COLUMN IF EXISTS traceparent;
+ALTER TABLE outbox DROP COLUMN IF EXISTS request_id;
+-- +goose StatementEnd
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Кому показывать время последнего визита и статус online
+ALTER TABLE users ADD COLUMN last_seen_visibility TEXT NOT NULL DEFAULT 'everyone'
+    CHECK (last_seen_visibility IN ('everyone', 'friends', 'nobody'));
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users DROP COLUMN IF EXISTS last_seen_visibility;
+-- +goose StatementEnd
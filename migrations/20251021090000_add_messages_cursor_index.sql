@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Пагинация истории диалога по курсору message_id. Индекс создается на
+-- каждом шарде messages, ведущая колонка совпадает с ключом распределения
+CREATE INDEX idx_messages_dialog_cursor ON messages (sender_id, recipient_id, message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_dialog_cursor;
+-- +goose StatementEnd
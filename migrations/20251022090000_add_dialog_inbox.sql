@@ -0,0 +1,50 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Список диалогов пользователя. dialogs распределена по user1_id, поэтому
+-- для второго участника выборка его диалогов шла бы по всем шардам.
+-- Инбокс хранит по строке на каждого участника и распределен по владельцу,
+-- так что список диалогов читается с одного шарда
+CREATE TABLE dialog_inbox (
+    user_id BIGINT NOT NULL,
+    peer_id BIGINT NOT NULL,
+    dialog_id BIGINT NOT NULL,
+    last_message_id BIGINT NOT NULL,
+    last_sender_id BIGINT NOT NULL,
+    last_message_text TEXT NOT NULL,
+    last_message_at TIMESTAMPTZ NOT NULL,
+    unread_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, peer_id)
+);
+
+CREATE INDEX idx_dialog_inbox_activity ON dialog_inbox (user_id, last_message_id DESC);
+
+SELECT create_distributed_table('dialog_inbox', 'user_id', colocate_with => 'users');
+
+-- Заполняем инбокс по уже отправленным сообщениям
+INSERT INTO dialog_inbox (user_id, peer_id, dialog_id, last_message_id, last_sender_id,
+                          last_message_text, last_message_at, unread_count)
+SELECT last.user_id, last.peer_id, last.dialog_id, last.message_id, last.sender_id,
+       left(last.content, 100), last.created_at, COALESCE(unread.cnt, 0)
+FROM (
+    SELECT DISTINCT ON (user_id, peer_id) *
+    FROM (
+        SELECT sender_id AS user_id, recipient_id AS peer_id, dialog_id, message_id, sender_id, content, created_at
+        FROM messages
+        UNION ALL
+        SELECT recipient_id AS user_id, sender_id AS peer_id, dialog_id, message_id, sender_id, content, created_at
+        FROM messages
+    ) AS sides
+    ORDER BY user_id, peer_id, message_id DESC
+) AS last
+LEFT JOIN (
+    SELECT recipient_id AS user_id, sender_id AS peer_id, count(*) AS cnt
+    FROM messages
+    WHERE read_at IS NULL
+    GROUP BY recipient_id, sender_id
+) AS unread ON unread.user_id = last.user_id AND unread.peer_id = last.peer_id;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dialog_inbox;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- edited_at - время последнего редактирования, deleted_at - удаление для
+-- всех (текст стирается, строка остается надгробием в истории),
+-- hidden_for - участники, удалившие сообщение только у себя
+ALTER TABLE messages ADD COLUMN edited_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN deleted_at TIMESTAMPTZ;
+ALTER TABLE messages ADD COLUMN hidden_for BIGINT[] NOT NULL DEFAULT '{}';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE messages DROP COLUMN IF EXISTS hidden_for;
+ALTER TABLE messages DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE messages DROP COLUMN IF EXISTS edited_at;
+-- +goose StatementEnd
@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- client_message_id - идентификатор, сгенерированный клиентом для повторов
+-- отправки. Уникален в пределах отправителя: индекс включает sender_id,
+-- колонку распределения messages, поэтому Citus проверяет его внутри шарда
+ALTER TABLE messages ADD COLUMN client_message_id TEXT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_messages_sender_client_id
+    ON messages (sender_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_sender_client_id;
+ALTER TABLE messages DROP COLUMN IF EXISTS client_message_id;
+-- +goose StatementEnd
@@ -0,0 +1,61 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Групповые чаты. Группа, её участники и сообщения распределены по group_id
+-- и лежат на одном шарде: отправка, история и проверка членства не выходят
+-- за пределы шарда группы. user_groups - обратный индекс членства,
+-- распределенный по user_id, чтобы список групп пользователя читался с
+-- одного шарда
+CREATE TABLE group_chats (
+    group_id BIGSERIAL NOT NULL,
+    title TEXT NOT NULL,
+    created_by BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id)
+);
+
+SELECT create_distributed_table('group_chats', 'group_id', colocate_with => 'none');
+
+CREATE TABLE group_members (
+    group_id BIGINT NOT NULL,
+    user_id BIGINT NOT NULL,
+    role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('admin', 'member')),
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, user_id)
+);
+
+SELECT create_distributed_table('group_members', 'group_id', colocate_with => 'group_chats');
+
+CREATE TABLE group_messages (
+    group_id BIGINT NOT NULL,
+    message_id BIGSERIAL NOT NULL,
+    sender_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    client_message_id TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (group_id, message_id)
+);
+
+CREATE UNIQUE INDEX idx_group_messages_client_id
+    ON group_messages (group_id, sender_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
+
+SELECT create_distributed_table('group_messages', 'group_id', colocate_with => 'group_chats');
+
+-- Внешний ключ на users возможен: таблицы колоцированы по user_id
+CREATE TABLE user_groups (
+    user_id BIGINT NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    group_id BIGINT NOT NULL,
+    joined_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    PRIMARY KEY (user_id, group_id)
+);
+
+SELECT create_distributed_table('user_groups', 'user_id', colocate_with => 'users');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS user_groups;
+DROP TABLE IF EXISTS group_messages;
+DROP TABLE IF EXISTS group_members;
+DROP TABLE IF EXISTS group_chats;
+-- +goose StatementEnd
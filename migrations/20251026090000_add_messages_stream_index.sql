@@ -0,0 +1,13 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Поток сообщений пользователя читает по курсору message_id его исходящие
+-- сообщения на шарде пользователя и входящие на всех шардах
+CREATE INDEX idx_messages_sender_cursor ON messages (sender_id, message_id);
+CREATE INDEX idx_messages_recipient_cursor ON messages (recipient_id, message_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_messages_recipient_cursor;
+DROP INDEX IF EXISTS idx_messages_sender_cursor;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Событие, не опубликованное за OUTBOX_MAX_ATTEMPTS попыток, помечается
+-- failed_at и больше не читается relay. Причина остается в last_error
+ALTER TABLE outbox ADD COLUMN failed_at TIMESTAMPTZ;
+
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox (id) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX idx_outbox_failed_at ON outbox (failed_at) WHERE failed_at IS NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_outbox_failed_at;
+DROP INDEX IF EXISTS idx_outbox_pending;
+CREATE INDEX idx_outbox_pending ON outbox (id) WHERE sent_at IS NULL;
+
+ALTER TABLE outbox DROP COLUMN IF EXISTS failed_at;
+-- +goose StatementEnd
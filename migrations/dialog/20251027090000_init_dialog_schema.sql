@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Схема выделенной базы сервиса диалогов. Повторяет таблицы диалогов общей
+-- базы, чтобы dialog-migrate переносил строки без преобразований. Таблицы
+-- колоцируются с dialogs вместо users: пользователей в этой базе нет
+CREATE TABLE dialogs (
+    dialog_id BIGSERIAL,
+    user1_id BIGINT NOT NULL,
+    user2_id BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (dialog_id, user1_id),
+    CONSTRAINT user_order CHECK (user1_id < user2_id)
+);
+
+CREATE INDEX idx_dialogs_user_pair ON dialogs (user1_id, user2_id);
+
+SELECT create_distributed_table('dialogs', 'user1_id');
+
+CREATE TABLE messages (
+    message_id BIGSERIAL,
+    dialog_id BIGINT NOT NULL,
+    sender_id BIGINT NOT NULL,
+    recipient_id BIGINT NOT NULL,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    read_at TIMESTAMPTZ,
+    edited_at TIMESTAMPTZ,
+    deleted_at TIMESTAMPTZ,
+    hidden_for BIGINT[] NOT NULL DEFAULT '{}',
+    client_message_id TEXT,
+    PRIMARY KEY (message_id, sender_id)
+);
+
+CREATE UNIQUE INDEX idx_messages_sender_client_id
+    ON messages (sender_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
+CREATE INDEX idx_messages_dialog_cursor ON messages (sender_id, recipient_id, message_id);
+CREATE INDEX idx_messages_sender_cursor ON messages (sender_id, message_id);
+CREATE INDEX idx_messages_recipient_cursor ON messages (recipient_id, message_id);
+
+SELECT create_distributed_table('messages', 'sender_id', colocate_with => 'dialogs');
+
+CREATE TABLE dialog_inbox (
+    user_id BIGINT NOT NULL,
+    peer_id BIGINT NOT NULL,
+    dialog_id BIGINT NOT NULL,
+    last_message_id BIGINT NOT NULL,
+    last_sender_id BIGINT NOT NULL,
+    last_message_text TEXT NOT NULL,
+    last_message_at TIMESTAMPTZ NOT NULL,
+    unread_count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (user_id, peer_id)
+);
+
+CREATE INDEX idx_dialog_inbox_activity ON dialog_inbox (user_id, last_message_id DESC);
+
+SELECT create_distributed_table('dialog_inbox', 'user_id', colocate_with => 'dialogs');
+
+-- События диалогов пишутся в одной транзакции с сообщениями, поэтому
+-- outbox у выделенной базы свой, его читает отдельный экземпляр relay
+CREATE TABLE outbox (
+    id BIGSERIAL,
+    aggregate_type TEXT NOT NULL,
+    aggregate_id BIGINT NOT NULL,
+    event_type TEXT NOT NULL,
+    payload JSONB NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    sent_at TIMESTAMPTZ,
+    attempts INT NOT NULL DEFAULT 0,
+    last_error TEXT,
+    request_id TEXT,
+    traceparent TEXT,
+    failed_at TIMESTAMPTZ,
+    PRIMARY KEY (id, aggregate_id)
+);
+
+CREATE INDEX idx_outbox_pending ON outbox (id) WHERE sent_at IS NULL AND failed_at IS NULL;
+CREATE INDEX idx_outbox_sent_at ON outbox (sent_at) WHERE sent_at IS NOT NULL;
+CREATE INDEX idx_outbox_failed_at ON outbox (failed_at) WHERE failed_at IS NOT NULL;
+
+SELECT create_distributed_table('outbox', 'aggregate_id', colocate_with => 'dialogs');
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS outbox;
+DROP TABLE IF EXISTS dialog_inbox;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS dialogs;
+-- +goose StatementEnd
@@ -0,0 +1,18 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Позиция dialog-migrate по каждой таблице: ключ последней перенесенной
+-- строки. Обновляется в одной транзакции с пачкой, поэтому перенос
+-- продолжается с места остановки. Локальная таблица координатора
+CREATE TABLE dialog_migrate_progress (
+    table_name TEXT PRIMARY KEY,
+    last_key1 BIGINT NOT NULL,
+    last_key2 BIGINT NOT NULL,
+    rows_copied BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE IF EXISTS dialog_migrate_progress;
+-- +goose StatementEnd
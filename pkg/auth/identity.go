@@ -0,0 +1,28 @@
+package auth
+
+import "context"
+
+// Метаданные исходящих вызовов с учетными данными пользователя
+const (
+	AuthorizationMetadata = "authorization"
+	UserIDMetadata        = "x-user-id"
+)
+
+// Identity - проверенный пользователь запроса и его токен для передачи в
+// исходящие вызовы
+type Identity struct {
+	UserID int
+	Token  string
+}
+
+type identityKey struct{}
+
+func WithIdentity(ctx context.Context, identity Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext возвращает пользователя запроса, если он проверен
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(Identity)
+	return identity, ok
+}
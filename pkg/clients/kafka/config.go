@@ -1,6 +1,10 @@
 package kafka
 
 type Config struct {
-	Address          string `env:"KAFKA_ADDRESS" env-default:"localhost:9092"`
-	FeedUpdatesTopic string `env:"KAFKA_FEED_UPDATES_TOPIC" env-default:"feed_updates"`
+	Address              string `env:"KAFKA_ADDRESS" env-default:"localhost:9092"`
+	FeedUpdatesTopic     string `env:"KAFKA_FEED_UPDATES_TOPIC" env-default:"feed_updates"`
+	FeedUpdatesDLQTopic  string `env:"KAFKA_FEED_UPDATES_DLQ_TOPIC" env-default:"feed_updates_dlq"`
+	FriendshipTopic      string `env:"KAFKA_FRIENDSHIP_TOPIC" env-default:"friendship_events"`
+	DialogEventsTopic    string `env:"KAFKA_DIALOG_EVENTS_TOPIC" env-default:"dialog_events"`
+	DialogEventsDLQTopic string `env:"KAFKA_DIALOG_EVENTS_DLQ_TOPIC" env-default:"dialog_events_dlq"`
 }
@@ -0,0 +1,64 @@
+package kafka
+
+import (
+	"context"
+
+	"github.com/segmentio/kafka-go"
+)
+
+// Consumer читает сообщения в составе группы с ручным подтверждением:
+// смещение фиксируется только после Commit
+type Consumer struct {
+	reader *kafka.Reader
+}
+
+func NewConsumer(brokers []string, topic, groupID string) *Consumer {
+	return &Consumer{
+		reader: kafka.NewReader(kafka.ReaderConfig{
+			Brokers:  brokers,
+			GroupID:  groupID,
+			Topic:    topic,
+			MinBytes: 1,
+			MaxBytes: 10e6, // 10MB
+		}),
+	}
+}
+
+// Fetch блокируется до получения сообщения или отмены ctx
+func (c *Consumer) Fetch(ctx context.Context) (kafka.Message, error) {
+	return c.reader.FetchMessage(ctx)
+}
+
+func (c *Consumer) Commit(ctx context.Context, msgs ...kafka.Message) error {
+	return c.reader.CommitMessages(ctx, msgs...)
+}
+
+func (c *Consumer) Close() error {
+	return c.reader.Close()
+}
+
+// Header возвращает значение заголовка сообщения или пустую строку
+func Header(msg kafka.Message, key string) string {
+	for _, h := range msg.Headers {
+		if h.Key == key {
+			return string(h.Value)
+		}
+	}
+
+	return ""
+}
+
+// Headers возвращает заголовки сообщения в виде map
+func Headers(msg kafka.Message) map[string]string {
+	headers := make(map[string]string, len(msg.Headers))
+	for _, h := range msg.Headers {
+		headers[h.Key] = string(h.Value)
+	}
+
+	return headers
+}
+
+// Lag возвращает отставание читателя от конца партиции
+func (c *Consumer) Lag() int64 {
+	return c.reader.Stats().Lag
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"log"
+	"time"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -15,26 +16,42 @@ type Producer struct {
 func NewProducer(brokers []string, topic string) *Producer {
 	return &Producer{
 		writer: &kafka.Writer{
-			Addr:     kafka.TCP(brokers...),
-			Topic:    topic,
-			Balancer: &kafka.LeastBytes{},
+			Addr:  kafka.TCP(brokers...),
+			Topic: topic,
+			// Сообщения с одним ключом попадают в одну партицию и сохраняют порядок
+			Balancer: &kafka.Hash{},
+			// WriteMessages синхронный: не ждём накопления пачки дольше необходимого
+			BatchTimeout: 10 * time.Millisecond,
+			RequiredAcks: kafka.RequireAll,
 		},
 	}
 }
 
 func (p *Producer) Publish(ctx context.Context, key string, value interface{}) error {
+	return p.PublishWithHeaders(ctx, key, value, nil)
+}
+
+// PublishWithHeaders публикует JSON сообщение с заголовками
+func (p *Producer) PublishWithHeaders(ctx context.Context, key string, value interface{}, headers map[string]string) error {
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
 		return err
 	}
 
-	err = p.writer.WriteMessages(ctx,
-		kafka.Message{
-			Key:   []byte(key),
-			Value: jsonValue,
-		},
-	)
-	if err != nil {
+	return p.PublishRaw(ctx, key, jsonValue, headers)
+}
+
+// PublishRaw публикует уже сериализованное значение
+func (p *Producer) PublishRaw(ctx context.Context, key string, value []byte, headers map[string]string) error {
+	msg := kafka.Message{
+		Key:   []byte(key),
+		Value: value,
+	}
+	for k, v := range headers {
+		msg.Headers = append(msg.Headers, kafka.Header{Key: k, Value: []byte(v)})
+	}
+
+	if err := p.writer.WriteMessages(ctx, msg); err != nil {
 		log.Printf("failed to write message to kafka: %v", err)
 		return err
 	}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	HeaderContentType = "content-type"
+	HeaderEventID     = "event-id"
+	HeaderEventType   = "event-type"
+	HeaderVersion     = "event-version"
+
+	ContentTypeProtobuf = "application/x-protobuf"
+	ContentTypeJSON     = "application/json"
+)
+
+// Marshal сериализует конверт в contentType (protobuf или JSON) и возвращает
+// значение сообщения и заголовки. Заголовки дублируют метаданные конверта,
+// чтобы их можно было фильтровать без разбора значения
+func Marshal(env *eventsv1.Envelope, contentType string) ([]byte, map[string]string, error) {
+	var (
+		value []byte
+		err   error
+	)
+
+	switch contentType {
+	case ContentTypeProtobuf, "":
+		contentType = ContentTypeProtobuf
+		value, err = proto.Marshal(env)
+	case ContentTypeJSON:
+		value, err = protojson.Marshal(env)
+	default:
+		return nil, nil, fmt.Errorf("unsupported content type %q", contentType)
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to marshal envelope: %w", err)
+	}
+
+	return value, map[string]string{
+		HeaderContentType: contentType,
+		HeaderEventID:     env.GetEventId(),
+		HeaderEventType:   env.GetType(),
+		HeaderVersion:     env.GetVersion(),
+	}, nil
+}
+
+// Unmarshal разбирает сообщение и проверяет конверт через Validate.
+// Сообщение без content-type разбирается как устаревший JSON формат
+func Unmarshal(value []byte, headers map[string]string) (*eventsv1.Envelope, error) {
+	env := &eventsv1.Envelope{}
+
+	switch contentType := headers[HeaderContentType]; contentType {
+	case ContentTypeProtobuf:
+		if err := proto.Unmarshal(value, env); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	case ContentTypeJSON:
+		if err := protojson.Unmarshal(value, env); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMalformed, err)
+		}
+	case "":
+		var err error
+		if env, err = fromLegacy(value, headers); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: content type %q", ErrMalformed, contentType)
+	}
+
+	if err := Validate(env); err != nil {
+		return nil, err
+	}
+
+	return env, nil
+}
+
+// legacyFeedUpdate - формат событий feed_updates до введения конверта
+type legacyFeedUpdate struct {
+	PostID    string `json:"post_id"`
+	AuthorID  int64  `json:"author_id"`
+	Action    string `json:"action"`
+	Text      string `json:"text,omitempty"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+var legacyPostActions = map[string]struct {
+	eventType string
+	action    eventsv1.PostAction
+}{
+	"create": {TypePostCreated, eventsv1.PostAction_POST_ACTION_CREATED},
+	"update": {TypePostUpdated, eventsv1.PostAction_POST_ACTION_UPDATED},
+	"delete": {TypePostDeleted, eventsv1.PostAction_POST_ACTION_DELETED},
+}
+
+func fromLegacy(value []byte, headers map[string]string) (*eventsv1.Envelope, error) {
+	var legacy legacyFeedUpdate
+	if err := json.Unmarshal(value, &legacy); err != nil {
+		return nil, fmt.Errorf("%w: legacy json: %v", ErrMalformed, err)
+	}
+
+	action, ok := legacyPostActions[legacy.Action]
+	if !ok || legacy.PostID == "" {
+		return nil, fmt.Errorf("%w: legacy action %q", ErrUnknownType, legacy.Action)
+	}
+
+	return New(headers[HeaderEventID], action.eventType, time.Unix(legacy.Timestamp, 0), nil, &eventsv1.PostEvent{
+		PostId:   legacy.PostID,
+		AuthorId: legacy.AuthorID,
+		Action:   action.action,
+		Text:     legacy.Text,
+	})
+}
@@ -0,0 +1,79 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestCodec_RoundTrip(t *testing.T) {
+	env, err := New("42", TypePostCreated, time.Unix(1700000000, 0), &eventsv1.TraceContext{RequestId: "req-1"},
+		&eventsv1.PostEvent{PostId: "p1", AuthorId: 7, Action: eventsv1.PostAction_POST_ACTION_CREATED, Text: "hi"})
+	require.NoError(t, err)
+
+	for _, contentType := range []string{ContentTypeProtobuf, ContentTypeJSON} {
+		t.Run(contentType, func(t *testing.T) {
+			value, headers, err := Marshal(env, contentType)
+			require.NoError(t, err)
+			assert.Equal(t, contentType, headers[HeaderContentType])
+			assert.Equal(t, TypePostCreated, headers[HeaderEventType])
+
+			decoded, err := Unmarshal(value, headers)
+			require.NoError(t, err)
+			assert.True(t, proto.Equal(env, decoded))
+
+			payload, err := Payload(decoded)
+			require.NoError(t, err)
+			assert.Equal(t, "p1", payload.(*eventsv1.PostEvent).GetPostId())
+		})
+	}
+}
+
+func TestCodec_LegacyJSON(t *testing.T) {
+	value := []byte(`{"post_id":"p1","author_id":7,"action":"update","text":"edited","timestamp":1700000000}`)
+
+	env, err := Unmarshal(value, nil)
+	require.NoError(t, err)
+	assert.Equal(t, TypePostUpdated, env.GetType())
+	assert.Equal(t, int64(1700000000), env.GetOccurredAt().GetSeconds())
+
+	payload, err := Payload(env)
+	require.NoError(t, err)
+	assert.Equal(t, eventsv1.PostAction_POST_ACTION_UPDATED, payload.(*eventsv1.PostEvent).GetAction())
+}
+
+func TestCodec_Rejects(t *testing.T) {
+	payload, err := proto.Marshal(&eventsv1.PostEvent{PostId: "p1"})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name string
+		env  *eventsv1.Envelope
+		err  error
+	}{
+		{"unknown major version", &eventsv1.Envelope{Type: TypePostCreated, Version: "2.0", Payload: payload}, ErrUnsupportedVersion},
+		{"invalid version", &eventsv1.Envelope{Type: TypePostCreated, Version: "", Payload: payload}, ErrUnsupportedVersion},
+		{"unknown type", &eventsv1.Envelope{Type: "post.liked", Version: "1.0", Payload: payload}, ErrUnknownType},
+		{"malformed payload", &eventsv1.Envelope{Type: TypePostCreated, Version: "1.3", Payload: []byte{0xff}}, ErrMalformed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, headers, err := Marshal(tt.env, ContentTypeProtobuf)
+			require.NoError(t, err)
+
+			_, err = Unmarshal(value, headers)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	t.Run("payload type mismatch", func(t *testing.T) {
+		_, err := New("1", TypePostCreated, time.Now(), nil, &eventsv1.FriendshipEvent{})
+		assert.ErrorIs(t, err, ErrMalformed)
+	})
+}
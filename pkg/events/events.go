@@ -0,0 +1,123 @@
+// Package events - общий для продюсеров и консьюмеров кодек событий Kafka.
+// Событие передаётся в конверте eventsv1.Envelope, формат значения задаётся
+// заголовком content-type. Сообщения без заголовка считаются устаревшим JSON
+// форматом и конвертируются в конверт на время миграции
+package events
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	eventsv1 "otus-highload-arh-homework/pkg/proto/events/v1"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	TypePostCreated       = "post.created"
+	TypePostUpdated       = "post.updated"
+	TypePostDeleted       = "post.deleted"
+	TypeFriendshipAdded   = "friendship.added"
+	TypeFriendshipRemoved = "friendship.removed"
+	TypeMessageCreated    = "message.created"
+	TypeMessageRead       = "message.read"
+	TypeUnreadChanged     = "unread.changed"
+	TypeMessageEdited     = "message.edited"
+	TypeMessageDeleted    = "message.deleted"
+
+	TypeGroupMessageCreated = "group.message.created"
+)
+
+// Version - текущая версия схем событий. Консьюмер принимает события
+// той же мажорной версии с любой минорной
+const Version = "1.0"
+
+var (
+	ErrUnsupportedVersion = errors.New("unsupported event version")
+	ErrUnknownType        = errors.New("unknown event type")
+	ErrMalformed          = errors.New("malformed event")
+)
+
+// registry сопоставляет тип события с сообщением payload
+var registry = map[string]func() proto.Message{
+	TypePostCreated:       func() proto.Message { return &eventsv1.PostEvent{} },
+	TypePostUpdated:       func() proto.Message { return &eventsv1.PostEvent{} },
+	TypePostDeleted:       func() proto.Message { return &eventsv1.PostEvent{} },
+	TypeFriendshipAdded:   func() proto.Message { return &eventsv1.FriendshipEvent{} },
+	TypeFriendshipRemoved: func() proto.Message { return &eventsv1.FriendshipEvent{} },
+	TypeMessageCreated:    func() proto.Message { return &eventsv1.DialogMessageEvent{} },
+	TypeMessageRead:       func() proto.Message { return &eventsv1.DialogReadEvent{} },
+	TypeUnreadChanged:     func() proto.Message { return &eventsv1.UnreadChangedEvent{} },
+	TypeMessageEdited:     func() proto.Message { return &eventsv1.DialogMessageEditedEvent{} },
+	TypeMessageDeleted:    func() proto.Message { return &eventsv1.DialogMessageDeletedEvent{} },
+
+	TypeGroupMessageCreated: func() proto.Message { return &eventsv1.GroupMessageEvent{} },
+}
+
+// New упаковывает payload в конверт текущей версии
+func New(id, eventType string, occurredAt time.Time, trace *eventsv1.TraceContext, payload proto.Message) (*eventsv1.Envelope, error) {
+	newPayload, ok := registry[eventType]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, eventType)
+	}
+	if want := newPayload().ProtoReflect().Descriptor().FullName(); payload.ProtoReflect().Descriptor().FullName() != want {
+		return nil, fmt.Errorf("%w: %s expects %s payload", ErrMalformed, eventType, want)
+	}
+
+	data, err := proto.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s payload: %w", eventType, err)
+	}
+
+	return &eventsv1.Envelope{
+		EventId:    id,
+		Type:       eventType,
+		Version:    Version,
+		OccurredAt: timestamppb.New(occurredAt),
+		Trace:      trace,
+		Payload:    data,
+	}, nil
+}
+
+// Validate проверяет версию, тип и схему payload конверта
+func Validate(env *eventsv1.Envelope) error {
+	_, err := Payload(env)
+	return err
+}
+
+// Payload разбирает payload конверта в сообщение, зарегистрированное для его типа
+func Payload(env *eventsv1.Envelope) (proto.Message, error) {
+	major, err := majorVersion(env.GetVersion())
+	if err != nil {
+		return nil, err
+	}
+	if current, _ := majorVersion(Version); major != current {
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedVersion, env.GetVersion())
+	}
+
+	newPayload, ok := registry[env.GetType()]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownType, env.GetType())
+	}
+
+	payload := newPayload()
+	if err := proto.Unmarshal(env.GetPayload(), payload); err != nil {
+		return nil, fmt.Errorf("%w: %s payload: %v", ErrMalformed, env.GetType(), err)
+	}
+
+	return payload, nil
+}
+
+func majorVersion(version string) (int, error) {
+	majorStr, _, _ := strings.Cut(version, ".")
+	major, err := strconv.Atoi(majorStr)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrUnsupportedVersion, version)
+	}
+
+	return major, nil
+}
@@ -22,13 +22,65 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type GroupRole int32
+
+const (
+	GroupRole_GROUP_ROLE_UNSPECIFIED GroupRole = 0
+	GroupRole_GROUP_ROLE_ADMIN       GroupRole = 1
+	GroupRole_GROUP_ROLE_MEMBER      GroupRole = 2
+)
+
+// Enum value maps for GroupRole.
+var (
+	GroupRole_name = map[int32]string{
+		0: "GROUP_ROLE_UNSPECIFIED",
+		1: "GROUP_ROLE_ADMIN",
+		2: "GROUP_ROLE_MEMBER",
+	}
+	GroupRole_value = map[string]int32{
+		"GROUP_ROLE_UNSPECIFIED": 0,
+		"GROUP_ROLE_ADMIN":       1,
+		"GROUP_ROLE_MEMBER":      2,
+	}
+)
+
+func (x GroupRole) Enum() *GroupRole {
+	p := new(GroupRole)
+	*p = x
+	return p
+}
+
+func (x GroupRole) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (GroupRole) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_dialog_v1_dialog_proto_enumTypes[0].Descriptor()
+}
+
+func (GroupRole) Type() protoreflect.EnumType {
+	return &file_pkg_proto_dialog_v1_dialog_proto_enumTypes[0]
+}
+
+func (x GroupRole) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use GroupRole.Descriptor instead.
+func (GroupRole) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{0}
+}
+
 type SendMessageRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	SenderId      string                 `protobuf:"bytes,1,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
-	ReceiverId    string                 `protobuf:"bytes,2,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
-	Text          string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
-	unknownFields protoimpl.UnknownFields
-	sizeCache     protoimpl.SizeCache
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	SenderId   string                 `protobuf:"bytes,1,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	ReceiverId string                 `protobuf:"bytes,2,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
+	Text       string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	// Необязательный идентификатор от клиента, до 64 символов. Повтор с тем же
+	// client_message_id от того же отправителя не создает новое сообщение
+	ClientMessageId string `protobuf:"bytes,4,opt,name=client_message_id,json=clientMessageId,proto3" json:"client_message_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
 }
 
 func (x *SendMessageRequest) Reset() {
@@ -82,11 +134,20 @@ func (x *SendMessageRequest) GetText() string {
 	return ""
 }
 
+func (x *SendMessageRequest) GetClientMessageId() string {
+	if x != nil {
+		return x.ClientMessageId
+	}
+	return ""
+}
+
 type SendMessageResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Success       bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
-	MessageId     string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	SentAt        *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Success   bool                   `protobuf:"varint,1,opt,name=success,proto3" json:"success,omitempty"`
+	MessageId string                 `protobuf:"bytes,2,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SentAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	// Сообщение с этим client_message_id уже было отправлено
+	Duplicate     bool `protobuf:"varint,4,opt,name=duplicate,proto3" json:"duplicate,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -142,10 +203,24 @@ func (x *SendMessageResponse) GetSentAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *SendMessageResponse) GetDuplicate() bool {
+	if x != nil {
+		return x.Duplicate
+	}
+	return false
+}
+
+// Без курсоров возвращается последняя страница от новых сообщений к старым.
+// before листает к более старым сообщениям (по убыванию), after - к более
+// новым (по возрастанию). Курсоры - message_id, указывать можно только один
 type GetMessagesRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
-	OtherUserId   string                 `protobuf:"bytes,2,opt,name=other_user_id,json=otherUserId,proto3" json:"other_user_id,omitempty"`
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	UserId      string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	OtherUserId string                 `protobuf:"bytes,2,opt,name=other_user_id,json=otherUserId,proto3" json:"other_user_id,omitempty"`
+	Before      string                 `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	After       string                 `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`
+	// По умолчанию 50, не больше 100
+	Limit         int32 `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -194,20 +269,99 @@ func (x *GetMessagesRequest) GetOtherUserId() string {
 	return ""
 }
 
-type DialogMessage struct {
+func (x *GetMessagesRequest) GetBefore() string {
+	if x != nil {
+		return x.Before
+	}
+	return ""
+}
+
+func (x *GetMessagesRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+func (x *GetMessagesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+// Пустой since_cursor - без догрузки, только сообщения после подписки.
+// Курсор - message_id последнего полученного сообщения
+type StreamMessagesRequest struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
-	SenderId      string                 `protobuf:"bytes,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
-	ReceiverId    string                 `protobuf:"bytes,3,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
-	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
-	SentAt        *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	SinceCursor   string                 `protobuf:"bytes,2,opt,name=since_cursor,json=sinceCursor,proto3" json:"since_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StreamMessagesRequest) Reset() {
+	*x = StreamMessagesRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StreamMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StreamMessagesRequest) ProtoMessage() {}
+
+func (x *StreamMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StreamMessagesRequest.ProtoReflect.Descriptor instead.
+func (*StreamMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *StreamMessagesRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *StreamMessagesRequest) GetSinceCursor() string {
+	if x != nil {
+		return x.SinceCursor
+	}
+	return ""
+}
+
+type DialogMessage struct {
+	state      protoimpl.MessageState `protogen:"open.v1"`
+	MessageId  string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderId   string                 `protobuf:"bytes,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	ReceiverId string                 `protobuf:"bytes,3,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
+	Text       string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	SentAt     *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	// Время последнего редактирования, не задано у неотредактированных
+	EditedAt *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=edited_at,json=editedAt,proto3" json:"edited_at,omitempty"`
+	// Сообщение удалено для всех, text пустой
+	Deleted       bool `protobuf:"varint,7,opt,name=deleted,proto3" json:"deleted,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *DialogMessage) Reset() {
 	*x = DialogMessage{}
-	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[3]
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -219,7 +373,7 @@ func (x *DialogMessage) String() string {
 func (*DialogMessage) ProtoMessage() {}
 
 func (x *DialogMessage) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[3]
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -232,7 +386,7 @@ func (x *DialogMessage) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use DialogMessage.ProtoReflect.Descriptor instead.
 func (*DialogMessage) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{3}
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{4}
 }
 
 func (x *DialogMessage) GetMessageId() string {
@@ -270,16 +424,32 @@ func (x *DialogMessage) GetSentAt() *timestamppb.Timestamp {
 	return nil
 }
 
+func (x *DialogMessage) GetEditedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.EditedAt
+	}
+	return nil
+}
+
+func (x *DialogMessage) GetDeleted() bool {
+	if x != nil {
+		return x.Deleted
+	}
+	return false
+}
+
 type GetMessagesResponse struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Messages      []*DialogMessage       `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	Messages []*DialogMessage       `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	// Курсор следующей страницы в том же направлении, пустой на последней странице
+	NextCursor    string `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
 func (x *GetMessagesResponse) Reset() {
 	*x = GetMessagesResponse{}
-	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[4]
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
@@ -291,7 +461,7 @@ func (x *GetMessagesResponse) String() string {
 func (*GetMessagesResponse) ProtoMessage() {}
 
 func (x *GetMessagesResponse) ProtoReflect() protoreflect.Message {
-	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[4]
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -304,7 +474,7 @@ func (x *GetMessagesResponse) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use GetMessagesResponse.ProtoReflect.Descriptor instead.
 func (*GetMessagesResponse) Descriptor() ([]byte, []int) {
-	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{4}
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{5}
 }
 
 func (x *GetMessagesResponse) GetMessages() []*DialogMessage {
@@ -314,37 +484,1941 @@ func (x *GetMessagesResponse) GetMessages() []*DialogMessage {
 	return nil
 }
 
-var File_pkg_proto_dialog_v1_dialog_proto protoreflect.FileDescriptor
+func (x *GetMessagesResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
 
-const file_pkg_proto_dialog_v1_dialog_proto_rawDesc = "" +
-	"\n" +
-	" pkg/proto/dialog/v1/dialog.proto\x12\tdialog.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"f\n" +
-	"\x12SendMessageRequest\x12\x1b\n" +
-	"\tsender_id\x18\x01 \x01(\tR\bsenderId\x12\x1f\n" +
-	"\vreceiver_id\x18\x02 \x01(\tR\n" +
-	"receiverId\x12\x12\n" +
-	"\x04text\x18\x03 \x01(\tR\x04text\"\x83\x01\n" +
-	"\x13SendMessageResponse\x12\x18\n" +
-	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
-	"\n" +
-	"message_id\x18\x02 \x01(\tR\tmessageId\x123\n" +
-	"\asent_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\"Q\n" +
-	"\x12GetMessagesRequest\x12\x17\n" +
-	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\"\n" +
-	"\rother_user_id\x18\x02 \x01(\tR\votherUserId\"\xb5\x01\n" +
-	"\rDialogMessage\x12\x1d\n" +
-	"\n" +
-	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x1b\n" +
-	"\tsender_id\x18\x02 \x01(\tR\bsenderId\x12\x1f\n" +
-	"\vreceiver_id\x18\x03 \x01(\tR\n" +
-	"receiverId\x12\x12\n" +
-	"\x04text\x18\x04 \x01(\tR\x04text\x123\n" +
-	"\asent_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\"K\n" +
-	"\x13GetMessagesResponse\x124\n" +
-	"\bmessages\x18\x01 \x03(\v2\x18.dialog.v1.DialogMessageR\bmessages2\xab\x01\n" +
-	"\rDialogService\x12L\n" +
-	"\vSendMessage\x12\x1d.dialog.v1.SendMessageRequest\x1a\x1e.dialog.v1.SendMessageResponse\x12L\n" +
-	"\vGetMessages\x12\x1d.dialog.v1.GetMessagesRequest\x1a\x1e.dialog.v1.GetMessagesResponseB\x1fZ\x1dsocial/pkg/dialog/v1;dialogv1b\x06proto3"
+// Диалоги пользователя от недавних к давним. cursor - next_cursor предыдущей страницы
+type ListDialogsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Cursor string                 `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// По умолчанию 50, не больше 100
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDialogsRequest) Reset() {
+	*x = ListDialogsRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDialogsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDialogsRequest) ProtoMessage() {}
+
+func (x *ListDialogsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDialogsRequest.ProtoReflect.Descriptor instead.
+func (*ListDialogsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *ListDialogsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListDialogsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListDialogsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type DialogPreview struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	PeerId string                 `protobuf:"bytes,1,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	// Последнее сообщение, text обрезан до 100 символов
+	LastMessage   *DialogMessage `protobuf:"bytes,2,opt,name=last_message,json=lastMessage,proto3" json:"last_message,omitempty"`
+	UnreadCount   int32          `protobuf:"varint,3,opt,name=unread_count,json=unreadCount,proto3" json:"unread_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DialogPreview) Reset() {
+	*x = DialogPreview{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DialogPreview) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialogPreview) ProtoMessage() {}
+
+func (x *DialogPreview) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialogPreview.ProtoReflect.Descriptor instead.
+func (*DialogPreview) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *DialogPreview) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *DialogPreview) GetLastMessage() *DialogMessage {
+	if x != nil {
+		return x.LastMessage
+	}
+	return nil
+}
+
+func (x *DialogPreview) GetUnreadCount() int32 {
+	if x != nil {
+		return x.UnreadCount
+	}
+	return 0
+}
+
+type ListDialogsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Dialogs       []*DialogPreview       `protobuf:"bytes,1,rep,name=dialogs,proto3" json:"dialogs,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListDialogsResponse) Reset() {
+	*x = ListDialogsResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListDialogsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListDialogsResponse) ProtoMessage() {}
+
+func (x *ListDialogsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListDialogsResponse.ProtoReflect.Descriptor instead.
+func (*ListDialogsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ListDialogsResponse) GetDialogs() []*DialogPreview {
+	if x != nil {
+		return x.Dialogs
+	}
+	return nil
+}
+
+func (x *ListDialogsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+// Отмечает прочитанными сообщения peer_id пользователю user_id до
+// up_to_message_id включительно
+type MarkReadRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PeerId        string                 `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	UpToMessageId string                 `protobuf:"bytes,3,opt,name=up_to_message_id,json=upToMessageId,proto3" json:"up_to_message_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkReadRequest) Reset() {
+	*x = MarkReadRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkReadRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkReadRequest) ProtoMessage() {}
+
+func (x *MarkReadRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkReadRequest.ProtoReflect.Descriptor instead.
+func (*MarkReadRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *MarkReadRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *MarkReadRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *MarkReadRequest) GetUpToMessageId() string {
+	if x != nil {
+		return x.UpToMessageId
+	}
+	return ""
+}
+
+type MarkReadResponse struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Marked int32                  `protobuf:"varint,1,opt,name=marked,proto3" json:"marked,omitempty"`
+	// Непрочитанные в диалоге с peer_id и всего после отметки
+	UnreadCount   int32 `protobuf:"varint,2,opt,name=unread_count,json=unreadCount,proto3" json:"unread_count,omitempty"`
+	TotalUnread   int32 `protobuf:"varint,3,opt,name=total_unread,json=totalUnread,proto3" json:"total_unread,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MarkReadResponse) Reset() {
+	*x = MarkReadResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MarkReadResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MarkReadResponse) ProtoMessage() {}
+
+func (x *MarkReadResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MarkReadResponse.ProtoReflect.Descriptor instead.
+func (*MarkReadResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MarkReadResponse) GetMarked() int32 {
+	if x != nil {
+		return x.Marked
+	}
+	return 0
+}
+
+func (x *MarkReadResponse) GetUnreadCount() int32 {
+	if x != nil {
+		return x.UnreadCount
+	}
+	return 0
+}
+
+func (x *MarkReadResponse) GetTotalUnread() int32 {
+	if x != nil {
+		return x.TotalUnread
+	}
+	return 0
+}
+
+type GetUnreadCountersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUnreadCountersRequest) Reset() {
+	*x = GetUnreadCountersRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUnreadCountersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUnreadCountersRequest) ProtoMessage() {}
+
+func (x *GetUnreadCountersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUnreadCountersRequest.ProtoReflect.Descriptor instead.
+func (*GetUnreadCountersRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *GetUnreadCountersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+type GetUnreadCountersResponse struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	TotalUnread int32                  `protobuf:"varint,1,opt,name=total_unread,json=totalUnread,proto3" json:"total_unread,omitempty"`
+	// Ненулевые счетчики по peer_id
+	Dialogs       map[string]int32 `protobuf:"bytes,2,rep,name=dialogs,proto3" json:"dialogs,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetUnreadCountersResponse) Reset() {
+	*x = GetUnreadCountersResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetUnreadCountersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetUnreadCountersResponse) ProtoMessage() {}
+
+func (x *GetUnreadCountersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetUnreadCountersResponse.ProtoReflect.Descriptor instead.
+func (*GetUnreadCountersResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *GetUnreadCountersResponse) GetTotalUnread() int32 {
+	if x != nil {
+		return x.TotalUnread
+	}
+	return 0
+}
+
+func (x *GetUnreadCountersResponse) GetDialogs() map[string]int32 {
+	if x != nil {
+		return x.Dialogs
+	}
+	return nil
+}
+
+// Редактировать может только отправитель user_id в течение окна редактирования
+type EditMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PeerId        string                 `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditMessageRequest) Reset() {
+	*x = EditMessageRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditMessageRequest) ProtoMessage() {}
+
+func (x *EditMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditMessageRequest.ProtoReflect.Descriptor instead.
+func (*EditMessageRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *EditMessageRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *EditMessageRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *EditMessageRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *EditMessageRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+type EditMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       *DialogMessage         `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EditMessageResponse) Reset() {
+	*x = EditMessageResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EditMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EditMessageResponse) ProtoMessage() {}
+
+func (x *EditMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EditMessageResponse.ProtoReflect.Descriptor instead.
+func (*EditMessageResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *EditMessageResponse) GetMessage() *DialogMessage {
+	if x != nil {
+		return x.Message
+	}
+	return nil
+}
+
+// Без for_everyone сообщение скрывается только у user_id. Удалить для всех
+// может только отправитель
+type DeleteMessageRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PeerId        string                 `protobuf:"bytes,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	MessageId     string                 `protobuf:"bytes,3,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	ForEveryone   bool                   `protobuf:"varint,4,opt,name=for_everyone,json=forEveryone,proto3" json:"for_everyone,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessageRequest) Reset() {
+	*x = DeleteMessageRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessageRequest) ProtoMessage() {}
+
+func (x *DeleteMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessageRequest.ProtoReflect.Descriptor instead.
+func (*DeleteMessageRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *DeleteMessageRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *DeleteMessageRequest) GetPeerId() string {
+	if x != nil {
+		return x.PeerId
+	}
+	return ""
+}
+
+func (x *DeleteMessageRequest) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *DeleteMessageRequest) GetForEveryone() bool {
+	if x != nil {
+		return x.ForEveryone
+	}
+	return false
+}
+
+type DeleteMessageResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DeleteMessageResponse) Reset() {
+	*x = DeleteMessageResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DeleteMessageResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DeleteMessageResponse) ProtoMessage() {}
+
+func (x *DeleteMessageResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DeleteMessageResponse.ProtoReflect.Descriptor instead.
+func (*DeleteMessageResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{16}
+}
+
+// Group - групповой чат; role - роль запросившего участника
+type Group struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	CreatedBy     string                 `protobuf:"bytes,3,opt,name=created_by,json=createdBy,proto3" json:"created_by,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	Role          GroupRole              `protobuf:"varint,5,opt,name=role,proto3,enum=dialog.v1.GroupRole" json:"role,omitempty"`
+	MemberCount   int32                  `protobuf:"varint,6,opt,name=member_count,json=memberCount,proto3" json:"member_count,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Group) Reset() {
+	*x = Group{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Group) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Group) ProtoMessage() {}
+
+func (x *Group) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Group.ProtoReflect.Descriptor instead.
+func (*Group) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *Group) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *Group) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *Group) GetCreatedBy() string {
+	if x != nil {
+		return x.CreatedBy
+	}
+	return ""
+}
+
+func (x *Group) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+func (x *Group) GetRole() GroupRole {
+	if x != nil {
+		return x.Role
+	}
+	return GroupRole_GROUP_ROLE_UNSPECIFIED
+}
+
+func (x *Group) GetMemberCount() int32 {
+	if x != nil {
+		return x.MemberCount
+	}
+	return 0
+}
+
+type GroupMember struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Role          GroupRole              `protobuf:"varint,2,opt,name=role,proto3,enum=dialog.v1.GroupRole" json:"role,omitempty"`
+	JoinedAt      *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=joined_at,json=joinedAt,proto3" json:"joined_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupMember) Reset() {
+	*x = GroupMember{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupMember) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupMember) ProtoMessage() {}
+
+func (x *GroupMember) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupMember.ProtoReflect.Descriptor instead.
+func (*GroupMember) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *GroupMember) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GroupMember) GetRole() GroupRole {
+	if x != nil {
+		return x.Role
+	}
+	return GroupRole_GROUP_ROLE_UNSPECIFIED
+}
+
+func (x *GroupMember) GetJoinedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.JoinedAt
+	}
+	return nil
+}
+
+type GroupMessage struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     string                 `protobuf:"bytes,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	SenderId      string                 `protobuf:"bytes,3,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	SentAt        *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=sent_at,json=sentAt,proto3" json:"sent_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupMessage) Reset() {
+	*x = GroupMessage{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupMessage) ProtoMessage() {}
+
+func (x *GroupMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupMessage.ProtoReflect.Descriptor instead.
+func (*GroupMessage) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *GroupMessage) GetMessageId() string {
+	if x != nil {
+		return x.MessageId
+	}
+	return ""
+}
+
+func (x *GroupMessage) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GroupMessage) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *GroupMessage) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *GroupMessage) GetSentAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.SentAt
+	}
+	return nil
+}
+
+// Создатель становится администратором, member_ids - остальные участники,
+// всего не больше 200
+type CreateGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Title         string                 `protobuf:"bytes,2,opt,name=title,proto3" json:"title,omitempty"`
+	MemberIds     []string               `protobuf:"bytes,3,rep,name=member_ids,json=memberIds,proto3" json:"member_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGroupRequest) Reset() {
+	*x = CreateGroupRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGroupRequest) ProtoMessage() {}
+
+func (x *CreateGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupRequest.ProtoReflect.Descriptor instead.
+func (*CreateGroupRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *CreateGroupRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetTitle() string {
+	if x != nil {
+		return x.Title
+	}
+	return ""
+}
+
+func (x *CreateGroupRequest) GetMemberIds() []string {
+	if x != nil {
+		return x.MemberIds
+	}
+	return nil
+}
+
+type CreateGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Group         *Group                 `protobuf:"bytes,1,opt,name=group,proto3" json:"group,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateGroupResponse) Reset() {
+	*x = CreateGroupResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateGroupResponse) ProtoMessage() {}
+
+func (x *CreateGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateGroupResponse.ProtoReflect.Descriptor instead.
+func (*CreateGroupResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *CreateGroupResponse) GetGroup() *Group {
+	if x != nil {
+		return x.Group
+	}
+	return nil
+}
+
+// Группы пользователя от новых к старым. cursor - next_cursor предыдущей страницы
+type ListGroupsRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Cursor string                 `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	// По умолчанию 50, не больше 100
+	Limit         int32 `protobuf:"varint,3,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsRequest) Reset() {
+	*x = ListGroupsRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsRequest) ProtoMessage() {}
+
+func (x *ListGroupsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupsRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{22}
+}
+
+func (x *ListGroupsRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListGroupsRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+func (x *ListGroupsRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type ListGroupsResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Groups        []*Group               `protobuf:"bytes,1,rep,name=groups,proto3" json:"groups,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupsResponse) Reset() {
+	*x = ListGroupsResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[23]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupsResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupsResponse) ProtoMessage() {}
+
+func (x *ListGroupsResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[23]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupsResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupsResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{23}
+}
+
+func (x *ListGroupsResponse) GetGroups() []*Group {
+	if x != nil {
+		return x.Groups
+	}
+	return nil
+}
+
+func (x *ListGroupsResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+type ListGroupMembersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupMembersRequest) Reset() {
+	*x = ListGroupMembersRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[24]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupMembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupMembersRequest) ProtoMessage() {}
+
+func (x *ListGroupMembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[24]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupMembersRequest.ProtoReflect.Descriptor instead.
+func (*ListGroupMembersRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{24}
+}
+
+func (x *ListGroupMembersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ListGroupMembersRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+type ListGroupMembersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Members       []*GroupMember         `protobuf:"bytes,1,rep,name=members,proto3" json:"members,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListGroupMembersResponse) Reset() {
+	*x = ListGroupMembersResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[25]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListGroupMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListGroupMembersResponse) ProtoMessage() {}
+
+func (x *ListGroupMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[25]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListGroupMembersResponse.ProtoReflect.Descriptor instead.
+func (*ListGroupMembersResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{25}
+}
+
+func (x *ListGroupMembersResponse) GetMembers() []*GroupMember {
+	if x != nil {
+		return x.Members
+	}
+	return nil
+}
+
+type AddGroupMembersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	MemberIds     []string               `protobuf:"bytes,3,rep,name=member_ids,json=memberIds,proto3" json:"member_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddGroupMembersRequest) Reset() {
+	*x = AddGroupMembersRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[26]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddGroupMembersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddGroupMembersRequest) ProtoMessage() {}
+
+func (x *AddGroupMembersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[26]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddGroupMembersRequest.ProtoReflect.Descriptor instead.
+func (*AddGroupMembersRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{26}
+}
+
+func (x *AddGroupMembersRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *AddGroupMembersRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *AddGroupMembersRequest) GetMemberIds() []string {
+	if x != nil {
+		return x.MemberIds
+	}
+	return nil
+}
+
+type AddGroupMembersResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Добавленные участники, уже состоявшие в группе не включаются
+	AddedIds      []string `protobuf:"bytes,1,rep,name=added_ids,json=addedIds,proto3" json:"added_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AddGroupMembersResponse) Reset() {
+	*x = AddGroupMembersResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[27]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AddGroupMembersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AddGroupMembersResponse) ProtoMessage() {}
+
+func (x *AddGroupMembersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[27]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AddGroupMembersResponse.ProtoReflect.Descriptor instead.
+func (*AddGroupMembersResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{27}
+}
+
+func (x *AddGroupMembersResponse) GetAddedIds() []string {
+	if x != nil {
+		return x.AddedIds
+	}
+	return nil
+}
+
+type RemoveGroupMemberRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	MemberId      string                 `protobuf:"bytes,3,opt,name=member_id,json=memberId,proto3" json:"member_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveGroupMemberRequest) Reset() {
+	*x = RemoveGroupMemberRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[28]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveGroupMemberRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveGroupMemberRequest) ProtoMessage() {}
+
+func (x *RemoveGroupMemberRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[28]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveGroupMemberRequest.ProtoReflect.Descriptor instead.
+func (*RemoveGroupMemberRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{28}
+}
+
+func (x *RemoveGroupMemberRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *RemoveGroupMemberRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *RemoveGroupMemberRequest) GetMemberId() string {
+	if x != nil {
+		return x.MemberId
+	}
+	return ""
+}
+
+type RemoveGroupMemberResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RemoveGroupMemberResponse) Reset() {
+	*x = RemoveGroupMemberResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[29]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RemoveGroupMemberResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RemoveGroupMemberResponse) ProtoMessage() {}
+
+func (x *RemoveGroupMemberResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[29]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RemoveGroupMemberResponse.ProtoReflect.Descriptor instead.
+func (*RemoveGroupMemberResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{29}
+}
+
+type SetGroupMemberRoleRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	MemberId      string                 `protobuf:"bytes,3,opt,name=member_id,json=memberId,proto3" json:"member_id,omitempty"`
+	Role          GroupRole              `protobuf:"varint,4,opt,name=role,proto3,enum=dialog.v1.GroupRole" json:"role,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetGroupMemberRoleRequest) Reset() {
+	*x = SetGroupMemberRoleRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[30]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetGroupMemberRoleRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetGroupMemberRoleRequest) ProtoMessage() {}
+
+func (x *SetGroupMemberRoleRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[30]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetGroupMemberRoleRequest.ProtoReflect.Descriptor instead.
+func (*SetGroupMemberRoleRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{30}
+}
+
+func (x *SetGroupMemberRoleRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *SetGroupMemberRoleRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *SetGroupMemberRoleRequest) GetMemberId() string {
+	if x != nil {
+		return x.MemberId
+	}
+	return ""
+}
+
+func (x *SetGroupMemberRoleRequest) GetRole() GroupRole {
+	if x != nil {
+		return x.Role
+	}
+	return GroupRole_GROUP_ROLE_UNSPECIFIED
+}
+
+type SetGroupMemberRoleResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetGroupMemberRoleResponse) Reset() {
+	*x = SetGroupMemberRoleResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[31]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetGroupMemberRoleResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetGroupMemberRoleResponse) ProtoMessage() {}
+
+func (x *SetGroupMemberRoleResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[31]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetGroupMemberRoleResponse.ProtoReflect.Descriptor instead.
+func (*SetGroupMemberRoleResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{31}
+}
+
+// Если уходит последний администратор, администратором становится
+// участник, вступивший раньше остальных
+type LeaveGroupRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveGroupRequest) Reset() {
+	*x = LeaveGroupRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[32]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveGroupRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveGroupRequest) ProtoMessage() {}
+
+func (x *LeaveGroupRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[32]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveGroupRequest.ProtoReflect.Descriptor instead.
+func (*LeaveGroupRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{32}
+}
+
+func (x *LeaveGroupRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *LeaveGroupRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+type LeaveGroupResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *LeaveGroupResponse) Reset() {
+	*x = LeaveGroupResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[33]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *LeaveGroupResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeaveGroupResponse) ProtoMessage() {}
+
+func (x *LeaveGroupResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[33]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeaveGroupResponse.ProtoReflect.Descriptor instead.
+func (*LeaveGroupResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{33}
+}
+
+type SendGroupMessageRequest struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	SenderId string                 `protobuf:"bytes,1,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	GroupId  string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Text     string                 `protobuf:"bytes,3,opt,name=text,proto3" json:"text,omitempty"`
+	// Как в SendMessageRequest: повтор не создает новое сообщение
+	ClientMessageId string `protobuf:"bytes,4,opt,name=client_message_id,json=clientMessageId,proto3" json:"client_message_id,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *SendGroupMessageRequest) Reset() {
+	*x = SendGroupMessageRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[34]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendGroupMessageRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendGroupMessageRequest) ProtoMessage() {}
+
+func (x *SendGroupMessageRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[34]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendGroupMessageRequest.ProtoReflect.Descriptor instead.
+func (*SendGroupMessageRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{34}
+}
+
+func (x *SendGroupMessageRequest) GetSenderId() string {
+	if x != nil {
+		return x.SenderId
+	}
+	return ""
+}
+
+func (x *SendGroupMessageRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *SendGroupMessageRequest) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *SendGroupMessageRequest) GetClientMessageId() string {
+	if x != nil {
+		return x.ClientMessageId
+	}
+	return ""
+}
+
+// Курсоры и порядок как в GetMessagesRequest
+type GetGroupMessagesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Before        string                 `protobuf:"bytes,3,opt,name=before,proto3" json:"before,omitempty"`
+	After         string                 `protobuf:"bytes,4,opt,name=after,proto3" json:"after,omitempty"`
+	Limit         int32                  `protobuf:"varint,5,opt,name=limit,proto3" json:"limit,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupMessagesRequest) Reset() {
+	*x = GetGroupMessagesRequest{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[35]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupMessagesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupMessagesRequest) ProtoMessage() {}
+
+func (x *GetGroupMessagesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[35]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupMessagesRequest.ProtoReflect.Descriptor instead.
+func (*GetGroupMessagesRequest) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{35}
+}
+
+func (x *GetGroupMessagesRequest) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *GetGroupMessagesRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *GetGroupMessagesRequest) GetBefore() string {
+	if x != nil {
+		return x.Before
+	}
+	return ""
+}
+
+func (x *GetGroupMessagesRequest) GetAfter() string {
+	if x != nil {
+		return x.After
+	}
+	return ""
+}
+
+func (x *GetGroupMessagesRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+type GetGroupMessagesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Messages      []*GroupMessage        `protobuf:"bytes,1,rep,name=messages,proto3" json:"messages,omitempty"`
+	NextCursor    string                 `protobuf:"bytes,2,opt,name=next_cursor,json=nextCursor,proto3" json:"next_cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetGroupMessagesResponse) Reset() {
+	*x = GetGroupMessagesResponse{}
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[36]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetGroupMessagesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetGroupMessagesResponse) ProtoMessage() {}
+
+func (x *GetGroupMessagesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_dialog_v1_dialog_proto_msgTypes[36]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetGroupMessagesResponse.ProtoReflect.Descriptor instead.
+func (*GetGroupMessagesResponse) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP(), []int{36}
+}
+
+func (x *GetGroupMessagesResponse) GetMessages() []*GroupMessage {
+	if x != nil {
+		return x.Messages
+	}
+	return nil
+}
+
+func (x *GetGroupMessagesResponse) GetNextCursor() string {
+	if x != nil {
+		return x.NextCursor
+	}
+	return ""
+}
+
+var File_pkg_proto_dialog_v1_dialog_proto protoreflect.FileDescriptor
+
+const file_pkg_proto_dialog_v1_dialog_proto_rawDesc = "" +
+	"\n" +
+	" pkg/proto/dialog/v1/dialog.proto\x12\tdialog.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\x92\x01\n" +
+	"\x12SendMessageRequest\x12\x1b\n" +
+	"\tsender_id\x18\x01 \x01(\tR\bsenderId\x12\x1f\n" +
+	"\vreceiver_id\x18\x02 \x01(\tR\n" +
+	"receiverId\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\x12*\n" +
+	"\x11client_message_id\x18\x04 \x01(\tR\x0fclientMessageId\"\xa1\x01\n" +
+	"\x13SendMessageResponse\x12\x18\n" +
+	"\asuccess\x18\x01 \x01(\bR\asuccess\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x02 \x01(\tR\tmessageId\x123\n" +
+	"\asent_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\x12\x1c\n" +
+	"\tduplicate\x18\x04 \x01(\bR\tduplicate\"\x95\x01\n" +
+	"\x12GetMessagesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\"\n" +
+	"\rother_user_id\x18\x02 \x01(\tR\votherUserId\x12\x16\n" +
+	"\x06before\x18\x03 \x01(\tR\x06before\x12\x14\n" +
+	"\x05after\x18\x04 \x01(\tR\x05after\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\"S\n" +
+	"\x15StreamMessagesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12!\n" +
+	"\fsince_cursor\x18\x02 \x01(\tR\vsinceCursor\"\x88\x02\n" +
+	"\rDialogMessage\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\tR\bsenderId\x12\x1f\n" +
+	"\vreceiver_id\x18\x03 \x01(\tR\n" +
+	"receiverId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x123\n" +
+	"\asent_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\x127\n" +
+	"\tedited_at\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\beditedAt\x12\x18\n" +
+	"\adeleted\x18\a \x01(\bR\adeleted\"l\n" +
+	"\x13GetMessagesResponse\x124\n" +
+	"\bmessages\x18\x01 \x03(\v2\x18.dialog.v1.DialogMessageR\bmessages\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"[\n" +
+	"\x12ListDialogsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"\x88\x01\n" +
+	"\rDialogPreview\x12\x17\n" +
+	"\apeer_id\x18\x01 \x01(\tR\x06peerId\x12;\n" +
+	"\flast_message\x18\x02 \x01(\v2\x18.dialog.v1.DialogMessageR\vlastMessage\x12!\n" +
+	"\funread_count\x18\x03 \x01(\x05R\vunreadCount\"j\n" +
+	"\x13ListDialogsResponse\x122\n" +
+	"\adialogs\x18\x01 \x03(\v2\x18.dialog.v1.DialogPreviewR\adialogs\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"l\n" +
+	"\x0fMarkReadRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\tR\x06peerId\x12'\n" +
+	"\x10up_to_message_id\x18\x03 \x01(\tR\rupToMessageId\"p\n" +
+	"\x10MarkReadResponse\x12\x16\n" +
+	"\x06marked\x18\x01 \x01(\x05R\x06marked\x12!\n" +
+	"\funread_count\x18\x02 \x01(\x05R\vunreadCount\x12!\n" +
+	"\ftotal_unread\x18\x03 \x01(\x05R\vtotalUnread\"3\n" +
+	"\x18GetUnreadCountersRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\"\xc7\x01\n" +
+	"\x19GetUnreadCountersResponse\x12!\n" +
+	"\ftotal_unread\x18\x01 \x01(\x05R\vtotalUnread\x12K\n" +
+	"\adialogs\x18\x02 \x03(\v21.dialog.v1.GetUnreadCountersResponse.DialogsEntryR\adialogs\x1a:\n" +
+	"\fDialogsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x05R\x05value:\x028\x01\"y\n" +
+	"\x12EditMessageRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\tR\x06peerId\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\"I\n" +
+	"\x13EditMessageResponse\x122\n" +
+	"\amessage\x18\x01 \x01(\v2\x18.dialog.v1.DialogMessageR\amessage\"\x8a\x01\n" +
+	"\x14DeleteMessageRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\tR\x06peerId\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x03 \x01(\tR\tmessageId\x12!\n" +
+	"\ffor_everyone\x18\x04 \x01(\bR\vforEveryone\"\x17\n" +
+	"\x15DeleteMessageResponse\"\xdf\x01\n" +
+	"\x05Group\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x1d\n" +
+	"\n" +
+	"created_by\x18\x03 \x01(\tR\tcreatedBy\x129\n" +
+	"\n" +
+	"created_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAt\x12(\n" +
+	"\x04role\x18\x05 \x01(\x0e2\x14.dialog.v1.GroupRoleR\x04role\x12!\n" +
+	"\fmember_count\x18\x06 \x01(\x05R\vmemberCount\"\x89\x01\n" +
+	"\vGroupMember\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12(\n" +
+	"\x04role\x18\x02 \x01(\x0e2\x14.dialog.v1.GroupRoleR\x04role\x127\n" +
+	"\tjoined_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\bjoinedAt\"\xae\x01\n" +
+	"\fGroupMessage\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\tR\tmessageId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x1b\n" +
+	"\tsender_id\x18\x03 \x01(\tR\bsenderId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x123\n" +
+	"\asent_at\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\x06sentAt\"b\n" +
+	"\x12CreateGroupRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x1d\n" +
+	"\n" +
+	"member_ids\x18\x03 \x03(\tR\tmemberIds\"=\n" +
+	"\x13CreateGroupResponse\x12&\n" +
+	"\x05group\x18\x01 \x01(\v2\x10.dialog.v1.GroupR\x05group\"Z\n" +
+	"\x11ListGroupsRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\x12\x14\n" +
+	"\x05limit\x18\x03 \x01(\x05R\x05limit\"_\n" +
+	"\x12ListGroupsResponse\x12(\n" +
+	"\x06groups\x18\x01 \x03(\v2\x10.dialog.v1.GroupR\x06groups\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor\"M\n" +
+	"\x17ListGroupMembersRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\"L\n" +
+	"\x18ListGroupMembersResponse\x120\n" +
+	"\amembers\x18\x01 \x03(\v2\x16.dialog.v1.GroupMemberR\amembers\"k\n" +
+	"\x16AddGroupMembersRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x1d\n" +
+	"\n" +
+	"member_ids\x18\x03 \x03(\tR\tmemberIds\"6\n" +
+	"\x17AddGroupMembersResponse\x12\x1b\n" +
+	"\tadded_ids\x18\x01 \x03(\tR\baddedIds\"k\n" +
+	"\x18RemoveGroupMemberRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x1b\n" +
+	"\tmember_id\x18\x03 \x01(\tR\bmemberId\"\x1b\n" +
+	"\x19RemoveGroupMemberResponse\"\x96\x01\n" +
+	"\x19SetGroupMemberRoleRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x1b\n" +
+	"\tmember_id\x18\x03 \x01(\tR\bmemberId\x12(\n" +
+	"\x04role\x18\x04 \x01(\x0e2\x14.dialog.v1.GroupRoleR\x04role\"\x1c\n" +
+	"\x1aSetGroupMemberRoleResponse\"G\n" +
+	"\x11LeaveGroupRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\"\x14\n" +
+	"\x12LeaveGroupResponse\"\x91\x01\n" +
+	"\x17SendGroupMessageRequest\x12\x1b\n" +
+	"\tsender_id\x18\x01 \x01(\tR\bsenderId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x12\n" +
+	"\x04text\x18\x03 \x01(\tR\x04text\x12*\n" +
+	"\x11client_message_id\x18\x04 \x01(\tR\x0fclientMessageId\"\x91\x01\n" +
+	"\x17GetGroupMessagesRequest\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12\x16\n" +
+	"\x06before\x18\x03 \x01(\tR\x06before\x12\x14\n" +
+	"\x05after\x18\x04 \x01(\tR\x05after\x12\x14\n" +
+	"\x05limit\x18\x05 \x01(\x05R\x05limit\"p\n" +
+	"\x18GetGroupMessagesResponse\x123\n" +
+	"\bmessages\x18\x01 \x03(\v2\x17.dialog.v1.GroupMessageR\bmessages\x12\x1f\n" +
+	"\vnext_cursor\x18\x02 \x01(\tR\n" +
+	"nextCursor*T\n" +
+	"\tGroupRole\x12\x1a\n" +
+	"\x16GROUP_ROLE_UNSPECIFIED\x10\x00\x12\x14\n" +
+	"\x10GROUP_ROLE_ADMIN\x10\x01\x12\x15\n" +
+	"\x11GROUP_ROLE_MEMBER\x10\x022\xa3\v\n" +
+	"\rDialogService\x12L\n" +
+	"\vSendMessage\x12\x1d.dialog.v1.SendMessageRequest\x1a\x1e.dialog.v1.SendMessageResponse\x12L\n" +
+	"\vGetMessages\x12\x1d.dialog.v1.GetMessagesRequest\x1a\x1e.dialog.v1.GetMessagesResponse\x12L\n" +
+	"\vListDialogs\x12\x1d.dialog.v1.ListDialogsRequest\x1a\x1e.dialog.v1.ListDialogsResponse\x12C\n" +
+	"\bMarkRead\x12\x1a.dialog.v1.MarkReadRequest\x1a\x1b.dialog.v1.MarkReadResponse\x12^\n" +
+	"\x11GetUnreadCounters\x12#.dialog.v1.GetUnreadCountersRequest\x1a$.dialog.v1.GetUnreadCountersResponse\x12L\n" +
+	"\vEditMessage\x12\x1d.dialog.v1.EditMessageRequest\x1a\x1e.dialog.v1.EditMessageResponse\x12R\n" +
+	"\rDeleteMessage\x12\x1f.dialog.v1.DeleteMessageRequest\x1a .dialog.v1.DeleteMessageResponse\x12N\n" +
+	"\x0eStreamMessages\x12 .dialog.v1.StreamMessagesRequest\x1a\x18.dialog.v1.DialogMessage0\x01\x12L\n" +
+	"\vCreateGroup\x12\x1d.dialog.v1.CreateGroupRequest\x1a\x1e.dialog.v1.CreateGroupResponse\x12I\n" +
+	"\n" +
+	"ListGroups\x12\x1c.dialog.v1.ListGroupsRequest\x1a\x1d.dialog.v1.ListGroupsResponse\x12[\n" +
+	"\x10ListGroupMembers\x12\".dialog.v1.ListGroupMembersRequest\x1a#.dialog.v1.ListGroupMembersResponse\x12X\n" +
+	"\x0fAddGroupMembers\x12!.dialog.v1.AddGroupMembersRequest\x1a\".dialog.v1.AddGroupMembersResponse\x12^\n" +
+	"\x11RemoveGroupMember\x12#.dialog.v1.RemoveGroupMemberRequest\x1a$.dialog.v1.RemoveGroupMemberResponse\x12a\n" +
+	"\x12SetGroupMemberRole\x12$.dialog.v1.SetGroupMemberRoleRequest\x1a%.dialog.v1.SetGroupMemberRoleResponse\x12I\n" +
+	"\n" +
+	"LeaveGroup\x12\x1c.dialog.v1.LeaveGroupRequest\x1a\x1d.dialog.v1.LeaveGroupResponse\x12V\n" +
+	"\x10SendGroupMessage\x12\".dialog.v1.SendGroupMessageRequest\x1a\x1e.dialog.v1.SendMessageResponse\x12[\n" +
+	"\x10GetGroupMessages\x12\".dialog.v1.GetGroupMessagesRequest\x1a#.dialog.v1.GetGroupMessagesResponseB\x1fZ\x1dsocial/pkg/dialog/v1;dialogv1b\x06proto3"
 
 var (
 	file_pkg_proto_dialog_v1_dialog_proto_rawDescOnce sync.Once
@@ -358,28 +2432,108 @@ func file_pkg_proto_dialog_v1_dialog_proto_rawDescGZIP() []byte {
 	return file_pkg_proto_dialog_v1_dialog_proto_rawDescData
 }
 
-var file_pkg_proto_dialog_v1_dialog_proto_msgTypes = make([]protoimpl.MessageInfo, 5)
+var file_pkg_proto_dialog_v1_dialog_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_proto_dialog_v1_dialog_proto_msgTypes = make([]protoimpl.MessageInfo, 38)
 var file_pkg_proto_dialog_v1_dialog_proto_goTypes = []any{
-	(*SendMessageRequest)(nil),    // 0: dialog.v1.SendMessageRequest
-	(*SendMessageResponse)(nil),   // 1: dialog.v1.SendMessageResponse
-	(*GetMessagesRequest)(nil),    // 2: dialog.v1.GetMessagesRequest
-	(*DialogMessage)(nil),         // 3: dialog.v1.DialogMessage
-	(*GetMessagesResponse)(nil),   // 4: dialog.v1.GetMessagesResponse
-	(*timestamppb.Timestamp)(nil), // 5: google.protobuf.Timestamp
+	(GroupRole)(0),                     // 0: dialog.v1.GroupRole
+	(*SendMessageRequest)(nil),         // 1: dialog.v1.SendMessageRequest
+	(*SendMessageResponse)(nil),        // 2: dialog.v1.SendMessageResponse
+	(*GetMessagesRequest)(nil),         // 3: dialog.v1.GetMessagesRequest
+	(*StreamMessagesRequest)(nil),      // 4: dialog.v1.StreamMessagesRequest
+	(*DialogMessage)(nil),              // 5: dialog.v1.DialogMessage
+	(*GetMessagesResponse)(nil),        // 6: dialog.v1.GetMessagesResponse
+	(*ListDialogsRequest)(nil),         // 7: dialog.v1.ListDialogsRequest
+	(*DialogPreview)(nil),              // 8: dialog.v1.DialogPreview
+	(*ListDialogsResponse)(nil),        // 9: dialog.v1.ListDialogsResponse
+	(*MarkReadRequest)(nil),            // 10: dialog.v1.MarkReadRequest
+	(*MarkReadResponse)(nil),           // 11: dialog.v1.MarkReadResponse
+	(*GetUnreadCountersRequest)(nil),   // 12: dialog.v1.GetUnreadCountersRequest
+	(*GetUnreadCountersResponse)(nil),  // 13: dialog.v1.GetUnreadCountersResponse
+	(*EditMessageRequest)(nil),         // 14: dialog.v1.EditMessageRequest
+	(*EditMessageResponse)(nil),        // 15: dialog.v1.EditMessageResponse
+	(*DeleteMessageRequest)(nil),       // 16: dialog.v1.DeleteMessageRequest
+	(*DeleteMessageResponse)(nil),      // 17: dialog.v1.DeleteMessageResponse
+	(*Group)(nil),                      // 18: dialog.v1.Group
+	(*GroupMember)(nil),                // 19: dialog.v1.GroupMember
+	(*GroupMessage)(nil),               // 20: dialog.v1.GroupMessage
+	(*CreateGroupRequest)(nil),         // 21: dialog.v1.CreateGroupRequest
+	(*CreateGroupResponse)(nil),        // 22: dialog.v1.CreateGroupResponse
+	(*ListGroupsRequest)(nil),          // 23: dialog.v1.ListGroupsRequest
+	(*ListGroupsResponse)(nil),         // 24: dialog.v1.ListGroupsResponse
+	(*ListGroupMembersRequest)(nil),    // 25: dialog.v1.ListGroupMembersRequest
+	(*ListGroupMembersResponse)(nil),   // 26: dialog.v1.ListGroupMembersResponse
+	(*AddGroupMembersRequest)(nil),     // 27: dialog.v1.AddGroupMembersRequest
+	(*AddGroupMembersResponse)(nil),    // 28: dialog.v1.AddGroupMembersResponse
+	(*RemoveGroupMemberRequest)(nil),   // 29: dialog.v1.RemoveGroupMemberRequest
+	(*RemoveGroupMemberResponse)(nil),  // 30: dialog.v1.RemoveGroupMemberResponse
+	(*SetGroupMemberRoleRequest)(nil),  // 31: dialog.v1.SetGroupMemberRoleRequest
+	(*SetGroupMemberRoleResponse)(nil), // 32: dialog.v1.SetGroupMemberRoleResponse
+	(*LeaveGroupRequest)(nil),          // 33: dialog.v1.LeaveGroupRequest
+	(*LeaveGroupResponse)(nil),         // 34: dialog.v1.LeaveGroupResponse
+	(*SendGroupMessageRequest)(nil),    // 35: dialog.v1.SendGroupMessageRequest
+	(*GetGroupMessagesRequest)(nil),    // 36: dialog.v1.GetGroupMessagesRequest
+	(*GetGroupMessagesResponse)(nil),   // 37: dialog.v1.GetGroupMessagesResponse
+	nil,                                // 38: dialog.v1.GetUnreadCountersResponse.DialogsEntry
+	(*timestamppb.Timestamp)(nil),      // 39: google.protobuf.Timestamp
 }
 var file_pkg_proto_dialog_v1_dialog_proto_depIdxs = []int32{
-	5, // 0: dialog.v1.SendMessageResponse.sent_at:type_name -> google.protobuf.Timestamp
-	5, // 1: dialog.v1.DialogMessage.sent_at:type_name -> google.protobuf.Timestamp
-	3, // 2: dialog.v1.GetMessagesResponse.messages:type_name -> dialog.v1.DialogMessage
-	0, // 3: dialog.v1.DialogService.SendMessage:input_type -> dialog.v1.SendMessageRequest
-	2, // 4: dialog.v1.DialogService.GetMessages:input_type -> dialog.v1.GetMessagesRequest
-	1, // 5: dialog.v1.DialogService.SendMessage:output_type -> dialog.v1.SendMessageResponse
-	4, // 6: dialog.v1.DialogService.GetMessages:output_type -> dialog.v1.GetMessagesResponse
-	5, // [5:7] is the sub-list for method output_type
-	3, // [3:5] is the sub-list for method input_type
-	3, // [3:3] is the sub-list for extension type_name
-	3, // [3:3] is the sub-list for extension extendee
-	0, // [0:3] is the sub-list for field type_name
+	39, // 0: dialog.v1.SendMessageResponse.sent_at:type_name -> google.protobuf.Timestamp
+	39, // 1: dialog.v1.DialogMessage.sent_at:type_name -> google.protobuf.Timestamp
+	39, // 2: dialog.v1.DialogMessage.edited_at:type_name -> google.protobuf.Timestamp
+	5,  // 3: dialog.v1.GetMessagesResponse.messages:type_name -> dialog.v1.DialogMessage
+	5,  // 4: dialog.v1.DialogPreview.last_message:type_name -> dialog.v1.DialogMessage
+	8,  // 5: dialog.v1.ListDialogsResponse.dialogs:type_name -> dialog.v1.DialogPreview
+	38, // 6: dialog.v1.GetUnreadCountersResponse.dialogs:type_name -> dialog.v1.GetUnreadCountersResponse.DialogsEntry
+	5,  // 7: dialog.v1.EditMessageResponse.message:type_name -> dialog.v1.DialogMessage
+	39, // 8: dialog.v1.Group.created_at:type_name -> google.protobuf.Timestamp
+	0,  // 9: dialog.v1.Group.role:type_name -> dialog.v1.GroupRole
+	0,  // 10: dialog.v1.GroupMember.role:type_name -> dialog.v1.GroupRole
+	39, // 11: dialog.v1.GroupMember.joined_at:type_name -> google.protobuf.Timestamp
+	39, // 12: dialog.v1.GroupMessage.sent_at:type_name -> google.protobuf.Timestamp
+	18, // 13: dialog.v1.CreateGroupResponse.group:type_name -> dialog.v1.Group
+	18, // 14: dialog.v1.ListGroupsResponse.groups:type_name -> dialog.v1.Group
+	19, // 15: dialog.v1.ListGroupMembersResponse.members:type_name -> dialog.v1.GroupMember
+	0,  // 16: dialog.v1.SetGroupMemberRoleRequest.role:type_name -> dialog.v1.GroupRole
+	20, // 17: dialog.v1.GetGroupMessagesResponse.messages:type_name -> dialog.v1.GroupMessage
+	1,  // 18: dialog.v1.DialogService.SendMessage:input_type -> dialog.v1.SendMessageRequest
+	3,  // 19: dialog.v1.DialogService.GetMessages:input_type -> dialog.v1.GetMessagesRequest
+	7,  // 20: dialog.v1.DialogService.ListDialogs:input_type -> dialog.v1.ListDialogsRequest
+	10, // 21: dialog.v1.DialogService.MarkRead:input_type -> dialog.v1.MarkReadRequest
+	12, // 22: dialog.v1.DialogService.GetUnreadCounters:input_type -> dialog.v1.GetUnreadCountersRequest
+	14, // 23: dialog.v1.DialogService.EditMessage:input_type -> dialog.v1.EditMessageRequest
+	16, // 24: dialog.v1.DialogService.DeleteMessage:input_type -> dialog.v1.DeleteMessageRequest
+	4,  // 25: dialog.v1.DialogService.StreamMessages:input_type -> dialog.v1.StreamMessagesRequest
+	21, // 26: dialog.v1.DialogService.CreateGroup:input_type -> dialog.v1.CreateGroupRequest
+	23, // 27: dialog.v1.DialogService.ListGroups:input_type -> dialog.v1.ListGroupsRequest
+	25, // 28: dialog.v1.DialogService.ListGroupMembers:input_type -> dialog.v1.ListGroupMembersRequest
+	27, // 29: dialog.v1.DialogService.AddGroupMembers:input_type -> dialog.v1.AddGroupMembersRequest
+	29, // 30: dialog.v1.DialogService.RemoveGroupMember:input_type -> dialog.v1.RemoveGroupMemberRequest
+	31, // 31: dialog.v1.DialogService.SetGroupMemberRole:input_type -> dialog.v1.SetGroupMemberRoleRequest
+	33, // 32: dialog.v1.DialogService.LeaveGroup:input_type -> dialog.v1.LeaveGroupRequest
+	35, // 33: dialog.v1.DialogService.SendGroupMessage:input_type -> dialog.v1.SendGroupMessageRequest
+	36, // 34: dialog.v1.DialogService.GetGroupMessages:input_type -> dialog.v1.GetGroupMessagesRequest
+	2,  // 35: dialog.v1.DialogService.SendMessage:output_type -> dialog.v1.SendMessageResponse
+	6,  // 36: dialog.v1.DialogService.GetMessages:output_type -> dialog.v1.GetMessagesResponse
+	9,  // 37: dialog.v1.DialogService.ListDialogs:output_type -> dialog.v1.ListDialogsResponse
+	11, // 38: dialog.v1.DialogService.MarkRead:output_type -> dialog.v1.MarkReadResponse
+	13, // 39: dialog.v1.DialogService.GetUnreadCounters:output_type -> dialog.v1.GetUnreadCountersResponse
+	15, // 40: dialog.v1.DialogService.EditMessage:output_type -> dialog.v1.EditMessageResponse
+	17, // 41: dialog.v1.DialogService.DeleteMessage:output_type -> dialog.v1.DeleteMessageResponse
+	5,  // 42: dialog.v1.DialogService.StreamMessages:output_type -> dialog.v1.DialogMessage
+	22, // 43: dialog.v1.DialogService.CreateGroup:output_type -> dialog.v1.CreateGroupResponse
+	24, // 44: dialog.v1.DialogService.ListGroups:output_type -> dialog.v1.ListGroupsResponse
+	26, // 45: dialog.v1.DialogService.ListGroupMembers:output_type -> dialog.v1.ListGroupMembersResponse
+	28, // 46: dialog.v1.DialogService.AddGroupMembers:output_type -> dialog.v1.AddGroupMembersResponse
+	30, // 47: dialog.v1.DialogService.RemoveGroupMember:output_type -> dialog.v1.RemoveGroupMemberResponse
+	32, // 48: dialog.v1.DialogService.SetGroupMemberRole:output_type -> dialog.v1.SetGroupMemberRoleResponse
+	34, // 49: dialog.v1.DialogService.LeaveGroup:output_type -> dialog.v1.LeaveGroupResponse
+	2,  // 50: dialog.v1.DialogService.SendGroupMessage:output_type -> dialog.v1.SendMessageResponse
+	37, // 51: dialog.v1.DialogService.GetGroupMessages:output_type -> dialog.v1.GetGroupMessagesResponse
+	35, // [35:52] is the sub-list for method output_type
+	18, // [18:35] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
 }
 
 func init() { file_pkg_proto_dialog_v1_dialog_proto_init() }
@@ -392,13 +2546,14 @@ func file_pkg_proto_dialog_v1_dialog_proto_init() {
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_dialog_v1_dialog_proto_rawDesc), len(file_pkg_proto_dialog_v1_dialog_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   5,
+			NumEnums:      1,
+			NumMessages:   38,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
 		GoTypes:           file_pkg_proto_dialog_v1_dialog_proto_goTypes,
 		DependencyIndexes: file_pkg_proto_dialog_v1_dialog_proto_depIdxs,
+		EnumInfos:         file_pkg_proto_dialog_v1_dialog_proto_enumTypes,
 		MessageInfos:      file_pkg_proto_dialog_v1_dialog_proto_msgTypes,
 	}.Build()
 	File_pkg_proto_dialog_v1_dialog_proto = out.File
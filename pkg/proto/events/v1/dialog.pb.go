@@ -0,0 +1,536 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pkg/proto/events/v1/dialog.proto
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// DialogMessageEvent - событие message.created
+type DialogMessageEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderId      int64                  `protobuf:"varint,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	ReceiverId    int64                  `protobuf:"varint,3,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DialogMessageEvent) Reset() {
+	*x = DialogMessageEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DialogMessageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialogMessageEvent) ProtoMessage() {}
+
+func (x *DialogMessageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialogMessageEvent.ProtoReflect.Descriptor instead.
+func (*DialogMessageEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *DialogMessageEvent) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *DialogMessageEvent) GetSenderId() int64 {
+	if x != nil {
+		return x.SenderId
+	}
+	return 0
+}
+
+func (x *DialogMessageEvent) GetReceiverId() int64 {
+	if x != nil {
+		return x.ReceiverId
+	}
+	return 0
+}
+
+func (x *DialogMessageEvent) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+// DialogReadEvent - событие message.read: reader_id прочитал сообщения
+// собеседника peer_id до up_to_message_id включительно
+type DialogReadEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	ReaderId      int64                  `protobuf:"varint,1,opt,name=reader_id,json=readerId,proto3" json:"reader_id,omitempty"`
+	PeerId        int64                  `protobuf:"varint,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	UpToMessageId int64                  `protobuf:"varint,3,opt,name=up_to_message_id,json=upToMessageId,proto3" json:"up_to_message_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DialogReadEvent) Reset() {
+	*x = DialogReadEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DialogReadEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialogReadEvent) ProtoMessage() {}
+
+func (x *DialogReadEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialogReadEvent.ProtoReflect.Descriptor instead.
+func (*DialogReadEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *DialogReadEvent) GetReaderId() int64 {
+	if x != nil {
+		return x.ReaderId
+	}
+	return 0
+}
+
+func (x *DialogReadEvent) GetPeerId() int64 {
+	if x != nil {
+		return x.PeerId
+	}
+	return 0
+}
+
+func (x *DialogReadEvent) GetUpToMessageId() int64 {
+	if x != nil {
+		return x.UpToMessageId
+	}
+	return 0
+}
+
+// UnreadChangedEvent - событие unread.changed: изменилось число непрочитанных
+// сообщений пользователя user_id в диалоге с peer_id
+type UnreadChangedEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	PeerId        int64                  `protobuf:"varint,2,opt,name=peer_id,json=peerId,proto3" json:"peer_id,omitempty"`
+	UnreadCount   int32                  `protobuf:"varint,3,opt,name=unread_count,json=unreadCount,proto3" json:"unread_count,omitempty"`
+	TotalUnread   int32                  `protobuf:"varint,4,opt,name=total_unread,json=totalUnread,proto3" json:"total_unread,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnreadChangedEvent) Reset() {
+	*x = UnreadChangedEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnreadChangedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnreadChangedEvent) ProtoMessage() {}
+
+func (x *UnreadChangedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnreadChangedEvent.ProtoReflect.Descriptor instead.
+func (*UnreadChangedEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *UnreadChangedEvent) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *UnreadChangedEvent) GetPeerId() int64 {
+	if x != nil {
+		return x.PeerId
+	}
+	return 0
+}
+
+func (x *UnreadChangedEvent) GetUnreadCount() int32 {
+	if x != nil {
+		return x.UnreadCount
+	}
+	return 0
+}
+
+func (x *UnreadChangedEvent) GetTotalUnread() int32 {
+	if x != nil {
+		return x.TotalUnread
+	}
+	return 0
+}
+
+// DialogMessageEditedEvent - событие message.edited
+type DialogMessageEditedEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderId      int64                  `protobuf:"varint,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	ReceiverId    int64                  `protobuf:"varint,3,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	EditedAt      int64                  `protobuf:"varint,5,opt,name=edited_at,json=editedAt,proto3" json:"edited_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DialogMessageEditedEvent) Reset() {
+	*x = DialogMessageEditedEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DialogMessageEditedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialogMessageEditedEvent) ProtoMessage() {}
+
+func (x *DialogMessageEditedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialogMessageEditedEvent.ProtoReflect.Descriptor instead.
+func (*DialogMessageEditedEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *DialogMessageEditedEvent) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *DialogMessageEditedEvent) GetSenderId() int64 {
+	if x != nil {
+		return x.SenderId
+	}
+	return 0
+}
+
+func (x *DialogMessageEditedEvent) GetReceiverId() int64 {
+	if x != nil {
+		return x.ReceiverId
+	}
+	return 0
+}
+
+func (x *DialogMessageEditedEvent) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *DialogMessageEditedEvent) GetEditedAt() int64 {
+	if x != nil {
+		return x.EditedAt
+	}
+	return 0
+}
+
+// DialogMessageDeletedEvent - событие message.deleted: сообщение удалено для всех
+type DialogMessageDeletedEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	SenderId      int64                  `protobuf:"varint,2,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	ReceiverId    int64                  `protobuf:"varint,3,opt,name=receiver_id,json=receiverId,proto3" json:"receiver_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *DialogMessageDeletedEvent) Reset() {
+	*x = DialogMessageDeletedEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *DialogMessageDeletedEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*DialogMessageDeletedEvent) ProtoMessage() {}
+
+func (x *DialogMessageDeletedEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use DialogMessageDeletedEvent.ProtoReflect.Descriptor instead.
+func (*DialogMessageDeletedEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *DialogMessageDeletedEvent) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *DialogMessageDeletedEvent) GetSenderId() int64 {
+	if x != nil {
+		return x.SenderId
+	}
+	return 0
+}
+
+func (x *DialogMessageDeletedEvent) GetReceiverId() int64 {
+	if x != nil {
+		return x.ReceiverId
+	}
+	return 0
+}
+
+// GroupMessageEvent - событие group.message.created: новое сообщение группы.
+// member_ids - участники на момент отправки, включая отправителя
+type GroupMessageEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	MessageId     int64                  `protobuf:"varint,1,opt,name=message_id,json=messageId,proto3" json:"message_id,omitempty"`
+	GroupId       int64                  `protobuf:"varint,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	SenderId      int64                  `protobuf:"varint,3,opt,name=sender_id,json=senderId,proto3" json:"sender_id,omitempty"`
+	Text          string                 `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	MemberIds     []int64                `protobuf:"varint,5,rep,packed,name=member_ids,json=memberIds,proto3" json:"member_ids,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GroupMessageEvent) Reset() {
+	*x = GroupMessageEvent{}
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GroupMessageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GroupMessageEvent) ProtoMessage() {}
+
+func (x *GroupMessageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_dialog_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GroupMessageEvent.ProtoReflect.Descriptor instead.
+func (*GroupMessageEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_dialog_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GroupMessageEvent) GetMessageId() int64 {
+	if x != nil {
+		return x.MessageId
+	}
+	return 0
+}
+
+func (x *GroupMessageEvent) GetGroupId() int64 {
+	if x != nil {
+		return x.GroupId
+	}
+	return 0
+}
+
+func (x *GroupMessageEvent) GetSenderId() int64 {
+	if x != nil {
+		return x.SenderId
+	}
+	return 0
+}
+
+func (x *GroupMessageEvent) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+func (x *GroupMessageEvent) GetMemberIds() []int64 {
+	if x != nil {
+		return x.MemberIds
+	}
+	return nil
+}
+
+var File_pkg_proto_events_v1_dialog_proto protoreflect.FileDescriptor
+
+const file_pkg_proto_events_v1_dialog_proto_rawDesc = "" +
+	"\n" +
+	" pkg/proto/events/v1/dialog.proto\x12\tevents.v1\"\x85\x01\n" +
+	"\x12DialogMessageEvent\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\x03R\tmessageId\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\x03R\bsenderId\x12\x1f\n" +
+	"\vreceiver_id\x18\x03 \x01(\x03R\n" +
+	"receiverId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\"p\n" +
+	"\x0fDialogReadEvent\x12\x1b\n" +
+	"\treader_id\x18\x01 \x01(\x03R\breaderId\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\x03R\x06peerId\x12'\n" +
+	"\x10up_to_message_id\x18\x03 \x01(\x03R\rupToMessageId\"\x8c\x01\n" +
+	"\x12UnreadChangedEvent\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x17\n" +
+	"\apeer_id\x18\x02 \x01(\x03R\x06peerId\x12!\n" +
+	"\funread_count\x18\x03 \x01(\x05R\vunreadCount\x12!\n" +
+	"\ftotal_unread\x18\x04 \x01(\x05R\vtotalUnread\"\xa8\x01\n" +
+	"\x18DialogMessageEditedEvent\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\x03R\tmessageId\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\x03R\bsenderId\x12\x1f\n" +
+	"\vreceiver_id\x18\x03 \x01(\x03R\n" +
+	"receiverId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x1b\n" +
+	"\tedited_at\x18\x05 \x01(\x03R\beditedAt\"x\n" +
+	"\x19DialogMessageDeletedEvent\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\x03R\tmessageId\x12\x1b\n" +
+	"\tsender_id\x18\x02 \x01(\x03R\bsenderId\x12\x1f\n" +
+	"\vreceiver_id\x18\x03 \x01(\x03R\n" +
+	"receiverId\"\x9d\x01\n" +
+	"\x11GroupMessageEvent\x12\x1d\n" +
+	"\n" +
+	"message_id\x18\x01 \x01(\x03R\tmessageId\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\x03R\agroupId\x12\x1b\n" +
+	"\tsender_id\x18\x03 \x01(\x03R\bsenderId\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text\x12\x1d\n" +
+	"\n" +
+	"member_ids\x18\x05 \x03(\x03R\tmemberIdsB\x1fZ\x1dsocial/pkg/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_pkg_proto_events_v1_dialog_proto_rawDescOnce sync.Once
+	file_pkg_proto_events_v1_dialog_proto_rawDescData []byte
+)
+
+func file_pkg_proto_events_v1_dialog_proto_rawDescGZIP() []byte {
+	file_pkg_proto_events_v1_dialog_proto_rawDescOnce.Do(func() {
+		file_pkg_proto_events_v1_dialog_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_dialog_proto_rawDesc), len(file_pkg_proto_events_v1_dialog_proto_rawDesc)))
+	})
+	return file_pkg_proto_events_v1_dialog_proto_rawDescData
+}
+
+var file_pkg_proto_events_v1_dialog_proto_msgTypes = make([]protoimpl.MessageInfo, 6)
+var file_pkg_proto_events_v1_dialog_proto_goTypes = []any{
+	(*DialogMessageEvent)(nil),        // 0: events.v1.DialogMessageEvent
+	(*DialogReadEvent)(nil),           // 1: events.v1.DialogReadEvent
+	(*UnreadChangedEvent)(nil),        // 2: events.v1.UnreadChangedEvent
+	(*DialogMessageEditedEvent)(nil),  // 3: events.v1.DialogMessageEditedEvent
+	(*DialogMessageDeletedEvent)(nil), // 4: events.v1.DialogMessageDeletedEvent
+	(*GroupMessageEvent)(nil),         // 5: events.v1.GroupMessageEvent
+}
+var file_pkg_proto_events_v1_dialog_proto_depIdxs = []int32{
+	0, // [0:0] is the sub-list for method output_type
+	0, // [0:0] is the sub-list for method input_type
+	0, // [0:0] is the sub-list for extension type_name
+	0, // [0:0] is the sub-list for extension extendee
+	0, // [0:0] is the sub-list for field type_name
+}
+
+func init() { file_pkg_proto_events_v1_dialog_proto_init() }
+func file_pkg_proto_events_v1_dialog_proto_init() {
+	if File_pkg_proto_events_v1_dialog_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_dialog_proto_rawDesc), len(file_pkg_proto_events_v1_dialog_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   6,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_proto_events_v1_dialog_proto_goTypes,
+		DependencyIndexes: file_pkg_proto_events_v1_dialog_proto_depIdxs,
+		MessageInfos:      file_pkg_proto_events_v1_dialog_proto_msgTypes,
+	}.Build()
+	File_pkg_proto_events_v1_dialog_proto = out.File
+	file_pkg_proto_events_v1_dialog_proto_goTypes = nil
+	file_pkg_proto_events_v1_dialog_proto_depIdxs = nil
+}
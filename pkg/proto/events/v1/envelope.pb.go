@@ -0,0 +1,236 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pkg/proto/events/v1/envelope.proto
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Envelope - обёртка каждого события в Kafka.
+// payload содержит сериализованное сообщение, соответствующее type
+type Envelope struct {
+	state   protoimpl.MessageState `protogen:"open.v1"`
+	EventId string                 `protobuf:"bytes,1,opt,name=event_id,json=eventId,proto3" json:"event_id,omitempty"`
+	// Тип события, например "post.created"
+	Type string `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	// Версия схемы payload в формате "<major>.<minor>". Консьюмер отклоняет
+	// события с неизвестной мажорной версией
+	Version       string                 `protobuf:"bytes,3,opt,name=version,proto3" json:"version,omitempty"`
+	OccurredAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=occurred_at,json=occurredAt,proto3" json:"occurred_at,omitempty"`
+	Trace         *TraceContext          `protobuf:"bytes,5,opt,name=trace,proto3" json:"trace,omitempty"`
+	Payload       []byte                 `protobuf:"bytes,6,opt,name=payload,proto3" json:"payload,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_pkg_proto_events_v1_envelope_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_envelope_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_envelope_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Envelope) GetEventId() string {
+	if x != nil {
+		return x.EventId
+	}
+	return ""
+}
+
+func (x *Envelope) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Envelope) GetVersion() string {
+	if x != nil {
+		return x.Version
+	}
+	return ""
+}
+
+func (x *Envelope) GetOccurredAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.OccurredAt
+	}
+	return nil
+}
+
+func (x *Envelope) GetTrace() *TraceContext {
+	if x != nil {
+		return x.Trace
+	}
+	return nil
+}
+
+func (x *Envelope) GetPayload() []byte {
+	if x != nil {
+		return x.Payload
+	}
+	return nil
+}
+
+// TraceContext - контекст запроса, породившего событие
+type TraceContext struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	RequestId string                 `protobuf:"bytes,1,opt,name=request_id,json=requestId,proto3" json:"request_id,omitempty"`
+	// W3C traceparent, если был передан клиентом
+	Traceparent   string `protobuf:"bytes,2,opt,name=traceparent,proto3" json:"traceparent,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TraceContext) Reset() {
+	*x = TraceContext{}
+	mi := &file_pkg_proto_events_v1_envelope_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TraceContext) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TraceContext) ProtoMessage() {}
+
+func (x *TraceContext) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_envelope_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TraceContext.ProtoReflect.Descriptor instead.
+func (*TraceContext) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_envelope_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *TraceContext) GetRequestId() string {
+	if x != nil {
+		return x.RequestId
+	}
+	return ""
+}
+
+func (x *TraceContext) GetTraceparent() string {
+	if x != nil {
+		return x.Traceparent
+	}
+	return ""
+}
+
+var File_pkg_proto_events_v1_envelope_proto protoreflect.FileDescriptor
+
+const file_pkg_proto_events_v1_envelope_proto_rawDesc = "" +
+	"\n" +
+	"\"pkg/proto/events/v1/envelope.proto\x12\tevents.v1\x1a\x1fgoogle/protobuf/timestamp.proto\"\xd9\x01\n" +
+	"\bEnvelope\x12\x19\n" +
+	"\bevent_id\x18\x01 \x01(\tR\aeventId\x12\x12\n" +
+	"\x04type\x18\x02 \x01(\tR\x04type\x12\x18\n" +
+	"\aversion\x18\x03 \x01(\tR\aversion\x12;\n" +
+	"\voccurred_at\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"occurredAt\x12-\n" +
+	"\x05trace\x18\x05 \x01(\v2\x17.events.v1.TraceContextR\x05trace\x12\x18\n" +
+	"\apayload\x18\x06 \x01(\fR\apayload\"O\n" +
+	"\fTraceContext\x12\x1d\n" +
+	"\n" +
+	"request_id\x18\x01 \x01(\tR\trequestId\x12 \n" +
+	"\vtraceparent\x18\x02 \x01(\tR\vtraceparentB\x1fZ\x1dsocial/pkg/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_pkg_proto_events_v1_envelope_proto_rawDescOnce sync.Once
+	file_pkg_proto_events_v1_envelope_proto_rawDescData []byte
+)
+
+func file_pkg_proto_events_v1_envelope_proto_rawDescGZIP() []byte {
+	file_pkg_proto_events_v1_envelope_proto_rawDescOnce.Do(func() {
+		file_pkg_proto_events_v1_envelope_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_envelope_proto_rawDesc), len(file_pkg_proto_events_v1_envelope_proto_rawDesc)))
+	})
+	return file_pkg_proto_events_v1_envelope_proto_rawDescData
+}
+
+var file_pkg_proto_events_v1_envelope_proto_msgTypes = make([]protoimpl.MessageInfo, 2)
+var file_pkg_proto_events_v1_envelope_proto_goTypes = []any{
+	(*Envelope)(nil),              // 0: events.v1.Envelope
+	(*TraceContext)(nil),          // 1: events.v1.TraceContext
+	(*timestamppb.Timestamp)(nil), // 2: google.protobuf.Timestamp
+}
+var file_pkg_proto_events_v1_envelope_proto_depIdxs = []int32{
+	2, // 0: events.v1.Envelope.occurred_at:type_name -> google.protobuf.Timestamp
+	1, // 1: events.v1.Envelope.trace:type_name -> events.v1.TraceContext
+	2, // [2:2] is the sub-list for method output_type
+	2, // [2:2] is the sub-list for method input_type
+	2, // [2:2] is the sub-list for extension type_name
+	2, // [2:2] is the sub-list for extension extendee
+	0, // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_pkg_proto_events_v1_envelope_proto_init() }
+func file_pkg_proto_events_v1_envelope_proto_init() {
+	if File_pkg_proto_events_v1_envelope_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_envelope_proto_rawDesc), len(file_pkg_proto_events_v1_envelope_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   2,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_proto_events_v1_envelope_proto_goTypes,
+		DependencyIndexes: file_pkg_proto_events_v1_envelope_proto_depIdxs,
+		MessageInfos:      file_pkg_proto_events_v1_envelope_proto_msgTypes,
+	}.Build()
+	File_pkg_proto_events_v1_envelope_proto = out.File
+	file_pkg_proto_events_v1_envelope_proto_goTypes = nil
+	file_pkg_proto_events_v1_envelope_proto_depIdxs = nil
+}
@@ -0,0 +1,198 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pkg/proto/events/v1/friendship.proto
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type FriendshipAction int32
+
+const (
+	FriendshipAction_FRIENDSHIP_ACTION_UNSPECIFIED FriendshipAction = 0
+	FriendshipAction_FRIENDSHIP_ACTION_ADDED       FriendshipAction = 1
+	FriendshipAction_FRIENDSHIP_ACTION_REMOVED     FriendshipAction = 2
+)
+
+// Enum value maps for FriendshipAction.
+var (
+	FriendshipAction_name = map[int32]string{
+		0: "FRIENDSHIP_ACTION_UNSPECIFIED",
+		1: "FRIENDSHIP_ACTION_ADDED",
+		2: "FRIENDSHIP_ACTION_REMOVED",
+	}
+	FriendshipAction_value = map[string]int32{
+		"FRIENDSHIP_ACTION_UNSPECIFIED": 0,
+		"FRIENDSHIP_ACTION_ADDED":       1,
+		"FRIENDSHIP_ACTION_REMOVED":     2,
+	}
+)
+
+func (x FriendshipAction) Enum() *FriendshipAction {
+	p := new(FriendshipAction)
+	*p = x
+	return p
+}
+
+func (x FriendshipAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (FriendshipAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_events_v1_friendship_proto_enumTypes[0].Descriptor()
+}
+
+func (FriendshipAction) Type() protoreflect.EnumType {
+	return &file_pkg_proto_events_v1_friendship_proto_enumTypes[0]
+}
+
+func (x FriendshipAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use FriendshipAction.Descriptor instead.
+func (FriendshipAction) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_friendship_proto_rawDescGZIP(), []int{0}
+}
+
+// FriendshipEvent - события friendship.added, friendship.removed
+type FriendshipEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        int64                  `protobuf:"varint,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	FriendId      int64                  `protobuf:"varint,2,opt,name=friend_id,json=friendId,proto3" json:"friend_id,omitempty"`
+	Action        FriendshipAction       `protobuf:"varint,3,opt,name=action,proto3,enum=events.v1.FriendshipAction" json:"action,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *FriendshipEvent) Reset() {
+	*x = FriendshipEvent{}
+	mi := &file_pkg_proto_events_v1_friendship_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *FriendshipEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FriendshipEvent) ProtoMessage() {}
+
+func (x *FriendshipEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_friendship_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FriendshipEvent.ProtoReflect.Descriptor instead.
+func (*FriendshipEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_friendship_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *FriendshipEvent) GetUserId() int64 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *FriendshipEvent) GetFriendId() int64 {
+	if x != nil {
+		return x.FriendId
+	}
+	return 0
+}
+
+func (x *FriendshipEvent) GetAction() FriendshipAction {
+	if x != nil {
+		return x.Action
+	}
+	return FriendshipAction_FRIENDSHIP_ACTION_UNSPECIFIED
+}
+
+var File_pkg_proto_events_v1_friendship_proto protoreflect.FileDescriptor
+
+const file_pkg_proto_events_v1_friendship_proto_rawDesc = "" +
+	"\n" +
+	"$pkg/proto/events/v1/friendship.proto\x12\tevents.v1\"|\n" +
+	"\x0fFriendshipEvent\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\x03R\x06userId\x12\x1b\n" +
+	"\tfriend_id\x18\x02 \x01(\x03R\bfriendId\x123\n" +
+	"\x06action\x18\x03 \x01(\x0e2\x1b.events.v1.FriendshipActionR\x06action*q\n" +
+	"\x10FriendshipAction\x12!\n" +
+	"\x1dFRIENDSHIP_ACTION_UNSPECIFIED\x10\x00\x12\x1b\n" +
+	"\x17FRIENDSHIP_ACTION_ADDED\x10\x01\x12\x1d\n" +
+	"\x19FRIENDSHIP_ACTION_REMOVED\x10\x02B\x1fZ\x1dsocial/pkg/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_pkg_proto_events_v1_friendship_proto_rawDescOnce sync.Once
+	file_pkg_proto_events_v1_friendship_proto_rawDescData []byte
+)
+
+func file_pkg_proto_events_v1_friendship_proto_rawDescGZIP() []byte {
+	file_pkg_proto_events_v1_friendship_proto_rawDescOnce.Do(func() {
+		file_pkg_proto_events_v1_friendship_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_friendship_proto_rawDesc), len(file_pkg_proto_events_v1_friendship_proto_rawDesc)))
+	})
+	return file_pkg_proto_events_v1_friendship_proto_rawDescData
+}
+
+var file_pkg_proto_events_v1_friendship_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_proto_events_v1_friendship_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pkg_proto_events_v1_friendship_proto_goTypes = []any{
+	(FriendshipAction)(0),   // 0: events.v1.FriendshipAction
+	(*FriendshipEvent)(nil), // 1: events.v1.FriendshipEvent
+}
+var file_pkg_proto_events_v1_friendship_proto_depIdxs = []int32{
+	0, // 0: events.v1.FriendshipEvent.action:type_name -> events.v1.FriendshipAction
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_pkg_proto_events_v1_friendship_proto_init() }
+func file_pkg_proto_events_v1_friendship_proto_init() {
+	if File_pkg_proto_events_v1_friendship_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_friendship_proto_rawDesc), len(file_pkg_proto_events_v1_friendship_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_proto_events_v1_friendship_proto_goTypes,
+		DependencyIndexes: file_pkg_proto_events_v1_friendship_proto_depIdxs,
+		EnumInfos:         file_pkg_proto_events_v1_friendship_proto_enumTypes,
+		MessageInfos:      file_pkg_proto_events_v1_friendship_proto_msgTypes,
+	}.Build()
+	File_pkg_proto_events_v1_friendship_proto = out.File
+	file_pkg_proto_events_v1_friendship_proto_goTypes = nil
+	file_pkg_proto_events_v1_friendship_proto_depIdxs = nil
+}
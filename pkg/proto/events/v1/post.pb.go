@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.6
+// 	protoc        v5.29.3
+// source: pkg/proto/events/v1/post.proto
+
+package eventsv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type PostAction int32
+
+const (
+	PostAction_POST_ACTION_UNSPECIFIED PostAction = 0
+	PostAction_POST_ACTION_CREATED     PostAction = 1
+	PostAction_POST_ACTION_UPDATED     PostAction = 2
+	PostAction_POST_ACTION_DELETED     PostAction = 3
+)
+
+// Enum value maps for PostAction.
+var (
+	PostAction_name = map[int32]string{
+		0: "POST_ACTION_UNSPECIFIED",
+		1: "POST_ACTION_CREATED",
+		2: "POST_ACTION_UPDATED",
+		3: "POST_ACTION_DELETED",
+	}
+	PostAction_value = map[string]int32{
+		"POST_ACTION_UNSPECIFIED": 0,
+		"POST_ACTION_CREATED":     1,
+		"POST_ACTION_UPDATED":     2,
+		"POST_ACTION_DELETED":     3,
+	}
+)
+
+func (x PostAction) Enum() *PostAction {
+	p := new(PostAction)
+	*p = x
+	return p
+}
+
+func (x PostAction) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (PostAction) Descriptor() protoreflect.EnumDescriptor {
+	return file_pkg_proto_events_v1_post_proto_enumTypes[0].Descriptor()
+}
+
+func (PostAction) Type() protoreflect.EnumType {
+	return &file_pkg_proto_events_v1_post_proto_enumTypes[0]
+}
+
+func (x PostAction) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use PostAction.Descriptor instead.
+func (PostAction) EnumDescriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_post_proto_rawDescGZIP(), []int{0}
+}
+
+// PostEvent - события post.created, post.updated, post.deleted
+type PostEvent struct {
+	state    protoimpl.MessageState `protogen:"open.v1"`
+	PostId   string                 `protobuf:"bytes,1,opt,name=post_id,json=postId,proto3" json:"post_id,omitempty"`
+	AuthorId int64                  `protobuf:"varint,2,opt,name=author_id,json=authorId,proto3" json:"author_id,omitempty"`
+	Action   PostAction             `protobuf:"varint,3,opt,name=action,proto3,enum=events.v1.PostAction" json:"action,omitempty"`
+	// Пустой для post.deleted
+	Text          string `protobuf:"bytes,4,opt,name=text,proto3" json:"text,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PostEvent) Reset() {
+	*x = PostEvent{}
+	mi := &file_pkg_proto_events_v1_post_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PostEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PostEvent) ProtoMessage() {}
+
+func (x *PostEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_pkg_proto_events_v1_post_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PostEvent.ProtoReflect.Descriptor instead.
+func (*PostEvent) Descriptor() ([]byte, []int) {
+	return file_pkg_proto_events_v1_post_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *PostEvent) GetPostId() string {
+	if x != nil {
+		return x.PostId
+	}
+	return ""
+}
+
+func (x *PostEvent) GetAuthorId() int64 {
+	if x != nil {
+		return x.AuthorId
+	}
+	return 0
+}
+
+func (x *PostEvent) GetAction() PostAction {
+	if x != nil {
+		return x.Action
+	}
+	return PostAction_POST_ACTION_UNSPECIFIED
+}
+
+func (x *PostEvent) GetText() string {
+	if x != nil {
+		return x.Text
+	}
+	return ""
+}
+
+var File_pkg_proto_events_v1_post_proto protoreflect.FileDescriptor
+
+const file_pkg_proto_events_v1_post_proto_rawDesc = "" +
+	"\n" +
+	"\x1epkg/proto/events/v1/post.proto\x12\tevents.v1\"\x84\x01\n" +
+	"\tPostEvent\x12\x17\n" +
+	"\apost_id\x18\x01 \x01(\tR\x06postId\x12\x1b\n" +
+	"\tauthor_id\x18\x02 \x01(\x03R\bauthorId\x12-\n" +
+	"\x06action\x18\x03 \x01(\x0e2\x15.events.v1.PostActionR\x06action\x12\x12\n" +
+	"\x04text\x18\x04 \x01(\tR\x04text*t\n" +
+	"\n" +
+	"PostAction\x12\x1b\n" +
+	"\x17POST_ACTION_UNSPECIFIED\x10\x00\x12\x17\n" +
+	"\x13POST_ACTION_CREATED\x10\x01\x12\x17\n" +
+	"\x13POST_ACTION_UPDATED\x10\x02\x12\x17\n" +
+	"\x13POST_ACTION_DELETED\x10\x03B\x1fZ\x1dsocial/pkg/events/v1;eventsv1b\x06proto3"
+
+var (
+	file_pkg_proto_events_v1_post_proto_rawDescOnce sync.Once
+	file_pkg_proto_events_v1_post_proto_rawDescData []byte
+)
+
+func file_pkg_proto_events_v1_post_proto_rawDescGZIP() []byte {
+	file_pkg_proto_events_v1_post_proto_rawDescOnce.Do(func() {
+		file_pkg_proto_events_v1_post_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_post_proto_rawDesc), len(file_pkg_proto_events_v1_post_proto_rawDesc)))
+	})
+	return file_pkg_proto_events_v1_post_proto_rawDescData
+}
+
+var file_pkg_proto_events_v1_post_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_pkg_proto_events_v1_post_proto_msgTypes = make([]protoimpl.MessageInfo, 1)
+var file_pkg_proto_events_v1_post_proto_goTypes = []any{
+	(PostAction)(0),   // 0: events.v1.PostAction
+	(*PostEvent)(nil), // 1: events.v1.PostEvent
+}
+var file_pkg_proto_events_v1_post_proto_depIdxs = []int32{
+	0, // 0: events.v1.PostEvent.action:type_name -> events.v1.PostAction
+	1, // [1:1] is the sub-list for method output_type
+	1, // [1:1] is the sub-list for method input_type
+	1, // [1:1] is the sub-list for extension type_name
+	1, // [1:1] is the sub-list for extension extendee
+	0, // [0:1] is the sub-list for field type_name
+}
+
+func init() { file_pkg_proto_events_v1_post_proto_init() }
+func file_pkg_proto_events_v1_post_proto_init() {
+	if File_pkg_proto_events_v1_post_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_pkg_proto_events_v1_post_proto_rawDesc), len(file_pkg_proto_events_v1_post_proto_rawDesc)),
+			NumEnums:      1,
+			NumMessages:   1,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_pkg_proto_events_v1_post_proto_goTypes,
+		DependencyIndexes: file_pkg_proto_events_v1_post_proto_depIdxs,
+		EnumInfos:         file_pkg_proto_events_v1_post_proto_enumTypes,
+		MessageInfos:      file_pkg_proto_events_v1_post_proto_msgTypes,
+	}.Build()
+	File_pkg_proto_events_v1_post_proto = out.File
+	file_pkg_proto_events_v1_post_proto_goTypes = nil
+	file_pkg_proto_events_v1_post_proto_depIdxs = nil
+}
@@ -0,0 +1,60 @@
+// Package saga выполняет последовательность шагов над разными хранилищами.
+// При ошибке шага уже выполненные шаги отменяются компенсациями в обратном
+// порядке, так что хранилища не остаются в частично обновленном состоянии
+package saga
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// compensationTimeout ограничивает компенсации: они выполняются и после
+// отмены контекста запроса
+const compensationTimeout = 10 * time.Second
+
+// ErrCompensation оборачивает ошибки компенсаций: после нее данные могут
+// остаться несогласованными до сверки
+var ErrCompensation = errors.New("saga compensation failed")
+
+// Step - шаг саги. Compensate отменяет успешно выполненный Action и может
+// быть nil, если отменять нечего
+type Step struct {
+	Name       string
+	Action     func(ctx context.Context) error
+	Compensate func(ctx context.Context) error
+}
+
+// Run выполняет шаги по порядку. При ошибке шага компенсирует выполненные
+// шаги в обратном порядке и возвращает ошибку шага вместе с ошибками компенсаций
+func Run(ctx context.Context, steps ...Step) error {
+	for i, step := range steps {
+		err := step.Action(ctx)
+		if err == nil {
+			continue
+		}
+
+		err = fmt.Errorf("saga step %q: %w", step.Name, err)
+		return errors.Join(err, compensate(ctx, steps[:i]))
+	}
+
+	return nil
+}
+
+func compensate(ctx context.Context, done []Step) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), compensationTimeout)
+	defer cancel()
+
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		if done[i].Compensate == nil {
+			continue
+		}
+		if err := done[i].Compensate(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("%w: step %q: %v", ErrCompensation, done[i].Name, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
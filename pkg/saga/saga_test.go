@@ -0,0 +1,77 @@
+package saga
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun_CompensatesInReverseOrder(t *testing.T) {
+	var calls []string
+	step := func(name string, fail bool) Step {
+		return Step{
+			Name: name,
+			Action: func(context.Context) error {
+				calls = append(calls, name)
+				if fail {
+					return errors.New("boom")
+				}
+				return nil
+			},
+			Compensate: func(context.Context) error {
+				calls = append(calls, "undo "+name)
+				return nil
+			},
+		}
+	}
+
+	err := Run(context.Background(), step("a", false), step("b", false), step("c", true), step("d", false))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `saga step "c"`)
+	assert.NotErrorIs(t, err, ErrCompensation)
+	assert.Equal(t, []string{"a", "b", "c", "undo b", "undo a"}, calls)
+}
+
+func TestRun_CompensationErrorsAndCanceledContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var compensatedWithLiveCtx bool
+	err := Run(ctx,
+		Step{
+			Name:   "a",
+			Action: func(context.Context) error { return nil },
+			Compensate: func(ctx context.Context) error {
+				compensatedWithLiveCtx = ctx.Err() == nil
+				return nil
+			},
+		},
+		Step{
+			Name:       "b",
+			Action:     func(context.Context) error { return nil },
+			Compensate: func(context.Context) error { return errors.New("undo failed") },
+		},
+		Step{
+			Name:   "c",
+			Action: func(context.Context) error { cancel(); return context.Canceled },
+		},
+	)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, ErrCompensation)
+	assert.True(t, compensatedWithLiveCtx, "компенсации не зависят от отмены запроса")
+}
+
+func TestRun_Success(t *testing.T) {
+	var compensated bool
+	err := Run(context.Background(), Step{
+		Name:       "a",
+		Action:     func(context.Context) error { return nil },
+		Compensate: func(context.Context) error { compensated = true; return nil },
+	})
+
+	require.NoError(t, err)
+	assert.False(t, compensated)
+}
@@ -0,0 +1,26 @@
+package tracing
+
+import "context"
+
+const (
+	RequestIDHeader   = "x-request-id"
+	TraceParentHeader = "traceparent"
+)
+
+// Trace - контекст запроса, передаваемый в события и исходящие вызовы
+type Trace struct {
+	RequestID   string
+	TraceParent string
+}
+
+type traceKey struct{}
+
+func WithTrace(ctx context.Context, trace Trace) context.Context {
+	return context.WithValue(ctx, traceKey{}, trace)
+}
+
+// FromContext возвращает контекст запроса или пустой Trace
+func FromContext(ctx context.Context) Trace {
+	trace, _ := ctx.Value(traceKey{}).(Trace)
+	return trace
+}